@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/approval"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+const requestUsage = `usage: maintctl request enable [flags] -reason "why" [-window-start RFC3339 -window-end RFC3339]
+       maintctl request list [flags]`
+
+const approveUsage = `usage: maintctl approve [flags] [-blackouts FILES] [-force -reason "why"] <id>`
+
+// protectedEnvironments lists the environments where enabling maintenance
+// needs a request and an approval, from MAINTCTL_PROTECTED_ENVIRONMENTS
+// (comma-separated, default production).
+func protectedEnvironments() []string {
+	v, ok := os.LookupEnv("MAINTCTL_PROTECTED_ENVIRONMENTS")
+	if !ok {
+		v = "production"
+	}
+	var envs []string
+	for _, env := range strings.Split(v, ",") {
+		if env = strings.TrimSpace(env); env != "" {
+			envs = append(envs, env)
+		}
+	}
+	return envs
+}
+
+func needsApproval(environment string) bool {
+	for _, env := range protectedEnvironments() {
+		if strings.EqualFold(env, environment) {
+			return true
+		}
+	}
+	return false
+}
+
+// stateEnvironment is the environment a change to s applies to. The
+// environment Terraform seeded into the state wins, and a flag that names a
+// different one is an error, so pointing -environment staging at the
+// production namespace can't skip the approval. States seeded without one
+// fall back to the flag, and with neither the environment is unknown, which
+// is an error rather than an unprotected environment.
+func stateEnvironment(s state.State, flag string) (string, error) {
+	if s.Environment == "" && flag == "" {
+		return "", errors.New("the state names no environment and neither -environment nor MAINTENANCE_ENVIRONMENT is set; re-seed the state with terraform apply -replace or pass -environment")
+	}
+	return s.EnvironmentFor(flag)
+}
+
+// identity is who runs the command: the -actor name and the ID Cloudflare
+// gives the API token in CLOUDFLARE_API_TOKEN.
+func identity(ctx context.Context, actor string) (approval.Identity, error) {
+	token, err := cloudflare.NewFromEnv().VerifyToken(ctx)
+	if err != nil {
+		return approval.Identity{}, fmt.Errorf("verifying CLOUDFLARE_API_TOKEN: %w", err)
+	}
+	return approval.Identity{Actor: actor, Token: token}, nil
+}
+
+// approvalFlags picks where requests are stored: the module's KV namespace
+// by default, or a JSON file.
+type approvalFlags struct {
+	store *string
+	kv    kvFlags
+}
+
+func addApprovalFlags(fs *flag.FlagSet, kv kvFlags) approvalFlags {
+	return approvalFlags{
+		store: fs.String("approval-store", os.Getenv("MAINTCTL_APPROVAL_STORE"), "where requests are kept: kv (the module's namespace) or a JSON file path (default $MAINTCTL_APPROVAL_STORE, else kv)"),
+		kv:    kv,
+	}
+}
+
+func (f approvalFlags) workflow() (approval.Workflow, error) {
+	if *f.store != "" && *f.store != "kv" {
+		return approval.Workflow{Store: approval.FileStore{Path: *f.store}}, nil
+	}
+	ns, err := f.kv.namespace()
+	if err != nil {
+		return approval.Workflow{}, err
+	}
+	return approval.Workflow{Store: approval.KVStore{KV: ns}}, nil
+}
+
+func runRequest(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(requestUsage)
+	}
+	sub := args[0]
+	fs := newFlagSet("request "+sub, stderr)
+	kv := addKVFlags(fs)
+	ap := addApprovalFlags(fs, kv)
+	environment := addEnvironmentFlag(fs)
+	actor := addActorFlag(fs)
+	reason := fs.String("reason", "", "why maintenance is needed (required for enable)")
+	windowStart := fs.String("window-start", "", "window the approval sets along with enabling (RFC3339)")
+	windowEnd := fs.String("window-end", "", "window end (RFC3339)")
+	ttl := fs.Duration("ttl", approval.DefaultTTL, "how long the request waits for approval")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf(requestUsage)
+	}
+	w, err := ap.workflow()
+	if err != nil {
+		return err
+	}
+	w.TTL = *ttl
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch sub {
+	case "enable":
+		if *environment == "" {
+			return fmt.Errorf("-environment or MAINTENANCE_ENVIRONMENT is required")
+		}
+		if err := (state.State{WindowStart: *windowStart, WindowEnd: *windowEnd}).Validate(); err != nil {
+			return err
+		}
+		who, err := identity(ctx, *actor)
+		if err != nil {
+			return err
+		}
+		change := approval.Request{Environment: *environment, Action: approval.ActionEnable, Reason: *reason, WindowStart: *windowStart, WindowEnd: *windowEnd}
+		r, err := w.Request(ctx, change, who)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "request %s: enable maintenance in %s, waiting for approval until %s\n", r.ID, r.Environment, r.ExpiresAt.Format(time.RFC3339))
+		if r.WindowStart != "" {
+			fmt.Fprintf(stdout, "the approval sets the window %s - %s\n", r.WindowStart, r.WindowEnd)
+		}
+		fmt.Fprintf(stdout, "someone other than %s, with another API token, must run: maintctl approve %s\n", r.RequestedBy, r.ID)
+	case "list":
+		list, err := w.List(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, r := range list {
+			fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Status(now), r.Environment, r.Action,
+				r.RequestedBy, r.RequestedAt.Format(time.RFC3339), r.Reason)
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", sub, requestUsage)
+	}
+	return nil
+}
+
+func runApprove(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("approve", stderr)
+	kv := addKVFlags(fs)
+	ap := addApprovalFlags(fs, kv)
+	sink := addAuditSinkFlag(fs)
+	actor := addActorFlag(fs)
+	sp := addStatusPageFlags(fs)
+	blackoutFiles := addBlackoutsFlag(fs)
+	force := fs.Bool("force", false, "enable during a blackout; needs -reason")
+	override := fs.String("reason", "", "why the approval overrides a blackout (with -force)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(approveUsage)
+	}
+	// Approvals gate protected environments, so there is no -no-audit here.
+	if err := checkAuditSink(*sink, false); err != nil {
+		return err
+	}
+	w, err := ap.workflow()
+	if err != nil {
+		return err
+	}
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	who, err := identity(ctx, *actor)
+	if err != nil {
+		return err
+	}
+	approver := who.Actor
+	r, err := w.Approve(ctx, fs.Arg(0), who, func(r approval.Request) error {
+		current, err := state.Load(ctx, ns)
+		if err != nil {
+			return err
+		}
+		// The audit event carries the request, not the approver's flags.
+		environment, err := stateEnvironment(current, r.Environment)
+		if err != nil {
+			return fmt.Errorf("request %s: %w", r.ID, err)
+		}
+		// A request can wait for approval into a blackout, so the check is
+		// made now. Protected environments can't skip it by leaving the
+		// blackouts unset.
+		if *blackoutFiles == "" && needsApproval(environment) {
+			return fmt.Errorf("request %s: enabling maintenance in %s needs -blackouts or MAINTCTL_BLACKOUTS to check against; point it at a file holding [] if there are none", r.ID, environment)
+		}
+		forced, err := checkBlackouts(ctx, ns, *blackoutFiles, r.WindowEnd, *force, *override, *sink, stderr)
+		if err != nil {
+			return fmt.Errorf("request %s: %w", r.ID, err)
+		}
+		action := r.Action
+		reason := fmt.Sprintf("%s (request %s by %s, approved by %s)", r.Reason, r.ID, r.RequestedBy, approver)
+		if forced {
+			action = "force-" + r.Action
+			reason += "; blackout overridden: " + *override
+		}
+		record := auditFlags{sink: sink, environment: &environment, actor: &approver, reason: &reason}
+		s, err := record.change(ctx, ns, action, func(s *state.State) {
+			s.Enabled = true
+			if r.WindowStart != "" {
+				s.WindowStart, s.WindowEnd = r.WindowStart, r.WindowEnd
+			}
+		}, stderr)
+		if err == nil {
+			err = sp.sync(ctx, ns, s, stderr)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "request %s approved by %s: maintenance enabled in %s\n", r.ID, r.ApprovedBy, r.Environment)
+	return nil
+}
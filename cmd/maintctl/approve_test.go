@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/audit"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+func TestStateEnableNeedsEnvironment(t *testing.T) {
+	// Neither the state nor the operator names the environment: it might be
+	// production, so enabling fails closed.
+	ns := mockState(t, state.State{RolloutPercentage: 100})
+	code, _, stderr := runMaintctl(t, "state", "enable", "-no-audit")
+	if code != 1 || !strings.Contains(stderr, "names no environment") {
+		t.Errorf("state enable without an environment = %d, %q", code, stderr)
+	}
+	if loadState(t, ns).Enabled {
+		t.Error("maintenance was enabled without an environment")
+	}
+
+	if code, _, stderr := runMaintctl(t, "state", "enable", "-no-audit", "-environment", "staging"); code != 0 {
+		t.Errorf("state enable -environment staging = %d, %q", code, stderr)
+	}
+}
+
+func TestStateEnableUsesNamespaceEnvironment(t *testing.T) {
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	for _, args := range [][]string{
+		{"state", "enable", "-no-audit"},
+		{"state", "enable", "-no-audit", "-environment", "staging"},
+		{"state", "enable", "-no-audit", "-environment", "Production"},
+	} {
+		code, _, stderr := runMaintctl(t, args...)
+		if code != 1 || !strings.Contains(stderr, "production") {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	if loadState(t, ns).Enabled {
+		t.Error("maintenance was enabled in production without an approval")
+	}
+}
+
+func TestApproveUsesNamespaceEnvironment(t *testing.T) {
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	t.Setenv("MAINTCTL_APPROVAL_STORE", filepath.Join(t.TempDir(), "requests.json"))
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	code, stdout, stderr := runMaintctl(t, "request", "enable", "-environment", "staging", "-reason", "test")
+	if code != 0 {
+		t.Fatalf("request enable = %d, %q", code, stderr)
+	}
+	id := strings.TrimSuffix(strings.Fields(stdout)[1], ":")
+	approveAsBob(t)
+	code, _, stderr = runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink, id)
+	if code != 1 || !strings.Contains(stderr, "holds the production state, not staging") {
+		t.Errorf("approving a staging request against production = %d, %q", code, stderr)
+	}
+	if loadState(t, ns).Enabled {
+		t.Error("a staging request enabled production")
+	}
+}
+
+func TestChangesUseNamespaceEnvironment(t *testing.T) {
+	enabled := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	ns := mockState(t, state.State{Environment: "production", Enabled: true, EnabledAt: enabled, RolloutPercentage: 100})
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+	changes := [][]string{
+		{"state", "set", "-title", "Upgrading"},
+		{"rollout", "set", "50"},
+		{"watchdog", "-once", "-max-duration", "30m"},
+		{"state", "disable"},
+	}
+
+	// with puts flags after the subcommand, ahead of any PERCENT.
+	with := func(change []string, flags ...string) []string {
+		args := append([]string{}, change[:2]...)
+		return append(append(args, flags...), change[2:]...)
+	}
+
+	// A mislabelled change fails before anything is saved or recorded.
+	for _, change := range changes {
+		args := with(change, "-audit-sink", sink, "-environment", "staging")
+		code, _, stderr := runMaintctl(t, args...)
+		if code != 1 || !strings.Contains(stderr, "holds the production state, not staging") {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	if s := loadState(t, ns); !s.Enabled || s.Title != "" || s.RolloutPercentage != 100 {
+		t.Errorf("state after mislabelled changes = %+v", s)
+	}
+	if _, err := os.Stat(sink); !os.IsNotExist(err) {
+		t.Errorf("mislabelled changes were recorded: %v", err)
+	}
+
+	// Without -environment the events name the namespace's environment.
+	for _, change := range changes {
+		args := with(change, "-audit-sink", sink)
+		if code, _, stderr := runMaintctl(t, args...); code != 0 {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	raw, err := os.ReadFile(sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != len(changes) {
+		t.Fatalf("audit log has %d events, want %d", len(lines), len(changes))
+	}
+	for _, line := range lines {
+		var e audit.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Environment != "production" {
+			t.Errorf("%s event recorded environment %q", e.Action, e.Environment)
+		}
+	}
+}
+
+// requestEnable files a production request as alice and returns its ID.
+func requestEnable(t *testing.T) string {
+	t.Helper()
+	t.Setenv("MAINTCTL_APPROVAL_STORE", filepath.Join(t.TempDir(), "requests.json"))
+	code, stdout, stderr := runMaintctl(t, "request", "enable", "-environment", "production", "-reason", "DB migration")
+	if code != 0 {
+		t.Fatalf("request enable = %d, %q", code, stderr)
+	}
+	return strings.TrimSuffix(strings.Fields(stdout)[1], ":")
+}
+
+// approveAsBob switches to the approver's API token.
+func approveAsBob(t *testing.T) {
+	t.Setenv("CLOUDFLARE_API_TOKEN", "bob-token")
+}
+
+func writeBlackouts(t *testing.T, raw string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "blackouts.json")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestApproveChecksBlackouts(t *testing.T) {
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	id := requestEnable(t)
+	approveAsBob(t)
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+	now := time.Now().UTC()
+	freeze := writeBlackouts(t, fmt.Sprintf(`[{"name": "freeze", "start": %q, "end": %q}]`,
+		now.Add(-time.Hour).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339)))
+
+	// The request was filed before the freeze was checked; approving it
+	// during the freeze is refused and leaves it pending.
+	for _, args := range [][]string{
+		{"-blackouts", freeze},
+		{"-blackouts", freeze, "-force"},
+	} {
+		args = append([]string{"approve", "-actor", "bob", "-audit-sink", sink}, append(args, id)...)
+		code, _, stderr := runMaintctl(t, args...)
+		if code != 1 || !strings.Contains(stderr, "blackout freeze") {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	if loadState(t, ns).Enabled {
+		t.Fatal("an approval enabled maintenance during a blackout")
+	}
+
+	code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink,
+		"-blackouts", freeze, "-force", "-reason", "payment outage INC-1250", id)
+	if code != 0 {
+		t.Fatalf("approve -force -reason = %d, %q", code, stderr)
+	}
+	if !loadState(t, ns).Enabled {
+		t.Error("approve -force did not enable maintenance")
+	}
+	raw, err := os.ReadFile(sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e audit.Event
+	if err := json.Unmarshal(raw, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Action != "force-enable" || e.Actor != "bob" ||
+		!strings.Contains(e.Reason, "DB migration") || !strings.Contains(e.Reason, "payment outage INC-1250") {
+		t.Errorf("audit event = %+v", e)
+	}
+}
+
+func TestApproveNeedsBlackoutsInProtectedEnvironment(t *testing.T) {
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	id := requestEnable(t)
+	approveAsBob(t)
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink, id)
+	if code != 1 || !strings.Contains(stderr, "needs -blackouts or MAINTCTL_BLACKOUTS") {
+		t.Errorf("approve without blackouts = %d, %q", code, stderr)
+	}
+	if loadState(t, ns).Enabled {
+		t.Fatal("maintenance was enabled without checking blackouts")
+	}
+
+	t.Setenv("MAINTCTL_BLACKOUTS", writeBlackouts(t, "[]"))
+	if code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink, id); code != 0 {
+		t.Errorf("approve with no blackouts listed = %d, %q", code, stderr)
+	}
+	if !loadState(t, ns).Enabled {
+		t.Error("approve did not enable maintenance")
+	}
+}
+
+func TestApproveNeedsAnotherToken(t *testing.T) {
+	// Another -actor is only a name; the token is what the requester can't
+	// change.
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	id := requestEnable(t)
+	t.Setenv("MAINTCTL_BLACKOUTS", writeBlackouts(t, "[]"))
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink, id)
+	if code != 1 || !strings.Contains(stderr, "different API token") {
+		t.Errorf("approve with the requester's token = %d, %q", code, stderr)
+	}
+	if loadState(t, ns).Enabled {
+		t.Fatal("the requester's token approved its own request")
+	}
+
+	approveAsBob(t)
+	if code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", sink, id); code != 0 {
+		t.Errorf("approve with another token = %d, %q", code, stderr)
+	}
+	if !loadState(t, ns).Enabled {
+		t.Error("approve did not enable maintenance")
+	}
+}
+
+func TestStateSetWindowNeedsApproval(t *testing.T) {
+	// A window shows the page without enabled, so setting one in a
+	// protected environment is gated like enable.
+	ns := mockState(t, state.State{Environment: "production", RolloutPercentage: 100})
+	now := time.Now().UTC()
+	start, end := now.Add(-time.Minute).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339)
+	for _, args := range [][]string{
+		{"state", "set", "-no-audit", "-window-start", start, "-window-end", end},
+		{"state", "set", "-no-audit", "-window-start", start, "-window-end", end, "-environment", "staging"},
+	} {
+		code, _, stderr := runMaintctl(t, args...)
+		if code != 1 || !strings.Contains(stderr, "production") {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	if s := loadState(t, ns); s.WindowStart != "" {
+		t.Fatalf("a window was set in production without an approval: %+v", s)
+	}
+	// Page text and clearing the window don't turn maintenance on.
+	for _, args := range [][]string{
+		{"state", "set", "-no-audit", "-title", "Upgrade"},
+		{"state", "set", "-no-audit", "-window-start", "", "-window-end", ""},
+	} {
+		if code, _, stderr := runMaintctl(t, args...); code != 0 {
+			t.Errorf("%s = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+
+	// The window goes through a request instead.
+	t.Setenv("MAINTCTL_APPROVAL_STORE", filepath.Join(t.TempDir(), "requests.json"))
+	code, stdout, stderr := runMaintctl(t, "request", "enable", "-environment", "production", "-reason", "DB migration",
+		"-window-start", start, "-window-end", end)
+	if code != 0 {
+		t.Fatalf("request enable with a window = %d, %q", code, stderr)
+	}
+	id := strings.TrimSuffix(strings.Fields(stdout)[1], ":")
+	approveAsBob(t)
+	t.Setenv("MAINTCTL_BLACKOUTS", writeBlackouts(t, "[]"))
+	if code, _, stderr := runMaintctl(t, "approve", "-actor", "bob", "-audit-sink", filepath.Join(t.TempDir(), "audit.jsonl"), id); code != 0 {
+		t.Fatalf("approve = %d, %q", code, stderr)
+	}
+	if s := loadState(t, ns); !s.Enabled || s.WindowStart != start || s.WindowEnd != end || s.Title != "Upgrade" {
+		t.Errorf("state after approval = %+v", s)
+	}
+}
+
+func TestStateSetWindowOutsideProtectedEnvironments(t *testing.T) {
+	ns := mockState(t, state.State{Environment: "staging", RolloutPercentage: 100})
+	code, _, stderr := runMaintctl(t, "state", "set", "-no-audit", "-window-start", "2025-04-06T08:00:00Z", "-window-end", "2025-04-06T10:00:00Z")
+	if code != 0 {
+		t.Fatalf("state set -window in staging = %d, %q", code, stderr)
+	}
+	if s := loadState(t, ns); s.WindowStart != "2025-04-06T08:00:00Z" {
+		t.Errorf("state = %+v", s)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/audit"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+const auditUsage = `usage: maintctl audit list [-sink SPEC] [-environment ENV] [-since T] [-until T] [-json]
+
+T is RFC3339, a date (2025-04-06) or a duration back from now (24h).`
+
+// auditFlags registers the flags that say where state changes are recorded
+// and on whose behalf. Like kvFlags they default from the environment.
+type auditFlags struct {
+	sink        *string
+	noAudit     *bool
+	environment *string
+	actor       *string
+	reason      *string
+}
+
+func addAuditFlags(fs *flag.FlagSet) auditFlags {
+	return auditFlags{
+		sink:        addAuditSinkFlag(fs),
+		noAudit:     addNoAuditFlag(fs),
+		environment: addEnvironmentFlag(fs),
+		actor:       addActorFlag(fs),
+		reason:      fs.String("reason", "", "why the change is made"),
+	}
+}
+
+func addAuditSinkFlag(fs *flag.FlagSet) *string {
+	return fs.String("audit-sink", os.Getenv("MAINTCTL_AUDIT_SINK"), "where to record the change: a JSONL path, s3://bucket/prefix or webhook://URL (default $MAINTCTL_AUDIT_SINK)")
+}
+
+func addNoAuditFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-audit", false, "make changes without an audit sink, leaving no record")
+}
+
+// checkAuditSink refuses a change that would go unrecorded, unless the
+// operator opted out with -no-audit.
+func checkAuditSink(sink string, noAudit bool) error {
+	if sink == "" && !noAudit {
+		return errors.New("no -audit-sink or MAINTCTL_AUDIT_SINK to record the change; pass -no-audit to make it without a record")
+	}
+	return nil
+}
+
+func (f auditFlags) check() error {
+	return checkAuditSink(*f.sink, f.noAudit != nil && *f.noAudit)
+}
+
+func addEnvironmentFlag(fs *flag.FlagSet) *string {
+	return fs.String("environment", os.Getenv("MAINTENANCE_ENVIRONMENT"), "the module's environment (default $MAINTENANCE_ENVIRONMENT)")
+}
+
+func addActorFlag(fs *flag.FlagSet) *string {
+	return fs.String("actor", defaultActor(), "who is making the change (default $MAINTCTL_ACTOR, else the OS user)")
+}
+
+func defaultActor() string {
+	if a := os.Getenv("MAINTCTL_ACTOR"); a != "" {
+		return a
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// change loads the state, applies fn, saves it and records the change. A
+// change that was saved but could not be recorded is still an error, so
+// scripts notice the gap in the log.
+func (f auditFlags) change(ctx context.Context, kv state.Store, action string, fn func(*state.State), stderr io.Writer) (state.State, error) {
+	if err := f.check(); err != nil {
+		return state.State{}, err
+	}
+	prev, err := state.Load(ctx, kv)
+	if err != nil {
+		return state.State{}, err
+	}
+	// The event names the environment the namespace belongs to, so a wrong
+	// -environment fails here instead of being recorded.
+	environment, err := prev.EnvironmentFor(*f.environment)
+	if err != nil {
+		return state.State{}, err
+	}
+	next := prev
+	fn(&next)
+	now := time.Now()
+	next, err = state.Save(ctx, kv, next, now)
+	if err != nil {
+		return state.State{}, err
+	}
+
+	if *f.sink == "" {
+		fmt.Fprintln(stderr, "warning: -no-audit; the change was not recorded")
+		return next, nil
+	}
+	sink, err := audit.Open(*f.sink)
+	if err != nil {
+		return next, fmt.Errorf("state saved but not audited: %w", err)
+	}
+	e := audit.NewEvent(now, environment, *f.actor, action, *f.reason, prev, next)
+	if err := sink.Append(ctx, e); err != nil {
+		return next, fmt.Errorf("state saved but not audited: %w", err)
+	}
+	return next, nil
+}
+
+func runAudit(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf(auditUsage)
+	}
+	fs := newFlagSet("audit list", stderr)
+	sinkSpec := fs.String("sink", os.Getenv("MAINTCTL_AUDIT_SINK"), "audit sink to read (default $MAINTCTL_AUDIT_SINK)")
+	environment := fs.String("environment", "", "only events for this environment")
+	since := fs.String("since", "", "only events at or after this time")
+	until := fs.String("until", "", "only events before this time")
+	asJSON := fs.Bool("json", false, "print events as JSON lines")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *sinkSpec == "" {
+		return fmt.Errorf(auditUsage)
+	}
+
+	now := time.Now()
+	filter := audit.Filter{Environment: *environment}
+	var err error
+	if filter.Since, err = parseSince(*since, now); err != nil {
+		return fmt.Errorf("-since: %w", err)
+	}
+	if filter.Until, err = parseSince(*until, now); err != nil {
+		return fmt.Errorf("-until: %w", err)
+	}
+
+	sink, err := audit.Open(*sinkSpec)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	events, err := audit.List(ctx, sink, filter)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(stdout)
+	for _, e := range events {
+		if *asJSON {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			continue
+		}
+		changes := strings.Join(e.Changes(), ", ")
+		if changes == "" {
+			changes = "no change"
+		}
+		fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Format(time.RFC3339), e.Environment, e.Actor, e.Action, changes, e.Reason)
+	}
+	return nil
+}
+
+// parseSince accepts RFC3339, a date or a duration back from now; empty is
+// the zero time.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC3339, YYYY-MM-DD or a duration like 24h", v)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/health"
+)
+
+const autoUsage = `usage: maintctl auto show [flags]`
+
+func runAuto(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "show" {
+		return fmt.Errorf(autoUsage)
+	}
+	fs := newFlagSet("auto show", stderr)
+	kv := addKVFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf(autoUsage)
+	}
+
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	trip, ok, err := health.LastTrip(ctx, ns)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(stdout, "no auto maintenance trips recorded")
+		return nil
+	}
+	status := "cooled down"
+	if time.Now().Before(trip.CooldownUntil) {
+		status = "cooling down"
+	}
+	fmt.Fprintf(stdout, "tripped at:      %s\n", trip.TrippedAt.Format(time.RFC3339))
+	fmt.Fprintf(stdout, "reason:          %s\n", trip.Reason)
+	fmt.Fprintf(stdout, "cool-down until: %s (%s)\n", trip.CooldownUntil.Format(time.RFC3339), status)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/drift"
+)
+
+const driftUsage = `usage: maintctl drift [-state show.json] [-module module.maintenance]
+
+Reads "terraform show -json" output (from -state, or stdin when it is -)
+and compares the worker, its routes, rulesets and DNS record with the live
+Cloudflare API. Exits 1 when anything differs.`
+
+func runDrift(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("drift", stderr)
+	stateFile := fs.String("state", "-", `"terraform show -json" output, - for stdin`)
+	module := fs.String("module", "", "address of the module in state (default: the first one with the maintenance worker)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf(driftUsage)
+	}
+
+	in := io.Reader(os.Stdin)
+	if *stateFile != "-" {
+		f, err := os.Open(*stateFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	intent, err := drift.ParseState(in, *module)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	diffs, err := drift.Check(ctx, cloudflare.NewFromEnv(), intent)
+	if err != nil {
+		return err
+	}
+	for _, d := range diffs {
+		fmt.Fprintln(stdout, d)
+	}
+	if len(diffs) > 0 {
+		return exitError{code: 1, err: fmt.Errorf("%d difference(s) between state and live objects", len(diffs))}
+	}
+	fmt.Fprintln(stdout, "no drift: live objects match the Terraform state")
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// kvFlags registers the flags that locate the module's KV namespace. The
+// defaults come from the environment so CI jobs only set them once; the API
+// token is always read from CLOUDFLARE_API_TOKEN.
+type kvFlags struct {
+	accountID   *string
+	namespaceID *string
+}
+
+func addKVFlags(fs *flag.FlagSet) kvFlags {
+	return kvFlags{
+		accountID:   fs.String("account-id", os.Getenv("CLOUDFLARE_ACCOUNT_ID"), "Cloudflare account ID (default $CLOUDFLARE_ACCOUNT_ID)"),
+		namespaceID: fs.String("namespace-id", os.Getenv("MAINTENANCE_KV_NAMESPACE_ID"), "KV namespace ID, the module's kv_namespace_id output (default $MAINTENANCE_KV_NAMESPACE_ID)"),
+	}
+}
+
+func (f kvFlags) namespace() (*cloudflare.KVNamespace, error) {
+	if *f.accountID == "" {
+		return nil, fmt.Errorf("-account-id or CLOUDFLARE_ACCOUNT_ID is required")
+	}
+	if *f.namespaceID == "" {
+		return nil, fmt.Errorf("-namespace-id or MAINTENANCE_KV_NAMESPACE_ID is required")
+	}
+	return cloudflare.NewFromEnv().KV(*f.accountID, *f.namespaceID), nil
+}
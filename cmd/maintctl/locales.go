@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/locale"
+)
+
+func runLocales(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("usage: maintctl locales validate -file <content.json> [-default-locale en]")
+	}
+
+	fs := newFlagSet("locales validate", stderr)
+	file := fs.String("file", "", "localized_content JSON (bare map or tfvars.json)")
+	defaultLocale := fs.String("default-locale", "", "default locale (overrides default_locale in the file; en if neither is set)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+
+	set, err := locale.LoadFile(*file)
+	if err != nil {
+		return err
+	}
+	if *defaultLocale != "" {
+		set.DefaultLocale = *defaultLocale
+	}
+	if set.DefaultLocale == "" {
+		set.DefaultLocale = "en"
+	}
+
+	errs := locale.Validate(set)
+	for _, e := range errs {
+		fmt.Fprintln(stdout, "error:", e)
+	}
+	if len(errs) > 0 {
+		return exitError{code: 1, err: fmt.Errorf("%d problem(s) in %s", len(errs), *file)}
+	}
+	fmt.Fprintf(stdout, "%s: %d locale(s) OK, default %q\n", *file, len(set.Content), set.DefaultLocale)
+	return nil
+}
@@ -0,0 +1,97 @@
+// Command maintctl is the operator CLI for the Cloudflare maintenance module.
+//
+// Usage:
+//
+//	maintctl <command> [flags]
+//
+// Run "maintctl help" for the list of commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"preview", "Render the maintenance page as seen from given timezones and locales", runPreview},
+	{"locales", "Validate localized page content (locales validate -file content.json)", runLocales},
+	{"template", "Lint a custom page template (template lint page.html)", runTemplate},
+	{"state", "Show or change the live state in Workers KV (state enable|disable|set|show)", runState},
+	{"auto", "Show the last automatic trip caused by origin failures (auto show)", runAuto},
+	{"rollout", "Show or set the share of clients that get the page (rollout set 25)", runRollout},
+	{"request", "Request a change that needs a second person's approval (request enable -reason \"...\")", runRequest},
+	{"approve", "Approve someone else's request and apply it (approve <id>)", runApprove},
+	{"audit", "List recorded state changes (audit list -environment production -since 24h)", runAudit},
+	{"schedule", "Replay, validate or export the schedules variable (schedule simulate|validate|ics)", runSchedule},
+	{"watchdog", "Turn maintenance off once its window or max duration has passed (watchdog -max-duration 4h)", runWatchdog},
+	{"drift", "Compare live Cloudflare objects with terraform show -json (drift -state show.json)", runDrift},
+	{"update", "Post, list or delete status updates shown on the page (update post \"...\")", runUpdate},
+	{"statuspage", "Mirror the live state as a scheduled maintenance on a status page (statuspage sync)", runStatusPage},
+	{"ratelimit", "Replay a traffic log against the rate limiting rules (ratelimit simulate -file tfvars.json -log LOG)", runRateLimit},
+	{"ruleset", "Add or remove the execute rule for a module ruleset in a shared phase entrypoint (ruleset attach|detach)", runRuleset},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(args[1:], stdout, stderr); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			fmt.Fprintf(stderr, "maintctl %s: %v\n", c.name, err)
+			var exit exitError
+			if errors.As(err, &exit) {
+				return exit.code
+			}
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "maintctl: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: maintctl <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+// exitError lets a command pick its exit status, e.g. to signal findings
+// rather than failures.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+func (e exitError) Unwrap() error { return e.err }
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("maintctl "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
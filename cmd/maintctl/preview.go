@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/locale"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/page"
+)
+
+func runPreview(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("preview", stderr)
+	var cfg page.Config
+	fs.StringVar(&cfg.Title, "title", "Maintenance Mode", "page title")
+	fs.StringVar(&cfg.Message, "message", "", "page message")
+	fs.StringVar(&cfg.ContactEmail, "contact-email", "", "contact email")
+	fs.StringVar(&cfg.LogoURL, "logo-url", "", "HTTPS logo URL")
+	fs.StringVar(&cfg.WindowStart, "window-start", "", "window start (RFC3339)")
+	fs.StringVar(&cfg.WindowEnd, "window-end", "", "window end (RFC3339)")
+	fs.StringVar(&cfg.DisplayTimezone, "display-timezone", "UTC", "timezone used for the server-rendered completion time")
+	cssFile := fs.String("custom-css-file", "", "file with custom CSS")
+	templateFile := fs.String("template-file", "", "custom page template (defaults to templates/default.html)")
+	viewerTZ := fs.String("viewer-tz", "", "comma-separated viewer timezones; empty renders the no-JavaScript page")
+	locales := fs.String("locale", "en-US", "comma-separated viewer locales")
+	nowFlag := fs.String("now", "", "pretend current time (RFC3339) for the countdown")
+	outDir := fs.String("out-dir", "", "write one file per timezone/locale instead of printing")
+	contentFile := fs.String("content-file", "", "localized_content JSON; page text is negotiated from each -locale")
+	defaultLocale := fs.String("default-locale", "en", "locale used when negotiation finds no match")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *cssFile != "" {
+		css, err := os.ReadFile(*cssFile)
+		if err != nil {
+			return err
+		}
+		cfg.CustomCSS = string(css)
+	}
+	if *templateFile != "" {
+		tmpl, err := os.ReadFile(*templateFile)
+		if err != nil {
+			return err
+		}
+		cfg.Template = string(tmpl)
+	}
+
+	var content locale.Set
+	if *contentFile != "" {
+		var err error
+		if content, err = locale.LoadFile(*contentFile); err != nil {
+			return err
+		}
+		if content.DefaultLocale == "" {
+			content.DefaultLocale = *defaultLocale
+		}
+	}
+
+	now := time.Now()
+	if *nowFlag != "" {
+		t, err := time.Parse(time.RFC3339, *nowFlag)
+		if err != nil {
+			return fmt.Errorf("invalid -now: %w", err)
+		}
+		now = t
+	}
+
+	zones := []string{""}
+	if *viewerTZ != "" {
+		zones = strings.Split(*viewerTZ, ",")
+	}
+	langs := strings.Split(*locales, ",")
+	if len(zones)*len(langs) > 1 && *outDir == "" {
+		return fmt.Errorf("-out-dir is required when previewing more than one timezone or locale")
+	}
+
+	for _, zone := range zones {
+		viewer := page.Viewer{Now: now}
+		if zone != "" {
+			loc, err := time.LoadLocation(strings.TrimSpace(zone))
+			if err != nil {
+				return fmt.Errorf("invalid viewer timezone: %w", err)
+			}
+			viewer.Timezone = loc
+		}
+		for _, lang := range langs {
+			viewer.Locale = strings.TrimSpace(lang)
+			html := page.Render(localize(cfg, content, viewer.Locale), viewer)
+			if *outDir == "" {
+				_, err := io.WriteString(stdout, html+"\n")
+				return err
+			}
+			name := previewFileName(zone, viewer.Locale)
+			if err := os.WriteFile(filepath.Join(*outDir, name), []byte(html), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, filepath.Join(*outDir, name))
+		}
+	}
+	return nil
+}
+
+// localize applies the content a visitor with the given Accept-Language would
+// be served, leaving cfg untouched when no localized content was supplied.
+func localize(cfg page.Config, content locale.Set, acceptLanguage string) page.Config {
+	if len(content.Content) == 0 {
+		return cfg
+	}
+	available := make([]string, 0, len(content.Content))
+	for tag := range content.Content {
+		available = append(available, tag)
+	}
+	cfg.Lang = locale.Negotiate(acceptLanguage, "", available, content.DefaultLocale, content.CountryLocales)
+	if c, ok := content.Content[cfg.Lang]; ok {
+		cfg.Title, cfg.Message = c.Title, c.Message
+	}
+	return cfg
+}
+
+func previewFileName(zone, locale string) string {
+	if zone == "" {
+		zone = "nojs"
+	}
+	return fmt.Sprintf("preview-%s-%s.html", strings.ReplaceAll(zone, "/", "_"), locale)
+}
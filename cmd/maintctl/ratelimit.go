@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/ratelimit"
+)
+
+const rateLimitUsage = `usage: maintctl ratelimit simulate -file FILE -log LOG [-format auto|json|csv] [-requests-per-period N] [-period S] [-mitigation-timeout S] [-top N] [-json]
+
+simulate replays a traffic log against the rate limiting rules and reports
+how many requests each rule would have acted on, which clients it would have
+blocked and for how long, per data center. FILE holds the module's
+rate_limit variable as JSON, a tfvars.json object with a "rate_limit" key,
+or "terraform show -json PLANFILE" output, which has the rules as planned,
+narrowed to the maintenance page when applies_to is maintenance_page. LOG is
+Cloudflare Logpush http_requests JSON lines, gzipped or not, or CSV with
+timestamp, ip and path columns, - for stdin. The threshold flags override
+every rule's values, to try others without editing FILE.`
+
+func runRateLimit(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "simulate" {
+		return fmt.Errorf(rateLimitUsage)
+	}
+	return runRateLimitSimulate(args[1:], stdout, stderr)
+}
+
+func runRateLimitSimulate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("ratelimit simulate", stderr)
+	file := fs.String("file", "", "rate_limit JSON, tfvars.json or terraform show -json of a plan")
+	logFile := fs.String("log", "", "traffic log, - for stdin")
+	format := fs.String("format", "auto", "log format: auto, json (Logpush) or csv")
+	requests := fs.Int("requests-per-period", 0, "override every rule's requests_per_period")
+	period := fs.Int("period", 0, "override every rule's period, in seconds")
+	timeout := fs.Int("mitigation-timeout", 0, "override every rule's mitigation_timeout, in seconds")
+	top := fs.Int("top", 10, "clients to list per rule")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *file == "" || *logFile == "" {
+		return fmt.Errorf(rateLimitUsage)
+	}
+
+	rules, err := loadRateLimitRules(*file)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		r := &rules[i]
+		if *requests > 0 {
+			r.RequestsPerPeriod = *requests
+		}
+		if *period > 0 {
+			r.Period = *period
+		}
+		if *timeout > 0 {
+			r.MitigationTimeout = *timeout
+		}
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+
+	in := io.Reader(os.Stdin)
+	if *logFile != "-" {
+		f, err := os.Open(*logFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	reqs, err := ratelimit.ReadLog(in, *format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *logFile, err)
+	}
+	rep, err := ratelimit.Simulate(rules, reqs)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+	printRateLimitReport(stdout, rep, *top)
+	return nil
+}
+
+func printRateLimitReport(w io.Writer, rep *ratelimit.Report, top int) {
+	if rep.Requests == 0 {
+		fmt.Fprintln(w, "the log has no requests")
+	} else {
+		fmt.Fprintf(w, "%d request(s) from %s to %s\n", rep.Requests, rep.From.Format(time.RFC3339), rep.To.Format(time.RFC3339))
+	}
+	for i, rr := range rep.Rules {
+		r := rr.Rule
+		fmt.Fprintf(w, "\nrules[%d] %q: %s over %d request(s) per %ds by %s, for %ds\n", i, r.Description, r.Action,
+			r.RequestsPerPeriod, r.Period, strings.Join(r.Characteristics, ", "), r.MitigationTimeout)
+		if !r.Enabled {
+			fmt.Fprintln(w, "  disabled")
+			continue
+		}
+		fmt.Fprintf(w, "  %d matched, %d counted, %d mitigated; %d client(s) over the rate\n", rr.Matched, rr.Counted, rr.Mitigated, len(rr.Clients))
+		for j, c := range rr.Clients {
+			if j == top {
+				fmt.Fprintf(w, "  ... and %d more\n", len(rr.Clients)-top)
+				break
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%d time(s), %s in all\t%d request(s) mitigated\tfirst at %s\n", c.Colo, c.Key, c.Episodes,
+				time.Duration(c.Seconds)*time.Second, c.Mitigated, c.First.Format(time.RFC3339))
+		}
+		for _, c := range rr.Colos {
+			fmt.Fprintf(w, "  colo %s\t%d client(s), %d time(s), %s in all\t%d request(s) mitigated\n", c.Colo, c.Clients, c.Episodes,
+				time.Duration(c.Seconds)*time.Second, c.Mitigated)
+		}
+	}
+}
+
+// loadRateLimitRules reads the rules from the rate_limit variable, a
+// tfvars.json holding it, or a plan.
+func loadRateLimitRules(path string) ([]ratelimit.Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	if _, ok := probe["planned_values"]; ok {
+		plan, err := ratelimit.ReadPlan(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if plan.Rules == nil {
+			return nil, fmt.Errorf("%s: the plan has no rate limiting ruleset", path)
+		}
+		return plan.Rules, nil
+	}
+	if v, ok := probe["rate_limit"]; ok {
+		raw = v
+	}
+	var cfg ratelimit.Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	if err := ratelimit.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.AppliesTo == "maintenance_page" {
+		return nil, fmt.Errorf("%s: applies_to is maintenance_page; simulate terraform show -json of a plan to get the rules narrowed to the page", path)
+	}
+	return cfg.Ruleset(), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+const rolloutUsage = `usage: maintctl rollout show [flags]
+       maintctl rollout set [flags] PERCENT`
+
+func runRollout(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(rolloutUsage)
+	}
+	sub := args[0]
+	fs := newFlagSet("rollout "+sub, stderr)
+	kv := addKVFlags(fs)
+	var af auditFlags
+	if sub != "show" {
+		af = addAuditFlags(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var change func(*state.State)
+	switch sub {
+	case "show":
+		if fs.NArg() != 0 {
+			return fmt.Errorf(rolloutUsage)
+		}
+	case "set":
+		if fs.NArg() != 1 {
+			return fmt.Errorf(rolloutUsage)
+		}
+		pct, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || pct < 0 || pct > 100 {
+			return fmt.Errorf("percent must be a whole number from 0 to 100, got %q", fs.Arg(0))
+		}
+		if err := af.check(); err != nil {
+			return err
+		}
+		change = func(s *state.State) { s.RolloutPercentage = pct }
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", sub, rolloutUsage)
+	}
+
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var s state.State
+	if change == nil {
+		s, err = state.Load(ctx, ns)
+	} else {
+		s, err = af.change(ctx, ns, "rollout", change, stderr)
+	}
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/entrypoint"
+)
+
+const rulesetUsage = `usage: maintctl ruleset attach -phase PHASE -ref REF -ruleset ID [-zone-id ZONE] [-first]
+       maintctl ruleset detach -phase PHASE -ref REF [-zone-id ZONE]
+
+attach adds a rule named REF to the zone's PHASE entrypoint that executes the
+custom ruleset ID, or points the existing one at it; detach removes it. Other
+rules in the entrypoint are left as they are. The module runs both with
+ruleset_mode = "execute" as its rulesets are created and destroyed. Both need
+CLOUDFLARE_API_TOKEN; at destroy time Terraform can't pass it, so it must be in
+the environment terraform destroy runs in.`
+
+func runRuleset(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || (args[0] != "attach" && args[0] != "detach") {
+		return fmt.Errorf(rulesetUsage)
+	}
+	fs := newFlagSet("ruleset "+args[0], stderr)
+	zoneID := fs.String("zone-id", os.Getenv("CLOUDFLARE_ZONE_ID"), "Cloudflare zone ID (default $CLOUDFLARE_ZONE_ID)")
+	phase := fs.String("phase", "", "entrypoint phase, e.g. http_ratelimit")
+	ref := fs.String("ref", "", "ref naming the execute rule")
+	rulesetID := fs.String("ruleset", "", "custom ruleset to execute (attach)")
+	first := fs.Bool("first", false, "add the rule before the others instead of after them (attach)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *zoneID == "" || *phase == "" || *ref == "" || (args[0] == "attach") != (*rulesetID != "") {
+		return fmt.Errorf(rulesetUsage)
+	}
+
+	client := cloudflare.NewFromEnv()
+	// Without a token the API call fails anyway, but the destroy-time detach
+	// should say why, or the execute rule outlives its ruleset unnoticed.
+	if client.Token == "" {
+		return fmt.Errorf("ruleset %s: CLOUDFLARE_API_TOKEN is not set; export a token that can edit the zone's rulesets and run terraform again", args[0])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if args[0] == "detach" {
+		removed, err := entrypoint.Detach(ctx, client, *zoneID, *phase, *ref)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Fprintf(stdout, "%s: removed %s\n", *phase, *ref)
+		} else {
+			fmt.Fprintf(stdout, "%s: no rule %s\n", *phase, *ref)
+		}
+		return nil
+	}
+	did, err := entrypoint.Attach(ctx, client, *zoneID, *phase, *ref, *rulesetID, *first)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: %s %s executing ruleset %s\n", *phase, *ref, did, *rulesetID)
+	return nil
+}
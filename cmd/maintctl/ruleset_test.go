@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+func TestRulesetNeedsToken(t *testing.T) {
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	t.Setenv("CLOUDFLARE_API_BASE_URL", client.BaseURL)
+	t.Setenv("CLOUDFLARE_ZONE_ID", cftest.ZoneID)
+	rules := func() []cloudflare.Rule {
+		t.Helper()
+		ep, err := client.GetEntrypoint(context.Background(), cftest.ZoneID, "http_ratelimit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ep.Rules
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", client.Token)
+	attach := []string{"ruleset", "attach", "-phase", "http_ratelimit", "-ref", "maintenance-test-rate-limit", "-ruleset", "rs-1"}
+	if code, _, stderr := runMaintctl(t, attach...); code != 0 {
+		t.Fatalf("ruleset attach = %d, %q", code, stderr)
+	}
+
+	// A destroy run without the token fails instead of leaving the execute
+	// rule behind quietly.
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	detach := []string{"ruleset", "detach", "-phase", "http_ratelimit", "-ref", "maintenance-test-rate-limit"}
+	for _, args := range [][]string{attach, detach} {
+		code, _, stderr := runMaintctl(t, args...)
+		if code != 1 || !strings.Contains(stderr, "CLOUDFLARE_API_TOKEN is not set") {
+			t.Errorf("%s without a token = %d, %q", strings.Join(args[:2], " "), code, stderr)
+		}
+	}
+	if len(rules()) != 1 {
+		t.Fatalf("rules after a failed detach = %+v", rules())
+	}
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", client.Token)
+	if code, _, stderr := runMaintctl(t, detach...); code != 0 {
+		t.Fatalf("ruleset detach = %d, %q", code, stderr)
+	}
+	if len(rules()) != 0 {
+		t.Errorf("rules after detach = %+v", rules())
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/schedule"
+)
+
+const scheduleUsage = `usage: maintctl schedule simulate -file schedules.json [-blackouts FILES] [-from T] [-days N]
+       maintctl schedule validate [-file schedules.json] [-blackouts FILES] [CRON...]
+       maintctl schedule ics -file schedules.json -host HOST [-blackouts FILES] [-window START,END] [-name NAME] [-days N] [-now T]
+
+The file holds the module's schedules variable as JSON: the list itself or a
+tfvars.json object with a "schedules" key. T is RFC3339 or a date. validate
+checks cron expressions, or every schedule in the file, with the same rules
+the module's variable validation and the worker use. FILES is a
+comma-separated list of blackouts: JSON like the blackouts variable or
+iCalendar (.ics) files. ics prints the feed the worker serves at
+/maintenance.ics on HOST; the window defaults to the file's
+"maintenance_window".`
+
+func runSchedule(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(scheduleUsage)
+	}
+	switch args[0] {
+	case "simulate":
+		return runScheduleSimulate(args[1:], stdout, stderr)
+	case "validate":
+		return runScheduleValidate(args[1:], stdout, stderr)
+	case "ics":
+		return runScheduleICS(args[1:], stdout, stderr)
+	}
+	return fmt.Errorf(scheduleUsage)
+}
+
+func runScheduleValidate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("schedule validate", stderr)
+	file := fs.String("file", "", "JSON file with the schedules")
+	blackoutFiles := addBlackoutsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*file == "" && *blackoutFiles == "" && fs.NArg() == 0) || (*file != "" && fs.NArg() > 0) {
+		return fmt.Errorf(scheduleUsage)
+	}
+
+	if *blackoutFiles != "" {
+		blackouts, err := loadBlackouts(*blackoutFiles)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%d blackout(s) valid\n", len(blackouts))
+	}
+	if *file != "" {
+		set, err := loadSchedules(*file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s: %d schedule(s) valid, worker checks every %d minute(s)\n", *file, set.Len(), set.TickMinutes())
+		return nil
+	}
+	bad := 0
+	for _, expr := range fs.Args() {
+		if _, err := schedule.ParseCron(expr); err != nil {
+			fmt.Fprintln(stdout, err)
+			bad++
+			continue
+		}
+		fmt.Fprintf(stdout, "cron %q: ok\n", expr)
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d of %d cron expression(s) invalid", bad, fs.NArg())
+	}
+	return nil
+}
+
+func runScheduleSimulate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("schedule simulate", stderr)
+	file := fs.String("file", "", "JSON file with the schedules")
+	blackoutFiles := addBlackoutsFlag(fs)
+	from := fs.String("from", "", "start of the replay (default today, UTC)")
+	days := fs.Int("days", 31, "how many days to replay")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *file == "" || *days < 1 {
+		return fmt.Errorf(scheduleUsage)
+	}
+
+	set, err := loadSchedules(*file)
+	if err != nil {
+		return err
+	}
+	blackouts, err := loadBlackouts(*blackoutFiles)
+	if err != nil {
+		return err
+	}
+	set.SetBlackouts(blackouts)
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if *from != "" {
+		if start, err = parseSince(*from, time.Now()); err != nil {
+			return fmt.Errorf("-from: %w", err)
+		}
+	}
+	end := start.AddDate(0, 0, *days)
+
+	tick := set.TickMinutes()
+	fmt.Fprintf(stdout, "worker checks every %d minute(s) from %s to %s\n", tick, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	timeline := set.Simulate(start, end)
+	for _, t := range timeline {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\t%s - %s\n", t.At.Format(time.RFC3339), t.Status, t.Window.Schedule,
+			t.Window.Start.Format(time.RFC3339), t.Window.End.Format(time.RFC3339))
+	}
+	if len(timeline) == 0 {
+		fmt.Fprintln(stdout, "no windows open in this period")
+	}
+	for _, w := range set.Expand(start, end) {
+		if w.Blackout != "" {
+			fmt.Fprintf(stdout, "%s\tBLOCKED\t%s\t%s - %s\tblackout %s\n", w.Start.Format(time.RFC3339), w.Schedule,
+				w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), w.Blackout)
+		}
+	}
+	return nil
+}
+
+func runScheduleICS(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("schedule ics", stderr)
+	file := fs.String("file", "", "JSON file with the schedules")
+	blackoutFiles := addBlackoutsFlag(fs)
+	host := fs.String("host", os.Getenv("MAINTCTL_STATUS_HOST"), "status hostname the feed is served on, e.g. maintenance-status-production.example.com (default $MAINTCTL_STATUS_HOST)")
+	window := fs.String("window", "", "maintenance window as START,END in RFC3339 (default the file's maintenance_window)")
+	name := fs.String("name", "Scheduled maintenance", "calendar name")
+	days := fs.Int("days", 30, "how many days ahead to list")
+	at := fs.String("now", "", "time the feed is made at (default now)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *file == "" || *host == "" || *days < 1 {
+		return fmt.Errorf(scheduleUsage)
+	}
+
+	set, err := loadSchedules(*file)
+	if err != nil {
+		return err
+	}
+	blackouts, err := loadBlackouts(*blackoutFiles)
+	if err != nil {
+		return err
+	}
+	set.SetBlackouts(blackouts)
+	now := time.Now()
+	if *at != "" {
+		if now, err = parseSince(*at, now); err != nil {
+			return fmt.Errorf("-now: %w", err)
+		}
+	}
+	win, err := loadWindow(*file, *window)
+	if err != nil {
+		return err
+	}
+	return set.WriteICS(stdout, schedule.ICSOptions{Name: *name, Host: strings.ToLower(*host), Window: win, Now: now, Days: *days})
+}
+
+// loadWindow parses START,END, or else reads a tfvars.json maintenance_window.
+func loadWindow(path, flagValue string) (*schedule.Window, error) {
+	var w struct {
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+	}
+	if flagValue != "" {
+		var ok bool
+		if w.StartTime, w.EndTime, ok = strings.Cut(flagValue, ","); !ok {
+			return nil, fmt.Errorf("-window must be START,END")
+		}
+	} else {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var vars struct {
+			Window *json.RawMessage `json:"maintenance_window"`
+		}
+		if json.Unmarshal(raw, &vars) != nil || vars.Window == nil {
+			return nil, nil
+		}
+		if err := json.Unmarshal(*vars.Window, &w); err != nil {
+			return nil, fmt.Errorf("%s: maintenance_window: %w", path, err)
+		}
+	}
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(w.StartTime))
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, strings.TrimSpace(w.EndTime))
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window end: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("maintenance window ends before it starts")
+	}
+	return &schedule.Window{Start: start, End: end}, nil
+}
+
+func loadSchedules(path string) (*schedule.Set, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []schedule.Schedule
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var vars struct {
+			Schedules []schedule.Schedule `json:"schedules"`
+		}
+		if err2 := json.Unmarshal(raw, &vars); err2 != nil {
+			return nil, fmt.Errorf("decoding %s: %w", path, err)
+		}
+		list = vars.Schedules
+	}
+	return schedule.NewSet(list)
+}
+
+func addBlackoutsFlag(fs *flag.FlagSet) *string {
+	return fs.String("blackouts", os.Getenv("MAINTCTL_BLACKOUTS"), "comma-separated blackout files, JSON or iCalendar (default $MAINTCTL_BLACKOUTS)")
+}
+
+// loadBlackouts reads comma-separated blackout files. A file is iCalendar
+// if it starts with BEGIN:VCALENDAR, otherwise the blackouts variable as JSON:
+// the list itself or a tfvars.json object with "blackouts" and
+// "blackout_calendar" keys.
+func loadBlackouts(paths string) ([]schedule.Blackout, error) {
+	var out []schedule.Blackout
+	for _, path := range strings.Split(paths, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("BEGIN:VCALENDAR")) {
+			cal, err := schedule.ParseICal(bytes.NewReader(raw))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			out = append(out, cal...)
+			continue
+		}
+		var vars struct {
+			Blackouts []schedule.BlackoutSpec `json:"blackouts"`
+			Calendar  string                  `json:"blackout_calendar"`
+		}
+		if err := json.Unmarshal(raw, &vars.Blackouts); err != nil {
+			if err2 := json.Unmarshal(raw, &vars); err2 != nil {
+				return nil, fmt.Errorf("decoding %s: %w", path, err)
+			}
+		}
+		resolved, err := schedule.ResolveBlackouts(vars.Blackouts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out = append(out, resolved...)
+		if vars.Calendar != "" {
+			cal, err := schedule.ParseICal(strings.NewReader(vars.Calendar))
+			if err != nil {
+				return nil, fmt.Errorf("%s: blackout_calendar: %w", path, err)
+			}
+			out = append(out, cal...)
+		}
+	}
+	return out, nil
+}
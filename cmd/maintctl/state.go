@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/schedule"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+const stateUsage = `usage: maintctl state show [flags]
+       maintctl state enable [flags] [-blackouts FILES] [-force -reason "why"]
+       maintctl state disable [flags]
+       maintctl state set [flags] [-title T] [-message M] [-window-start RFC3339 -window-end RFC3339]
+
+enable, disable and set are recorded in -audit-sink, which they need unless
+-no-audit is passed, and mirrored on -status-page when it is set.`
+
+func runState(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(stateUsage)
+	}
+	sub := args[0]
+	fs := newFlagSet("state "+sub, stderr)
+	kv := addKVFlags(fs)
+	var af auditFlags
+	var sp statusPageFlags
+	if sub != "show" {
+		af = addAuditFlags(fs)
+		sp = addStatusPageFlags(fs)
+	}
+	var title, message, windowStart, windowEnd string
+	var blackoutFiles *string
+	var force *bool
+	if sub == "enable" {
+		blackoutFiles = addBlackoutsFlag(fs)
+		force = fs.Bool("force", false, "enable during a blackout; needs -reason and an audit sink")
+	}
+	if sub == "set" {
+		fs.StringVar(&title, "title", "", "page title (empty falls back to maintenance_title)")
+		fs.StringVar(&message, "message", "", "page message (empty falls back to maintenance_message)")
+		fs.StringVar(&windowStart, "window-start", "", "window start (RFC3339); pass empty start and end to clear")
+		fs.StringVar(&windowEnd, "window-end", "", "window end (RFC3339)")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf(stateUsage)
+	}
+
+	var change func(*state.State)
+	set := map[string]bool{}
+	switch sub {
+	case "show":
+	case "enable", "disable":
+		enabled := sub == "enable"
+		change = func(s *state.State) { s.Enabled = enabled }
+	case "set":
+		// Only the content flags; -namespace-id and the audit flags say where
+		// and why, not what to set.
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "title", "message", "window-start", "window-end":
+				set[f.Name] = true
+			}
+		})
+		if len(set) == 0 {
+			return fmt.Errorf("nothing to set\n%s", stateUsage)
+		}
+		change = func(s *state.State) {
+			if set["title"] {
+				s.Title = title
+			}
+			if set["message"] {
+				s.Message = message
+			}
+			if set["window-start"] {
+				s.WindowStart = windowStart
+			}
+			if set["window-end"] {
+				s.WindowEnd = windowEnd
+			}
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", sub, stateUsage)
+	}
+	if change != nil {
+		if err := af.check(); err != nil {
+			return err
+		}
+	}
+
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// The worker shows the page inside the window even while enabled is
+	// false, so setting one turns maintenance on just like enable. Clearing
+	// it doesn't.
+	setsWindow := (set["window-start"] || set["window-end"]) && (windowStart != "" || windowEnd != "")
+	action := sub
+	if sub == "enable" || setsWindow {
+		current, err := state.Load(ctx, ns)
+		if err != nil {
+			return err
+		}
+		if *af.environment, err = stateEnvironment(current, *af.environment); err != nil {
+			return err
+		}
+		if needsApproval(*af.environment) && setsWindow {
+			return fmt.Errorf("setting a window in %s turns maintenance on for it and needs a second person: run maintctl request enable -window-start ... -window-end ... -reason \"...\" and have someone else run maintctl approve <id>", *af.environment)
+		}
+		if needsApproval(*af.environment) {
+			return fmt.Errorf("enabling maintenance in %s needs a second person: run maintctl request enable -reason \"...\" and have someone else run maintctl approve <id>", *af.environment)
+		}
+	}
+	if sub == "enable" {
+		forced, err := checkBlackouts(ctx, ns, *blackoutFiles, "", *force, *af.reason, *af.sink, stderr)
+		if err != nil {
+			return err
+		}
+		if forced {
+			action = "force-enable"
+		}
+	}
+
+	var s state.State
+	if change == nil {
+		s, err = state.Load(ctx, ns)
+	} else {
+		s, err = af.change(ctx, ns, action, change, stderr)
+		if err == nil {
+			err = sp.sync(ctx, ns, s, stderr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// checkBlackouts refuses to enable maintenance during a blackout in files
+// unless force is set with a reason and an audit sink, and reports whether
+// the enable overrides one. windowEnd is the end of a window set along with
+// enabling, if any.
+func checkBlackouts(ctx context.Context, kv state.Store, files, windowEnd string, force bool, reason, sink string, stderr io.Writer) (bool, error) {
+	b, blocked, err := enableBlackout(ctx, kv, files, windowEnd, time.Now())
+	if err != nil || !blocked {
+		return false, err
+	}
+	span := fmt.Sprintf("blackout %s (%s - %s)", b.Name, b.Start.Format(time.RFC3339), b.End.Format(time.RFC3339))
+	if !force {
+		return false, fmt.Errorf("maintenance can't be enabled during %s; pass -force -reason \"...\" to override", span)
+	}
+	if reason == "" || sink == "" {
+		return false, fmt.Errorf("-force during %s needs -reason and -audit-sink so the override is recorded", span)
+	}
+	fmt.Fprintf(stderr, "warning: enabling maintenance during %s\n", span)
+	return true, nil
+}
+
+// enableBlackout finds a blackout overlapping maintenance enabled at now: until
+// windowEnd or the state's window end, whichever is later, else just now.
+func enableBlackout(ctx context.Context, kv state.Store, files, windowEnd string, now time.Time) (schedule.Blackout, bool, error) {
+	if files == "" {
+		return schedule.Blackout{}, false, nil
+	}
+	blackouts, err := loadBlackouts(files)
+	if err != nil {
+		return schedule.Blackout{}, false, err
+	}
+	s, err := state.Load(ctx, kv)
+	if err != nil {
+		return schedule.Blackout{}, false, err
+	}
+	end := now
+	for _, v := range []string{s.WindowEnd, windowEnd} {
+		if t, err := time.Parse(time.RFC3339, v); err == nil && t.After(end) {
+			end = t
+		}
+	}
+	b, blocked := schedule.FindBlackout(blackouts, now, end)
+	return b, blocked, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+// runMaintctl runs the CLI and returns its exit code and output.
+func runMaintctl(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// mockState points maintctl at a mock API whose KV namespace holds s, with
+// none of the operator's own settings.
+func mockState(t *testing.T, s state.State) *cloudflare.KVNamespace {
+	t.Helper()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	nsID, err := client.CreateKVNamespace(context.Background(), cftest.AccountID, "maintenance-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := client.KV(cftest.AccountID, nsID)
+	raw, _ := json.Marshal(s)
+	if err := ns.Put(context.Background(), state.Key, raw); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{
+		"CLOUDFLARE_API_BASE_URL":     client.BaseURL,
+		"CLOUDFLARE_API_TOKEN":        client.Token,
+		"CLOUDFLARE_ACCOUNT_ID":       cftest.AccountID,
+		"MAINTENANCE_KV_NAMESPACE_ID": nsID,
+		"MAINTCTL_AUDIT_SINK":         "",
+		"MAINTCTL_BLACKOUTS":          "",
+		"MAINTENANCE_ENVIRONMENT":     "",
+		"MAINTCTL_ACTOR":              "alice",
+		"MAINTCTL_APPROVAL_STORE":     "",
+		"MAINTCTL_STATUS_PAGE":        "",
+	} {
+		t.Setenv(k, v)
+	}
+	// Unset, not empty: empty protects no environment.
+	t.Setenv("MAINTCTL_PROTECTED_ENVIRONMENTS", "")
+	os.Unsetenv("MAINTCTL_PROTECTED_ENVIRONMENTS")
+	return ns
+}
+
+func loadState(t *testing.T, ns *cloudflare.KVNamespace) state.State {
+	t.Helper()
+	s, err := state.Load(context.Background(), ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStateSetNeedsContent(t *testing.T) {
+	// -namespace-id and -account-id locate the state; they are not something
+	// to set.
+	code, _, stderr := runMaintctl(t, "state", "set", "-account-id", "a", "-namespace-id", "X")
+	if code != 1 || !strings.Contains(stderr, "nothing to set") {
+		t.Errorf("state set -namespace-id X = %d, %q", code, stderr)
+	}
+}
+
+func TestStateChangesNeedAuditSink(t *testing.T) {
+	ns := mockState(t, state.State{Enabled: true, RolloutPercentage: 100})
+
+	for _, args := range [][]string{
+		{"state", "disable"},
+		{"state", "set", "-title", "Upgrade"},
+		{"rollout", "set", "50"},
+		{"watchdog", "-once"},
+	} {
+		if code, _, stderr := runMaintctl(t, args...); code != 1 || !strings.Contains(stderr, "-no-audit") {
+			t.Errorf("%s without a sink = %d, %q", strings.Join(args, " "), code, stderr)
+		}
+	}
+	if s := loadState(t, ns); !s.Enabled || s.Title != "" || s.RolloutPercentage != 100 {
+		t.Errorf("refused changes were saved: %+v", s)
+	}
+
+	if code, _, stderr := runMaintctl(t, "state", "disable", "-no-audit"); code != 0 || !strings.Contains(stderr, "not recorded") {
+		t.Errorf("state disable -no-audit = %d, %q", code, stderr)
+	}
+	if loadState(t, ns).Enabled {
+		t.Error("state disable -no-audit left maintenance on")
+	}
+
+	sink := filepath.Join(t.TempDir(), "audit.jsonl")
+	if code, _, stderr := runMaintctl(t, "state", "set", "-title", "Upgrade", "-audit-sink", sink, "-reason", "INC-1"); code != 0 {
+		t.Fatalf("state set with a sink = %d, %q", code, stderr)
+	}
+	if raw, err := os.ReadFile(sink); err != nil || !strings.Contains(string(raw), "INC-1") {
+		t.Errorf("audit log = %q, %v", raw, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/statuspage"
+)
+
+const statusPageUsage = `usage: maintctl statuspage sync [flags]
+
+Brings the scheduled maintenance on the status page in line with the live
+state: created when maintenance is on or a window is ahead, updated when the
+title, message or window change, completed when it is over. state and
+approve do this after each change when -status-page is set; run sync from
+cron to follow changes made by the worker's schedules and watchdog.`
+
+// statusPageFlags say which status page mirrors the state, and what the
+// worker's bindings show when the state leaves title and message empty.
+type statusPageFlags struct {
+	spec     *string
+	title    *string
+	message  *string
+	duration *time.Duration
+}
+
+func addStatusPageFlags(fs *flag.FlagSet) statusPageFlags {
+	return statusPageFlags{
+		spec:     fs.String("status-page", os.Getenv("MAINTCTL_STATUS_PAGE"), "status page to mirror the state on: statuspage://PAGE_ID or instatus://PAGE_ID (default $MAINTCTL_STATUS_PAGE)"),
+		title:    fs.String("status-title", envOr("MAINTENANCE_TITLE", "Maintenance Mode"), "title when the state has none, the module's maintenance_title (default $MAINTENANCE_TITLE)"),
+		message:  fs.String("status-message", envOr("MAINTENANCE_MESSAGE", "We are currently performing scheduled maintenance. We will be back shortly."), "message when the state has none, the module's maintenance_message (default $MAINTENANCE_MESSAGE)"),
+		duration: fs.Duration("status-duration", time.Hour, "how long maintenance turned on without a window is announced for"),
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// sync mirrors s on the status page, if one is set. Like an audit failure,
+// a state that was saved but not mirrored is an error.
+func (f statusPageFlags) sync(ctx context.Context, kv state.Store, s state.State, stderr io.Writer) error {
+	if *f.spec == "" {
+		return nil
+	}
+	provider, err := statuspage.Open(*f.spec)
+	if err != nil {
+		return fmt.Errorf("state saved but status page not updated: %w", err)
+	}
+	y := statuspage.Syncer{
+		Provider: provider,
+		Spec:     *f.spec,
+		KV:       kv,
+		Defaults: statuspage.Defaults{Title: *f.title, Message: *f.message, Duration: *f.duration},
+	}
+	did, err := y.Sync(ctx, s, time.Now())
+	if err != nil {
+		return fmt.Errorf("state saved but status page not updated: %w", err)
+	}
+	if did != "" {
+		fmt.Fprintf(stderr, "status page: maintenance %s on %s\n", did, *f.spec)
+	}
+	return nil
+}
+
+func runStatusPage(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return fmt.Errorf(statusPageUsage)
+	}
+	fs := newFlagSet("statuspage sync", stderr)
+	kv := addKVFlags(fs)
+	sp := addStatusPageFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || *sp.spec == "" {
+		return fmt.Errorf(statusPageUsage)
+	}
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	s, err := state.Load(ctx, ns)
+	if err != nil {
+		return err
+	}
+	return sp.sync(ctx, ns, s, stderr)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/pagetemplate"
+)
+
+func runTemplate(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "lint" {
+		return fmt.Errorf("usage: maintctl template lint <template.html>...")
+	}
+
+	fs := newFlagSet("template lint", stderr)
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no template files given")
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		findings := pagetemplate.Lint(string(src))
+		for _, f := range findings {
+			fmt.Fprintf(stdout, "%s: %s\n", path, f)
+		}
+		if pagetemplate.HasErrors(findings) || (*strict && len(findings) > 0) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitError{code: 1, err: fmt.Errorf("%d of %d template(s) failed", failed, fs.NArg())}
+	}
+	fmt.Fprintf(stdout, "%d template(s) OK\n", fs.NArg())
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/updates"
+)
+
+const updateUsage = `usage: maintctl update post [flags] "message"
+       maintctl update list [flags]
+       maintctl update delete [flags] <id>`
+
+func runUpdate(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf(updateUsage)
+	}
+	sub := args[0]
+	fs := newFlagSet("update "+sub, stderr)
+	kv := addKVFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var run func(context.Context, updates.Timeline) error
+	switch sub {
+	case "post":
+		if fs.NArg() == 0 {
+			return fmt.Errorf(updateUsage)
+		}
+		message := strings.Join(fs.Args(), " ")
+		run = func(ctx context.Context, tl updates.Timeline) error {
+			u, err := tl.Post(ctx, message)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "posted %s\n", u.ID)
+			return nil
+		}
+	case "list":
+		run = func(ctx context.Context, tl updates.Timeline) error {
+			list, err := tl.List(ctx)
+			if err != nil {
+				return err
+			}
+			for _, u := range list {
+				fmt.Fprintf(stdout, "%s\t%s\t%s\n", u.ID, u.PostedAt.Format(time.RFC3339), u.Message)
+			}
+			return nil
+		}
+	case "delete":
+		if fs.NArg() != 1 {
+			return fmt.Errorf(updateUsage)
+		}
+		id := fs.Arg(0)
+		run = func(ctx context.Context, tl updates.Timeline) error {
+			if err := tl.Delete(ctx, id); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "deleted %s\n", id)
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q\n%s", sub, updateUsage)
+	}
+
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return run(ctx, updates.Timeline{KV: ns})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/audit"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/watchdog"
+)
+
+const watchdogUsage = `usage: maintctl watchdog [flags]
+
+Checks the live state every -interval and turns maintenance off once the
+window has ended plus -grace, or once it has been on for -max-duration.
+Runs until interrupted; -once checks a single time, for cron jobs. Changes
+are recorded in -audit-sink, which is required unless -no-audit is passed.`
+
+func runWatchdog(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("watchdog", stderr)
+	kv := addKVFlags(fs)
+	environment := addEnvironmentFlag(fs)
+	sinkSpec := addAuditSinkFlag(fs)
+	noAudit := addNoAuditFlag(fs)
+	interval := fs.Duration("interval", time.Minute, "how often to check")
+	maxDuration := fs.Duration("max-duration", 0, "turn maintenance off after it has been on this long (0 for no limit)")
+	grace := fs.Duration("grace", 15*time.Minute, "how long maintenance may stay on after the window ends")
+	targets := fs.String("notify", os.Getenv("MAINTCTL_NOTIFY"), "comma-separated slack://, pagerduty:// or webhook:// targets told when maintenance is turned off (default $MAINTCTL_NOTIFY)")
+	once := fs.Bool("once", false, "check once and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf(watchdogUsage)
+	}
+	if *interval < time.Second || *maxDuration < 0 || *grace < 0 {
+		return errors.New("-interval must be at least 1s and -max-duration and -grace must not be negative")
+	}
+	if err := checkAuditSink(*sinkSpec, *noAudit); err != nil {
+		return err
+	}
+
+	ns, err := kv.namespace()
+	if err != nil {
+		return err
+	}
+	w := &watchdog.Watchdog{
+		KV:          ns,
+		Config:      watchdog.Config{MaxDuration: *maxDuration, Grace: *grace},
+		Environment: *environment,
+		Logf: func(format string, args ...any) {
+			fmt.Fprintf(stdout, "%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+		},
+	}
+	for _, t := range strings.Split(*targets, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			w.Notify = append(w.Notify, t)
+		}
+	}
+	if *sinkSpec != "" {
+		if w.Audit, err = audit.Open(*sinkSpec); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintln(stderr, "warning: -no-audit; changes will not be recorded")
+	}
+
+	if *once {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, err := w.Tick(ctx, time.Now())
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := w.Run(ctx, watchdog.RealClock, *interval); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
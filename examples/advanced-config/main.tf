@@ -15,6 +15,10 @@ module "maintenance" {
   # Toggle maintenance mode based on environment
   enabled = var.environment == "production" ? false : true
 
+  # Live state and status updates in Workers KV, changed with maintctl
+  kv_runtime_state      = true
+  enable_status_updates = true
+
   # Custom maintenance page content
   maintenance_title = "Scheduled System Maintenance"
   contact_email     = "support@example.com"
@@ -29,8 +33,9 @@ module "maintenance" {
   }
 
   # Custom styling
-  custom_css = file("${path.module}/custom-styles.css")
-  logo_url   = "https://example.com/logo-large.png"
+  page_template_file = "${path.module}/maintenance.html"
+  custom_css         = file("${path.module}/custom-styles.css")
+  logo_url           = "https://example.com/logo-large.png"
 }
 
 # Create a DNS record for direct access to maintenance page
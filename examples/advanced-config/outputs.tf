@@ -36,3 +36,8 @@ output "allowed_ip_count" {
   description = "Number of IPs allowed to bypass maintenance"
   value       = length(concat(var.office_ip_ranges, var.monitoring_ips))
 }
+
+output "kv_namespace_id" {
+  description = "KV namespace for `maintctl state` and `maintctl update` (-namespace-id)"
+  value       = module.maintenance.kv_namespace_id
+}
@@ -24,6 +24,7 @@ module "maintenance" {
     start_time = "2025-04-06T08:00:00Z"
     end_time   = "2025-04-06T10:00:00Z"
   }
+  display_timezone = "America/Los_Angeles"
 
   # Custom maintenance page
   maintenance_title   = "Scheduled System Maintenance"
@@ -48,7 +49,8 @@ module "maintenance" {
   allowed_ips     = ["192.168.1.100", "10.0.0.1"]
   allowed_regions = ["US", "CA"]
 
-  # Support for cron-based schedules (for documentation purposes)
+  # Recurring windows; the worker opens and closes them in the KV runtime state
+  kv_runtime_state = true
   schedules = [
     {
       name     = "weekly-maintenance"
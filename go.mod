@@ -0,0 +1,3 @@
+module github.com/thomasvincent/terraform-cloudflare-maintenance
+
+go 1.23.0
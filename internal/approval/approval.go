@@ -0,0 +1,235 @@
+// Package approval implements two-person approval for risky state changes:
+// one identity requests the change with a reason, a different identity
+// approves it, and only the approval applies it. Requests live in a Store,
+// either a local file or the module's KV namespace.
+//
+// An identity is a name and an API token ID. Names are whatever the operator
+// passes, so they only keep honest people apart; the token ID comes from
+// Cloudflare, and a request can't be approved with the token that made it.
+// Anyone whose token can write the namespace can still change the state or
+// the stored requests directly, so the control is only as strong as the
+// split of tokens between people.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long a request can wait for approval.
+const DefaultTTL = time.Hour
+
+// retention is how long decided and expired requests are kept.
+const retention = 30 * 24 * time.Hour
+
+// Actions that can be requested.
+const (
+	ActionEnable = "enable"
+)
+
+// Request statuses. A pending request past its ExpiresAt is reported as
+// StatusExpired by Status.
+const (
+	StatusPending = "pending"
+	StatusApplied = "applied"
+	StatusExpired = "expired"
+)
+
+var (
+	ErrUnknownID    = errors.New("no request with that id")
+	ErrSelfApproval = errors.New("a request must be approved by someone other than its requester")
+	ErrExpired      = errors.New("request has expired; create a new one")
+	ErrNotPending   = errors.New("request was already approved")
+	ErrSameToken    = errors.New("a request must be approved with a different API token than the one that made it")
+)
+
+// Identity is who requests or approves: the name they go by and the ID of
+// the API token they hold.
+type Identity struct {
+	Actor string
+	Token string
+}
+
+// Request is one pending or decided change.
+type Request struct {
+	ID          string `json:"id"`
+	Environment string `json:"environment"`
+	Action      string `json:"action"`
+	Reason      string `json:"reason"`
+	// WindowStart and WindowEnd, when set, are the window the approval
+	// writes along with enabling maintenance.
+	WindowStart   string    `json:"window_start,omitempty"`
+	WindowEnd     string    `json:"window_end,omitempty"`
+	RequestedBy   string    `json:"requested_by"`
+	RequestedWith string    `json:"requested_with,omitempty"`
+	RequestedAt   time.Time `json:"requested_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	// State is the stored status; Status also accounts for expiry.
+	State        string    `json:"status"`
+	ApprovedBy   string    `json:"approved_by,omitempty"`
+	ApprovedWith string    `json:"approved_with,omitempty"`
+	ApprovedAt   time.Time `json:"approved_at"`
+}
+
+// Status is the request's status at now.
+func (r Request) Status(now time.Time) string {
+	if r.State == StatusPending && !now.Before(r.ExpiresAt) {
+		return StatusExpired
+	}
+	return r.State
+}
+
+// Store keeps the list of requests.
+type Store interface {
+	Load(ctx context.Context) ([]Request, error)
+	Save(ctx context.Context, requests []Request) error
+}
+
+// Workflow creates and approves requests in a Store.
+type Workflow struct {
+	Store Store
+	TTL   time.Duration    // defaults to DefaultTTL
+	Now   func() time.Time // defaults to time.Now
+}
+
+func (w Workflow) now() time.Time {
+	if w.Now != nil {
+		return w.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Request records a pending change: the environment, action, reason and
+// window of change, made by who. The reason and both parts of who are
+// required.
+func (w Workflow) Request(ctx context.Context, change Request, who Identity) (Request, error) {
+	if change.Action != ActionEnable {
+		return Request{}, fmt.Errorf("unsupported action %q (only %s can be requested)", change.Action, ActionEnable)
+	}
+	if strings.TrimSpace(change.Reason) == "" {
+		return Request{}, errors.New("a reason is required")
+	}
+	if err := who.check("requester"); err != nil {
+		return Request{}, err
+	}
+	ttl := w.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	list, err := w.Store.Load(ctx)
+	if err != nil {
+		return Request{}, err
+	}
+	now := w.now()
+	r := Request{
+		ID:            newID(list),
+		Environment:   change.Environment,
+		Action:        change.Action,
+		Reason:        strings.TrimSpace(change.Reason),
+		WindowStart:   change.WindowStart,
+		WindowEnd:     change.WindowEnd,
+		RequestedBy:   who.Actor,
+		RequestedWith: who.Token,
+		RequestedAt:   now,
+		ExpiresAt:     now.Add(ttl),
+		State:         StatusPending,
+	}
+	return r, w.Store.Save(ctx, prune(append(list, r), now))
+}
+
+// Approve checks that id is pending and that who is neither the requester
+// nor holds the requester's token, then calls apply. The request is marked applied only when apply succeeds,
+// so a failed apply can be retried with the same request.
+func (w Workflow) Approve(ctx context.Context, id string, who Identity, apply func(Request) error) (Request, error) {
+	if err := who.check("approver"); err != nil {
+		return Request{}, err
+	}
+	list, err := w.Store.Load(ctx)
+	if err != nil {
+		return Request{}, err
+	}
+	i := indexOf(list, id)
+	if i < 0 {
+		return Request{}, fmt.Errorf("%w: %s", ErrUnknownID, id)
+	}
+	r := list[i]
+	now := w.now()
+	switch r.Status(now) {
+	case StatusExpired:
+		return r, ErrExpired
+	case StatusApplied:
+		return r, fmt.Errorf("%w by %s", ErrNotPending, r.ApprovedBy)
+	}
+	if strings.EqualFold(strings.TrimSpace(who.Actor), strings.TrimSpace(r.RequestedBy)) {
+		return r, ErrSelfApproval
+	}
+	// Requests from before tokens were recorded can't show they came from
+	// another token.
+	if r.RequestedWith == "" || r.RequestedWith == who.Token {
+		return r, ErrSameToken
+	}
+	if err := apply(r); err != nil {
+		return r, err
+	}
+	r.State, r.ApprovedBy, r.ApprovedWith, r.ApprovedAt = StatusApplied, who.Actor, who.Token, now
+	list[i] = r
+	return r, w.Store.Save(ctx, prune(list, now))
+}
+
+func (who Identity) check(role string) error {
+	if strings.TrimSpace(who.Actor) == "" {
+		return fmt.Errorf("the %s's identity is unknown; set -actor or MAINTCTL_ACTOR", role)
+	}
+	if who.Token == "" {
+		return fmt.Errorf("the %s's API token is unknown", role)
+	}
+	return nil
+}
+
+// List returns all kept requests, newest first.
+func (w Workflow) List(ctx context.Context) ([]Request, error) {
+	list, err := w.Store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].RequestedAt.After(list[j].RequestedAt) })
+	return list, nil
+}
+
+func indexOf(list []Request, id string) int {
+	for i, r := range list {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// prune drops requests that were decided or expired more than retention ago.
+func prune(list []Request, now time.Time) []Request {
+	kept := list[:0]
+	for _, r := range list {
+		if now.Sub(r.ExpiresAt) < retention || (r.State == StatusApplied && now.Sub(r.ApprovedAt) < retention) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// newID returns a short random ID that is easy to read out to a colleague.
+func newID(list []Request) string {
+	for {
+		var b [4]byte
+		_, _ = rand.Read(b[:])
+		id := hex.EncodeToString(b[:])
+		if indexOf(list, id) < 0 {
+			return id
+		}
+	}
+}
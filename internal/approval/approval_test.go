@@ -0,0 +1,212 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+type memKV map[string][]byte
+
+func (m memKV) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("get %s: %w", key, cloudflare.ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m memKV) Put(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+var (
+	alice = Identity{Actor: "alice", Token: "token-a"}
+	bob   = Identity{Actor: "bob", Token: "token-b"}
+)
+
+func enable(reason string) Request {
+	return Request{Environment: "production", Action: ActionEnable, Reason: reason}
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func stores(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"file": FileStore{Path: filepath.Join(t.TempDir(), "approvals.json")},
+		"kv":   KVStore{KV: memKV{}},
+	}
+}
+
+func TestRequestApprove(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := &fakeClock{time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)}
+			w := Workflow{Store: store, Now: clock.Now}
+
+			// The state the approval gates.
+			kv := memKV{}
+			if _, err := state.Save(ctx, kv, state.State{RolloutPercentage: 100}, clock.Now()); err != nil {
+				t.Fatal(err)
+			}
+			enabled := func() bool {
+				s, err := state.Load(ctx, kv)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s.Enabled
+			}
+			applies := 0
+			apply := func(Request) error {
+				applies++
+				_, err := state.Update(ctx, kv, clock.Now(), func(s *state.State) { s.Enabled = true })
+				return err
+			}
+
+			r, err := w.Request(ctx, enable("  DB migration INC-1234 "), alice)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Reason != "DB migration INC-1234" || r.Status(clock.Now()) != StatusPending || !r.ExpiresAt.Equal(clock.Now().Add(DefaultTTL)) {
+				t.Errorf("request = %+v", r)
+			}
+			if enabled() {
+				t.Fatal("requesting flipped the state")
+			}
+
+			if _, err := w.Approve(ctx, r.ID, Identity{Actor: "Alice", Token: "token-b"}, apply); !errors.Is(err, ErrSelfApproval) {
+				t.Errorf("self-approval: err = %v", err)
+			}
+			// A second name doesn't help while the token is the same.
+			if _, err := w.Approve(ctx, r.ID, Identity{Actor: "mallory", Token: "token-a"}, apply); !errors.Is(err, ErrSameToken) {
+				t.Errorf("same token: err = %v", err)
+			}
+			if _, err := w.Approve(ctx, "nope", bob, apply); !errors.Is(err, ErrUnknownID) {
+				t.Errorf("unknown id: err = %v", err)
+			}
+			if applies != 0 || enabled() {
+				t.Fatal("rejected approvals flipped the state")
+			}
+
+			clock.Advance(10 * time.Minute)
+			approved, err := w.Approve(ctx, r.ID, bob, apply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if approved.Status(clock.Now()) != StatusApplied || approved.ApprovedBy != "bob" || approved.ApprovedWith != "token-b" || !approved.ApprovedAt.Equal(clock.Now()) {
+				t.Errorf("approved = %+v", approved)
+			}
+			if applies != 1 || !enabled() {
+				t.Fatal("approval did not flip the state")
+			}
+
+			if _, err := w.Approve(ctx, r.ID, Identity{Actor: "carol", Token: "token-c"}, apply); !errors.Is(err, ErrNotPending) {
+				t.Errorf("second approval: err = %v", err)
+			}
+			if applies != 1 {
+				t.Error("second approval applied again")
+			}
+
+			list, err := w.List(ctx)
+			if err != nil || len(list) != 1 || list[0].ApprovedBy != "bob" {
+				t.Errorf("List = %+v, %v", list, err)
+			}
+		})
+	}
+}
+
+func TestApproveFailedApplyCanBeRetried(t *testing.T) {
+	ctx := context.Background()
+	w := Workflow{Store: KVStore{KV: memKV{}}}
+	r, _ := w.Request(ctx, enable("deploy"), alice)
+
+	if _, err := w.Approve(ctx, r.ID, bob, func(Request) error { return errors.New("KV unavailable") }); err == nil {
+		t.Fatal("Approve ignored the apply error")
+	}
+	list, _ := w.List(ctx)
+	if list[0].State != StatusPending {
+		t.Fatalf("failed apply left the request %s", list[0].State)
+	}
+	if _, err := w.Approve(ctx, r.ID, bob, func(Request) error { return nil }); err != nil {
+		t.Errorf("retry: %v", err)
+	}
+}
+
+func TestRequestExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)}
+	w := Workflow{Store: KVStore{KV: memKV{}}, TTL: 15 * time.Minute, Now: clock.Now}
+	r, _ := w.Request(ctx, enable("deploy"), alice)
+
+	clock.Advance(15 * time.Minute)
+	if r.Status(clock.Now()) != StatusExpired {
+		t.Errorf("status at expiry = %s", r.Status(clock.Now()))
+	}
+	if _, err := w.Approve(ctx, r.ID, bob, func(Request) error { t.Error("expired request applied"); return nil }); !errors.Is(err, ErrExpired) {
+		t.Errorf("err = %v, want ErrExpired", err)
+	}
+
+	// Old requests are dropped once a later save happens.
+	clock.Advance(retention)
+	if _, err := w.Request(ctx, enable("deploy again"), alice); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := w.List(ctx); len(list) != 1 || list[0].ID == r.ID {
+		t.Errorf("List after retention = %+v", list)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	ctx := context.Background()
+	w := Workflow{Store: KVStore{KV: memKV{}}}
+	for _, tc := range []struct {
+		action, reason string
+		who            Identity
+	}{
+		{"disable", "x", alice},
+		{ActionEnable, "   ", alice},
+		{ActionEnable, "deploy", Identity{Token: "token-a"}},
+		{ActionEnable, "deploy", Identity{Actor: "alice"}},
+	} {
+		change := Request{Environment: "production", Action: tc.action, Reason: tc.reason}
+		if _, err := w.Request(ctx, change, tc.who); err == nil {
+			t.Errorf("Request(%q, %q, %+v) succeeded", tc.action, tc.reason, tc.who)
+		}
+	}
+	if _, err := w.Approve(ctx, "x", Identity{Actor: " ", Token: "token-b"}, nil); err == nil {
+		t.Error("Approve without an approver succeeded")
+	}
+	if _, err := w.Approve(ctx, "x", Identity{Actor: "bob"}, nil); err == nil {
+		t.Error("Approve without a token succeeded")
+	}
+}
+
+func TestApproveNeedsRequesterToken(t *testing.T) {
+	// Requests stored before tokens were recorded can't be approved.
+	ctx := context.Background()
+	kv := memKV{}
+	w := Workflow{Store: KVStore{KV: kv}}
+	r, err := w.Request(ctx, enable("deploy"), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, _ := w.List(ctx)
+	list[0].RequestedWith = ""
+	if err := w.Store.Save(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Approve(ctx, r.ID, bob, func(Request) error { t.Error("applied"); return nil }); !errors.Is(err, ErrSameToken) {
+		t.Errorf("err = %v, want ErrSameToken", err)
+	}
+}
@@ -0,0 +1,98 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// FileStore keeps requests in a JSON file, replaced atomically on save.
+// It suits a shared host or tests; teams working from their own machines
+// should use KVStore.
+type FileStore struct {
+	Path string
+}
+
+// Load reads the file; a missing file has no requests.
+func (s FileStore) Load(context.Context) ([]Request, error) {
+	raw, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Request
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", s.Path, err)
+	}
+	return list, nil
+}
+
+// Save writes the list to a temporary file next to Path and renames it.
+func (s FileStore) Save(_ context.Context, list []Request) error {
+	raw, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// Key is the KV key KVStore keeps requests under.
+const Key = "approval_requests"
+
+// KV is the subset of a KV namespace KVStore needs.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// KVStore keeps requests in the module's KV namespace, next to the state
+// they gate. KV has no transactions, so two saves at the same moment can
+// lose one of them; the loser's request is simply not found on approve.
+type KVStore struct {
+	KV KV
+}
+
+// Load reads the list; a missing key has no requests.
+func (s KVStore) Load(ctx context.Context) ([]Request, error) {
+	raw, err := s.KV.Get(ctx, Key)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Request
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", Key, err)
+	}
+	return list, nil
+}
+
+// Save writes the list.
+func (s KVStore) Save(ctx context.Context, list []Request) error {
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return s.KV.Put(ctx, Key, raw)
+}
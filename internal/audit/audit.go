@@ -0,0 +1,151 @@
+// Package audit records who changed the maintenance state, when and why.
+// maintctl appends an Event to a Sink for every change it makes; sinks are
+// a local JSONL file, an S3-compatible bucket or a webhook, picked with a
+// URL-like spec (see Open).
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+// Event is one state change.
+type Event struct {
+	ID          string      `json:"id"`
+	Time        time.Time   `json:"time"`
+	Environment string      `json:"environment"`
+	Actor       string      `json:"actor"`
+	Action      string      `json:"action"`
+	Reason      string      `json:"reason,omitempty"`
+	Previous    state.State `json:"previous"`
+	New         state.State `json:"new"`
+}
+
+// NewEvent builds an event stamped with now and a fresh ID.
+func NewEvent(now time.Time, environment, actor, action, reason string, previous, next state.State) Event {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	now = now.UTC()
+	return Event{
+		ID:          strconv.FormatInt(now.UnixMilli(), 36) + hex.EncodeToString(b[:]),
+		Time:        now,
+		Environment: environment,
+		Actor:       actor,
+		Action:      action,
+		Reason:      reason,
+		Previous:    previous,
+		New:         next,
+	}
+}
+
+// Changes describes the fields that differ between Previous and New, e.g.
+// "enabled false -> true". UpdatedAt is not a change of its own.
+func (e Event) Changes() []string {
+	var out []string
+	field := func(name, from, to string) {
+		if from != to {
+			out = append(out, fmt.Sprintf("%s %s -> %s", name, from, to))
+		}
+	}
+	p, n := e.Previous, e.New
+	field("enabled", strconv.FormatBool(p.Enabled), strconv.FormatBool(n.Enabled))
+	field("title", strconv.Quote(p.Title), strconv.Quote(n.Title))
+	field("message", strconv.Quote(p.Message), strconv.Quote(n.Message))
+	field("window", window(p), window(n))
+	field("rollout", strconv.Itoa(p.RolloutPercentage)+"%", strconv.Itoa(n.RolloutPercentage)+"%")
+	return out
+}
+
+func window(s state.State) string {
+	if s.WindowStart == "" && s.WindowEnd == "" {
+		return "none"
+	}
+	return s.WindowStart + ".." + s.WindowEnd
+}
+
+// Filter selects events for List. Zero fields match everything; Until is
+// exclusive.
+type Filter struct {
+	Environment string
+	Since       time.Time
+	Until       time.Time
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Event) bool {
+	if f.Environment != "" && e.Environment != f.Environment {
+		return false
+	}
+	return f.inRange(e.Time)
+}
+
+func (f Filter) inRange(t time.Time) bool {
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || t.Before(f.Until)
+}
+
+// Sink stores events.
+type Sink interface {
+	Append(ctx context.Context, e Event) error
+}
+
+// Lister is implemented by sinks that can read their events back, oldest
+// first.
+type Lister interface {
+	List(ctx context.Context, f Filter) ([]Event, error)
+}
+
+// ErrWriteOnly is returned when listing a sink that cannot be read back.
+var ErrWriteOnly = errors.New("audit sink is write-only")
+
+// List reads events back from sink, or fails with ErrWriteOnly.
+func List(ctx context.Context, sink Sink, f Filter) ([]Event, error) {
+	l, ok := sink.(Lister)
+	if !ok {
+		return nil, ErrWriteOnly
+	}
+	return l.List(ctx, f)
+}
+
+// Open returns the sink for spec:
+//
+//	/var/log/maintenance-audit.jsonl       JSONL file (also file:///...)
+//	s3://bucket/prefix/                    S3-compatible bucket
+//	webhook://https://audit.example.com    POST each event as JSON
+//
+// S3 credentials, region and endpoint come from the usual AWS_* variables
+// (see NewS3SinkFromEnv), so R2 and MinIO work as well as AWS.
+func Open(spec string) (Sink, error) {
+	switch {
+	case spec == "":
+		return nil, errors.New("empty audit sink")
+	case strings.HasPrefix(spec, "s3://"):
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(spec, "s3://"), "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("audit sink %q has no bucket", spec)
+		}
+		return NewS3SinkFromEnv(bucket, prefix)
+	case strings.HasPrefix(spec, "webhook://"):
+		url := strings.TrimPrefix(spec, "webhook://")
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return nil, fmt.Errorf("audit sink %q: webhook URL must start with http:// or https://", spec)
+		}
+		return &WebhookSink{URL: url}, nil
+	case strings.HasPrefix(spec, "file://"):
+		return &FileSink{Path: strings.TrimPrefix(spec, "file://")}, nil
+	case strings.Contains(spec, "://"):
+		return nil, fmt.Errorf("audit sink %q: unknown scheme (use a path, file://, s3:// or webhook://)", spec)
+	default:
+		return &FileSink{Path: spec}, nil
+	}
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// FileSink appends events to a JSONL file, one event per line. Each event
+// is a single write to a file opened with O_APPEND, so concurrent maintctl
+// runs on one host don't interleave lines.
+type FileSink struct {
+	Path string
+}
+
+// Append writes e as one line.
+func (s *FileSink) Append(_ context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List reads the file; a missing file has no events.
+func (s *FileSink) List(_ context.Context, filter Filter) ([]Event, error) {
+	f, err := os.Open(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.Path, n, err)
+		}
+		if filter.Match(e) {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,76 @@
+// Package cftest starts tests/mocks/cloudflare-mock-server.js for Go tests,
+// so the CLI and API client are exercised against the same mock the JS
+// suites use. Tests are skipped when node is not installed.
+package cftest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// AccountID is the account the mock's seed zone belongs to.
+const AccountID = "test-account-id"
+
+// ZoneID and SecondZoneID are the zones the mock server is seeded with.
+const (
+	ZoneID       = "test-zone-id"
+	SecondZoneID = "test-zone-id-2"
+)
+
+// StartMock launches a fresh mock server and returns its API base URL
+// (".../client/v4"). The server is stopped when the test ends.
+func StartMock(t testing.TB) string {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed; skipping mock Cloudflare API test")
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(node, mockServerPath())
+	cmd.Env = append(os.Environ(), fmt.Sprintf("MOCK_SERVER_PORT=%d", port))
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	base := fmt.Sprintf("http://127.0.0.1:%d/client/v4", port)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(base + "/user/tokens/verify")
+		if err == nil {
+			resp.Body.Close()
+			return base
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("mock Cloudflare API did not start on port %d", port)
+	return ""
+}
+
+func mockServerPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "tests", "mocks", "cloudflare-mock-server.js")
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,175 @@
+// Package cloudflare is a minimal client for the parts of the Cloudflare v4
+// API that maintctl needs. Requests go to CLOUDFLARE_API_BASE_URL when it is
+// set, which is how tests point it at tests/mocks/cloudflare-mock-server.js.
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public Cloudflare API.
+const DefaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+// ErrNotFound is returned (wrapped) for 404 responses.
+var ErrNotFound = errors.New("not found")
+
+// Client talks to the Cloudflare API with a bearer token.
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewFromEnv builds a client from CLOUDFLARE_API_TOKEN and, if set,
+// CLOUDFLARE_API_BASE_URL.
+func NewFromEnv() *Client {
+	base := os.Getenv("CLOUDFLARE_API_BASE_URL")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: base,
+		Token:   os.Getenv("CLOUDFLARE_API_TOKEN"),
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// APIError carries the error list of an unsuccessful API response.
+type APIError struct {
+	Status int
+	Errors []Message
+}
+
+// Message is an entry of the errors/messages arrays in API responses.
+type Message struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("cloudflare: HTTP %d", e.Status)
+	}
+	msgs := make([]string, len(e.Errors))
+	for i, m := range e.Errors {
+		msgs[i] = fmt.Sprintf("%s (code %d)", m.Message, m.Code)
+	}
+	return fmt.Sprintf("cloudflare: HTTP %d: %s", e.Status, strings.Join(msgs, "; "))
+}
+
+func (e *APIError) Unwrap() error {
+	if e.Status == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type envelope struct {
+	Success bool            `json:"success"`
+	Errors  []Message       `json:"errors"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// do sends a request and returns the response for 2xx statuses; anything
+// else is decoded into an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &APIError{Status: resp.StatusCode}
+	var env envelope
+	if json.NewDecoder(resp.Body).Decode(&env) == nil {
+		apiErr.Errors = env.Errors
+	}
+	return nil, apiErr
+}
+
+// doJSON sends in (if non-nil) as JSON and decodes the envelope's result
+// into out (if non-nil).
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(b), "application/json"
+	}
+	resp, err := c.do(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	return decodeResult(resp, out)
+}
+
+// decodeResult reads an API envelope and unmarshals its result into out.
+func decodeResult(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("cloudflare: decoding %s %s: %w", resp.Request.Method, resp.Request.URL.Path, err)
+	}
+	if !env.Success {
+		return &APIError{Status: resp.StatusCode, Errors: env.Errors}
+	}
+	if out == nil || len(env.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Result, out)
+}
+
+// VerifyToken returns the ID of the client's API token. Cloudflare assigns
+// it, so unlike a name the holder types in, it can't be picked to look like
+// someone else.
+func (c *Client) VerifyToken(ctx context.Context) (string, error) {
+	var token struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, pathEscape("user", "tokens", "verify"), nil, &token); err != nil {
+		return "", err
+	}
+	if token.Status != "active" {
+		return "", fmt.Errorf("cloudflare: API token %s is %s", token.ID, token.Status)
+	}
+	return token.ID, nil
+}
+
+func pathEscape(segments ...string) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(s))
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+)
+
+// DNSRecord is a zone DNS record. Name is the fully qualified name the API
+// returns, even when the record was created with a relative one.
+type DNSRecord struct {
+	ID       string `json:"id,omitempty"`
+	ZoneName string `json:"zone_name,omitempty"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	Proxied  bool   `json:"proxied"`
+	TTL      int    `json:"ttl"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// CreateDNSRecord creates a record and returns it with its ID.
+func (c *Client) CreateDNSRecord(ctx context.Context, zoneID string, r DNSRecord) (DNSRecord, error) {
+	var created DNSRecord
+	err := c.doJSON(ctx, http.MethodPost, pathEscape("zones", zoneID, "dns_records"), r, &created)
+	return created, err
+}
+
+// GetDNSRecord returns a record. A missing record returns an error wrapping
+// ErrNotFound.
+func (c *Client) GetDNSRecord(ctx context.Context, zoneID, recordID string) (DNSRecord, error) {
+	var r DNSRecord
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("zones", zoneID, "dns_records", recordID), nil, &r)
+	return r, err
+}
+
+// UpdateDNSRecord changes the fields set in patch on an existing record.
+func (c *Client) UpdateDNSRecord(ctx context.Context, zoneID, recordID string, patch map[string]any) (DNSRecord, error) {
+	var updated DNSRecord
+	err := c.doJSON(ctx, http.MethodPatch, pathEscape("zones", zoneID, "dns_records", recordID), patch, &updated)
+	return updated, err
+}
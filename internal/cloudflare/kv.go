@@ -0,0 +1,78 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// KVNamespace is a handle on one Workers KV namespace.
+type KVNamespace struct {
+	client      *Client
+	AccountID   string
+	NamespaceID string
+}
+
+// KV returns a handle for the given namespace.
+func (c *Client) KV(accountID, namespaceID string) *KVNamespace {
+	return &KVNamespace{client: c, AccountID: accountID, NamespaceID: namespaceID}
+}
+
+// CreateKVNamespace creates a namespace and returns its ID.
+func (c *Client) CreateKVNamespace(ctx context.Context, accountID, title string) (string, error) {
+	var ns struct {
+		ID string `json:"id"`
+	}
+	err := c.doJSON(ctx, http.MethodPost, pathEscape("accounts", accountID, "storage", "kv", "namespaces"), map[string]string{"title": title}, &ns)
+	return ns.ID, err
+}
+
+func (kv *KVNamespace) path(parts ...string) string {
+	return pathEscape(append([]string{"accounts", kv.AccountID, "storage", "kv", "namespaces", kv.NamespaceID}, parts...)...)
+}
+
+// Get returns the raw value stored under key. Missing keys return an error
+// wrapping ErrNotFound.
+func (kv *KVNamespace) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := kv.client.do(ctx, http.MethodGet, kv.path("values", key), nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Put stores value under key.
+func (kv *KVNamespace) Put(ctx context.Context, key string, value []byte) error {
+	resp, err := kv.client.do(ctx, http.MethodPut, kv.path("values", key), bytes.NewReader(value), "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	return decodeResult(resp, nil)
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (kv *KVNamespace) Delete(ctx context.Context, key string) error {
+	return kv.client.doJSON(ctx, http.MethodDelete, kv.path("values", key), nil, nil)
+}
+
+// ListKeys returns the names of keys starting with prefix.
+func (kv *KVNamespace) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []struct {
+		Name string `json:"name"`
+	}
+	p := kv.path("keys")
+	if prefix != "" {
+		p += "?prefix=" + url.QueryEscape(prefix)
+	}
+	if err := kv.client.doJSON(ctx, http.MethodGet, p, nil, &keys); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,61 @@
+package cloudflare_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+func TestKVRoundTripAgainstMock(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+
+	nsID, err := client.CreateKVNamespace(ctx, cftest.AccountID, "maintenance-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.KV(cftest.AccountID, nsID)
+
+	if _, err := kv.Get(ctx, "missing"); !errors.Is(err, cloudflare.ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	for key, value := range map[string]string{"status_updates": `[{"id":"a"}]`, "state:enabled": "true", "other": "x"} {
+		if err := kv.Put(ctx, key, []byte(value)); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	got, err := kv.Get(ctx, "status_updates")
+	if err != nil || string(got) != `[{"id":"a"}]` {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	keys, err := kv.ListKeys(ctx, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"state:enabled", "status_updates"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ListKeys(s) = %v, want %v", keys, want)
+	}
+
+	if err := kv.Delete(ctx, "status_updates"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.Get(ctx, "status_updates"); !errors.Is(err, cloudflare.ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := &cloudflare.APIError{Status: 403, Errors: []cloudflare.Message{{Code: 10000, Message: "Authentication error"}}}
+	if got, want := err.Error(), "cloudflare: HTTP 403: Authentication error (code 10000)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		t.Error("403 must not match ErrNotFound")
+	}
+}
@@ -0,0 +1,114 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+)
+
+// Ruleset is a zone ruleset such as the module's maintenance-bypass-<env>
+// and rate limiting rulesets.
+type Ruleset struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind"`
+	Phase       string `json:"phase"`
+	Rules       []Rule `json:"rules"`
+}
+
+// Rule is one rule of a ruleset. ActionParameters stays untyped
+// because its shape depends on the action. Ref is a caller-chosen name
+// that, unlike ID, survives the rule being recreated.
+type Rule struct {
+	ID               string         `json:"id,omitempty"`
+	Ref              string         `json:"ref,omitempty"`
+	Action           string         `json:"action"`
+	ActionParameters map[string]any `json:"action_parameters,omitempty"`
+	Expression       string         `json:"expression"`
+	Description      string         `json:"description,omitempty"`
+	Enabled          bool           `json:"enabled"`
+	RateLimit        *RateLimit     `json:"ratelimit,omitempty"`
+}
+
+// RateLimit holds the thresholds of an http_ratelimit rule.
+type RateLimit struct {
+	Characteristics    []string `json:"characteristics"`
+	Period             int      `json:"period"`
+	RequestsPerPeriod  int      `json:"requests_per_period"`
+	MitigationTimeout  int      `json:"mitigation_timeout"`
+	RequestsToOrigin   bool     `json:"requests_to_origin"`
+	CountingExpression string   `json:"counting_expression,omitempty"`
+}
+
+// CreateRuleset creates a zone ruleset and returns it with its ID.
+func (c *Client) CreateRuleset(ctx context.Context, zoneID string, rs Ruleset) (Ruleset, error) {
+	var created Ruleset
+	err := c.doJSON(ctx, http.MethodPost, pathEscape("zones", zoneID, "rulesets"), rs, &created)
+	return created, err
+}
+
+// GetRuleset returns a zone ruleset. A missing ruleset returns an error
+// wrapping ErrNotFound.
+func (c *Client) GetRuleset(ctx context.Context, zoneID, rulesetID string) (Ruleset, error) {
+	var rs Ruleset
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("zones", zoneID, "rulesets", rulesetID), nil, &rs)
+	return rs, err
+}
+
+// UpdateRuleset replaces the rules (and name, description) of a zone ruleset.
+func (c *Client) UpdateRuleset(ctx context.Context, zoneID string, rs Ruleset) (Ruleset, error) {
+	var updated Ruleset
+	err := c.doJSON(ctx, http.MethodPut, pathEscape("zones", zoneID, "rulesets", rs.ID), rs, &updated)
+	return updated, err
+}
+
+// GetEntrypoint returns the zone's entrypoint ruleset for phase, the one
+// Cloudflare runs for every request in that phase. A zone without one
+// returns an error wrapping ErrNotFound.
+func (c *Client) GetEntrypoint(ctx context.Context, zoneID, phase string) (Ruleset, error) {
+	var rs Ruleset
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("zones", zoneID, "rulesets", "phases", phase, "entrypoint"), nil, &rs)
+	return rs, err
+}
+
+// UpdateEntrypoint creates the zone's entrypoint ruleset for phase, or
+// replaces all of its rules.
+func (c *Client) UpdateEntrypoint(ctx context.Context, zoneID, phase string, rs Ruleset) (Ruleset, error) {
+	var updated Ruleset
+	err := c.doJSON(ctx, http.MethodPut, pathEscape("zones", zoneID, "rulesets", "phases", phase, "entrypoint"), rs, &updated)
+	return updated, err
+}
+
+// AddRule adds one rule to a ruleset, leaving the others as they are, and
+// returns the updated ruleset. The rule goes last, or first with first set.
+func (c *Client) AddRule(ctx context.Context, zoneID, rulesetID string, r Rule, first bool) (Ruleset, error) {
+	body := struct {
+		Rule
+		Position *rulePosition `json:"position,omitempty"`
+	}{Rule: r}
+	if first {
+		body.Position = &rulePosition{Before: ""}
+	}
+	var updated Ruleset
+	err := c.doJSON(ctx, http.MethodPost, pathEscape("zones", zoneID, "rulesets", rulesetID, "rules"), body, &updated)
+	return updated, err
+}
+
+// rulePosition places an added rule; an empty Before puts it first.
+type rulePosition struct {
+	Before string `json:"before"`
+}
+
+// UpdateRule replaces one rule of a ruleset, found by r.ID, in place.
+func (c *Client) UpdateRule(ctx context.Context, zoneID, rulesetID string, r Rule) (Ruleset, error) {
+	var updated Ruleset
+	err := c.doJSON(ctx, http.MethodPatch, pathEscape("zones", zoneID, "rulesets", rulesetID, "rules", r.ID), r, &updated)
+	return updated, err
+}
+
+// DeleteRule removes one rule of a ruleset.
+func (c *Client) DeleteRule(ctx context.Context, zoneID, rulesetID, ruleID string) (Ruleset, error) {
+	var updated Ruleset
+	err := c.doJSON(ctx, http.MethodDelete, pathEscape("zones", zoneID, "rulesets", rulesetID, "rules", ruleID), nil, &updated)
+	return updated, err
+}
@@ -0,0 +1,72 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WorkerRoute is a zone route pointing a URL pattern at a worker script.
+type WorkerRoute struct {
+	ID      string `json:"id,omitempty"`
+	Pattern string `json:"pattern"`
+	Script  string `json:"script"`
+}
+
+// ListWorkerRoutes returns the worker routes of a zone.
+func (c *Client) ListWorkerRoutes(ctx context.Context, zoneID string) ([]WorkerRoute, error) {
+	var routes []WorkerRoute
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("zones", zoneID, "workers", "routes"), nil, &routes)
+	return routes, err
+}
+
+// CreateWorkerRoute points pattern at a worker script.
+func (c *Client) CreateWorkerRoute(ctx context.Context, zoneID, pattern, script string) (WorkerRoute, error) {
+	var route WorkerRoute
+	err := c.doJSON(ctx, http.MethodPost, pathEscape("zones", zoneID, "workers", "routes"), WorkerRoute{Pattern: pattern, Script: script}, &route)
+	return route, err
+}
+
+// GetWorkerRoute returns one route of a zone. A missing route returns an
+// error wrapping ErrNotFound.
+func (c *Client) GetWorkerRoute(ctx context.Context, zoneID, routeID string) (WorkerRoute, error) {
+	var route WorkerRoute
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("zones", zoneID, "workers", "routes", routeID), nil, &route)
+	return route, err
+}
+
+// UpdateWorkerRoute changes the pattern and script of an existing route.
+func (c *Client) UpdateWorkerRoute(ctx context.Context, zoneID string, route WorkerRoute) (WorkerRoute, error) {
+	var updated WorkerRoute
+	err := c.doJSON(ctx, http.MethodPut, pathEscape("zones", zoneID, "workers", "routes", route.ID), WorkerRoute{Pattern: route.Pattern, Script: route.Script}, &updated)
+	return updated, err
+}
+
+// WorkerScript is an entry of the account's script list.
+type WorkerScript struct {
+	ID         string    `json:"id"`
+	ModifiedOn time.Time `json:"modified_on"`
+}
+
+// ListWorkerScripts returns the worker scripts of an account.
+func (c *Client) ListWorkerScripts(ctx context.Context, accountID string) ([]WorkerScript, error) {
+	var scripts []WorkerScript
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("accounts", accountID, "workers", "scripts"), nil, &scripts)
+	return scripts, err
+}
+
+// WorkerBinding is a script binding. Text is only returned for plain_text
+// bindings; secrets are never readable.
+type WorkerBinding struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+}
+
+// ListWorkerBindings returns the bindings of a worker script.
+func (c *Client) ListWorkerBindings(ctx context.Context, accountID, scriptName string) ([]WorkerBinding, error) {
+	var bindings []WorkerBinding
+	err := c.doJSON(ctx, http.MethodGet, pathEscape("accounts", accountID, "workers", "scripts", scriptName, "bindings"), nil, &bindings)
+	return bindings, err
+}
@@ -0,0 +1,62 @@
+package cloudflare_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+// uploadScript does what the provider does: a multipart PUT with a metadata
+// part listing the bindings.
+func uploadScript(t *testing.T, base, name, metadata string) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("metadata", metadata)
+	_ = w.WriteField("script", "addEventListener('fetch', () => {})")
+	_ = w.Close()
+	req, _ := http.NewRequest(http.MethodPut, base+"/accounts/"+cftest.AccountID+"/workers/scripts/"+name, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload %s: HTTP %d", name, resp.StatusCode)
+	}
+}
+
+func TestWorkerScriptsAndBindingsAgainstMock(t *testing.T) {
+	ctx := context.Background()
+	base := cftest.StartMock(t)
+	client := &cloudflare.Client{BaseURL: base}
+
+	uploadScript(t, base, "maintenance-page-worker-staging",
+		`{"body_part":"script","bindings":[{"type":"plain_text","name":"MAINTENANCE_TITLE","text":"staging"},{"type":"secret_text","name":"ALLOWED_IPS","text":"[]"}]}`)
+
+	scripts, err := client.ListWorkerScripts(ctx, cftest.AccountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 1 || scripts[0].ID != "maintenance-page-worker-staging" {
+		t.Fatalf("ListWorkerScripts = %+v", scripts)
+	}
+
+	bindings, err := client.ListWorkerBindings(ctx, cftest.AccountID, "maintenance-page-worker-staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cloudflare.WorkerBinding{
+		{Name: "MAINTENANCE_TITLE", Type: "plain_text", Text: "staging"},
+		{Name: "ALLOWED_IPS", Type: "secret_text"},
+	}
+	if len(bindings) != len(want) || bindings[0] != want[0] || bindings[1] != want[1] {
+		t.Errorf("ListWorkerBindings = %+v, want %+v", bindings, want)
+	}
+}
@@ -0,0 +1,282 @@
+// Package drift compares the Cloudflare objects the module created, as
+// recorded in Terraform state, with what the API returns now. Dashboard
+// edits to the worker, its routes, the bypass and rate limiting rulesets or
+// the status DNS record show up as field-level differences.
+package drift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// Missing stands in for an object or field that exists on one side only.
+const Missing = "(missing)"
+
+// Diff is one difference between state and the live object. Object names
+// the module output that identifies it (worker_id, ruleset_id, ...); an
+// empty Field means the whole object is gone.
+type Diff struct {
+	Object string
+	ID     string
+	Field  string
+	Want   string
+	Got    string
+}
+
+func (d Diff) String() string {
+	if d.Field == "" {
+		return fmt.Sprintf("%s (%s): deleted outside Terraform", d.Object, d.ID)
+	}
+	return fmt.Sprintf("%s (%s): %s: want %s, live %s", d.Object, d.ID, d.Field, quote(d.Want), quote(d.Got))
+}
+
+// maxShown keeps long bindings such as PAGE_TEMPLATE readable in reports.
+const maxShown = 120
+
+func quote(v string) string {
+	if v == Missing {
+		return v
+	}
+	if len(v) > maxShown {
+		return strconv.Quote(v[:maxShown]) + "..."
+	}
+	return strconv.Quote(v)
+}
+
+// API is the part of the Cloudflare client the checks read from.
+type API interface {
+	ListWorkerBindings(ctx context.Context, accountID, scriptName string) ([]cloudflare.WorkerBinding, error)
+	GetWorkerRoute(ctx context.Context, zoneID, routeID string) (cloudflare.WorkerRoute, error)
+	GetRuleset(ctx context.Context, zoneID, rulesetID string) (cloudflare.Ruleset, error)
+	GetDNSRecord(ctx context.Context, zoneID, recordID string) (cloudflare.DNSRecord, error)
+}
+
+// Check fetches every object in the intent and returns the differences,
+// in the order script, routes, bypass ruleset, rate limit ruleset, record.
+// Objects deleted out of band are reported as a Diff; other API errors
+// abort the check.
+func Check(ctx context.Context, api API, in *Intent) ([]Diff, error) {
+	var diffs []Diff
+	if in.Script != nil {
+		d, err := checkScript(ctx, api, in.Script)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d...)
+	}
+	for _, route := range in.Routes {
+		d, err := checkRoute(ctx, api, route)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d...)
+	}
+	for _, rs := range []struct {
+		object string
+		want   *Ruleset
+	}{{"ruleset_id", in.Bypass}, {"rate_limit_ruleset_id", in.RateLimit}} {
+		if rs.want == nil {
+			continue
+		}
+		d, err := checkRuleset(ctx, api, rs.object, rs.want)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d...)
+	}
+	if in.StatusRecord != nil {
+		d, err := checkRecord(ctx, api, in.StatusRecord)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d...)
+	}
+	return diffs, nil
+}
+
+// differ collects field differences for one object.
+type differ struct {
+	object, id string
+	diffs      []Diff
+}
+
+func (d *differ) field(name, want, got string) {
+	if want != got {
+		d.diffs = append(d.diffs, Diff{Object: d.object, ID: d.id, Field: name, Want: want, Got: got})
+	}
+}
+
+// gone reports a deleted object, or returns err unchanged when the lookup
+// failed for another reason.
+func (d *differ) gone(err error) ([]Diff, error) {
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return []Diff{{Object: d.object, ID: d.id, Want: "present", Got: Missing}}, nil
+	}
+	return nil, fmt.Errorf("%s %s: %w", d.object, d.id, err)
+}
+
+func checkScript(ctx context.Context, api API, want *Script) ([]Diff, error) {
+	d := &differ{object: "worker_id", id: want.Name}
+	bindings, err := api.ListWorkerBindings(ctx, want.AccountID, want.Name)
+	if err != nil {
+		return d.gone(err)
+	}
+	live := map[string]cloudflare.WorkerBinding{}
+	for _, b := range bindings {
+		live[b.Name] = b
+	}
+
+	for _, name := range sortedKeys(want.PlainText) {
+		got := Missing
+		if b, ok := live[name]; ok && b.Type == "plain_text" {
+			got = b.Text
+		} else if ok {
+			got = "(" + b.Type + " binding)"
+		}
+		d.field("bindings."+name, want.PlainText[name], got)
+	}
+	for _, name := range sortedKeys(want.KVNamespaces) {
+		got := Missing
+		if b, ok := live[name]; ok {
+			got = b.NamespaceID
+		}
+		d.field("bindings."+name+".namespace_id", want.KVNamespaces[name], got)
+	}
+	for _, name := range want.Secrets {
+		got := Missing
+		if b, ok := live[name]; ok && b.Type == "secret_text" {
+			got = "(secret)"
+		}
+		d.field("bindings."+name, "(secret)", got)
+	}
+
+	known := map[string]bool{}
+	for name := range want.PlainText {
+		known[name] = true
+	}
+	for name := range want.KVNamespaces {
+		known[name] = true
+	}
+	for _, name := range want.Secrets {
+		known[name] = true
+	}
+	for _, b := range bindings {
+		if !known[b.Name] {
+			got := b.Text
+			if b.Type != "plain_text" {
+				got = "(" + b.Type + " binding)"
+			}
+			d.field("bindings."+b.Name, Missing, got)
+		}
+	}
+	return d.diffs, nil
+}
+
+func checkRoute(ctx context.Context, api API, want Route) ([]Diff, error) {
+	d := &differ{object: "worker_routes[" + want.Key + "]", id: want.ID}
+	got, err := api.GetWorkerRoute(ctx, want.ZoneID, want.ID)
+	if err != nil {
+		return d.gone(err)
+	}
+	d.field("pattern", want.Pattern, got.Pattern)
+	d.field("script", want.Script, got.Script)
+	return d.diffs, nil
+}
+
+func checkRuleset(ctx context.Context, api API, object string, want *Ruleset) ([]Diff, error) {
+	d := &differ{object: object, id: want.ID}
+	got, err := api.GetRuleset(ctx, want.ZoneID, want.ID)
+	if err != nil {
+		return d.gone(err)
+	}
+	d.field("name", want.Name, got.Name)
+	d.field("description", want.Description, got.Description)
+	d.field("kind", want.Kind, got.Kind)
+	d.field("phase", want.Phase, got.Phase)
+	d.field("rules.length", strconv.Itoa(len(want.Rules)), strconv.Itoa(len(got.Rules)))
+
+	for i := 0; i < len(want.Rules) && i < len(got.Rules); i++ {
+		w, g := want.Rules[i], got.Rules[i]
+		prefix := fmt.Sprintf("rules[%d].", i)
+		d.field(prefix+"action", w.Action, g.Action)
+		d.field(prefix+"expression", w.Expression, g.Expression)
+		d.field(prefix+"description", w.Description, g.Description)
+		d.field(prefix+"enabled", strconv.FormatBool(w.Enabled), strconv.FormatBool(g.Enabled))
+		// The state's action_parameters carries every optional field of the
+		// schema as null; phases is the one the module sets.
+		if phases := stringList(w.ActionParameters["phases"]); phases != "" {
+			d.field(prefix+"action_parameters.phases", phases, stringList(g.ActionParameters["phases"]))
+		}
+		checkRateLimit(d, prefix+"ratelimit.", w.RateLimit, g.RateLimit)
+	}
+	return d.diffs, nil
+}
+
+func checkRateLimit(d *differ, prefix string, want, got *cloudflare.RateLimit) {
+	if want == nil && got == nil {
+		return
+	}
+	if want == nil || got == nil {
+		d.field(strings.TrimSuffix(prefix, "."), present(want != nil), present(got != nil))
+		return
+	}
+	d.field(prefix+"characteristics", strings.Join(want.Characteristics, ","), strings.Join(got.Characteristics, ","))
+	d.field(prefix+"period", strconv.Itoa(want.Period), strconv.Itoa(got.Period))
+	d.field(prefix+"requests_per_period", strconv.Itoa(want.RequestsPerPeriod), strconv.Itoa(got.RequestsPerPeriod))
+	d.field(prefix+"mitigation_timeout", strconv.Itoa(want.MitigationTimeout), strconv.Itoa(got.MitigationTimeout))
+	d.field(prefix+"requests_to_origin", strconv.FormatBool(want.RequestsToOrigin), strconv.FormatBool(got.RequestsToOrigin))
+	d.field(prefix+"counting_expression", want.CountingExpression, got.CountingExpression)
+}
+
+func checkRecord(ctx context.Context, api API, want *Record) ([]Diff, error) {
+	d := &differ{object: "dns_record_id", id: want.ID}
+	got, err := api.GetDNSRecord(ctx, want.ZoneID, want.ID)
+	if err != nil {
+		return d.gone(err)
+	}
+	// Terraform keeps the relative name from the config; the API returns it
+	// qualified with the zone name.
+	name := want.Name
+	if got.ZoneName != "" && name != got.ZoneName && !strings.HasSuffix(name, "."+got.ZoneName) {
+		name += "." + got.ZoneName
+	}
+	d.field("name", name, got.Name)
+	d.field("type", want.Type, got.Type)
+	d.field("content", want.Content, got.Content)
+	d.field("proxied", strconv.FormatBool(want.Proxied), strconv.FormatBool(got.Proxied))
+	d.field("ttl", strconv.Itoa(want.TTL), strconv.Itoa(got.TTL))
+	d.field("comment", want.Comment, got.Comment)
+	return d.diffs, nil
+}
+
+func present(ok bool) string {
+	if ok {
+		return "present"
+	}
+	return Missing
+}
+
+// stringList renders a JSON string array decoded into []any.
+func stringList(v any) string {
+	items, _ := v.([]any)
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		parts = append(parts, fmt.Sprint(item))
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
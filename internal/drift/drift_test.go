@@ -0,0 +1,318 @@
+package drift_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/drift"
+)
+
+const (
+	scriptName = "maintenance-page-worker-prod"
+	bypassExpr = `ip.src in {"192.0.2.1"}`
+)
+
+// uploadScript does what the provider does: a multipart PUT with a metadata
+// part listing the bindings.
+func uploadScript(t *testing.T, base string, bindings []map[string]string) {
+	t.Helper()
+	metadata, _ := json.Marshal(map[string]any{"body_part": "script", "bindings": bindings})
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("metadata", string(metadata))
+	_ = w.WriteField("script", "addEventListener('fetch', () => {})")
+	_ = w.Close()
+	req, _ := http.NewRequest(http.MethodPut, base+"/accounts/"+cftest.AccountID+"/workers/scripts/"+scriptName, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload %s: HTTP %d", scriptName, resp.StatusCode)
+	}
+}
+
+func scriptBindings(title string) []map[string]string {
+	return []map[string]string{
+		{"type": "plain_text", "name": "MAINTENANCE_ENABLED", "text": "true"},
+		{"type": "plain_text", "name": "MAINTENANCE_TITLE", "text": title},
+		{"type": "secret_text", "name": "ALLOWED_IPS", "text": `["192.0.2.1"]`},
+	}
+}
+
+// applied is what a terraform apply of the module created in the mock.
+type applied struct {
+	route     cloudflare.WorkerRoute
+	bypass    cloudflare.Ruleset
+	rateLimit cloudflare.Ruleset
+	record    cloudflare.DNSRecord
+}
+
+func apply(t *testing.T, ctx context.Context, base string, client *cloudflare.Client) applied {
+	t.Helper()
+	var a applied
+	var err error
+	uploadScript(t, base, scriptBindings("Maintenance Mode"))
+	if a.route, err = client.CreateWorkerRoute(ctx, cftest.ZoneID, "example.com/*", scriptName); err != nil {
+		t.Fatal(err)
+	}
+	a.bypass, err = client.CreateRuleset(ctx, cftest.ZoneID, cloudflare.Ruleset{
+		Name:  "maintenance-bypass-prod",
+		Kind:  "zone",
+		Phase: "http_request_firewall_custom",
+		Rules: []cloudflare.Rule{{
+			Action:           "skip",
+			ActionParameters: map[string]any{"phases": []string{"http_request_firewall_managed", "http_ratelimit", "http_request_firewall_custom"}},
+			Expression:       bypassExpr,
+			Description:      "Allow bypass for maintenance mode from specific IPs and regions",
+			Enabled:          true,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.rateLimit, err = client.CreateRuleset(ctx, cftest.ZoneID, cloudflare.Ruleset{
+		Name:        "Rate Limiting Rules",
+		Description: "Rate limiting for maintenance page protection",
+		Kind:        "zone",
+		Phase:       "http_ratelimit",
+		Rules: []cloudflare.Rule{{
+			Action:      "block",
+			Expression:  `(http.request.uri.path matches ".*")`,
+			Description: "Rate limit all requests",
+			Enabled:     true,
+			RateLimit: &cloudflare.RateLimit{
+				Characteristics:   []string{"cf.colo.id", "ip.src"},
+				Period:            60,
+				RequestsPerPeriod: 100,
+				MitigationTimeout: 600,
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.record, err = client.CreateDNSRecord(ctx, cftest.ZoneID, cloudflare.DNSRecord{
+		Name: "maintenance-status-prod", Type: "AAAA", Content: "100::", Proxied: true, TTL: 1,
+		Comment: "Maintenance status page for prod environment",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// showJSON renders the module's resources the way `terraform show -json`
+// does with provider v4: nested blocks as lists, unset attributes as null.
+func showJSON(t *testing.T, a applied) []byte {
+	t.Helper()
+	res := func(typ, name string, index any, values map[string]any) map[string]any {
+		r := map[string]any{"address": "module.maintenance." + typ + "." + name, "mode": "managed", "type": typ, "name": name, "values": values}
+		if index != nil {
+			r["index"] = index
+		}
+		return r
+	}
+	doc := map[string]any{
+		"format_version": "1.0",
+		"values": map[string]any{
+			"outputs": map[string]any{},
+			"root_module": map[string]any{
+				"child_modules": []any{map[string]any{
+					"address": "module.maintenance",
+					"resources": []any{
+						res("cloudflare_workers_script", "maintenance", nil, map[string]any{
+							"id": scriptName, "account_id": cftest.AccountID, "name": scriptName,
+							"plain_text_binding": []any{
+								map[string]any{"name": "MAINTENANCE_ENABLED", "text": "true"},
+								map[string]any{"name": "MAINTENANCE_TITLE", "text": "Maintenance Mode"},
+							},
+							"kv_namespace_binding": []any{},
+							"secret_text_binding":  []any{map[string]any{"name": "ALLOWED_IPS", "text": `["192.0.2.1"]`}},
+						}),
+						res("cloudflare_workers_route", "maintenance", "worker_route", map[string]any{
+							"id": a.route.ID, "zone_id": cftest.ZoneID, "pattern": "example.com/*", "script_name": scriptName,
+						}),
+						res("cloudflare_record", "maintenance_status", 0, map[string]any{
+							"id": a.record.ID, "zone_id": cftest.ZoneID, "name": "maintenance-status-prod", "type": "AAAA",
+							"content": "100::", "value": nil, "proxied": true, "ttl": 1,
+							"comment": "Maintenance status page for prod environment",
+						}),
+						res("cloudflare_ruleset", "maintenance_bypass", 0, map[string]any{
+							"id": a.bypass.ID, "zone_id": cftest.ZoneID, "account_id": nil, "name": "maintenance-bypass-prod",
+							"description": "", "kind": "zone", "phase": "http_request_firewall_custom",
+							"rules": []any{map[string]any{
+								"action": "skip",
+								"action_parameters": []any{map[string]any{
+									"phases":   []string{"http_request_firewall_managed", "http_ratelimit", "http_request_firewall_custom"},
+									"products": nil, "ruleset": nil, "rules": nil,
+								}},
+								"expression":  bypassExpr,
+								"description": "Allow bypass for maintenance mode from specific IPs and regions",
+								"enabled":     true,
+								"ratelimit":   []any{},
+							}},
+						}),
+						res("cloudflare_ruleset", "rate_limit", 0, map[string]any{
+							"id": a.rateLimit.ID, "zone_id": cftest.ZoneID, "name": "Rate Limiting Rules",
+							"description": "Rate limiting for maintenance page protection", "kind": "zone", "phase": "http_ratelimit",
+							"rules": []any{map[string]any{
+								"action":            "block",
+								"action_parameters": []any{},
+								"expression":        `(http.request.uri.path matches ".*")`,
+								"description":       "Rate limit all requests",
+								"enabled":           true,
+								"ratelimit": []any{map[string]any{
+									"characteristics": []string{"cf.colo.id", "ip.src"}, "period": 60,
+									"requests_per_period": 100, "mitigation_timeout": 600,
+									"requests_to_origin": false, "counting_expression": nil,
+								}},
+							}},
+						}),
+					},
+				}},
+			},
+		},
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDriftAgainstMock(t *testing.T) {
+	ctx := context.Background()
+	base := cftest.StartMock(t)
+	client := &cloudflare.Client{BaseURL: base}
+	a := apply(t, ctx, base, client)
+
+	intent, err := drift.ParseState(bytes.NewReader(showJSON(t, a)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := drift.Check(ctx, client, intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("fresh apply reports drift: %v", diffs)
+	}
+
+	// Out-of-band edits, as someone would make them in the dashboard.
+	uploadScript(t, base, append(scriptBindings("Back soon"), map[string]string{"type": "plain_text", "name": "DEBUG", "text": "1"}))
+	a.route.Pattern = "example.com/api/*"
+	if _, err := client.UpdateWorkerRoute(ctx, cftest.ZoneID, a.route); err != nil {
+		t.Fatal(err)
+	}
+	bypass, err := client.GetRuleset(ctx, cftest.ZoneID, a.bypass.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bypass.Rules[0].Expression = `ip.src in {"192.0.2.1" "203.0.113.9"}`
+	if _, err := client.UpdateRuleset(ctx, cftest.ZoneID, bypass); err != nil {
+		t.Fatal(err)
+	}
+	rateLimit, err := client.GetRuleset(ctx, cftest.ZoneID, a.rateLimit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rateLimit.Rules[0].RateLimit.RequestsPerPeriod = 1000
+	rateLimit.Rules[0].Enabled = false
+	if _, err := client.UpdateRuleset(ctx, cftest.ZoneID, rateLimit); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpdateDNSRecord(ctx, cftest.ZoneID, a.record.ID, map[string]any{"proxied": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err = drift.Check(ctx, client, intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []drift.Diff{
+		{Object: "worker_id", ID: scriptName, Field: "bindings.MAINTENANCE_TITLE", Want: "Maintenance Mode", Got: "Back soon"},
+		{Object: "worker_id", ID: scriptName, Field: "bindings.DEBUG", Want: drift.Missing, Got: "1"},
+		{Object: `worker_routes["worker_route"]`, ID: a.route.ID, Field: "pattern", Want: "example.com/*", Got: "example.com/api/*"},
+		{Object: "ruleset_id", ID: a.bypass.ID, Field: "rules[0].expression", Want: bypassExpr, Got: `ip.src in {"192.0.2.1" "203.0.113.9"}`},
+		{Object: "rate_limit_ruleset_id", ID: a.rateLimit.ID, Field: "rules[0].enabled", Want: "true", Got: "false"},
+		{Object: "rate_limit_ruleset_id", ID: a.rateLimit.ID, Field: "rules[0].ratelimit.requests_per_period", Want: "100", Got: "1000"},
+		{Object: "dns_record_id", ID: a.record.ID, Field: "proxied", Want: "true", Got: "false"},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("diffs after edits:\n got  %v\n want %v", diffs, want)
+	}
+
+	// Deleting an object is drift too, not an error.
+	req, _ := http.NewRequest(http.MethodDelete, base+"/zones/"+cftest.ZoneID+"/dns_records/"+a.record.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	diffs, err = drift.Check(ctx, client, intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := diffs[len(diffs)-1]
+	if got := last.String(); got != "dns_record_id ("+a.record.ID+"): deleted outside Terraform" {
+		t.Errorf("deleted record reported as %q", got)
+	}
+}
+
+func TestParseStateProviderV5(t *testing.T) {
+	// Provider v5 keeps bindings in one list and nested blocks as objects.
+	const doc = `{"values":{"root_module":{"resources":[
+	  {"mode":"managed","type":"cloudflare_workers_script","name":"maintenance","values":{
+	    "account_id":"acc","script_name":"maintenance-page-worker-staging",
+	    "bindings":[{"type":"plain_text","name":"MAINTENANCE_MODE","text":"read_only"},
+	                {"type":"kv_namespace","name":"MAINTENANCE_KV","namespace_id":"ns1"},
+	                {"type":"secret_text","name":"ALLOWED_REGIONS"}]}},
+	  {"mode":"managed","type":"cloudflare_ruleset","name":"rate_limit","index":0,"values":{
+	    "id":"rs1","zone_id":"z","name":"Rate Limiting Rules","kind":"zone","phase":"http_ratelimit",
+	    "rules":[{"action":"log","expression":"true","ratelimit":{"characteristics":["ip.src"],"period":10,"requests_per_period":5,"mitigation_timeout":60}}]}},
+	  {"mode":"data","type":"cloudflare_zone","name":"maintenance","values":{}}
+	]}}}`
+	intent, err := drift.ParseState(strings.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := intent.Script
+	if s.Name != "maintenance-page-worker-staging" || s.PlainText["MAINTENANCE_MODE"] != "read_only" ||
+		s.KVNamespaces["MAINTENANCE_KV"] != "ns1" || !reflect.DeepEqual(s.Secrets, []string{"ALLOWED_REGIONS"}) {
+		t.Errorf("script = %+v", s)
+	}
+	rl := intent.RateLimit.Rules[0]
+	if !rl.Enabled || rl.RateLimit == nil || rl.RateLimit.RequestsPerPeriod != 5 || rl.RateLimit.Period != 10 {
+		t.Errorf("rate limit rule = %+v", rl)
+	}
+	if intent.Bypass != nil || intent.StatusRecord != nil || len(intent.Routes) != 0 {
+		t.Errorf("objects the module did not create: %+v", intent)
+	}
+}
+
+func TestParseStateErrors(t *testing.T) {
+	for name, tc := range map[string]struct{ doc, module, want string }{
+		"not applied":    {`{"format_version":"1.0"}`, "", "run terraform apply first"},
+		"no module":      {`{"values":{"root_module":{}}}`, "", "no module in state has cloudflare_workers_script.maintenance"},
+		"wrong address":  {`{"values":{"root_module":{}}}`, "module.other", "module module.other not found"},
+		"missing fields": {`{"values":{"root_module":{"resources":[{"mode":"managed","type":"cloudflare_workers_script","name":"maintenance","values":{}}]}}}`, "", "missing account_id or name"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := drift.ParseState(strings.NewReader(tc.doc), tc.module)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
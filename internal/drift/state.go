@@ -0,0 +1,328 @@
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// Intent is what the module applied, read from `terraform show -json`.
+// Nil and empty fields are objects the module did not create.
+type Intent struct {
+	Script       *Script
+	Routes       []Route
+	Bypass       *Ruleset
+	RateLimit    *Ruleset
+	StatusRecord *Record
+}
+
+// Script is the worker script and the bindings whose values can be read back.
+type Script struct {
+	AccountID string
+	Name      string
+	// PlainText maps plain_text binding names to their text.
+	PlainText map[string]string
+	// KVNamespaces maps kv_namespace binding names to namespace IDs.
+	KVNamespaces map[string]string
+	// Secrets lists secret_text binding names; only their presence is checked.
+	Secrets []string
+}
+
+// Route is one cloudflare_workers_route instance.
+type Route struct {
+	// Key is the instance key as the address writes it: "worker_route" or
+	// "ZONE/PATTERN", or 0 in state from before routes were keyed.
+	Key    string
+	ZoneID string
+	cloudflare.WorkerRoute
+}
+
+// Ruleset is a cloudflare_ruleset instance.
+type Ruleset struct {
+	ZoneID string
+	cloudflare.Ruleset
+}
+
+// Record is the maintenance status DNS record.
+type Record struct {
+	ZoneID string
+	cloudflare.DNSRecord
+}
+
+// Addresses of the main.tf resources the checks cover.
+const (
+	scriptResource = "cloudflare_workers_script.maintenance"
+	routeResource  = "cloudflare_workers_route.maintenance"
+	recordResource = "cloudflare_record.maintenance_status"
+	bypassResource = "cloudflare_ruleset.maintenance_bypass"
+	rateResource   = "cloudflare_ruleset.rate_limit"
+)
+
+type showDoc struct {
+	Values *struct {
+		RootModule module `json:"root_module"`
+	} `json:"values"`
+}
+
+type module struct {
+	Address      string     `json:"address"`
+	Resources    []resource `json:"resources"`
+	ChildModules []module   `json:"child_modules"`
+}
+
+type resource struct {
+	Mode   string          `json:"mode"`
+	Type   string          `json:"type"`
+	Name   string          `json:"name"`
+	Index  json.RawMessage `json:"index"`
+	Values json.RawMessage `json:"values"`
+}
+
+func (r resource) key() string { return r.Type + "." + r.Name }
+
+// ParseState reads `terraform show -json` output and returns the intent of
+// the module at address moduleAddr ("module.maintenance"). An empty address
+// picks the first module, the root included, that holds the worker script.
+func ParseState(r io.Reader, moduleAddr string) (*Intent, error) {
+	var doc showDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("reading terraform show -json output: %w", err)
+	}
+	if doc.Values == nil {
+		return nil, errors.New("state has no values; run terraform apply first")
+	}
+	mod := findModule(&doc.Values.RootModule, moduleAddr)
+	if mod == nil {
+		if moduleAddr != "" {
+			return nil, fmt.Errorf("module %s not found in state", moduleAddr)
+		}
+		return nil, fmt.Errorf("no module in state has %s", scriptResource)
+	}
+
+	in := &Intent{}
+	for _, res := range mod.Resources {
+		if res.Mode != "managed" {
+			continue
+		}
+		var err error
+		switch res.key() {
+		case scriptResource:
+			in.Script, err = parseScript(res.Values)
+		case routeResource:
+			var route Route
+			if route, err = parseRoute(res); err == nil {
+				in.Routes = append(in.Routes, route)
+			}
+		case bypassResource:
+			in.Bypass, err = parseRuleset(res.Values)
+		case rateResource:
+			in.RateLimit, err = parseRuleset(res.Values)
+		case recordResource:
+			in.StatusRecord, err = parseRecord(res.Values)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", res.key(), err)
+		}
+	}
+	return in, nil
+}
+
+func findModule(m *module, addr string) *module {
+	if addr != "" && m.Address == addr {
+		return m
+	}
+	if addr == "" {
+		for _, res := range m.Resources {
+			if res.Mode == "managed" && res.key() == scriptResource {
+				return m
+			}
+		}
+	}
+	for i := range m.ChildModules {
+		if found := findModule(&m.ChildModules[i], addr); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// The provider v4 schema stores bindings as blocks per type; v5 has one
+// bindings list with a type field. Both are accepted.
+type binding struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	NamespaceID string `json:"namespace_id"`
+}
+
+func parseScript(raw json.RawMessage) (*Script, error) {
+	var v struct {
+		AccountID    string    `json:"account_id"`
+		Name         string    `json:"name"`
+		ScriptName   string    `json:"script_name"`
+		PlainText    []binding `json:"plain_text_binding"`
+		KVNamespaces []binding `json:"kv_namespace_binding"`
+		Secrets      []binding `json:"secret_text_binding"`
+		Bindings     []binding `json:"bindings"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	s := &Script{
+		AccountID:    v.AccountID,
+		Name:         v.Name,
+		PlainText:    map[string]string{},
+		KVNamespaces: map[string]string{},
+	}
+	if s.Name == "" {
+		s.Name = v.ScriptName
+	}
+	for _, b := range v.PlainText {
+		s.PlainText[b.Name] = b.Text
+	}
+	for _, b := range v.KVNamespaces {
+		s.KVNamespaces[b.Name] = b.NamespaceID
+	}
+	for _, b := range v.Secrets {
+		s.Secrets = append(s.Secrets, b.Name)
+	}
+	for _, b := range v.Bindings {
+		switch b.Type {
+		case "plain_text":
+			s.PlainText[b.Name] = b.Text
+		case "kv_namespace":
+			s.KVNamespaces[b.Name] = b.NamespaceID
+		case "secret_text":
+			s.Secrets = append(s.Secrets, b.Name)
+		}
+	}
+	if s.AccountID == "" || s.Name == "" {
+		return nil, errors.New("missing account_id or name")
+	}
+	return s, nil
+}
+
+func parseRoute(res resource) (Route, error) {
+	var v struct {
+		ID         string `json:"id"`
+		ZoneID     string `json:"zone_id"`
+		Pattern    string `json:"pattern"`
+		ScriptName string `json:"script_name"`
+		Script     string `json:"script"`
+	}
+	if err := json.Unmarshal(res.Values, &v); err != nil {
+		return Route{}, err
+	}
+	route := Route{ZoneID: v.ZoneID, WorkerRoute: cloudflare.WorkerRoute{ID: v.ID, Pattern: v.Pattern, Script: v.ScriptName}}
+	if route.Script == "" {
+		route.Script = v.Script
+	}
+	if len(res.Index) > 0 {
+		var key any
+		if err := json.Unmarshal(res.Index, &key); err != nil {
+			return Route{}, fmt.Errorf("index %s: %w", res.Index, err)
+		}
+		route.Key = string(res.Index)
+	}
+	if route.ID == "" || route.ZoneID == "" {
+		return Route{}, errors.New("missing id or zone_id")
+	}
+	return route, nil
+}
+
+func parseRuleset(raw json.RawMessage) (*Ruleset, error) {
+	var v struct {
+		ID          string `json:"id"`
+		ZoneID      string `json:"zone_id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Kind        string `json:"kind"`
+		Phase       string `json:"phase"`
+		Rules       []struct {
+			Action           string          `json:"action"`
+			ActionParameters json.RawMessage `json:"action_parameters"`
+			Expression       string          `json:"expression"`
+			Description      string          `json:"description"`
+			Enabled          *bool           `json:"enabled"`
+			RateLimit        json.RawMessage `json:"ratelimit"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	if v.ID == "" || v.ZoneID == "" {
+		return nil, errors.New("missing id or zone_id")
+	}
+	rs := &Ruleset{ZoneID: v.ZoneID, Ruleset: cloudflare.Ruleset{
+		ID: v.ID, Name: v.Name, Description: v.Description, Kind: v.Kind, Phase: v.Phase,
+	}}
+	for i, r := range v.Rules {
+		rule := cloudflare.Rule{
+			Action:      r.Action,
+			Expression:  r.Expression,
+			Description: r.Description,
+			Enabled:     r.Enabled == nil || *r.Enabled,
+		}
+		var params map[string]any
+		if err := unmarshalBlock(r.ActionParameters, &params); err != nil {
+			return nil, fmt.Errorf("rules[%d].action_parameters: %w", i, err)
+		}
+		rule.ActionParameters = params
+		var rl *cloudflare.RateLimit
+		if err := unmarshalBlock(r.RateLimit, &rl); err != nil {
+			return nil, fmt.Errorf("rules[%d].ratelimit: %w", i, err)
+		}
+		rule.RateLimit = rl
+		rs.Rules = append(rs.Rules, rule)
+	}
+	return rs, nil
+}
+
+// unmarshalBlock decodes a nested block, which provider v4 stores as a list
+// of at most one object and v5 as the object itself.
+func unmarshalBlock(raw json.RawMessage, out any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if raw[0] == '[' {
+		var list []json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		raw = list[0]
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func parseRecord(raw json.RawMessage) (*Record, error) {
+	var v struct {
+		ID      string `json:"id"`
+		ZoneID  string `json:"zone_id"`
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Content string `json:"content"`
+		Value   string `json:"value"`
+		Proxied bool   `json:"proxied"`
+		TTL     int    `json:"ttl"`
+		Comment string `json:"comment"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	if v.ID == "" || v.ZoneID == "" {
+		return nil, errors.New("missing id or zone_id")
+	}
+	rec := &Record{ZoneID: v.ZoneID, DNSRecord: cloudflare.DNSRecord{
+		ID: v.ID, Name: v.Name, Type: v.Type, Content: v.Content, Proxied: v.Proxied, TTL: v.TTL, Comment: v.Comment,
+	}}
+	if rec.Content == "" {
+		rec.Content = v.Value
+	}
+	return rec, nil
+}
@@ -0,0 +1,104 @@
+// Package entrypoint runs the module's rulesets from a zone's phase
+// entrypoints without owning them. A zone has one entrypoint ruleset per
+// phase, and cloudflare_ruleset replaces all of its rules, so a module that
+// creates the entrypoint clobbers, or fails against, rules other teams manage
+// there. With ruleset_mode = "execute" the module keeps its rules in custom
+// rulesets, and Attach adds a single execute rule for each to the
+// entrypoint, found again by its ref. Every other rule is left alone.
+package entrypoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// API is the part of *cloudflare.Client Attach and Detach use.
+type API interface {
+	GetEntrypoint(ctx context.Context, zoneID, phase string) (cloudflare.Ruleset, error)
+	UpdateEntrypoint(ctx context.Context, zoneID, phase string, rs cloudflare.Ruleset) (cloudflare.Ruleset, error)
+	AddRule(ctx context.Context, zoneID, rulesetID string, r cloudflare.Rule, first bool) (cloudflare.Ruleset, error)
+	UpdateRule(ctx context.Context, zoneID, rulesetID string, r cloudflare.Rule) (cloudflare.Ruleset, error)
+	DeleteRule(ctx context.Context, zoneID, rulesetID, ruleID string) (cloudflare.Ruleset, error)
+}
+
+// Outcomes of Attach.
+const (
+	Created   = "created"   // the entrypoint did not exist and now holds only the rule
+	Added     = "added"     // the rule was added next to the others
+	Updated   = "updated"   // the rule was there but ran something else
+	Unchanged = "unchanged" // the rule was already there
+)
+
+// ExecuteRule is the rule, named ref, that runs rulesetID for every request.
+func ExecuteRule(ref, rulesetID string) cloudflare.Rule {
+	return cloudflare.Rule{
+		Ref:              ref,
+		Action:           "execute",
+		ActionParameters: map[string]any{"id": rulesetID},
+		Expression:       "true",
+		Description:      "Maintenance module rules (" + ref + "), managed by maintctl ruleset attach",
+		Enabled:          true,
+	}
+}
+
+// Attach makes the zone's phase entrypoint run rulesetID through the rule
+// named ref, adding it last, or first with first set, when it isn't there.
+// It is safe to repeat. A zone without an entrypoint for phase gets one.
+func Attach(ctx context.Context, api API, zoneID, phase, ref, rulesetID string, first bool) (string, error) {
+	want := ExecuteRule(ref, rulesetID)
+	ep, err := api.GetEntrypoint(ctx, zoneID, phase)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		if _, err := api.UpdateEntrypoint(ctx, zoneID, phase, cloudflare.Ruleset{Kind: "zone", Phase: phase, Rules: []cloudflare.Rule{want}}); err != nil {
+			return "", fmt.Errorf("creating the %s entrypoint: %w", phase, err)
+		}
+		return Created, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the %s entrypoint: %w", phase, err)
+	}
+
+	for _, r := range ep.Rules {
+		if r.Ref != ref {
+			continue
+		}
+		if r.Action == want.Action && r.ActionParameters["id"] == rulesetID && r.Expression == want.Expression && r.Enabled {
+			return Unchanged, nil
+		}
+		want.ID = r.ID
+		if _, err := api.UpdateRule(ctx, zoneID, ep.ID, want); err != nil {
+			return "", fmt.Errorf("updating rule %s in the %s entrypoint: %w", ref, phase, err)
+		}
+		return Updated, nil
+	}
+	if _, err := api.AddRule(ctx, zoneID, ep.ID, want, first); err != nil {
+		return "", fmt.Errorf("adding rule %s to the %s entrypoint: %w", ref, phase, err)
+	}
+	return Added, nil
+}
+
+// Detach removes the rule named ref from the zone's phase entrypoint and
+// reports whether there was one. The entrypoint stays, even when empty,
+// since other rules may come and go in it.
+func Detach(ctx context.Context, api API, zoneID, phase, ref string) (bool, error) {
+	ep, err := api.GetEntrypoint(ctx, zoneID, phase)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading the %s entrypoint: %w", phase, err)
+	}
+	removed := false
+	for _, r := range ep.Rules {
+		if r.Ref != ref {
+			continue
+		}
+		if _, err := api.DeleteRule(ctx, zoneID, ep.ID, r.ID); err != nil {
+			return removed, fmt.Errorf("deleting rule %s from the %s entrypoint: %w", ref, phase, err)
+		}
+		removed = true
+	}
+	return removed, nil
+}
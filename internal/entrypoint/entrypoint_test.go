@@ -0,0 +1,122 @@
+package entrypoint_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/entrypoint"
+)
+
+const phase = "http_request_firewall_custom"
+
+// foreign is what another team keeps in the entrypoint.
+var foreign = []cloudflare.Rule{
+	{Ref: "waf-block-bad-asn", Action: "block", Expression: "ip.geoip.asnum eq 64496", Description: "Block a bad network", Enabled: true},
+	{Ref: "waf-challenge-admin", Action: "managed_challenge", Expression: `starts_with(http.request.uri.path, "/admin")`, Enabled: true},
+}
+
+func withoutIDs(rules []cloudflare.Rule) []cloudflare.Rule {
+	out := make([]cloudflare.Rule, len(rules))
+	for i, r := range rules {
+		r.ID = ""
+		out[i] = r
+	}
+	return out
+}
+
+func TestAttachKeepsForeignRules(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	if _, err := client.UpdateEntrypoint(ctx, cftest.ZoneID, phase, cloudflare.Ruleset{Rules: foreign}); err != nil {
+		t.Fatal(err)
+	}
+	custom, err := client.CreateRuleset(ctx, cftest.ZoneID, cloudflare.Ruleset{Name: "maintenance-bypass-prod", Kind: "custom", Phase: phase})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const ref = "maintenance-prod-maintenance-bypass"
+
+	did, err := entrypoint.Attach(ctx, client, cftest.ZoneID, phase, ref, custom.ID, true)
+	if err != nil || did != entrypoint.Added {
+		t.Fatalf("Attach = %q, %v", did, err)
+	}
+	ep, err := client.GetEntrypoint(ctx, cftest.ZoneID, phase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]cloudflare.Rule{entrypoint.ExecuteRule(ref, custom.ID)}, foreign...)
+	if got := withoutIDs(ep.Rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("entrypoint rules after Attach:\n got %+v\nwant %+v", got, want)
+	}
+
+	if did, err := entrypoint.Attach(ctx, client, cftest.ZoneID, phase, ref, custom.ID, true); err != nil || did != entrypoint.Unchanged {
+		t.Errorf("second Attach = %q, %v", did, err)
+	}
+
+	// A replaced custom ruleset is attached in place of the old one.
+	if did, err := entrypoint.Attach(ctx, client, cftest.ZoneID, phase, ref, "replacement-id", true); err != nil || did != entrypoint.Updated {
+		t.Errorf("Attach of a new ruleset = %q, %v", did, err)
+	}
+	ep, _ = client.GetEntrypoint(ctx, cftest.ZoneID, phase)
+	want[0] = entrypoint.ExecuteRule(ref, "replacement-id")
+	if got := withoutIDs(ep.Rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("entrypoint rules after the update:\n got %+v\nwant %+v", got, want)
+	}
+
+	removed, err := entrypoint.Detach(ctx, client, cftest.ZoneID, phase, ref)
+	if err != nil || !removed {
+		t.Fatalf("Detach = %v, %v", removed, err)
+	}
+	ep, _ = client.GetEntrypoint(ctx, cftest.ZoneID, phase)
+	if got := withoutIDs(ep.Rules); !reflect.DeepEqual(got, foreign) {
+		t.Errorf("entrypoint rules after Detach:\n got %+v\nwant %+v", got, foreign)
+	}
+	if removed, err := entrypoint.Detach(ctx, client, cftest.ZoneID, phase, ref); err != nil || removed {
+		t.Errorf("second Detach = %v, %v", removed, err)
+	}
+}
+
+func TestAttachCreatesEntrypoint(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	const ref = "maintenance-prod-rate-limit"
+
+	if removed, err := entrypoint.Detach(ctx, client, cftest.ZoneID, "http_ratelimit", ref); err != nil || removed {
+		t.Errorf("Detach without an entrypoint = %v, %v", removed, err)
+	}
+	did, err := entrypoint.Attach(ctx, client, cftest.ZoneID, "http_ratelimit", ref, "custom-id", false)
+	if err != nil || did != entrypoint.Created {
+		t.Fatalf("Attach = %q, %v", did, err)
+	}
+	ep, err := client.GetEntrypoint(ctx, cftest.ZoneID, "http_ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := withoutIDs(ep.Rules); !reflect.DeepEqual(got, []cloudflare.Rule{entrypoint.ExecuteRule(ref, "custom-id")}) {
+		t.Errorf("new entrypoint rules = %+v", got)
+	}
+
+	// Another team's rule added afterwards goes last and survives Detach.
+	if _, err := client.AddRule(ctx, cftest.ZoneID, ep.ID, foreign[0], false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := entrypoint.Detach(ctx, client, cftest.ZoneID, "http_ratelimit", ref); err != nil {
+		t.Fatal(err)
+	}
+	ep, _ = client.GetEntrypoint(ctx, cftest.ZoneID, "http_ratelimit")
+	if got := withoutIDs(ep.Rules); !reflect.DeepEqual(got, foreign[:1]) {
+		t.Errorf("entrypoint rules after Detach = %+v", got)
+	}
+
+	// Owning the entrypoint, as ruleset_mode = "entrypoint" does, fails now
+	// that one exists.
+	_, err = client.CreateRuleset(ctx, cftest.ZoneID, cloudflare.Ruleset{Name: "Rate Limiting Rules", Kind: "zone", Phase: "http_ratelimit"})
+	var apiErr *cloudflare.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != 400 {
+		t.Errorf("creating a second entrypoint: %v", err)
+	}
+}
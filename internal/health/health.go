@@ -0,0 +1,132 @@
+// Package health mirrors the circuit breaker the worker uses for
+// auto_maintenance: origin 5xx responses and timeouts are counted in a sliding
+// window, and once they reach the threshold the worker serves the maintenance
+// page without contacting the origin until the cool-down ends. The first
+// request after the cool-down probes the origin and either closes the breaker
+// or trips it again. Keep in sync with the auto maintenance functions in
+// worker.js; both are tested against tests/fixtures/auto-maintenance.json.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// TripKey is the Workers KV key the worker records its last trip under.
+const TripKey = "auto_trip"
+
+// Config is the module's auto_maintenance variable.
+type Config struct {
+	FailureThreshold int `json:"failure_threshold"`
+	WindowSeconds    int `json:"window_seconds"`
+	TimeoutMS        int `json:"timeout_ms"`
+	CooldownSeconds  int `json:"cooldown_seconds"`
+}
+
+// Trip is the record written under TripKey when the breaker opens.
+type Trip struct {
+	TrippedAt     time.Time `json:"tripped_at"`
+	Reason        string    `json:"reason"`
+	CooldownUntil time.Time `json:"cooldown_until"`
+}
+
+// Getter is the subset of a KV namespace LastTrip needs.
+type Getter interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// LastTrip reads the most recent trip the worker recorded. ok is false when
+// the breaker has never tripped.
+func LastTrip(ctx context.Context, kv Getter) (trip Trip, ok bool, err error) {
+	raw, err := kv.Get(ctx, TripKey)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return Trip{}, false, nil
+	}
+	if err != nil {
+		return Trip{}, false, err
+	}
+	if err := json.Unmarshal(raw, &trip); err != nil {
+		return Trip{}, false, fmt.Errorf("decoding %s: %w", TripKey, err)
+	}
+	return trip, true, nil
+}
+
+// Breaker is the per-isolate breaker state.
+type Breaker struct {
+	Config   Config
+	failures []time.Time
+	openedAt time.Time
+	reason   string
+}
+
+// Open reports whether the breaker serves the page at now without asking
+// the origin.
+func (b *Breaker) Open(now time.Time) bool {
+	return !b.openedAt.IsZero() && now.Before(b.openedAt.Add(b.cooldown()))
+}
+
+// Record reports the outcome of an origin request made at now; failure is
+// empty for a healthy response. It returns the trip when the breaker opened
+// (or reopened after a failed probe), and nil otherwise. Healthy responses
+// don't reset the failure count, so an origin that fails now and then trips
+// once enough failures fall within the window.
+func (b *Breaker) Record(now time.Time, failure string) *Trip {
+	probing := !b.openedAt.IsZero()
+	if failure == "" {
+		if probing {
+			b.openedAt, b.reason = time.Time{}, ""
+		}
+		return nil
+	}
+	if probing {
+		return b.trip(now, "origin still failing after cool-down, last: "+failure)
+	}
+
+	cutoff := now.Add(-time.Duration(b.Config.WindowSeconds) * time.Second)
+	kept := b.failures[:0]
+	for _, at := range b.failures {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	b.failures = append(kept, now)
+	if len(b.failures) < b.Config.FailureThreshold {
+		return nil
+	}
+	return b.trip(now, fmt.Sprintf("%d origin failures within %ds, last: %s", len(b.failures), b.Config.WindowSeconds, failure))
+}
+
+// Reason is why the breaker is open, or empty.
+func (b *Breaker) Reason() string { return b.reason }
+
+func (b *Breaker) trip(now time.Time, reason string) *Trip {
+	b.failures = nil
+	b.openedAt, b.reason = now, reason
+	return &Trip{TrippedAt: now.UTC(), Reason: reason, CooldownUntil: now.Add(b.cooldown()).UTC()}
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	return time.Duration(b.Config.CooldownSeconds) * time.Second
+}
+
+// Failure describes an origin outcome the way the worker does: "HTTP 502"
+// for a 5xx, "timeout after 10000ms" for a timeout, the error otherwise, and
+// empty for a healthy response.
+func Failure(resp *http.Response, err error, timeout time.Duration) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("timeout after %dms", timeout.Milliseconds())
+	case err != nil:
+		return "fetch failed: " + err.Error()
+	case resp.StatusCode >= 500:
+		return fmt.Sprintf("HTTP %d", resp.StatusCode)
+	default:
+		return ""
+	}
+}
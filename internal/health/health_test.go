@@ -0,0 +1,223 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+var epoch = time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+
+// The same steps drive the auto maintenance functions in
+// tests/unit/worker.test.js.
+func TestBreakerSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/auto-maintenance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Config Config `json:"config"`
+		Steps  []struct {
+			AtMS       int64  `json:"at_ms"`
+			Origin     string `json:"origin"`
+			Want       string `json:"want"`
+			WantReason string `json:"want_reason"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Breaker{Config: fixture.Config}
+	for _, step := range fixture.Steps {
+		now := epoch.Add(time.Duration(step.AtMS) * time.Millisecond)
+		got := "page"
+		if !b.Open(now) {
+			if b.Record(now, fixtureFailure(t, step.Origin)) == nil {
+				got = "pass"
+			}
+		}
+		if got != step.Want || b.Reason() != step.WantReason {
+			t.Errorf("at %dms (%s): %s with reason %q, want %s with reason %q", step.AtMS, step.Origin, got, b.Reason(), step.Want, step.WantReason)
+		}
+	}
+}
+
+// fixtureFailure turns a fixture outcome ("ok", "timeout" or "HTTP 502")
+// into what Failure reports for it.
+func fixtureFailure(t *testing.T, origin string) string {
+	t.Helper()
+	switch origin {
+	case "ok":
+		return ""
+	case "timeout":
+		return Failure(nil, context.DeadlineExceeded, time.Second)
+	}
+	var status int
+	if _, err := fmt.Sscanf(origin, "HTTP %d", &status); err != nil {
+		t.Fatalf("bad fixture outcome %q", origin)
+	}
+	return Failure(&http.Response{StatusCode: status}, nil, time.Second)
+}
+
+// flappingOrigin is a fake origin whose health can be switched mid-test.
+type flappingOrigin struct {
+	status atomic.Int32 // 0 hangs until the client gives up
+	hits   atomic.Int32
+}
+
+func (o *flappingOrigin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.hits.Add(1)
+	status := int(o.status.Load())
+	if status == 0 {
+		<-r.Context().Done()
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// guard is the worker's guardedFetch: consult the breaker, otherwise call
+// the origin with a timeout and record the outcome.
+type guard struct {
+	breaker *Breaker
+	url     string
+	trips   []Trip
+}
+
+func (g *guard) serve(t *testing.T, now time.Time) string {
+	t.Helper()
+	if g.breaker.Open(now) {
+		return "page"
+	}
+	timeout := time.Duration(g.breaker.Config.TimeoutMS) * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if trip := g.breaker.Record(now, Failure(resp, err, timeout)); trip != nil {
+		g.trips = append(g.trips, *trip)
+		return "page"
+	}
+	return "pass"
+}
+
+func TestFlappingOriginTripsAndRecovers(t *testing.T) {
+	origin := &flappingOrigin{}
+	origin.status.Store(http.StatusOK)
+	server := httptest.NewServer(origin)
+	defer server.Close()
+
+	g := &guard{
+		breaker: &Breaker{Config: Config{FailureThreshold: 3, WindowSeconds: 60, TimeoutMS: 50, CooldownSeconds: 120}},
+		url:     server.URL,
+	}
+	clock := epoch
+	tick := func(d time.Duration) time.Time { clock = clock.Add(d); return clock }
+
+	// Blips too far apart to reach the threshold within the window are
+	// passed through.
+	for i := 0; i < 10; i++ {
+		if i%4 == 3 {
+			origin.status.Store(http.StatusBadGateway)
+		} else {
+			origin.status.Store(http.StatusOK)
+		}
+		if got := g.serve(t, tick(30*time.Second)); got != "pass" {
+			t.Fatalf("request %d during blips: %s", i, got)
+		}
+	}
+
+	// A hard failure, mixing 5xx and timeouts, trips on the third.
+	origin.status.Store(http.StatusServiceUnavailable)
+	g.serve(t, tick(time.Second))
+	origin.status.Store(0)
+	g.serve(t, tick(time.Second))
+	if got := g.serve(t, tick(time.Second)); got != "page" {
+		t.Fatalf("third failure: %s, want page", got)
+	}
+	if len(g.trips) != 1 || g.trips[0].Reason != "3 origin failures within 60s, last: timeout after 50ms" {
+		t.Fatalf("trips = %+v", g.trips)
+	}
+	if want := clock.Add(120 * time.Second); !g.trips[0].CooldownUntil.Equal(want) {
+		t.Errorf("CooldownUntil = %v, want %v", g.trips[0].CooldownUntil, want)
+	}
+
+	// The origin recovers, but the page stays up and the origin is left
+	// alone until the cool-down ends.
+	origin.status.Store(http.StatusOK)
+	hits := origin.hits.Load()
+	for i := 0; i < 100; i++ {
+		if got := g.serve(t, tick(time.Second)); got != "page" {
+			t.Fatalf("during cool-down: %s", got)
+		}
+	}
+	if origin.hits.Load() != hits {
+		t.Errorf("origin got %d requests during the cool-down", origin.hits.Load()-hits)
+	}
+
+	// It fails again just as the cool-down ends: the probe re-trips.
+	origin.status.Store(http.StatusInternalServerError)
+	if got := g.serve(t, tick(20*time.Second)); got != "page" {
+		t.Fatalf("failed probe: %s, want page", got)
+	}
+	if len(g.trips) != 2 || g.trips[1].Reason != "origin still failing after cool-down, last: HTTP 500" {
+		t.Fatalf("trips = %+v", g.trips)
+	}
+
+	// Healthy by the next probe: traffic flows and the failure count starts
+	// over, so two new failures don't trip.
+	origin.status.Store(http.StatusOK)
+	if got := g.serve(t, tick(121*time.Second)); got != "pass" {
+		t.Fatalf("healthy probe: %s, want pass", got)
+	}
+	origin.status.Store(http.StatusBadGateway)
+	for i := 0; i < 2; i++ {
+		if got := g.serve(t, tick(time.Second)); got != "pass" {
+			t.Fatalf("failure %d after recovery: %s", i, got)
+		}
+	}
+	if g.breaker.Reason() != "" || len(g.trips) != 2 {
+		t.Errorf("breaker did not recover: reason %q, %d trips", g.breaker.Reason(), len(g.trips))
+	}
+}
+
+type memStore map[string][]byte
+
+func (m memStore) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("get %s: %w", key, cloudflare.ErrNotFound)
+	}
+	return v, nil
+}
+
+func TestLastTrip(t *testing.T) {
+	ctx := context.Background()
+	if _, ok, err := LastTrip(ctx, memStore{}); ok || err != nil {
+		t.Fatalf("LastTrip on empty KV = ok %v, err %v", ok, err)
+	}
+
+	// What the worker writes: toISOString timestamps with milliseconds.
+	kv := memStore{TripKey: []byte(`{"tripped_at":"2025-04-06T08:00:00.000Z","reason":"5 origin failures within 60s, last: HTTP 502","cooldown_until":"2025-04-06T08:02:00.000Z"}`)}
+	trip, ok, err := LastTrip(ctx, kv)
+	if err != nil || !ok {
+		t.Fatalf("LastTrip = ok %v, err %v", ok, err)
+	}
+	if !trip.CooldownUntil.Equal(epoch.Add(2*time.Minute)) || trip.Reason != "5 origin failures within 60s, last: HTTP 502" {
+		t.Errorf("LastTrip = %+v", trip)
+	}
+}
@@ -0,0 +1,109 @@
+// Package locale holds the multi-language page content model: Accept-Language
+// negotiation (mirroring worker.js) and validation of localized_content.
+package locale
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Content is the per-locale page text, matching the localized_content
+// variable's object type.
+type Content struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Set is the full localized_content map plus the locale used when nothing
+// else matches.
+type Set struct {
+	DefaultLocale  string             `json:"default_locale"`
+	Content        map[string]Content `json:"localized_content"`
+	CountryLocales map[string]string  `json:"country_locales"`
+}
+
+var (
+	tagPattern     = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// LoadFile reads either a bare locale → content JSON object or a
+// tfvars-style object with localized_content/default_locale keys.
+func LoadFile(path string) (Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Set{}, err
+	}
+
+	var wrapped Set
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Content != nil {
+		return wrapped, nil
+	}
+
+	var bare map[string]Content
+	if err := json.Unmarshal(data, &bare); err != nil {
+		return Set{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return Set{Content: bare}, nil
+}
+
+// Validate reports every problem with the set rather than stopping at the
+// first, so a translator can fix a file in one pass.
+func Validate(s Set) []error {
+	var errs []error
+	if !tagPattern.MatchString(s.DefaultLocale) {
+		errs = append(errs, fmt.Errorf("default locale %q is not a valid language tag", s.DefaultLocale))
+	}
+	if len(s.Content) > 0 {
+		if _, ok := s.Content[s.DefaultLocale]; !ok {
+			errs = append(errs, fmt.Errorf("default locale %q has no localized content", s.DefaultLocale))
+		}
+	}
+
+	lower := map[string]string{}
+	for _, tag := range sortedKeys(s.Content) {
+		c := s.Content[tag]
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("locale %q is not a valid language tag", tag))
+		}
+		if prev, dup := lower[strings.ToLower(tag)]; dup {
+			errs = append(errs, fmt.Errorf("locale %q duplicates %q (tags are case-insensitive)", tag, prev))
+		}
+		lower[strings.ToLower(tag)] = tag
+		if strings.TrimSpace(c.Title) == "" {
+			errs = append(errs, fmt.Errorf("locale %q is missing a title", tag))
+		}
+		if strings.TrimSpace(c.Message) == "" {
+			errs = append(errs, fmt.Errorf("locale %q is missing a message", tag))
+		}
+	}
+
+	countries := make([]string, 0, len(s.CountryLocales))
+	for country := range s.CountryLocales {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	for _, country := range countries {
+		tag := s.CountryLocales[country]
+		if !countryPattern.MatchString(country) {
+			errs = append(errs, fmt.Errorf("country %q is not an ISO 3166-1 alpha-2 code", country))
+		}
+		if _, ok := s.Content[tag]; !ok {
+			errs = append(errs, fmt.Errorf("country %s maps to locale %q which has no localized content", country, tag))
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string]Content) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package locale
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The same decision table drives negotiateLocale in tests/unit/worker.test.js.
+func TestNegotiateSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/locale-negotiation.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Available      []string          `json:"available"`
+		DefaultLocale  string            `json:"default_locale"`
+		CountryLocales map[string]string `json:"country_locales"`
+		Cases          []struct {
+			Name           string `json:"name"`
+			AcceptLanguage string `json:"accept_language"`
+			Country        string `json:"country"`
+			Want           string `json:"want"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range fixture.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := Negotiate(tc.AcceptLanguage, tc.Country, fixture.Available, fixture.DefaultLocale, fixture.CountryLocales)
+			if got != tc.Want {
+				t.Errorf("Negotiate(%q, %q) = %q, want %q", tc.AcceptLanguage, tc.Country, got, tc.Want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Set{
+		DefaultLocale: "en",
+		Content: map[string]Content{
+			"en":    {Title: "Maintenance", Message: "Back soon"},
+			"de-DE": {Title: "Wartung", Message: "Bald zurück"},
+		},
+		CountryLocales: map[string]string{"DE": "de-DE"},
+	}
+	if errs := Validate(valid); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	invalid := Set{
+		DefaultLocale: "fr",
+		Content: map[string]Content{
+			"en":    {Title: "Maintenance"},
+			"EN":    {Title: "Maintenance", Message: "x"},
+			"de_DE": {Title: "", Message: "Bald zurück"},
+			"ja":    {Title: "メンテナンス", Message: "まもなく戻ります"},
+		},
+		CountryLocales: map[string]string{"Germany": "de"},
+	}
+	var msgs []string
+	for _, err := range Validate(invalid) {
+		msgs = append(msgs, err.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`default locale "fr" has no localized content`,
+		`locale "en" is missing a message`,
+		`locale "de_DE" is not a valid language tag`,
+		`locale "de_DE" is missing a title`,
+		`duplicates`,
+		`country "Germany" is not an ISO 3166-1 alpha-2 code`,
+		`maps to locale "de" which has no localized content`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("missing error %q in:\n%s", want, joined)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	bare := filepath.Join(dir, "bare.json")
+	os.WriteFile(bare, []byte(`{"en": {"title": "T", "message": "M"}}`), 0o644)
+	s, err := LoadFile(bare)
+	if err != nil || s.Content["en"].Title != "T" {
+		t.Fatalf("bare file: %+v, %v", s, err)
+	}
+
+	wrapped := filepath.Join(dir, "terraform.tfvars.json")
+	os.WriteFile(wrapped, []byte(`{"default_locale": "de", "localized_content": {"de": {"title": "T", "message": "M"}}}`), 0o644)
+	s, err = LoadFile(wrapped)
+	if err != nil || s.DefaultLocale != "de" || s.Content["de"].Message != "M" {
+		t.Fatalf("wrapped file: %+v, %v", s, err)
+	}
+}
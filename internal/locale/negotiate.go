@@ -0,0 +1,74 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type languageRange struct {
+	tag   string
+	q     float64
+	index int
+}
+
+// Negotiate picks the best available locale: Accept-Language ranges in q
+// order (exact tag, then primary language, then any sibling region), then the
+// country mapping, then def. Keep in sync with negotiateLocale in worker.js.
+func Negotiate(acceptLanguage, country string, available []string, def string, countryLocales map[string]string) string {
+	byLower := map[string]string{}
+	for _, tag := range available {
+		byLower[strings.ToLower(tag)] = tag
+	}
+	candidates := make([]string, 0, len(byLower))
+	for k := range byLower {
+		candidates = append(candidates, k)
+	}
+	sort.Strings(candidates)
+
+	for _, r := range parseAcceptLanguage(acceptLanguage) {
+		if tag, ok := byLower[r.tag]; ok {
+			return tag
+		}
+		primary := strings.SplitN(r.tag, "-", 2)[0]
+		if tag, ok := byLower[primary]; ok {
+			return tag
+		}
+		for _, c := range candidates {
+			if strings.SplitN(c, "-", 2)[0] == primary {
+				return byLower[c]
+			}
+		}
+	}
+
+	if tag, ok := countryLocales[country]; ok && country != "" {
+		if match, ok := byLower[strings.ToLower(tag)]; ok {
+			return match
+		}
+	}
+	return def
+}
+
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for i, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		r := languageRange{tag: strings.ToLower(strings.TrimSpace(fields[0])), q: 1, index: i}
+		for _, param := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				q, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					q = 0
+				}
+				r.q = q
+			}
+		}
+		if r.tag == "" || r.tag == "*" || r.q <= 0 {
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	sort.SliceStable(ranges, func(a, b int) bool { return ranges[a].q > ranges[b].q })
+	return ranges
+}
@@ -0,0 +1,41 @@
+// Package mode decides what the worker does with a request while maintenance
+// is on, depending on the module's mode variable. Keep in sync with
+// maintenanceAction in worker.js; both are tested against
+// tests/fixtures/read-only-mode.json.
+package mode
+
+import "strings"
+
+// The values of the module's mode variable.
+const (
+	Full     = "full"
+	ReadOnly = "read_only"
+)
+
+// Action is what the worker does with a request.
+type Action string
+
+const (
+	// Page serves the HTML maintenance page.
+	Page Action = "page"
+	// Origin passes the request through.
+	Origin Action = "origin"
+	// Reject answers with a 503 JSON error.
+	Reject Action = "reject"
+)
+
+// Decide returns the action for a request method. In read_only mode only
+// GET, HEAD and OPTIONS reach the origin; every other method, including ones
+// the worker doesn't know, is treated as a write. Unknown modes behave as
+// full.
+func Decide(mode, method string) Action {
+	if mode != ReadOnly {
+		return Page
+	}
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS":
+		return Origin
+	default:
+		return Reject
+	}
+}
@@ -0,0 +1,34 @@
+package mode
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// The same table drives maintenanceAction in tests/unit/worker.test.js.
+func TestDecideSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/read-only-mode.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Cases []struct {
+			Mode   string `json:"mode"`
+			Method string `json:"method"`
+			Want   Action `json:"want"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	if len(fixture.Cases) == 0 {
+		t.Fatal("fixture has no cases")
+	}
+
+	for _, tc := range fixture.Cases {
+		if got := Decide(tc.Mode, tc.Method); got != tc.Want {
+			t.Errorf("Decide(%q, %q) = %q, want %q", tc.Mode, tc.Method, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package notify sends maintenance notifications to the targets the
+// notifications submodule understands: slack://T/B/X, pagerduty://ROUTING_KEY
+// and webhook://URL. Payloads match the submodule's so one receiver handles
+// both. Keep in sync with notificationRequest in worker.js; both are tested
+// against tests/fixtures/notifications.json.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Statuses used by the submodule and the watchdog.
+const (
+	StatusStarting  = "STARTING"
+	StatusActive    = "ACTIVE"
+	StatusEnding    = "ENDING"
+	StatusCompleted = "COMPLETED"
+)
+
+// PagerDutyEventsURL is where pagerduty:// targets are sent. Tests point it
+// at a local server.
+var PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Message is one notification. Detail is optional free text, such as why the
+// watchdog turned maintenance off.
+type Message struct {
+	Status      string
+	Schedule    string
+	Environment string
+	WindowStart string
+	WindowEnd   string
+	Detail      string
+}
+
+// Request is the HTTP POST a target receives.
+type Request struct {
+	URL  string
+	Body map[string]any
+}
+
+// Build returns the request for one target.
+func Build(target string, m Message) (Request, error) {
+	summary := fmt.Sprintf("Maintenance %s: %s", m.Status, m.Schedule)
+	switch {
+	case strings.HasPrefix(target, "slack://"):
+		fields := []any{
+			mrkdwn("Status", m.Status),
+			mrkdwn("Window", m.WindowStart+" - "+m.WindowEnd),
+			mrkdwn("Environment", m.Environment),
+			mrkdwn("Schedule", m.Schedule),
+		}
+		if m.Detail != "" {
+			fields = append(fields, mrkdwn("Detail", m.Detail))
+		}
+		return Request{
+			URL: "https://hooks.slack.com/services/" + strings.TrimPrefix(target, "slack://"),
+			Body: map[string]any{
+				"text": "Maintenance " + m.Status,
+				"blocks": []any{
+					map[string]any{"type": "header", "text": map[string]any{"type": "plain_text", "text": summary}},
+					map[string]any{"type": "section", "fields": fields},
+				},
+			},
+		}, nil
+	case strings.HasPrefix(target, "pagerduty://"):
+		details := map[string]any{
+			"status":      m.Status,
+			"environment": m.Environment,
+			"schedule":    m.Schedule,
+			"start_time":  m.WindowStart,
+			"end_time":    m.WindowEnd,
+		}
+		if m.Detail != "" {
+			details["detail"] = m.Detail
+		}
+		return Request{
+			URL: PagerDutyEventsURL,
+			Body: map[string]any{
+				"routing_key":  strings.TrimPrefix(target, "pagerduty://"),
+				"event_action": "trigger",
+				"payload": map[string]any{
+					"summary":        summary,
+					"severity":       "warning",
+					"source":         "terraform-cloudflare-maintenance",
+					"custom_details": details,
+				},
+			},
+		}, nil
+	case strings.HasPrefix(target, "webhook://"):
+		body := map[string]any{
+			"status":        m.Status,
+			"schedule_name": m.Schedule,
+			"environment":   m.Environment,
+			"maintenance_window": map[string]any{
+				"start_time": m.WindowStart,
+				"end_time":   m.WindowEnd,
+			},
+		}
+		if m.Detail != "" {
+			body["detail"] = m.Detail
+		}
+		return Request{URL: strings.TrimPrefix(target, "webhook://"), Body: body}, nil
+	}
+	return Request{}, fmt.Errorf("notification target %q must start with slack://, pagerduty:// or webhook://", target)
+}
+
+func mrkdwn(label, value string) map[string]any {
+	return map[string]any{"type": "mrkdwn", "text": "*" + label + ":*\n" + value}
+}
+
+// Send posts m to every target. A failing target does not stop the others;
+// their errors are joined.
+func Send(ctx context.Context, client *http.Client, targets []string, m Message) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var errs []error
+	for _, target := range targets {
+		if err := send(ctx, client, target, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func send(ctx context.Context, client *http.Client, target string, m Message) error {
+	r, err := Build(target, m)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(r.Body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifying %s: %w", scheme(target), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notifying %s: %s", scheme(target), resp.Status)
+	}
+	return nil
+}
+
+// scheme keeps webhook URLs and routing keys, which are credentials, out of
+// error messages.
+func scheme(target string) string {
+	if i := strings.Index(target, "://"); i >= 0 {
+		return target[:i]
+	}
+	return "target"
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The same cases drive notificationRequest in tests/unit/worker.test.js.
+func TestBuildSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/notifications.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Message struct {
+			Status      string `json:"status"`
+			Schedule    string `json:"schedule"`
+			Environment string `json:"environment"`
+			WindowStart string `json:"window_start"`
+			WindowEnd   string `json:"window_end"`
+			Detail      string `json:"detail"`
+		} `json:"message"`
+		Cases []struct {
+			Target string         `json:"target"`
+			URL    string         `json:"url"`
+			Body   map[string]any `json:"body"`
+			Error  bool           `json:"error"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	m := Message(fixture.Message)
+
+	for _, tc := range fixture.Cases {
+		r, err := Build(tc.Target, m)
+		if tc.Error {
+			if err == nil {
+				t.Errorf("Build(%q) succeeded", tc.Target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Build(%q): %v", tc.Target, err)
+			continue
+		}
+		// Round-trip through JSON so the comparison sees what is sent.
+		raw, _ := json.Marshal(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		if r.URL != tc.URL || !reflect.DeepEqual(body, tc.Body) {
+			t.Errorf("Build(%q) = %s %s, want %s %v", tc.Target, r.URL, raw, tc.URL, tc.Body)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusInternalServerError)
+			return
+		}
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		got = append(got, body)
+	}))
+	defer srv.Close()
+	PagerDutyEventsURL = srv.URL + "/v2/enqueue"
+	t.Cleanup(func() { PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue" })
+
+	targets := []string{"webhook://" + srv.URL + "/fail", "webhook://" + srv.URL + "/hook", "pagerduty://secret-key"}
+	err := Send(context.Background(), srv.Client(), targets, Message{Status: StatusEnding, Schedule: "watchdog"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Send error = %v, want the failing target's status", err)
+	}
+	if strings.Contains(err.Error(), srv.URL) {
+		t.Errorf("Send error %q leaks the target URL", err)
+	}
+	if len(got) != 2 || got[0]["status"] != StatusEnding || got[1]["routing_key"] != "secret-key" {
+		t.Errorf("received %v", got)
+	}
+}
@@ -0,0 +1,235 @@
+// Package page renders the maintenance page the same way worker.js does, so
+// it can be previewed and tested without deploying a worker.
+package page
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/pagetemplate"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/updates"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/templates"
+)
+
+// Config mirrors the worker bindings that shape the page.
+type Config struct {
+	Template        string // empty uses templates/default.html
+	Lang            string
+	Title           string
+	Message         string
+	ContactEmail    string
+	CustomCSS       string
+	LogoURL         string
+	WindowStart     string
+	WindowEnd       string
+	DisplayTimezone string
+	Updates         []updates.Update
+}
+
+// Viewer describes who is looking at the page. A nil Timezone means the
+// visitor has JavaScript disabled and sees the server-rendered text.
+type Viewer struct {
+	Timezone *time.Location
+	Locale   string
+	Now      time.Time
+}
+
+const (
+	defaultTitle   = "Maintenance Mode"
+	defaultMessage = "We are currently performing scheduled maintenance. We will be back shortly."
+	previewNonce   = "preview"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[^\s@<>'"]+@[^\s@<>'"]+\.[^\s@<>'"]+$`)
+	styleClose   = regexp.MustCompile(`(?i)</style>`)
+	timePattern  = regexp.MustCompile(`(<time [^>]*data-maintenance-end>)[^<]*(</time>)`)
+	countdownTag = `<p class="countdown" id="maintenance-countdown" style="font-size: 0.9rem; color: #888;"></p>`
+)
+
+// Render produces the page HTML as the given viewer would see it once the
+// countdown script has run.
+func Render(cfg Config, v Viewer) string {
+	title := escapeHTML(cfg.Title)
+	if title == "" {
+		title = defaultTitle
+	}
+	message := escapeHTML(cfg.Message)
+	if message == "" {
+		message = defaultMessage
+	}
+
+	contact := ""
+	if email := sanitizeEmail(cfg.ContactEmail); email != "" {
+		contact = fmt.Sprintf(`<p class="contact">Contact: <a href="mailto:%s">%s</a></p>`, email, email)
+	}
+
+	lang := cfg.Lang
+	if lang == "" {
+		lang = "en"
+	}
+
+	tmpl := cfg.Template
+	if tmpl == "" {
+		tmpl = templates.Default
+	}
+	html := pagetemplate.Render(tmpl, map[string]string{
+		"lang":       escapeHTML(lang),
+		"title":      title,
+		"message":    message,
+		"updates":    UpdatesHTML(cfg.Updates, cfg.DisplayTimezone),
+		"window":     WindowMessage(cfg),
+		"contact":    contact,
+		"logo":       logoHTML(cfg.LogoURL),
+		"custom_css": styleClose.ReplaceAllString(cfg.CustomCSS, ""),
+	})
+	return applyViewer(html, cfg, v)
+}
+
+// WindowMessage returns the "Expected completion" block, or an empty string
+// when no window is configured.
+func WindowMessage(cfg Config) string {
+	if cfg.WindowStart == "" || cfg.WindowEnd == "" {
+		return ""
+	}
+	end, err := time.Parse(time.RFC3339, cfg.WindowEnd)
+	if err != nil {
+		return ""
+	}
+	tz := cfg.DisplayTimezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	return fmt.Sprintf(`<p class="window" style="font-size: 0.9rem; color: #888;">Expected completion: <time datetime="%s" data-maintenance-end>%s</time></p>
+    %s
+    <script nonce="%s">/* countdown */</script>`,
+		end.UTC().Format("2006-01-02T15:04:05.000Z"), escapeHTML(FormatInTimeZone(end, tz)), countdownTag, previewNonce)
+}
+
+// UpdatesHTML renders the status update timeline newest first, or an empty
+// string when there are no updates. Keep in sync with renderStatusUpdates in
+// worker.js.
+func UpdatesHTML(list []updates.Update, tz string) string {
+	if tz == "" {
+		tz = "UTC"
+	}
+	sorted := make([]updates.Update, 0, len(list))
+	for _, u := range list {
+		if u.Message != "" && !u.PostedAt.IsZero() {
+			sorted = append(sorted, u)
+		}
+	}
+	if len(sorted) == 0 {
+		return ""
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].PostedAt.After(sorted[j].PostedAt) })
+	var b strings.Builder
+	b.WriteString(`<ul class="updates">`)
+	for _, u := range sorted {
+		fmt.Fprintf(&b, `<li><time datetime="%s">%s</time> %s</li>`,
+			u.PostedAt.UTC().Format("2006-01-02T15:04:05.000Z"), escapeHTML(FormatInTimeZone(u.PostedAt, tz)), escapeHTML(u.Message))
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+// FormatInTimeZone matches the worker's Intl.DateTimeFormat('en-US') output,
+// e.g. "Sun, Apr 6, 2025, 06:00 EDT". Unknown zones fall back to UTC in
+// toUTCString's format, "Sun, 06 Apr 2025 10:00:00 GMT".
+func FormatInTimeZone(t time.Time, tz string) string {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return t.UTC().Format(http.TimeFormat)
+	}
+	local := t.In(loc)
+	return local.Format("Mon, Jan 2, 2006, 15:04 ") + zoneName(local)
+}
+
+// localeLayouts approximate the numeric toLocaleString output browsers produce
+// for the countdown script's options. Unknown locales use en-US.
+var localeLayouts = map[string]string{
+	"en-US": "01/02/2006, 03:04 PM",
+	"en-GB": "02/01/2006, 15:04",
+	"de":    "02.01.2006, 15:04",
+	"fr":    "02/01/2006 15:04",
+	"es":    "02/01/2006, 15:04",
+	"ja":    "2006/01/02 15:04",
+}
+
+// FormatForViewer approximates what the countdown script writes into the
+// <time> element for a visitor in the given timezone and locale.
+func FormatForViewer(t time.Time, loc *time.Location, locale string) string {
+	layout, ok := localeLayouts[locale]
+	if !ok {
+		layout, ok = localeLayouts[strings.SplitN(locale, "-", 2)[0]]
+	}
+	if !ok {
+		layout = localeLayouts["en-US"]
+	}
+	local := t.In(loc)
+	return local.Format(layout) + " " + zoneName(local)
+}
+
+// Countdown renders the text the countdown script shows at now.
+func Countdown(end, now time.Time) string {
+	left := end.Sub(now)
+	if left <= 0 {
+		return "We should be back any moment now."
+	}
+	s := int(left / time.Second)
+	return fmt.Sprintf("Time remaining: %d:%02d:%02d", s/3600, s%3600/60, s%60)
+}
+
+func applyViewer(html string, cfg Config, v Viewer) string {
+	if v.Timezone == nil || WindowMessage(cfg) == "" {
+		return html
+	}
+	end, _ := time.Parse(time.RFC3339, cfg.WindowEnd)
+	now := v.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	html = timePattern.ReplaceAllString(html, "${1}"+escapeHTML(FormatForViewer(end, v.Timezone, v.Locale))+"${2}")
+	return strings.Replace(html, countdownTag, strings.Replace(countdownTag, "></p>", ">"+Countdown(end, now)+"</p>", 1), 1)
+}
+
+// zoneName renders numeric-only abbreviations the way Intl does ("GMT+2").
+func zoneName(t time.Time) string {
+	name, offset := t.Zone()
+	if name != "" && name[0] != '+' && name[0] != '-' {
+		return name
+	}
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	if offset%3600 == 0 {
+		return fmt.Sprintf("GMT%s%d", sign, offset/3600)
+	}
+	return fmt.Sprintf("GMT%s%d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+func logoHTML(raw string) string {
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || u.Scheme != "https" {
+		return ""
+	}
+	clean := strings.NewReplacer("'", "", `"`, "", "<", "", ">", "", "`", "", "&", "").Replace(raw)
+	return fmt.Sprintf(`<img src="%s" alt="Logo" style="max-width: 200px; margin-bottom: 1rem;">`, clean)
+}
+
+func sanitizeEmail(email string) string {
+	if !emailPattern.MatchString(email) {
+		return ""
+	}
+	return strings.NewReplacer("<", "", ">", "", "'", "", `"`, "", "&", "").Replace(email)
+}
+
+func escapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#039;").Replace(s)
+}
@@ -0,0 +1,171 @@
+package page
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/updates"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/templates"
+)
+
+// Shared with tests/unit/worker.test.js so the preview and the worker format
+// times the same way.
+func TestFormatInTimeZone(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/timezone-format.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Cases []struct {
+			Name     string    `json:"name"`
+			Time     time.Time `json:"time"`
+			Timezone string    `json:"timezone"`
+			Want     string    `json:"want"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range fixture.Cases {
+		if got := FormatInTimeZone(tc.Time, tc.Timezone); got != tc.Want {
+			t.Errorf("%s: FormatInTimeZone(%q) = %q, want %q", tc.Name, tc.Timezone, got, tc.Want)
+		}
+	}
+
+	// Not in the fixture: Node's ICU data names Tokyo "GMT+9".
+	if got := FormatInTimeZone(time.Date(2025, 4, 6, 10, 0, 0, 0, time.UTC), "Asia/Tokyo"); got != "Sun, Apr 6, 2025, 19:00 JST" {
+		t.Errorf("FormatInTimeZone(Asia/Tokyo) = %q", got)
+	}
+}
+
+func TestWindowMessage(t *testing.T) {
+	cfg := Config{
+		WindowStart:     "2025-04-06T08:00:00Z",
+		WindowEnd:       "2025-04-06T10:00:00Z",
+		DisplayTimezone: "Europe/Berlin",
+	}
+	msg := WindowMessage(cfg)
+	if !strings.Contains(msg, `datetime="2025-04-06T10:00:00.000Z"`) {
+		t.Errorf("missing machine-readable end time: %s", msg)
+	}
+	if !strings.Contains(msg, "12:00 CEST") {
+		t.Errorf("expected Berlin local time: %s", msg)
+	}
+
+	for _, bad := range []Config{{}, {WindowStart: "x", WindowEnd: "not-a-date"}} {
+		if got := WindowMessage(bad); got != "" {
+			t.Errorf("WindowMessage(%+v) = %q, want empty", bad, got)
+		}
+	}
+}
+
+func TestRenderForViewer(t *testing.T) {
+	cfg := Config{
+		Title:       "Upgrade",
+		WindowStart: "2025-04-06T08:00:00Z",
+		WindowEnd:   "2025-04-06T10:00:00Z",
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	now := time.Date(2025, 4, 6, 8, 30, 15, 0, time.UTC)
+
+	noJS := Render(cfg, Viewer{Now: now})
+	if !strings.Contains(noJS, "Sun, Apr 6, 2025, 10:00 UTC</time>") {
+		t.Errorf("no-JS viewer should see display timezone text")
+	}
+
+	html := Render(cfg, Viewer{Timezone: tokyo, Locale: "ja-JP", Now: now})
+	if !strings.Contains(html, ">2025/04/06 19:00 JST</time>") {
+		t.Errorf("Tokyo viewer should see local time, got:\n%s", html)
+	}
+	if !strings.Contains(html, "Time remaining: 1:29:45") {
+		t.Errorf("missing countdown in:\n%s", html)
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	end := time.Date(2025, 4, 6, 10, 0, 0, 0, time.UTC)
+	if got := Countdown(end, end.Add(-26*time.Hour-5*time.Second)); got != "Time remaining: 26:00:05" {
+		t.Errorf("got %q", got)
+	}
+	if got := Countdown(end, end.Add(time.Minute)); !strings.Contains(got, "any moment") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	html := Render(Config{
+		Title:        "<script>x</script>",
+		ContactEmail: "<script>@example.com",
+		CustomCSS:    "a{}</STYLE><img src=x>",
+		LogoURL:      "javascript:alert(1)",
+	}, Viewer{})
+	if strings.Contains(html, "<script>") || strings.Contains(html, "javascript:") {
+		t.Errorf("unsafe content rendered:\n%s", html)
+	}
+	if strings.Count(strings.ToLower(html), "</style>") != 1 {
+		t.Errorf("custom CSS broke out of the style block:\n%s", html)
+	}
+}
+
+// worker.js carries its own copy of the built-in template so it works
+// without a PAGE_TEMPLATE binding; make sure the two never drift apart.
+func TestWorkerDefaultTemplateMatchesFile(t *testing.T) {
+	src, err := os.ReadFile("../../worker.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const marker = "const DEFAULT_TEMPLATE = `"
+	start := strings.Index(string(src), marker)
+	if start < 0 {
+		t.Fatal("DEFAULT_TEMPLATE not found in worker.js")
+	}
+	rest := string(src)[start+len(marker):]
+	inWorker := rest[:strings.Index(rest, "`")]
+	if inWorker != templates.Default {
+		t.Error("DEFAULT_TEMPLATE in worker.js differs from templates/default.html")
+	}
+}
+
+func TestRenderCustomTemplate(t *testing.T) {
+	html := Render(Config{
+		Template:     `<html lang="{{lang}}"><h2>{{title}}</h2>{{message}}{{window}}{{contact}}</html>`,
+		Title:        "Checkout paused",
+		Message:      "Back at 10",
+		ContactEmail: "ops@example.com",
+	}, Viewer{})
+	want := `<html lang="en"><h2>Checkout paused</h2>Back at 10<p class="contact">Contact: <a href="mailto:ops@example.com">ops@example.com</a></p></html>`
+	if html != want {
+		t.Errorf("got  %s\nwant %s", html, want)
+	}
+}
+
+// The renderStatusUpdates unit test in tests/unit uses the same input and
+// expected markup.
+func TestUpdatesHTML(t *testing.T) {
+	list := []updates.Update{
+		{ID: "a", Message: "Started <migration>", PostedAt: time.Date(2025, 4, 6, 8, 5, 0, 0, time.UTC)},
+		{ID: "b", Message: "Migration 60% done", PostedAt: time.Date(2025, 4, 6, 9, 0, 0, 0, time.UTC)},
+		{ID: "c", Message: "", PostedAt: time.Date(2025, 4, 6, 9, 30, 0, 0, time.UTC)},
+	}
+	want := `<ul class="updates">` +
+		`<li><time datetime="2025-04-06T09:00:00.000Z">Sun, Apr 6, 2025, 05:00 EDT</time> Migration 60% done</li>` +
+		`<li><time datetime="2025-04-06T08:05:00.000Z">Sun, Apr 6, 2025, 04:05 EDT</time> Started &lt;migration&gt;</li>` +
+		`</ul>`
+	if got := UpdatesHTML(list, "America/New_York"); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if got := UpdatesHTML(nil, ""); got != "" {
+		t.Errorf("UpdatesHTML(nil) = %q, want empty", got)
+	}
+
+	html := Render(Config{Updates: list}, Viewer{})
+	if !strings.Contains(html, "<p>"+defaultMessage+"</p>\n    "+`<ul class="updates">`) {
+		t.Errorf("updates should follow the message in the default template:\n%s", html)
+	}
+}
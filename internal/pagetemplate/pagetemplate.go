@@ -0,0 +1,152 @@
+// Package pagetemplate implements the {{placeholder}} substitution shared with
+// worker.js and a linter that catches templates the worker or its
+// Content-Security-Policy would break before they are applied.
+package pagetemplate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholders lists every name the worker substitutes. Values are HTML
+// fragments the worker has already escaped.
+var Placeholders = map[string]string{
+	"lang":       "negotiated page language",
+	"title":      "page title",
+	"message":    "page message",
+	"updates":    "status update timeline (empty until one is posted)",
+	"window":     "expected completion time, countdown and its nonced script",
+	"contact":    "contact paragraph (empty without contact_email)",
+	"logo":       "logo <img> (empty without logo_url)",
+	"custom_css": "custom_css, to be placed inside a <style> block",
+}
+
+// Required placeholders must appear at least once; without them a custom
+// template silently drops information customers rely on.
+var Required = []string{"title", "message", "window", "contact"}
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// Render substitutes placeholders in a single pass, exactly like
+// renderTemplate in worker.js. Unknown names render as empty strings.
+func Render(tmpl string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return values[placeholderPattern.FindStringSubmatch(m)[1]]
+	})
+}
+
+// Severity of a lint finding. Errors fail `maintctl template lint`.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Finding is a single lint result with a 1-based line number (0 when the
+// finding applies to the whole template).
+type Finding struct {
+	Severity Severity
+	Line     int
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("%s: line %d: %s", f.Severity, f.Line, f.Message)
+}
+
+type rule struct {
+	severity Severity
+	pattern  *regexp.Regexp
+	message  string
+}
+
+// The worker serves pages with
+//
+//	default-src 'none'; script-src 'nonce-…'; style-src 'unsafe-inline'; img-src https:
+//
+// so anything that needs another source type is dead on arrival.
+var rules = []rule{
+	{Error, regexp.MustCompile(`(?i)<script\b`), "<script> tags are not allowed; only the worker's nonced countdown script may run"},
+	{Error, regexp.MustCompile(`(?i)\son[a-z]+\s*=`), "inline event handlers are blocked by the Content-Security-Policy"},
+	{Error, regexp.MustCompile(`(?i)javascript:`), "javascript: URLs are not allowed"},
+	{Error, regexp.MustCompile(`(?i)<link\b[^>]*\bstylesheet\b`), "external stylesheets are blocked (style-src allows inline styles only)"},
+	{Error, regexp.MustCompile(`(?i)@import\b`), "@import is blocked (style-src allows inline styles only)"},
+	{Error, regexp.MustCompile(`(?i)@font-face\b`), "web fonts are blocked (font-src falls back to default-src 'none')"},
+	{Error, regexp.MustCompile(`(?i)<(iframe|frame|object|embed|video|audio|source)\b`), "embedded content is blocked by default-src 'none'"},
+	{Error, regexp.MustCompile(`(?i)<base\b`), "<base> changes how every URL on the page resolves and is not allowed"},
+	{Error, regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["']?(http:|data:|//|/|\.)`), "images must use absolute https: URLs (img-src https:)"},
+	{Error, regexp.MustCompile(`(?i)url\(\s*["']?(http:|data:|//|/|\.)`), "CSS url() must use absolute https: URLs (img-src https:)"},
+	{Warning, regexp.MustCompile(`(?i)<form\b`), "forms cannot be submitted usefully during maintenance"},
+	{Warning, regexp.MustCompile(`(?i)<meta\b[^>]*http-equiv\s*=\s*["']?refresh`), "meta refresh fights the Retry-After header; let clients retry on their own"},
+}
+
+// Lint checks a template for missing or unknown placeholders, forbidden
+// markup and anything the worker's Content-Security-Policy would block.
+func Lint(tmpl string) []Finding {
+	var findings []Finding
+
+	seen := map[string]bool{}
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(tmpl, -1) {
+		name := tmpl[m[2]:m[3]]
+		seen[name] = true
+		if _, ok := Placeholders[name]; !ok {
+			findings = append(findings, Finding{Error, lineOf(tmpl, m[0]), fmt.Sprintf("unknown placeholder {{%s}}", name)})
+		}
+	}
+	for _, name := range Required {
+		if !seen[name] {
+			findings = append(findings, Finding{Error, 0, fmt.Sprintf("missing required placeholder {{%s}}", name)})
+		}
+	}
+	if !seen["lang"] {
+		findings = append(findings, Finding{Warning, 0, "no {{lang}} placeholder; localized pages will not declare their language"})
+	}
+	if seen["custom_css"] && !insideStyle(tmpl) {
+		findings = append(findings, Finding{Error, 0, "{{custom_css}} must be inside a <style> block"})
+	}
+
+	for _, r := range rules {
+		for _, m := range r.pattern.FindAllStringIndex(tmpl, -1) {
+			findings = append(findings, Finding{r.severity, lineOf(tmpl, m[0]), r.message})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func insideStyle(tmpl string) bool {
+	lower := strings.ToLower(tmpl)
+	idx := placeholderPattern.FindAllStringSubmatchIndex(tmpl, -1)
+	for _, m := range idx {
+		if tmpl[m[2]:m[3]] != "custom_css" {
+			continue
+		}
+		open := strings.LastIndex(lower[:m[0]], "<style")
+		closed := strings.LastIndex(lower[:m[0]], "</style")
+		if open < 0 || closed > open {
+			return false
+		}
+	}
+	return true
+}
+
+func lineOf(s string, offset int) int {
+	return strings.Count(s[:offset], "\n") + 1
+}
@@ -0,0 +1,61 @@
+package pagetemplate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/templates"
+)
+
+func TestDefaultTemplateIsClean(t *testing.T) {
+	if findings := Lint(templates.Default); len(findings) != 0 {
+		t.Errorf("built-in template has findings: %v", findings)
+	}
+}
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		name     string
+		tmpl     string
+		want     string
+		severity Severity
+	}{
+		{"missing contact", "<title>{{title}}</title>{{message}}{{window}}", "missing required placeholder {{contact}}", Error},
+		{"unknown placeholder", "{{title}}{{message}}{{window}}{{contact}}{{footer}}", "unknown placeholder {{footer}}", Error},
+		{"script tag", "{{title}}{{message}}{{window}}{{contact}}<SCRIPT src=x>", "<script> tags are not allowed", Error},
+		{"event handler", `{{title}}{{message}}{{window}}{{contact}}<body onload="x()">`, "inline event handlers", Error},
+		{"external stylesheet", `{{title}}{{message}}{{window}}{{contact}}<link rel="stylesheet" href="https://cdn/x.css">`, "external stylesheets", Error},
+		{"http image", `{{title}}{{message}}{{window}}{{contact}}<img src="http://x/logo.png">`, "absolute https: URLs", Error},
+		{"relative background", `<style>{{custom_css}} body { background: url('/bg.png') }</style>{{title}}{{message}}{{window}}{{contact}}`, "CSS url()", Error},
+		{"web font", `<style>@font-face { src: url(https://x/f.woff) }</style>{{title}}{{message}}{{window}}{{contact}}`, "web fonts", Error},
+		{"css outside style", `{{title}}{{message}}{{window}}{{contact}}<div>{{custom_css}}</div>`, "must be inside a <style> block", Error},
+		{"meta refresh", `<meta http-equiv="refresh" content="30">{{title}}{{message}}{{window}}{{contact}}`, "meta refresh", Warning},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var found bool
+			for _, f := range Lint(tc.tmpl) {
+				if strings.Contains(f.Message, tc.want) && f.Severity == tc.severity {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected %s %q, got %v", tc.severity, tc.want, Lint(tc.tmpl))
+			}
+		})
+	}
+}
+
+func TestLintReportsLines(t *testing.T) {
+	findings := Lint("{{title}}\n{{message}}\n{{window}}{{contact}}\n<iframe src=x>")
+	if len(findings) == 0 || findings[len(findings)-1].Line != 4 {
+		t.Errorf("expected finding on line 4, got %v", findings)
+	}
+}
+
+func TestRenderIsSinglePass(t *testing.T) {
+	got := Render("<h1>{{ title }}</h1>{{unknown}}", map[string]string{"title": "{{message}}", "message": "boom"})
+	if got != "<h1>{{message}}</h1>" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,503 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled Rules language expression. Compile understands the part
+// of the language rate limiting rules usually need, which includes every
+// expression main.tf builds:
+//
+//	fields     http.host, http.request.uri, http.request.uri.path,
+//	           http.request.uri.query, http.request.method, http.user_agent,
+//	           http.request.headers["name"], http.request.cookies["name"],
+//	           http.request.uri.args["name"], http.response.code, ip.src,
+//	           ip.geoip.country, ip.geoip.asnum, cf.bot_management.ja3_hash,
+//	           cf.bot_management.ja4, http.request.timestamp.sec
+//	operators  eq ne lt le gt ge contains matches in, and their symbols
+//	logic      not and xor or, parentheses, true, false
+//	functions  lower upper len starts_with ends_with
+//
+// Header, cookie and argument fields stand for their first value. Anything
+// else is a compile error rather than a guess.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses src.
+func Compile(src string) (*Expr, error) {
+	p := &parser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of e.
+func (e *Expr) String() string { return e.src }
+
+// Match reports whether r matches e.
+func (e *Expr) Match(r *Request) bool { return e.root.eval(r).truthy() }
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindIP
+	kindBool
+)
+
+type value struct {
+	kind kind
+	s    string
+	n    int64
+	ip   netip.Addr
+	b    bool
+}
+
+func (v value) truthy() bool {
+	switch v.kind {
+	case kindBool:
+		return v.b
+	case kindString:
+		return v.s != ""
+	case kindInt:
+		return v.n != 0
+	}
+	return v.ip.IsValid()
+}
+
+type node interface{ eval(*Request) value }
+
+type logic struct {
+	op          string
+	left, right node
+}
+
+func (l logic) eval(r *Request) value {
+	a := l.left.eval(r).truthy()
+	switch l.op {
+	case "and":
+		return value{kind: kindBool, b: a && l.right.eval(r).truthy()}
+	case "or":
+		return value{kind: kindBool, b: a || l.right.eval(r).truthy()}
+	}
+	return value{kind: kindBool, b: a != l.right.eval(r).truthy()}
+}
+
+type not struct{ n node }
+
+func (n not) eval(r *Request) value { return value{kind: kindBool, b: !n.n.eval(r).truthy()} }
+
+type literal struct{ v value }
+
+func (l literal) eval(*Request) value { return l.v }
+
+type field struct {
+	name string
+	kind kind
+	get  func(*Request) value
+}
+
+func (f field) eval(r *Request) value { return f.get(r) }
+
+type call struct {
+	name string
+	args []node
+}
+
+func (c call) eval(r *Request) value {
+	a := c.args[0].eval(r)
+	switch c.name {
+	case "lower":
+		return value{kind: kindString, s: strings.ToLower(a.s)}
+	case "upper":
+		return value{kind: kindString, s: strings.ToUpper(a.s)}
+	case "len":
+		return value{kind: kindInt, n: int64(len(a.s))}
+	case "starts_with":
+		return value{kind: kindBool, b: strings.HasPrefix(a.s, c.args[1].eval(r).s)}
+	}
+	return value{kind: kindBool, b: strings.HasSuffix(a.s, c.args[1].eval(r).s)}
+}
+
+type compare struct {
+	op    string
+	left  node
+	right value
+	set   []value
+	re    *regexp.Regexp
+}
+
+func (c compare) eval(r *Request) value {
+	v := c.left.eval(r)
+	var ok bool
+	switch c.op {
+	case "in":
+		for _, m := range c.set {
+			if equal(v, m) {
+				ok = true
+				break
+			}
+		}
+	case "eq":
+		ok = equal(v, c.right)
+	case "ne":
+		ok = !equal(v, c.right)
+	case "contains":
+		ok = strings.Contains(v.s, c.right.s)
+	case "matches":
+		ok = c.re.MatchString(v.s)
+	default:
+		d := v.n - c.right.n
+		if v.kind == kindString {
+			d = int64(strings.Compare(v.s, c.right.s))
+		}
+		ok = map[string]bool{"lt": d < 0, "le": d <= 0, "gt": d > 0, "ge": d >= 0}[c.op]
+	}
+	return value{kind: kindBool, b: ok}
+}
+
+// equal compares a field's value with a literal; an IP literal with a
+// prefix length matches every address in it.
+func equal(v, lit value) bool {
+	switch v.kind {
+	case kindIP:
+		if lit.kind == kindString {
+			if p, err := netip.ParsePrefix(lit.s); err == nil {
+				return v.ip.IsValid() && p.Contains(v.ip)
+			}
+			return v.ip.IsValid() && v.ip.String() == lit.s
+		}
+		return false
+	case kindInt:
+		return lit.kind == kindInt && v.n == lit.n
+	case kindBool:
+		return lit.kind == kindBool && v.b == lit.b
+	}
+	return lit.kind == kindString && v.s == lit.s
+}
+
+var operators = map[string]string{
+	"eq": "eq", "==": "eq", "ne": "ne", "!=": "ne",
+	"lt": "lt", "<": "lt", "le": "le", "<=": "le", "gt": "gt", ">": "gt", "ge": "ge", ">=": "ge",
+	"contains": "contains", "matches": "matches", "~": "matches", "in": "in",
+}
+
+type token struct {
+	text   string
+	quoted bool // a string literal; text is unquoted
+}
+
+type parser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return fmt.Errorf("unterminated string")
+			}
+			p.toks = append(p.toks, token{text: b.String(), quoted: true})
+			i = j + 1
+		case strings.ContainsRune("(){}[],*", rune(c)):
+			p.toks = append(p.toks, token{text: string(c)})
+			i++
+		case strings.ContainsRune("=!<>~&|^", rune(c)):
+			j := i + 1
+			if j < len(s) && strings.ContainsRune("=&|^", rune(s[j])) {
+				j++
+			}
+			p.toks = append(p.toks, token{text: s[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || strings.ContainsRune("._:/-", rune(s[j]))) {
+				j++
+			}
+			if j == i {
+				return fmt.Errorf("unexpected %q", c)
+			}
+			p.toks = append(p.toks, token{text: s[i:j]})
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.toks) && !p.toks[p.pos].quoted {
+		return p.toks[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos == len(p.toks) {
+		return token{}, fmt.Errorf("unexpected end")
+	}
+	p.pos++
+	return p.toks[p.pos-1], nil
+}
+
+func (p *parser) expect(text string) error {
+	t, err := p.next()
+	if err == nil && (t.quoted || t.text != text) {
+		err = fmt.Errorf("want %q, got %q", text, t.text)
+	}
+	return err
+}
+
+func (p *parser) binary(op string, symbol string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	for err == nil && (p.peek() == op || p.peek() == symbol) {
+		p.pos++
+		var right node
+		if right, err = operand(); err == nil {
+			left = logic{op: op, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) or() (node, error) { return p.binary("or", "||", p.xor) }
+
+func (p *parser) xor() (node, error) { return p.binary("xor", "^^", p.and) }
+
+func (p *parser) and() (node, error) { return p.binary("and", "&&", p.unary) }
+
+func (p *parser) unary() (node, error) {
+	switch p.peek() {
+	case "not", "!":
+		p.pos++
+		n, err := p.unary()
+		return not{n}, err
+	case "(":
+		p.pos++
+		n, err := p.or()
+		if err == nil {
+			err = p.expect(")")
+		}
+		return n, err
+	case "true", "false":
+		p.pos++
+		return literal{value{kind: kindBool, b: p.toks[p.pos-1].text == "true"}}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, k, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := operators[p.peek()]
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	c := compare{op: op, left: left}
+	if op == "in" {
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for p.peek() != "}" {
+			v, err := p.literal(k)
+			if err != nil {
+				return nil, err
+			}
+			c.set = append(c.set, v)
+		}
+		p.pos++
+		return c, nil
+	}
+	if c.right, err = p.literal(k); err != nil {
+		return nil, err
+	}
+	if op == "matches" {
+		if c.re, err = regexp.Compile(c.right.s); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// literal reads a value to compare with a field of kind k. IP addresses and
+// ranges may be quoted or bare.
+func (p *parser) literal(k kind) (value, error) {
+	t, err := p.next()
+	if err != nil {
+		return value{}, err
+	}
+	switch {
+	case k == kindIP:
+		if _, err := netip.ParsePrefix(t.text); err != nil {
+			if _, err := netip.ParseAddr(t.text); err != nil {
+				return value{}, fmt.Errorf("%q is not an IP address or range", t.text)
+			}
+		}
+		return value{kind: kindString, s: t.text}, nil
+	case t.quoted:
+		return value{kind: kindString, s: t.text}, nil
+	case t.text == "true" || t.text == "false":
+		return value{kind: kindBool, b: t.text == "true"}, nil
+	}
+	n, err := strconv.ParseInt(t.text, 10, 64)
+	if err != nil {
+		return value{}, fmt.Errorf("unexpected %q", t.text)
+	}
+	return value{kind: kindInt, n: n}, nil
+}
+
+var functions = map[string]int{"lower": 1, "upper": 1, "len": 1, "starts_with": 2, "ends_with": 2}
+
+// operand reads a field, a map field with its key, or a function call.
+func (p *parser) operand() (node, kind, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, 0, err
+	}
+	if t.quoted {
+		return literal{value{kind: kindString, s: t.text}}, kindString, nil
+	}
+	if arity, ok := functions[t.text]; ok {
+		c := call{name: t.text}
+		if err := p.expect("("); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < arity; i++ {
+			if i > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, 0, err
+				}
+			}
+			arg, _, err := p.operand()
+			if err != nil {
+				return nil, 0, err
+			}
+			c.args = append(c.args, arg)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, 0, err
+		}
+		k := kindBool
+		switch t.text {
+		case "lower", "upper":
+			k = kindString
+		case "len":
+			k = kindInt
+		}
+		return c, k, nil
+	}
+	if p.peek() == "[" {
+		p.pos++
+		key, err := p.next()
+		if err == nil && !key.quoted {
+			err = fmt.Errorf("%s[...] needs a quoted key", t.text)
+		}
+		if err == nil {
+			err = p.expect("]")
+		}
+		if err == nil && p.peek() == "[" {
+			// [0] picks the first value, which is what a map field stands for.
+			p.pos++
+			if err = p.expect("0"); err == nil {
+				err = p.expect("]")
+			}
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		f, ok := mapField(t.text, key.text)
+		if !ok {
+			return nil, 0, fmt.Errorf("unsupported field %s[%q]", t.text, key.text)
+		}
+		return f, kindString, nil
+	}
+	f, ok := fields[t.text]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported field or function %q", t.text)
+	}
+	return f, f.kind, nil
+}
+
+func str(get func(*Request) string) func(*Request) value {
+	return func(r *Request) value { return value{kind: kindString, s: get(r)} }
+}
+
+func num(get func(*Request) int64) func(*Request) value {
+	return func(r *Request) value { return value{kind: kindInt, n: get(r)} }
+}
+
+var fields = map[string]field{}
+
+func init() {
+	for _, f := range []field{
+		{name: "http.host", get: str(func(r *Request) string { return r.Host })},
+		{name: "http.request.uri", get: str(func(r *Request) string { return r.uri() })},
+		{name: "http.request.uri.path", get: str(func(r *Request) string { return r.Path })},
+		{name: "http.request.uri.query", get: str(func(r *Request) string { return r.Query })},
+		{name: "http.request.method", get: str(func(r *Request) string { return r.Method })},
+		{name: "http.user_agent", get: str(func(r *Request) string { return r.UserAgent })},
+		{name: "ip.geoip.country", get: str(func(r *Request) string { return r.Country })},
+		{name: "ip.src.country", get: str(func(r *Request) string { return r.Country })},
+		{name: "cf.bot_management.ja3_hash", get: str(func(r *Request) string { return r.JA3 })},
+		{name: "cf.bot_management.ja4", get: str(func(r *Request) string { return r.JA4 })},
+		{name: "ip.geoip.asnum", kind: kindInt, get: num(func(r *Request) int64 { return int64(r.ASN) })},
+		{name: "ip.src.asnum", kind: kindInt, get: num(func(r *Request) int64 { return int64(r.ASN) })},
+		{name: "http.response.code", kind: kindInt, get: num(func(r *Request) int64 { return int64(r.Status) })},
+		{name: "http.request.timestamp.sec", kind: kindInt, get: num(func(r *Request) int64 { return r.Time.Unix() })},
+		{name: "ip.src", kind: kindIP, get: func(r *Request) value {
+			ip, _ := netip.ParseAddr(r.IP)
+			return value{kind: kindIP, ip: ip}
+		}},
+	} {
+		fields[f.name] = f
+	}
+}
+
+func mapField(name, key string) (field, bool) {
+	var get func(*Request) string
+	switch name {
+	case "http.request.headers":
+		key = strings.ToLower(key)
+		get = func(r *Request) string { return r.Headers[key] }
+	case "http.request.cookies":
+		get = func(r *Request) string { return r.Cookies[key] }
+	case "http.request.uri.args":
+		get = func(r *Request) string { return r.arg(key) }
+	default:
+		return field{}, false
+	}
+	return field{name: name, get: str(get)}, true
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	r := &Request{
+		Time:    time.Unix(1772366400, 0),
+		IP:      "192.0.2.10",
+		Host:    "app.example.com",
+		Method:  "POST",
+		Path:    "/checkout/pay",
+		Query:   "step=2&coupon=SAVE",
+		Country: "NL",
+		ASN:     64500,
+		Status:  429,
+		Headers: map[string]string{"x-api-key": "k1"},
+		Cookies: map[string]string{"session": "abc"},
+	}
+	for expr, want := range map[string]bool{
+		LegacyExpression:                                         true,
+		`http.host eq "app.example.com"`:                         true,
+		`http.host == "example.com"`:                             false,
+		`http.request.method ne "GET"`:                           true,
+		`http.request.uri eq "/checkout/pay?step=2&coupon=SAVE"`: true,
+		`http.request.uri.query contains "coupon"`:               true,
+		`ends_with(http.host, ".example.com")`:                   true,
+		`starts_with(http.request.uri.path, "/api/")`:            false,
+		`lower(http.request.method) eq "post"`:                   true,
+		`len(http.host) gt 10`:                                   true,
+		`ip.src in {192.0.2.0/24 198.51.100.7}`:                  true,
+		`ip.src in {"192.0.2.1"}`:                                false,
+		`ip.src eq 192.0.2.10`:                                   true,
+		`ip.geoip.country in {"CA" "NL"}`:                        true,
+		`ip.geoip.asnum eq 64500`:                                true,
+		`http.response.code in {401 403 429}`:                    true,
+		`http.response.code >= 500`:                              false,
+		`http.request.headers["X-API-Key"] eq "k1"`:              true,
+		`http.request.headers["x-api-key"][0] eq "k1"`:           true,
+		`http.request.cookies["session"] ne ""`:                  true,
+		`http.request.uri.args["step"] eq "2"`:                   true,
+		`http.request.timestamp.sec ge 1772366400`:               true,
+		`not true or false`:                                      false,
+		`true xor true`:                                          false,
+		`!(http.host eq "a") && (ip.src in {10.0.0.0/8} || http.request.method in {"POST"})`: true,
+		// The page-only expression tests/rate_limit.tftest.hcl plans.
+		`(http.host eq "app.example.com") and (not (ip.src in {"192.0.2.1"} or ip.geoip.country in {"CA"})) and (not (http.request.method in {"GET" "HEAD" "OPTIONS"})) and (not (((http.request.uri.path eq "/status" or starts_with(http.request.uri.path, "/status/"))))) and ((http.request.uri.path matches ".*"))`: true,
+	} {
+		e, err := Compile(expr)
+		if err != nil {
+			t.Errorf("Compile(%s): %v", expr, err)
+			continue
+		}
+		if got := e.Match(r); got != want {
+			t.Errorf("%s matched %v, want %v", expr, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for expr, want := range map[string]string{
+		`http.request.body.raw contains "x"`:       "unsupported field",
+		`any(http.request.headers["a"][*] eq "b")`: "unsupported field or function",
+		`http.host eq "a`:                          "unterminated string",
+		`(http.host eq "a"`:                        "unexpected end",
+		`http.host eq "a" "b"`:                     `unexpected "b"`,
+		`ip.src in {"example.com"}`:                "not an IP address",
+		`http.request.uri.path matches "("`:        "missing closing )",
+		`http.request.headers[x] eq "1"`:           "needs a quoted key",
+	} {
+		_, err := Compile(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%s) = %v, want an error containing %q", expr, err, want)
+		}
+	}
+}
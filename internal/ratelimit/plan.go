@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Plan is what a plan does to the rate limiting ruleset.
+type Plan struct {
+	// Config is the rate_limit variable the plan was made with.
+	Config Config
+	// Rules are the planned ruleset's rules, in order; nil when the plan has
+	// no ruleset.
+	Rules []Rule
+}
+
+// rateResource is the ruleset in main.tf.
+const rateResource = "cloudflare_ruleset.rate_limit"
+
+type planDoc struct {
+	Variables map[string]struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"variables"`
+	PlannedValues *struct {
+		RootModule module `json:"root_module"`
+	} `json:"planned_values"`
+}
+
+type module struct {
+	Address   string `json:"address"`
+	Resources []struct {
+		Mode   string          `json:"mode"`
+		Type   string          `json:"type"`
+		Name   string          `json:"name"`
+		Values json.RawMessage `json:"values"`
+	} `json:"resources"`
+	ChildModules []module `json:"child_modules"`
+}
+
+// ReadPlan reads `terraform show -json PLANFILE` output for the root module.
+// The variables are only the root module's, so a plan of a configuration
+// that calls the module yields the rules but an empty Config.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var doc planDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("reading terraform show -json output: %w", err)
+	}
+	if doc.PlannedValues == nil {
+		return nil, errors.New("no planned_values; pass a plan file to terraform show -json")
+	}
+	p := &Plan{}
+	if v, ok := doc.Variables["rate_limit"]; ok {
+		if err := json.Unmarshal(v.Value, &p.Config); err != nil {
+			return nil, fmt.Errorf("variable rate_limit: %w", err)
+		}
+	}
+	raw := findRuleset(&doc.PlannedValues.RootModule)
+	if raw == nil {
+		return p, nil
+	}
+	rules, err := parseRules(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rateResource, err)
+	}
+	p.Rules = rules
+	return p, nil
+}
+
+func findRuleset(m *module) json.RawMessage {
+	for _, res := range m.Resources {
+		if res.Mode == "managed" && res.Type+"."+res.Name == rateResource {
+			return res.Values
+		}
+	}
+	for i := range m.ChildModules {
+		if raw := findRuleset(&m.ChildModules[i]); raw != nil {
+			return raw
+		}
+	}
+	return nil
+}
+
+func parseRules(raw json.RawMessage) ([]Rule, error) {
+	var v struct {
+		Rules []struct {
+			Action      string          `json:"action"`
+			Expression  string          `json:"expression"`
+			Description string          `json:"description"`
+			Enabled     *bool           `json:"enabled"`
+			RateLimit   json.RawMessage `json:"ratelimit"`
+		} `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	for i, r := range v.Rules {
+		var rl struct {
+			Characteristics    []string `json:"characteristics"`
+			Period             int      `json:"period"`
+			RequestsPerPeriod  int      `json:"requests_per_period"`
+			MitigationTimeout  int      `json:"mitigation_timeout"`
+			RequestsToOrigin   bool     `json:"requests_to_origin"`
+			CountingExpression *string  `json:"counting_expression"`
+		}
+		// Provider v4 plans a nested block as a list of at most one object.
+		var list []json.RawMessage
+		if err := json.Unmarshal(r.RateLimit, &list); err != nil || len(list) != 1 {
+			return nil, fmt.Errorf("rules[%d]: want one ratelimit block", i)
+		}
+		if err := json.Unmarshal(list[0], &rl); err != nil {
+			return nil, fmt.Errorf("rules[%d].ratelimit: %w", i, err)
+		}
+		rule := Rule{
+			Description:       r.Description,
+			Expression:        r.Expression,
+			Characteristics:   rl.Characteristics,
+			Action:            r.Action,
+			Period:            rl.Period,
+			RequestsPerPeriod: rl.RequestsPerPeriod,
+			MitigationTimeout: rl.MitigationTimeout,
+			RequestsToOrigin:  rl.RequestsToOrigin,
+			Enabled:           r.Enabled == nil || *r.Enabled,
+		}
+		if rl.CountingExpression != nil {
+			rule.CountingExpression = *rl.CountingExpression
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,180 @@
+// Package ratelimit describes the module's rate limiting: the rate_limit
+// variable, the checks variables.tf makes on it, and the rules a plan puts in
+// cloudflare_ruleset.rate_limit. Validate is the reference for the
+// rate_limit validations in variables.tf; both are checked against
+// tests/fixtures/rate-limit-rules.json. Simulate replays a traffic log
+// against the rules to tune them before an apply.
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule is one rate_limit.rules entry. Rules are evaluated in order and the
+// first one over its threshold acts.
+type Rule struct {
+	Description string `json:"description"`
+	Expression  string `json:"expression"`
+	// Characteristics are what requests are counted by; cf.colo.id is
+	// required.
+	Characteristics    []string `json:"characteristics"`
+	Action             string   `json:"action"`
+	Period             int      `json:"period"`
+	RequestsPerPeriod  int      `json:"requests_per_period"`
+	MitigationTimeout  int      `json:"mitigation_timeout"`
+	RequestsToOrigin   bool     `json:"requests_to_origin"`
+	CountingExpression string   `json:"counting_expression"`
+	Enabled            bool     `json:"enabled"`
+}
+
+// Config is the rate_limit variable. Without Rules, the top-level fields
+// make a single rule over every request, as before rules existed.
+type Config struct {
+	Enabled            bool   `json:"enabled"`
+	RequestsPerPeriod  int    `json:"requests_per_period"`
+	Period             int    `json:"period"`
+	Action             string `json:"action"`
+	MitigationTimeout  int    `json:"mitigation_timeout"`
+	CountingExpression string `json:"counting_expression"`
+	RequestsToOrigin   bool   `json:"requests_to_origin"`
+	// AppliesTo is "zone" for every request, or "maintenance_page" for
+	// only the requests that get the page, while maintenance is on.
+	AppliesTo string `json:"applies_to"`
+	Rules     []Rule `json:"rules"`
+}
+
+// DefaultCharacteristics count requests per data center and client IP.
+var DefaultCharacteristics = []string{"cf.colo.id", "ip.src"}
+
+// The optional() defaults in variables.tf.
+var (
+	defaultConfig = Config{RequestsPerPeriod: 100, Period: 60, Action: "block", MitigationTimeout: 600, AppliesTo: "zone"}
+	defaultRule   = Rule{Action: "block", Period: 60, RequestsPerPeriod: 100, MitigationTimeout: 600, Enabled: true}
+)
+
+// UnmarshalJSON applies the variable's defaults to fields c leaves out.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	type plain Config
+	p := plain(defaultConfig)
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*c = Config(p)
+	return nil
+}
+
+// UnmarshalJSON applies the variable's defaults to fields r leaves out.
+func (r *Rule) UnmarshalJSON(b []byte) error {
+	type plain Rule
+	p := plain(defaultRule)
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	if p.Characteristics == nil {
+		p.Characteristics = append([]string(nil), DefaultCharacteristics...)
+	}
+	*r = Rule(p)
+	return nil
+}
+
+// LegacyExpression is what the single rule made from the top-level fields
+// matches: every request.
+const LegacyExpression = `(http.request.uri.path matches ".*")`
+
+// Ruleset returns the rules the ruleset holds, in order. Keep in sync with
+// local.rate_limit_rules in main.tf. With AppliesTo "maintenance_page",
+// main.tf also narrows each expression to the requests that get the page.
+func (c Config) Ruleset() []Rule {
+	if len(c.Rules) > 0 {
+		return c.Rules
+	}
+	return []Rule{{
+		Description:        "Rate limit all requests",
+		Expression:         LegacyExpression,
+		Characteristics:    append([]string(nil), DefaultCharacteristics...),
+		Action:             c.Action,
+		Period:             c.Period,
+		RequestsPerPeriod:  c.RequestsPerPeriod,
+		MitigationTimeout:  c.MitigationTimeout,
+		RequestsToOrigin:   c.RequestsToOrigin,
+		CountingExpression: c.CountingExpression,
+		Enabled:            true,
+	}}
+}
+
+// Actions are the actions a rule may take.
+var Actions = []string{"block", "challenge", "js_challenge", "managed_challenge", "log"}
+
+// characteristic matches the characteristics a rule may count by. Header
+// names must be lowercase, as Cloudflare stores them. The same pattern is in
+// variables.tf.
+var characteristic = regexp.MustCompile(`^(cf\.colo\.id|ip\.src|cf\.unique_visitor_id|http\.host|http\.request\.uri\.path|ip\.geoip\.asnum|ip\.geoip\.country|cf\.bot_management\.ja3_hash|cf\.bot_management\.ja4|http\.request\.headers\["[a-z0-9_-]+"\]|http\.request\.(cookies|uri\.args|body\.form)\["[^"]+"\])$`)
+
+// Validate reports every way c breaks the rate_limit validations in
+// variables.tf.
+func Validate(c Config) error {
+	var errs []error
+	if c.Period < 10 || c.Period > 86400 {
+		errs = append(errs, fmt.Errorf("period %d must be between 10 and 86400 seconds", c.Period))
+	}
+	if !contains(Actions, c.Action) {
+		errs = append(errs, fmt.Errorf("action %q must be one of %s", c.Action, strings.Join(Actions, ", ")))
+	}
+	if c.MitigationTimeout < 60 || c.MitigationTimeout > 86400 {
+		errs = append(errs, fmt.Errorf("mitigation_timeout %d must be between 60 and 86400 seconds", c.MitigationTimeout))
+	}
+	if c.AppliesTo != "zone" && c.AppliesTo != "maintenance_page" {
+		errs = append(errs, fmt.Errorf("applies_to %q must be zone or maintenance_page", c.AppliesTo))
+	}
+	for i, r := range c.Rules {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every way r breaks the checks variables.tf makes on a
+// rules entry.
+func (r Rule) Validate() error {
+	var errs []error
+	if strings.TrimSpace(r.Expression) == "" {
+		errs = append(errs, errors.New("expression is empty"))
+	}
+	if !contains(r.Characteristics, "cf.colo.id") {
+		errs = append(errs, errors.New("characteristics must include cf.colo.id"))
+	}
+	seen := map[string]bool{}
+	for _, ch := range r.Characteristics {
+		if !characteristic.MatchString(ch) || seen[ch] {
+			errs = append(errs, fmt.Errorf("characteristic %q is not supported or repeated", ch))
+		}
+		seen[ch] = true
+	}
+	if r.Period < 10 || r.Period > 86400 {
+		errs = append(errs, fmt.Errorf("period %d must be between 10 and 86400 seconds", r.Period))
+	}
+	if r.RequestsPerPeriod < 1 {
+		errs = append(errs, fmt.Errorf("requests_per_period %d must be at least 1", r.RequestsPerPeriod))
+	}
+	if !contains(Actions, r.Action) {
+		errs = append(errs, fmt.Errorf("action %q must be one of %s", r.Action, strings.Join(Actions, ", ")))
+	}
+	if r.MitigationTimeout < 60 || r.MitigationTimeout > 86400 {
+		errs = append(errs, fmt.Errorf("mitigation_timeout %d must be between 60 and 86400 seconds", r.MitigationTimeout))
+	}
+	return errors.Join(errs...)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
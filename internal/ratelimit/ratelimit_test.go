@@ -0,0 +1,303 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite tests/rate_limit_validation.tftest.hcl from the rules fixture")
+
+const validationTests = "../../tests/rate_limit_validation.tftest.hcl"
+
+type fixtureCase struct {
+	Name      string          `json:"name"`
+	RateLimit json.RawMessage `json:"rate_limit"`
+	Error     string          `json:"error"`
+}
+
+func loadFixture(t *testing.T) (accept, reject []fixtureCase) {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/fixtures/rate-limit-rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f struct {
+		Accept []fixtureCase `json:"accept"`
+		Reject []fixtureCase `json:"reject"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f.Accept, f.Reject
+}
+
+func (c fixtureCase) config(t *testing.T) Config {
+	t.Helper()
+	var cfg Config
+	if err := json.Unmarshal(c.RateLimit, &cfg); err != nil {
+		t.Fatalf("%s: %v", c.Name, err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	accept, reject := loadFixture(t)
+	for _, c := range accept {
+		if err := Validate(c.config(t)); err != nil {
+			t.Errorf("accept %s: %v", c.Name, err)
+		}
+	}
+	for _, c := range reject {
+		err := Validate(c.config(t))
+		if err == nil || !strings.Contains(err.Error(), c.Error) {
+			t.Errorf("reject %s: got %v, want an error containing %q", c.Name, err, c.Error)
+		}
+	}
+}
+
+func TestRuleDefaults(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"enabled":true,"rules":[{"expression":"true"}]}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{Expression: "true", Characteristics: []string{"cf.colo.id", "ip.src"}, Action: "block", Period: 60, RequestsPerPeriod: 100, MitigationTimeout: 600, Enabled: true}
+	if got := c.Ruleset(); len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Ruleset() = %+v, want [%+v]", got, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"enabled":true,"period":120,"action":"log"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	got := c.Ruleset()
+	if len(got) != 1 || got[0].Expression != LegacyExpression || got[0].Period != 120 || got[0].Action != "log" || got[0].RequestsPerPeriod != 100 {
+		t.Errorf("top-level fields made %+v", got)
+	}
+}
+
+// The plan fixture is `terraform show -json` of a plan made with the
+// per_path_rules case of the rules fixture.
+func TestReadPlan(t *testing.T) {
+	f, err := os.Open("../../tests/fixtures/rate-limit-plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, err := ReadPlan(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(p.Config); err != nil {
+		t.Errorf("planned rate_limit: %v", err)
+	}
+	var order []string
+	for _, r := range p.Rules {
+		order = append(order, r.Description)
+	}
+	want := []string{"Login attempts per IP", "API calls per key", "Sessions", "Everything else per network"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("planned rules in order %q, want %q", order, want)
+	}
+	if !reflect.DeepEqual(p.Rules, p.Config.Ruleset()) {
+		t.Errorf("planned rules differ from the variable:\n got %+v\nwant %+v", p.Rules, p.Config.Ruleset())
+	}
+	if r := p.Rules[1]; r.Characteristics[1] != `http.request.headers["x-api-key"]` || r.CountingExpression != "(http.response.code eq 429)" {
+		t.Errorf("rules[1] = %+v", r)
+	}
+	if p.Rules[3].Enabled {
+		t.Error("rules[3] should be planned disabled")
+	}
+}
+
+func TestReadPlanErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"state, not a plan": `{"values":{"root_module":{}}}`,
+		"no ratelimit":      `{"planned_values":{"root_module":{"resources":[{"mode":"managed","type":"cloudflare_ruleset","name":"rate_limit","values":{"rules":[{"expression":"true","ratelimit":[]}]}}]}}}`,
+		"not JSON":          `Plan: 3 to add`,
+	} {
+		if _, err := ReadPlan(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	p, err := ReadPlan(strings.NewReader(`{"planned_values":{"root_module":{"resources":[]}}}`))
+	if err != nil || p.Rules != nil {
+		t.Errorf("plan without the ruleset: %+v, %v", p, err)
+	}
+}
+
+// The rate_limit validations in variables.tf are checked by terraform test
+// against cases generated from the rules fixture; accepted cases also assert
+// the planned rules keep Ruleset's order. Run with -update after changing the
+// fixture.
+func TestTerraformValidationCases(t *testing.T) {
+	accept, reject := loadFixture(t)
+	var b bytes.Buffer
+	b.WriteString("# Code generated by go test ./internal/ratelimit -update from\n")
+	b.WriteString("# tests/fixtures/rate-limit-rules.json. DO NOT EDIT.\n")
+	for _, c := range accept {
+		var expressions []string
+		for _, r := range c.config(t).Ruleset() {
+			expressions = append(expressions, strconv.Quote(r.Expression))
+		}
+		fmt.Fprintf(&b, "\n# Validate accepts %s\n", c.Name)
+		writeRun(t, &b, "rate_limit_accepted_"+c.Name, c.RateLimit)
+		b.WriteString("\n  assert {\n")
+		fmt.Fprintf(&b, "    condition     = [for r in cloudflare_ruleset.rate_limit[0].rules : r.expression] == [%s]\n", strings.Join(expressions, ", "))
+		b.WriteString("    error_message = \"Rate limit rules should be planned in rate_limit.rules order\"\n  }\n}\n")
+	}
+	for _, c := range reject {
+		fmt.Fprintf(&b, "\n# Validate rejects %s: %s\n", c.Name, c.Error)
+		writeRun(t, &b, "rate_limit_rejected_"+c.Name, c.RateLimit)
+		b.WriteString("\n  expect_failures = [\n    var.rate_limit,\n  ]\n}\n")
+	}
+
+	if *update {
+		if err := os.WriteFile(validationTests, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(validationTests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, b.Bytes()) {
+		t.Errorf("%s is out of date with tests/fixtures/rate-limit-rules.json; run go test ./internal/ratelimit -update", validationTests)
+	}
+}
+
+// writeRun opens a run block planning the module with rateLimit; the caller
+// closes it.
+func writeRun(t *testing.T, b *bytes.Buffer, name string, rateLimit json.RawMessage) {
+	t.Helper()
+	v, err := decodeOrdered(json.NewDecoder(bytes.NewReader(rateLimit)))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	fmt.Fprintf(b, "run %q {\n  variables {\n", name)
+	b.WriteString("    cloudflare_account_id = \"test-account-id\"\n")
+	b.WriteString("    cloudflare_zone_id    = \"test-zone-id\"\n")
+	b.WriteString("    environment           = \"test\"\n")
+	b.WriteString("    rate_limit = ")
+	writeHCL(b, v, "    ")
+	b.WriteString("\n  }\n\n  module {\n    source = \"../\"\n  }\n\n  command = plan\n")
+}
+
+// object keeps the fixture's key order, so the generated cases read like
+// the fixture.
+type object []struct {
+	key   string
+	value any
+}
+
+func decodeOrdered(d *json.Decoder) (any, error) {
+	d.UseNumber()
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var o object
+		for d.More() {
+			key, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, struct {
+				key   string
+				value any
+			}{key.(string), v})
+		}
+		_, err = d.Token()
+		return o, err
+	case json.Delim('['):
+		list := []any{}
+		for d.More() {
+			v, err := decodeOrdered(d)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		_, err = d.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// writeHCL writes v as terraform fmt would: attributes on one line aligned
+// in runs, objects and lists of objects across lines.
+func writeHCL(b *bytes.Buffer, v any, indent string) {
+	switch v := v.(type) {
+	case object:
+		b.WriteString("{\n")
+		for i := 0; i < len(v); {
+			j, width := i, 0
+			for ; j < len(v) && inline(v[j].value); j++ {
+				width = max(width, len(v[j].key))
+			}
+			for ; i < j; i++ {
+				fmt.Fprintf(b, "%s  %-*s = ", indent, width, v[i].key)
+				writeHCL(b, v[i].value, indent+"  ")
+				b.WriteString("\n")
+			}
+			if i < len(v) {
+				fmt.Fprintf(b, "%s  %s = ", indent, v[i].key)
+				writeHCL(b, v[i].value, indent+"  ")
+				b.WriteString("\n")
+				i++
+			}
+		}
+		b.WriteString(indent + "}")
+	case []any:
+		if inline(v) {
+			b.WriteString("[")
+			for i, e := range v {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				writeHCL(b, e, indent)
+			}
+			b.WriteString("]")
+			return
+		}
+		b.WriteString("[\n")
+		for _, e := range v {
+			b.WriteString(indent + "  ")
+			writeHCL(b, e, indent+"  ")
+			b.WriteString(",\n")
+		}
+		b.WriteString(indent + "]")
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case nil:
+		b.WriteString("null")
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+func inline(v any) bool {
+	switch v := v.(type) {
+	case object:
+		return false
+	case []any:
+		for _, e := range v {
+			if !inline(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package ratelimit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report is what Simulate found: per rule, how many requests the action
+// would have applied to, which clients and for how long, and where.
+type Report struct {
+	Requests int          `json:"requests"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Rules    []RuleReport `json:"rules"`
+}
+
+// RuleReport is one rule's share of a Report.
+type RuleReport struct {
+	Rule Rule `json:"rule"`
+	// Matched counts requests the rule's expression matched; Counted those
+	// that counted towards its rate; Mitigated those its action applied to.
+	Matched   int `json:"matched"`
+	Counted   int `json:"counted"`
+	Mitigated int `json:"mitigated"`
+	// Clients are the counters that went over the rate, most mitigated
+	// requests first.
+	Clients []Client `json:"clients"`
+	// Colos sum Clients by data center.
+	Colos []Colo `json:"colos"`
+}
+
+// Client is one counter, a set of characteristic values, that went over a
+// rule's rate.
+type Client struct {
+	// Key holds the characteristic values other than cf.colo.id, in the
+	// rule's order, separated by spaces.
+	Key  string `json:"key"`
+	Colo string `json:"colo"`
+	// Episodes counts the times the counter went over the rate; each
+	// mitigates the client for the rule's mitigation_timeout, and Seconds
+	// adds them up.
+	Episodes  int       `json:"episodes"`
+	Seconds   int       `json:"seconds"`
+	Mitigated int       `json:"mitigated"`
+	First     time.Time `json:"first"`
+}
+
+// Colo sums a rule's clients in one data center.
+type Colo struct {
+	Colo      string `json:"colo"`
+	Clients   int    `json:"clients"`
+	Episodes  int    `json:"episodes"`
+	Seconds   int    `json:"seconds"`
+	Mitigated int    `json:"mitigated"`
+}
+
+// Simulate replays reqs, in time order, against rules as Cloudflare applies
+// them, so thresholds can be tuned before an apply. Each rule counts the
+// requests its counting expression (or else its expression) matches, per
+// distinct set of characteristic values, over a sliding window of period
+// seconds. A matching request that takes a counter over requests_per_period
+// starts a mitigation: for mitigation_timeout seconds the action applies to
+// every request the expression matches with those values, after which the
+// counter starts again from zero. A rule whose action is not log ends the
+// request, so later rules don't see it. Disabled rules are reported but never
+// match.
+//
+// Cloudflare counts per data center and approximates the window, so the
+// numbers are an estimate, close for steady traffic and exact in neither
+// direction.
+func Simulate(rules []Rule, reqs []Request) (*Report, error) {
+	sims := make([]*ruleSim, len(rules))
+	for i, r := range rules {
+		s, err := newRuleSim(r)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		sims[i] = s
+	}
+
+	for i := range reqs {
+		r := &reqs[i]
+		for _, s := range sims {
+			if s.request(r) && s.rule.Action != "log" {
+				break
+			}
+		}
+	}
+
+	rep := &Report{Requests: len(reqs), Rules: []RuleReport{}}
+	if len(reqs) > 0 {
+		rep.From, rep.To = reqs[0].Time, reqs[len(reqs)-1].Time
+	}
+	for _, s := range sims {
+		rep.Rules = append(rep.Rules, s.report())
+	}
+	return rep, nil
+}
+
+type ruleSim struct {
+	rule    Rule
+	match   *Expr
+	count   *Expr // nil counts what match matches
+	period  time.Duration
+	values  []func(*Request) string
+	colo    int // index of cf.colo.id in values
+	windows map[string][]time.Time
+	until   map[string]time.Time
+	clients map[string]*Client
+	rep     RuleReport
+}
+
+func newRuleSim(r Rule) (*ruleSim, error) {
+	s := &ruleSim{
+		rule:    r,
+		period:  time.Duration(r.Period) * time.Second,
+		colo:    -1,
+		windows: map[string][]time.Time{},
+		until:   map[string]time.Time{},
+		clients: map[string]*Client{},
+		rep:     RuleReport{Rule: r, Clients: []Client{}, Colos: []Colo{}},
+	}
+	if !r.Enabled {
+		return s, nil
+	}
+	var err error
+	if s.match, err = Compile(r.Expression); err != nil {
+		return nil, err
+	}
+	if r.CountingExpression != "" {
+		if s.count, err = Compile(r.CountingExpression); err != nil {
+			return nil, fmt.Errorf("counting_expression: %w", err)
+		}
+	}
+	for i, c := range r.Characteristics {
+		get, err := characteristicValue(c)
+		if err != nil {
+			return nil, err
+		}
+		if c == "cf.colo.id" {
+			s.colo = i
+		}
+		s.values = append(s.values, get)
+	}
+	return s, nil
+}
+
+var mapCharacteristic = regexp.MustCompile(`^(http\.request\.(?:headers|cookies|uri\.args))\["([^"]+)"\]$`)
+
+// characteristicValue reads characteristic c from a request. A log has no
+// unique visitor ID and no request body, so those can't be simulated.
+func characteristicValue(c string) (func(*Request) string, error) {
+	if c == "cf.colo.id" {
+		return func(r *Request) string { return r.Colo }, nil
+	}
+	if c == "ip.src" {
+		return func(r *Request) string { return r.IP }, nil
+	}
+	f, ok := fields[c]
+	if m := mapCharacteristic.FindStringSubmatch(c); m != nil {
+		f, ok = mapField(m[1], m[2])
+	}
+	if !ok {
+		return nil, fmt.Errorf("characteristic %q can't be read from a traffic log", c)
+	}
+	return func(r *Request) string {
+		v := f.get(r)
+		if v.kind == kindInt {
+			return strconv.FormatInt(v.n, 10)
+		}
+		return v.s
+	}, nil
+}
+
+// request runs r through the rule and reports whether the action applied.
+func (s *ruleSim) request(r *Request) bool {
+	if s.match == nil {
+		return false
+	}
+	matched := s.match.Match(r)
+	counts := matched
+	if s.count != nil {
+		counts = s.count.Match(r)
+	}
+	if s.rule.RequestsToOrigin && r.Cached {
+		counts = false
+	}
+	if !matched && !counts {
+		return false
+	}
+
+	values := make([]string, len(s.values))
+	for i, get := range s.values {
+		values[i] = get(r)
+	}
+	key := strings.Join(values, "\x00")
+	if matched {
+		s.rep.Matched++
+		if r.Time.Before(s.until[key]) {
+			s.mitigate(key, values, r, false)
+			return true
+		}
+	}
+	if !counts {
+		return false
+	}
+	s.rep.Counted++
+	w := s.windows[key]
+	for len(w) > 0 && !w[0].After(r.Time.Add(-s.period)) {
+		w = w[1:]
+	}
+	w = append(w, r.Time)
+	if !matched || len(w) <= s.rule.RequestsPerPeriod {
+		s.windows[key] = w
+		return false
+	}
+	delete(s.windows, key)
+	s.until[key] = r.Time.Add(time.Duration(s.rule.MitigationTimeout) * time.Second)
+	s.mitigate(key, values, r, true)
+	return true
+}
+
+func (s *ruleSim) mitigate(key string, values []string, r *Request, start bool) {
+	s.rep.Mitigated++
+	c, ok := s.clients[key]
+	if !ok {
+		c = &Client{First: r.Time}
+		var rest []string
+		for i, v := range values {
+			if i == s.colo {
+				c.Colo = v
+			} else {
+				rest = append(rest, v)
+			}
+		}
+		c.Key = strings.Join(rest, " ")
+		s.clients[key] = c
+	}
+	c.Mitigated++
+	if start {
+		c.Episodes++
+		c.Seconds += s.rule.MitigationTimeout
+	}
+}
+
+func (s *ruleSim) report() RuleReport {
+	colos := map[string]*Colo{}
+	for _, c := range s.clients {
+		s.rep.Clients = append(s.rep.Clients, *c)
+		col, ok := colos[c.Colo]
+		if !ok {
+			col = &Colo{Colo: c.Colo}
+			colos[c.Colo] = col
+		}
+		col.Clients++
+		col.Episodes += c.Episodes
+		col.Seconds += c.Seconds
+		col.Mitigated += c.Mitigated
+	}
+	sort.Slice(s.rep.Clients, func(i, j int) bool {
+		a, b := s.rep.Clients[i], s.rep.Clients[j]
+		if a.Mitigated != b.Mitigated {
+			return a.Mitigated > b.Mitigated
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Colo < b.Colo
+	})
+	for _, col := range colos {
+		s.rep.Colos = append(s.rep.Colos, *col)
+	}
+	sort.Slice(s.rep.Colos, func(i, j int) bool {
+		a, b := s.rep.Colos[i], s.rep.Colos[j]
+		if a.Mitigated != b.Mitigated {
+			return a.Mitigated > b.Mitigated
+		}
+		return a.Colo < b.Colo
+	})
+	return s.rep
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTraffic(t *testing.T, name, format string) []Request {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/fixtures/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := ReadLog(bytes.NewReader(data), format)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return reqs
+}
+
+// The two traffic fixtures log the same requests, the Logpush one out of
+// order.
+func TestReadLog(t *testing.T) {
+	reqs := readTraffic(t, "rate-limit-traffic.jsonl", "auto")
+	if csv := readTraffic(t, "rate-limit-traffic.csv", "auto"); !reflect.DeepEqual(reqs, csv) {
+		t.Errorf("Logpush and CSV logs differ:\n%+v\n%+v", reqs, csv)
+	}
+	if len(reqs) != 10 {
+		t.Fatalf("read %d requests, want 10", len(reqs))
+	}
+	for i := 1; i < len(reqs); i++ {
+		if reqs[i].Time.Before(reqs[i-1].Time) {
+			t.Errorf("request %d is out of order", i)
+		}
+	}
+	if r := reqs[1]; r.Path != "/login" || r.Query != "next=/cart" || r.Colo != "LHR" || r.Country != "GB" {
+		t.Errorf("reqs[1] = %+v", r)
+	}
+	if r := reqs[5]; r.Headers["x-api-key"] != "k1" || !r.Cached {
+		t.Errorf("reqs[5] = %+v", r)
+	}
+
+	data, _ := os.ReadFile("../../tests/fixtures/rate-limit-traffic.jsonl")
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(data)
+	w.Close()
+	if got, err := ReadLog(&gz, "json"); err != nil || !reflect.DeepEqual(got, reqs) {
+		t.Errorf("gzipped log: %v", err)
+	}
+}
+
+func TestReadLogErrors(t *testing.T) {
+	for name, c := range map[string]struct{ log, format, want string }{
+		"no path column": {"timestamp,ip\n2026-03-01T12:00:00Z,192.0.2.1\n", "csv", "no path column"},
+		"bad timestamp":  {"timestamp,ip,path\nyesterday,192.0.2.1,/\n", "auto", "line 2: timestamp"},
+		"bad status":     {"timestamp,ip,path,status\n1772366400,192.0.2.1,/,ok\n", "csv", `status "ok"`},
+		"no ClientIP":    {`{"EdgeStartTimestamp":1772366400}`, "auto", "record 1: no ClientIP"},
+		"not JSON":       {"{\"ClientIP\":\n", "json", "record 1"},
+		"unknown format": {"", "parquet", "must be auto, json or csv"},
+	} {
+		_, err := ReadLog(strings.NewReader(c.log), c.format)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, c.want)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	reqs := readTraffic(t, "rate-limit-traffic.jsonl", "json")
+	login := Rule{
+		Description:       "Login attempts per IP",
+		Expression:        `(http.request.uri.path eq "/login")`,
+		Characteristics:   []string{"cf.colo.id", "ip.src"},
+		Action:            "block",
+		Period:            10,
+		RequestsPerPeriod: 3,
+		MitigationTimeout: 60,
+		Enabled:           true,
+	}
+	api := Rule{
+		Description:       "API calls per key",
+		Expression:        `starts_with(http.request.uri.path, "/api/")`,
+		Characteristics:   []string{"cf.colo.id", `http.request.headers["x-api-key"]`},
+		Action:            "block",
+		Period:            60,
+		RequestsPerPeriod: 1,
+		MitigationTimeout: 120,
+		Enabled:           true,
+	}
+	rep, err := Simulate([]Rule{login, api}, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Requests != 10 || !rep.From.Equal(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)) || !rep.To.Equal(time.Date(2026, 3, 1, 12, 1, 7, 0, time.UTC)) {
+		t.Errorf("report covers %d requests from %s to %s", rep.Requests, rep.From, rep.To)
+	}
+
+	// 192.0.2.10 logs in six times: the fourth attempt within 10s starts a
+	// minute's block that also catches the fifth, and the sixth comes after.
+	got := rep.Rules[0]
+	want := RuleReport{
+		Rule:      login,
+		Matched:   8,
+		Counted:   7,
+		Mitigated: 2,
+		Clients:   []Client{{Key: "192.0.2.10", Colo: "AMS", Episodes: 1, Seconds: 60, Mitigated: 2, First: time.Date(2026, 3, 1, 12, 0, 6, 0, time.UTC)}},
+		Colos:     []Colo{{Colo: "AMS", Clients: 1, Episodes: 1, Seconds: 60, Mitigated: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("login rule:\n got %+v\nwant %+v", got, want)
+	}
+
+	// Two IPs share the key, so the second call is over.
+	if got := rep.Rules[1]; got.Mitigated != 1 || len(got.Clients) != 1 || got.Clients[0].Key != "k1" || got.Clients[0].Colo != "LHR" {
+		t.Errorf("api rule: %+v", got)
+	}
+
+	// The first call was a cache hit, which requests_to_origin doesn't count.
+	api.RequestsToOrigin = true
+	if rep, _ := Simulate([]Rule{api}, reqs); rep.Rules[0].Counted != 1 || rep.Rules[0].Mitigated != 0 {
+		t.Errorf("requests_to_origin: %+v", rep.Rules[0])
+	}
+
+	// Counting only 401s, the third one within 10s, at 12:00:04, starts the
+	// block and catches the two after it.
+	counting := login
+	counting.RequestsPerPeriod = 2
+	counting.CountingExpression = "http.response.code eq 401"
+	if rep, _ := Simulate([]Rule{counting}, reqs); rep.Rules[0].Mitigated != 3 || rep.Rules[0].Counted != 3 {
+		t.Errorf("counting_expression: %+v", rep.Rules[0])
+	}
+}
+
+func TestSimulateRuleOrder(t *testing.T) {
+	reqs := readTraffic(t, "rate-limit-traffic.jsonl", "json")
+	all := Rule{Expression: "true", Characteristics: []string{"cf.colo.id"}, Action: "log", Period: 60, RequestsPerPeriod: 2, MitigationTimeout: 60, Enabled: true}
+	after := all
+	after.RequestsPerPeriod = 100
+
+	// A log rule lets requests through to the next rule; any other action
+	// ends them.
+	rep, err := Simulate([]Rule{all, after}, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Rules[1].Matched != 10 {
+		t.Errorf("after a log rule, the next rule matched %d requests, want 10", rep.Rules[1].Matched)
+	}
+	all.Action = "managed_challenge"
+	rep, _ = Simulate([]Rule{all, after}, reqs)
+	if n := rep.Rules[0].Mitigated; rep.Rules[1].Matched != 10-n || n == 0 {
+		t.Errorf("after %d challenged requests, the next rule matched %d", n, rep.Rules[1].Matched)
+	}
+
+	all.Enabled = false
+	rep, _ = Simulate([]Rule{all}, reqs)
+	if rep.Rules[0].Matched != 0 {
+		t.Errorf("a disabled rule matched %d requests", rep.Rules[0].Matched)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	for _, r := range []Rule{
+		{Expression: "http.request.body.raw contains \"x\"", Characteristics: DefaultCharacteristics, Enabled: true},
+		{Expression: "true", CountingExpression: "http.host eq", Characteristics: DefaultCharacteristics, Enabled: true},
+		{Expression: "true", Characteristics: []string{"cf.colo.id", "cf.unique_visitor_id"}, Enabled: true},
+	} {
+		if _, err := Simulate([]Rule{r}, nil); err == nil || !strings.HasPrefix(err.Error(), "rules[0]: ") {
+			t.Errorf("%+v: got %v", r, err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package ratelimit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is one logged request, with the fields rules can match and count
+// by.
+type Request struct {
+	Time      time.Time
+	IP        string
+	Host      string
+	Method    string
+	Path      string
+	Query     string // without the leading "?"
+	Colo      string // data center code, e.g. AMS
+	Country   string // ISO code, as ip.geoip.country has it
+	ASN       int
+	Status    int
+	UserAgent string
+	// Cached is set when the cache answered and the origin never saw the
+	// request.
+	Cached  bool
+	Headers map[string]string // lowercase names
+	Cookies map[string]string
+	JA3     string
+	JA4     string
+}
+
+func (r *Request) uri() string {
+	if r.Query == "" {
+		return r.Path
+	}
+	return r.Path + "?" + r.Query
+}
+
+func (r *Request) arg(name string) string {
+	q, _ := url.ParseQuery(r.Query)
+	return q.Get(name)
+}
+
+// ReadLog reads a traffic log: Cloudflare Logpush http_requests records as
+// JSON lines (or a JSON array), or CSV with a header row. format is "json",
+// "csv" or "auto" to tell them apart by the first byte. Gzipped logs, as
+// Logpush writes them, are read as they are. Requests come back in time
+// order.
+func ReadLog(r io.Reader, format string) ([]Request, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	if format == "auto" || format == "" {
+		format = "csv"
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				break
+			}
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				if c == '{' || c == '[' {
+					format = "json"
+				}
+				br.UnreadByte()
+				break
+			}
+		}
+	}
+
+	var reqs []Request
+	var err error
+	switch format {
+	case "json":
+		reqs, err = readLogpush(br)
+	case "csv":
+		reqs, err = readCSV(br)
+	default:
+		return nil, fmt.Errorf("log format %q must be auto, json or csv", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(reqs, func(i, j int) bool { return reqs[i].Time.Before(reqs[j].Time) })
+	return reqs, nil
+}
+
+// logpushRecord holds the http_requests fields the simulator uses. Headers
+// and cookies are there when the Logpush job logs them as custom fields.
+type logpushRecord struct {
+	EdgeStartTimestamp     json.RawMessage   `json:"EdgeStartTimestamp"`
+	ClientIP               string            `json:"ClientIP"`
+	ClientRequestHost      string            `json:"ClientRequestHost"`
+	ClientRequestMethod    string            `json:"ClientRequestMethod"`
+	ClientRequestPath      string            `json:"ClientRequestPath"`
+	ClientRequestURI       string            `json:"ClientRequestURI"`
+	ClientRequestUserAgent string            `json:"ClientRequestUserAgent"`
+	ClientCountry          string            `json:"ClientCountry"`
+	ClientASN              int               `json:"ClientASN"`
+	EdgeColoCode           string            `json:"EdgeColoCode"`
+	EdgeResponseStatus     int               `json:"EdgeResponseStatus"`
+	CacheCacheStatus       string            `json:"CacheCacheStatus"`
+	RequestHeaders         map[string]string `json:"RequestHeaders"`
+	Cookies                map[string]string `json:"Cookies"`
+	JA3Hash                string            `json:"JA3Hash"`
+	JA4                    string            `json:"JA4"`
+}
+
+func readLogpush(r *bufio.Reader) ([]Request, error) {
+	var reqs []Request
+	add := func(n int, rec logpushRecord) error {
+		t, err := parseTimestamp(strings.Trim(string(rec.EdgeStartTimestamp), `"`))
+		if err != nil {
+			return fmt.Errorf("record %d: EdgeStartTimestamp: %w", n, err)
+		}
+		if rec.ClientIP == "" {
+			return fmt.Errorf("record %d: no ClientIP", n)
+		}
+		path, query, _ := strings.Cut(rec.ClientRequestURI, "?")
+		if rec.ClientRequestPath != "" {
+			path = rec.ClientRequestPath
+		}
+		headers := map[string]string{}
+		for k, v := range rec.RequestHeaders {
+			headers[strings.ToLower(k)] = v
+		}
+		if rec.Cookies == nil {
+			rec.Cookies = map[string]string{}
+		}
+		reqs = append(reqs, Request{
+			Time:      t,
+			IP:        rec.ClientIP,
+			Host:      rec.ClientRequestHost,
+			Method:    rec.ClientRequestMethod,
+			Path:      path,
+			Query:     query,
+			Colo:      rec.EdgeColoCode,
+			Country:   strings.ToUpper(rec.ClientCountry),
+			ASN:       rec.ClientASN,
+			Status:    rec.EdgeResponseStatus,
+			UserAgent: rec.ClientRequestUserAgent,
+			Cached:    cached(rec.CacheCacheStatus),
+			Headers:   headers,
+			Cookies:   rec.Cookies,
+			JA3:       rec.JA3Hash,
+			JA4:       rec.JA4,
+		})
+		return nil
+	}
+
+	d := json.NewDecoder(r)
+	if c, _ := r.Peek(1); len(c) == 1 && c[0] == '[' {
+		var recs []logpushRecord
+		if err := d.Decode(&recs); err != nil {
+			return nil, fmt.Errorf("reading log: %w", err)
+		}
+		for i, rec := range recs {
+			if err := add(i+1, rec); err != nil {
+				return nil, err
+			}
+		}
+		return reqs, nil
+	}
+	for n := 1; ; n++ {
+		var rec logpushRecord
+		if err := d.Decode(&rec); errors.Is(err, io.EOF) {
+			return reqs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if err := add(n, rec); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// cached reports whether a CacheCacheStatus (or CSV cache column) means the
+// origin was not reached.
+func cached(status string) bool {
+	switch strings.ToLower(status) {
+	case "hit", "stale", "updating", "true":
+		return true
+	}
+	return false
+}
+
+// readCSV reads CSV with a header row. timestamp, ip and path are required;
+// host, method, query, colo, country, asn, status, user_agent, cache, ja3 and
+// ja4 are optional, and header.NAME and cookie.NAME columns fill headers and
+// cookies. A path column holding a query string is split.
+func readCSV(r io.Reader) ([]Request, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	head, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range head {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "ip", "path"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", name)
+		}
+	}
+
+	var reqs []Request
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return reqs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		t, err := parseTimestamp(get("timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: timestamp: %w", line, err)
+		}
+		req := Request{
+			Time:      t,
+			IP:        get("ip"),
+			Host:      get("host"),
+			Method:    get("method"),
+			Query:     get("query"),
+			Colo:      get("colo"),
+			Country:   strings.ToUpper(get("country")),
+			UserAgent: get("user_agent"),
+			Cached:    cached(get("cache")),
+			Headers:   map[string]string{},
+			Cookies:   map[string]string{},
+			JA3:       get("ja3"),
+			JA4:       get("ja4"),
+		}
+		if req.IP == "" {
+			return nil, fmt.Errorf("line %d: no ip", line)
+		}
+		path, query, ok := strings.Cut(get("path"), "?")
+		req.Path = path
+		if ok {
+			req.Query = query
+		}
+		for _, name := range []string{"asn", "status"} {
+			if s := get(name); s != "" {
+				n, err := strconv.Atoi(s)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s %q is not a number", line, name, s)
+				}
+				if name == "asn" {
+					req.ASN = n
+				} else {
+					req.Status = n
+				}
+			}
+		}
+		for name, i := range col {
+			if i >= len(rec) || rec[i] == "" {
+				continue
+			}
+			if h, ok := strings.CutPrefix(name, "header."); ok {
+				req.Headers[h] = rec[i]
+			} else if c, ok := strings.CutPrefix(strings.TrimSpace(head[i]), "cookie."); ok {
+				req.Cookies[c] = rec[i]
+			}
+		}
+		reqs = append(reqs, req)
+	}
+}
+
+// parseTimestamp takes RFC 3339, or Unix time in seconds, milliseconds or
+// nanoseconds as Logpush's timestamp_format options write it.
+func parseTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC 3339 nor Unix time", s)
+	}
+	switch {
+	case n > 1e17:
+		return time.Unix(0, n).UTC(), nil
+	case n > 1e11:
+		return time.UnixMilli(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
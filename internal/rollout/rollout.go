@@ -0,0 +1,102 @@
+// Package rollout decides which clients see the maintenance page when
+// rollout_percentage is below 100. Keep in sync with the rollout functions in
+// worker.js; both are tested against tests/fixtures/rollout-buckets.json.
+package rollout
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Buckets is the number of buckets clients are spread over, so a percentage
+// maps to whole buckets.
+const Buckets = 100
+
+// CookieName pins a client to its bucket, so it stays on the same side of the
+// rollout when its IP changes. Its value is the bucket and an HMAC-SHA256 of
+// it under the worker's ROLLOUT_COOKIE_KEY, so a client can't pick a bucket.
+const CookieName = "maintenance_bucket"
+
+// Bucket hashes a client key (its IP) to a bucket with 32-bit FNV-1a.
+func Bucket(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % Buckets)
+}
+
+// Included reports whether a client in bucket gets the maintenance page.
+// Raising the percentage only adds buckets, so clients already in
+// maintenance stay there as the rollout grows.
+func Included(bucket, percentage int) bool {
+	return bucket < percentage
+}
+
+// Decision is the outcome for one request.
+type Decision struct {
+	Bucket   int
+	Included bool
+	// SetCookie is true when the bucket did not come from the cookie and the
+	// response should pin it.
+	SetCookie bool
+}
+
+// Decide buckets a request from its cookie, falling back to the client IP.
+// Only cookies signed with key count, and without a key no cookie is read or
+// set. At 0 and 100 percent every client gets the same answer, so no cookie
+// is set.
+func Decide(cookie, clientIP string, percentage int, key []byte) Decision {
+	if percentage <= 0 || percentage >= Buckets {
+		return Decision{Included: percentage >= Buckets}
+	}
+	if bucket, ok := parseBucket(cookie, key); ok {
+		return Decision{Bucket: bucket, Included: Included(bucket, percentage)}
+	}
+	bucket := Bucket(clientIP)
+	return Decision{Bucket: bucket, Included: Included(bucket, percentage), SetCookie: len(key) > 0}
+}
+
+// Sign is the cookie value pinning bucket: the bucket, a dot and the hex
+// HMAC-SHA256 of the bucket under key.
+func Sign(bucket int, key []byte) string {
+	b := strconv.Itoa(bucket)
+	return b + "." + hex.EncodeToString(signature(b, key))
+}
+
+func signature(bucket string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(bucket))
+	return mac.Sum(nil)
+}
+
+// parseBucket accepts plain digits with a valid signature, like the worker's
+// check.
+func parseBucket(value string, key []byte) (int, bool) {
+	b, sig, ok := strings.Cut(value, ".")
+	if len(key) == 0 || !ok || b == "" || strings.Trim(b, "0123456789") != "" {
+		return 0, false
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, signature(b, key)) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(b)
+	if err != nil || n >= Buckets {
+		return 0, false
+	}
+	return n, true
+}
+
+// CookieValue reads the bucket cookie from a request's Cookie header.
+func CookieValue(header string) string {
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && name == CookieName {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The same table drives the rollout functions in tests/unit/worker.test.js.
+func TestDecideSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/rollout-buckets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		CookieKey string         `json:"cookie_key"`
+		Buckets   map[string]int `json:"buckets"`
+		Cases     []struct {
+			Name          string `json:"name"`
+			Cookie        string `json:"cookie"`
+			ClientIP      string `json:"client_ip"`
+			Percentage    int    `json:"percentage"`
+			WantBucket    int    `json:"want_bucket"`
+			WantIncluded  bool   `json:"want_included"`
+			WantSetCookie bool   `json:"want_set_cookie"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range fixture.Buckets {
+		if got := Bucket(key); got != want {
+			t.Errorf("Bucket(%q) = %d, want %d", key, got, want)
+		}
+	}
+	for _, tc := range fixture.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := Decide(CookieValue(tc.Cookie), tc.ClientIP, tc.Percentage, []byte(fixture.CookieKey))
+			want := Decision{Bucket: tc.WantBucket, Included: tc.WantIncluded, SetCookie: tc.WantSetCookie}
+			if got != want {
+				t.Errorf("Decide = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+var testKey = []byte("rollout-test-key")
+
+// syntheticIPs spreads clients over the v4 and v6 documentation ranges plus
+// private space, roughly like real traffic.
+func syntheticIPs(n int) []string {
+	ips := make([]string, 0, n)
+	for i := 0; len(ips) < n; i++ {
+		switch i % 3 {
+		case 0:
+			ips = append(ips, fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff))
+		case 1:
+			ips = append(ips, fmt.Sprintf("2001:db8::%x:%x", i>>16, i&0xffff))
+		default:
+			ips = append(ips, fmt.Sprintf("198.51.%d.%d", i>>8&0xff, i&0xff))
+		}
+	}
+	return ips
+}
+
+func TestBucketDistribution(t *testing.T) {
+	const clients = 20000
+	counts := make([]int, Buckets)
+	for _, ip := range syntheticIPs(clients) {
+		counts[Bucket(ip)]++
+	}
+
+	// Chi-squared with 99 degrees of freedom; 148.2 is the 0.1% critical value.
+	expected := float64(clients) / Buckets
+	chi2 := 0.0
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+	if chi2 > 148.2 {
+		t.Errorf("bucket distribution is not uniform: chi2 = %.1f, counts = %v", chi2, counts)
+	}
+}
+
+func TestIncludedShareMatchesPercentage(t *testing.T) {
+	ips := syntheticIPs(20000)
+	for _, pct := range []int{1, 10, 25, 50, 75, 99} {
+		included := 0
+		for _, ip := range ips {
+			if Decide("", ip, pct, testKey).Included {
+				included++
+			}
+		}
+		share := float64(included) / float64(len(ips)) * 100
+		// Four standard deviations of a binomial share, so the test is not flaky.
+		p := float64(pct) / 100
+		tolerance := 4 * math.Sqrt(p*(1-p)/float64(len(ips))) * 100
+		if math.Abs(share-float64(pct)) > tolerance {
+			t.Errorf("%d%%: %.2f%% of clients included, want within %.2f", pct, share, tolerance)
+		}
+	}
+}
+
+func TestDecisionIsSticky(t *testing.T) {
+	ips := syntheticIPs(5000)
+	for _, ip := range ips {
+		first := Decide("", ip, 25, testKey)
+		// Same IP again, and the same client coming back from a new IP with
+		// the cookie the first response set.
+		if again := Decide("", ip, 25, testKey); again != first {
+			t.Fatalf("%s: %+v then %+v", ip, first, again)
+		}
+		cookie := CookieValue(fmt.Sprintf("%s=%s", CookieName, Sign(first.Bucket, testKey)))
+		if moved := Decide(cookie, "192.0.2.99", 25, testKey); moved.Included != first.Included || moved.SetCookie {
+			t.Fatalf("%s: cookie decision %+v, want included=%v", ip, moved, first.Included)
+		}
+	}
+}
+
+func TestRaisingPercentageKeepsIncludedClients(t *testing.T) {
+	for _, ip := range syntheticIPs(5000) {
+		was := false
+		for pct := 0; pct <= 100; pct += 5 {
+			now := Decide("", ip, pct, testKey).Included
+			if was && !now {
+				t.Fatalf("%s left maintenance when the rollout grew to %d%%", ip, pct)
+			}
+			was = now
+		}
+	}
+}
+
+func TestForgedCookieIsIgnored(t *testing.T) {
+	// 192.0.2.1 hashes to bucket 76, outside a 25% rollout.
+	for _, cookie := range []string{
+		"5",
+		"5." + strings.Repeat("0", 64),
+		Sign(5, []byte("another-key")),
+		Sign(5, testKey)[:20],
+		strings.Replace(Sign(5, testKey), "5.", "6.", 1),
+	} {
+		if got := Decide(cookie, "192.0.2.1", 25, testKey); got.Bucket != 76 || got.Included || !got.SetCookie {
+			t.Errorf("cookie %q: %+v", cookie, got)
+		}
+	}
+	if got := Decide(Sign(5, testKey), "192.0.2.1", 25, testKey); got.Bucket != 5 || !got.Included || got.SetCookie {
+		t.Errorf("signed cookie: %+v", got)
+	}
+}
+
+func TestNoKeyIgnoresCookies(t *testing.T) {
+	// Without a key a cookie can't be checked, so none is trusted or set.
+	for _, cookie := range []string{"", "5", Sign(5, nil)} {
+		if got := Decide(cookie, "192.0.2.1", 25, nil); got.Bucket != 76 || got.Included || got.SetCookie {
+			t.Errorf("cookie %q: %+v", cookie, got)
+		}
+	}
+}
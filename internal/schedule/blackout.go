@@ -0,0 +1,237 @@
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// BlackoutSpec is one entry of the blackouts variable. Start and End are
+// dates or RFC3339 times; dates cover whole days in Timezone, End included.
+// An empty End means the Start date only.
+type BlackoutSpec struct {
+	Name     string `json:"name"`
+	Start    string `json:"start"`
+	End      string `json:"end,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Blackout is a period in which no scheduled window may start, [Start, End).
+type Blackout struct {
+	Name  string    `json:"name"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Overlaps reports whether the window [start, end) shares any time with b.
+// An empty window overlaps when its start is inside b.
+func (b Blackout) Overlaps(start, end time.Time) bool {
+	if !end.After(start) {
+		return !start.Before(b.Start) && start.Before(b.End)
+	}
+	return start.Before(b.End) && b.Start.Before(end)
+}
+
+// FindBlackout returns the first of blackouts that overlaps [start, end).
+func FindBlackout(blackouts []Blackout, start, end time.Time) (Blackout, bool) {
+	for _, b := range blackouts {
+		if b.Overlaps(start, end) {
+			return b, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// ResolveBlackouts turns specs into periods. A date starts at midnight in the
+// spec's timezone, so a day is 23 or 25 hours long when the clocks change.
+func ResolveBlackouts(specs []BlackoutSpec) ([]Blackout, error) {
+	var out []Blackout
+	for _, s := range specs {
+		tz := s.Timezone
+		if tz == "" {
+			tz = "UTC"
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %s: timezone %q: %w", s.Name, tz, err)
+		}
+		start, startDate, err := blackoutTime(s.Start, loc)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %s: start: %w", s.Name, err)
+		}
+		endText := s.End
+		if endText == "" {
+			if !startDate {
+				return nil, fmt.Errorf("blackout %s: an RFC3339 start needs an end", s.Name)
+			}
+			endText = s.Start
+		}
+		end, endDate, err := blackoutTime(endText, loc)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %s: end: %w", s.Name, err)
+		}
+		if endDate {
+			end = end.AddDate(0, 0, 1)
+		}
+		b := Blackout{Name: s.Name, Start: start.UTC(), End: end.In(loc).UTC()}
+		if !b.End.After(b.Start) {
+			return nil, fmt.Errorf("blackout %s: end %s is not after start %s", s.Name, endText, s.Start)
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// blackoutTime parses a date (midnight in loc) or an RFC3339 time.
+func blackoutTime(v string, loc *time.Location) (t time.Time, date bool, err error) {
+	if d, err := time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+		return d, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not YYYY-MM-DD or RFC3339", v)
+}
+
+// ParseICal reads the VEVENTs of an iCalendar file as blackouts, named by
+// their SUMMARY. Times may be UTC, carry an IANA TZID or be floating; floating
+// times and dates use the calendar's X-WR-TIMEZONE, else UTC. Cancelled
+// events are skipped. Recurring events are not supported.
+func ParseICal(r io.Reader) ([]Blackout, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+	floating := time.UTC
+	for _, line := range lines {
+		if name, _, value := icalProperty(line); name == "X-WR-TIMEZONE" {
+			if floating, err = time.LoadLocation(value); err != nil {
+				return nil, fmt.Errorf("X-WR-TIMEZONE: %w", err)
+			}
+		}
+	}
+
+	var out []Blackout
+	var ev map[string]string
+	var evParams map[string]map[string]string
+	for _, line := range lines {
+		name, params, value := icalProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			ev, evParams = map[string]string{}, map[string]map[string]string{}
+		case name == "END" && value == "VEVENT":
+			if ev == nil {
+				return nil, fmt.Errorf("END:VEVENT without BEGIN")
+			}
+			b, skip, err := icalEvent(ev, evParams, floating)
+			if err != nil {
+				return nil, err
+			}
+			if !skip {
+				out = append(out, b)
+			}
+			ev = nil
+		case ev != nil:
+			ev[name] = value
+			evParams[name] = params
+		}
+	}
+	return out, nil
+}
+
+func icalEvent(ev map[string]string, params map[string]map[string]string, floating *time.Location) (Blackout, bool, error) {
+	name := icalText(ev["SUMMARY"])
+	if name == "" {
+		name = ev["UID"]
+	}
+	if strings.EqualFold(ev["STATUS"], "CANCELLED") {
+		return Blackout{}, true, nil
+	}
+	if ev["RRULE"] != "" || ev["RDATE"] != "" {
+		return Blackout{}, false, fmt.Errorf("event %q: recurring events are not supported", name)
+	}
+	if ev["DTSTART"] == "" {
+		return Blackout{}, false, fmt.Errorf("event %q: no DTSTART", name)
+	}
+	start, date, err := icalTime(ev["DTSTART"], params["DTSTART"], floating)
+	if err != nil {
+		return Blackout{}, false, fmt.Errorf("event %q: DTSTART: %w", name, err)
+	}
+	var end time.Time
+	switch {
+	case ev["DTEND"] != "":
+		if end, _, err = icalTime(ev["DTEND"], params["DTEND"], floating); err != nil {
+			return Blackout{}, false, fmt.Errorf("event %q: DTEND: %w", name, err)
+		}
+	case ev["DURATION"] != "":
+		return Blackout{}, false, fmt.Errorf("event %q: DURATION is not supported, use DTEND", name)
+	case date:
+		// A date without an end is that one day.
+		end = start.AddDate(0, 0, 1)
+	default:
+		return Blackout{}, false, fmt.Errorf("event %q: no DTEND", name)
+	}
+	b := Blackout{Name: name, Start: start.UTC(), End: end.UTC()}
+	if !b.End.After(b.Start) {
+		return Blackout{}, false, fmt.Errorf("event %q: DTEND is not after DTSTART", name)
+	}
+	return b, false, nil
+}
+
+// icalTime parses a DATE or DATE-TIME value. DTEND dates are exclusive in
+// iCalendar, so both ends of a date are used as they are.
+func icalTime(value string, params map[string]string, floating *time.Location) (time.Time, bool, error) {
+	loc := floating
+	if tz := strings.Trim(params["TZID"], `"`); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, false, fmt.Errorf("TZID %q is not an IANA timezone", tz)
+		}
+	}
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// unfoldICal splits r into content lines, joining folded continuations.
+func unfoldICal(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// icalProperty splits NAME;PARAM=V;...:VALUE.
+func icalProperty(line string) (name string, params map[string]string, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params = map[string]string{}
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.ToUpper(k)] = v
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// icalText undoes TEXT escaping.
+func icalText(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
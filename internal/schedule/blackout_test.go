@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type blackoutFixture struct {
+	Blackouts        []BlackoutSpec `json:"blackouts"`
+	Calendar         string         `json:"calendar"`
+	Resolved         []Blackout     `json:"resolved"`
+	CalendarResolved []Blackout     `json:"calendar_resolved"`
+	Overlaps         []struct {
+		Name     string    `json:"name"`
+		Start    time.Time `json:"start"`
+		End      time.Time `json:"end"`
+		Blackout string    `json:"blackout"`
+	} `json:"overlaps"`
+	Replay struct {
+		replay
+		Blocked []Window `json:"blocked"`
+	} `json:"replay"`
+}
+
+// The same fixture drives the blackout functions in tests/unit/worker.test.js.
+func loadBlackoutFixture(t *testing.T) blackoutFixture {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/fixtures/blackouts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f blackoutFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f blackoutFixture) all(t *testing.T) []Blackout {
+	t.Helper()
+	resolved, err := ResolveBlackouts(f.Blackouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := ParseICal(strings.NewReader(f.Calendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(resolved, cal...)
+}
+
+func TestResolveBlackouts(t *testing.T) {
+	f := loadBlackoutFixture(t)
+	got, err := ResolveBlackouts(f.Blackouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, f.Resolved) {
+		t.Errorf("ResolveBlackouts =\n%+v\nwant\n%+v", got, f.Resolved)
+	}
+
+	for name, spec := range map[string]BlackoutSpec{
+		"bad date":         {Name: "x", Start: "28/11/2025"},
+		"time without end": {Name: "x", Start: "2025-11-28T05:00:00Z"},
+		"end before start": {Name: "x", Start: "2025-12-01", End: "2025-11-28"},
+		"unknown timezone": {Name: "x", Start: "2025-11-28", Timezone: "Mars/Olympus_Mons"},
+	} {
+		if _, err := ResolveBlackouts([]BlackoutSpec{spec}); err == nil {
+			t.Errorf("%s: ResolveBlackouts succeeded", name)
+		}
+	}
+}
+
+func TestParseICal(t *testing.T) {
+	f := loadBlackoutFixture(t)
+	got, err := ParseICal(strings.NewReader(f.Calendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, f.CalendarResolved) {
+		t.Errorf("ParseICal =\n%+v\nwant\n%+v", got, f.CalendarResolved)
+	}
+
+	for name, ev := range map[string]string{
+		"recurring":     "DTSTART:20251128T050000Z\nDTEND:20251128T060000Z\nRRULE:FREQ=YEARLY",
+		"duration":      "DTSTART:20251128T050000Z\nDURATION:PT1H",
+		"windows zone":  "DTSTART;TZID=Eastern Standard Time:20251128T000000\nDTEND;TZID=Eastern Standard Time:20251129T000000",
+		"no end":        "DTSTART:20251128T050000Z",
+		"end first":     "DTSTART:20251128T050000Z\nDTEND:20251128T040000Z",
+		"bad timestamp": "DTSTART:2025-11-28\nDTEND:2025-11-29",
+	} {
+		cal := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:x\n" + ev + "\nEND:VEVENT\nEND:VCALENDAR\n"
+		if _, err := ParseICal(strings.NewReader(cal)); err == nil {
+			t.Errorf("%s: ParseICal succeeded", name)
+		}
+	}
+}
+
+func TestFindBlackout(t *testing.T) {
+	f := loadBlackoutFixture(t)
+	all := f.all(t)
+	for _, tc := range f.Overlaps {
+		b, ok := FindBlackout(all, tc.Start, tc.End)
+		if ok != (tc.Blackout != "") || b.Name != tc.Blackout {
+			t.Errorf("%s: FindBlackout = %q, %v, want %q", tc.Name, b.Name, ok, tc.Blackout)
+		}
+	}
+}
+
+func TestSimulateWithBlackouts(t *testing.T) {
+	f := loadBlackoutFixture(t)
+	r := f.Replay
+	set, err := NewSet(r.Schedules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SetBlackouts(f.all(t))
+
+	got := flatten(set.Simulate(r.From, r.To))
+	if len(got) != len(r.Transitions) {
+		t.Fatalf("%d transitions, want %d:\n%+v", len(got), len(r.Transitions), got)
+	}
+	for i := range got {
+		want := r.Transitions[i]
+		if !got[i].At.Equal(want.At) || got[i].Status != want.Status || got[i].Schedule != want.Schedule ||
+			!got[i].Start.Equal(want.Start) || !got[i].End.Equal(want.End) {
+			t.Errorf("transition %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+
+	var blocked []Window
+	for _, w := range set.Expand(r.From, r.To) {
+		if w.Blackout != "" {
+			blocked = append(blocked, w)
+		}
+	}
+	if !reflect.DeepEqual(blocked, r.Blocked) {
+		t.Errorf("blocked windows =\n%+v\nwant\n%+v", blocked, r.Blocked)
+	}
+}
+
+// A blocked start doesn't hide a later start of the same schedule that is
+// clear of the blackout.
+func TestActiveSkipsBlockedStart(t *testing.T) {
+	set, err := NewSet([]Schedule{{Name: "hourly", Cron: "0 * * * *", Duration: "2h", Timezone: "UTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 4, 6, 9, 30, 0, 0, time.UTC)
+	set.SetBlackouts([]Blackout{{Name: "freeze", Start: at.Add(-3 * time.Hour), End: at.Add(-time.Hour)}})
+	open := set.Active(at)
+	if len(open) != 1 || !open[0].Start.Equal(at.Add(-30*time.Minute)) {
+		t.Errorf("Active = %+v, want the window from 09:00", open)
+	}
+}
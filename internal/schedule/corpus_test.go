@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite tests/cron_validation.tftest.hcl from the cron corpus")
+
+const validationTests = "../../tests/cron_validation.tftest.hcl"
+
+type cronCorpus struct {
+	Accept []string `json:"accept"`
+	Reject []string `json:"reject"`
+}
+
+func loadCorpus(t *testing.T) cronCorpus {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/fixtures/cron-corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c cronCorpus
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// The same corpus drives parseCron in tests/unit/worker.test.js.
+func TestCronCorpus(t *testing.T) {
+	c := loadCorpus(t)
+	for _, expr := range c.Accept {
+		if _, err := ParseCron(expr); err != nil {
+			t.Errorf("accept %q: %v", expr, err)
+		}
+	}
+	for _, expr := range c.Reject {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("reject %q: parsed", expr)
+		}
+	}
+}
+
+// The schedules and watchdog.cron validations in variables.tf are checked by terraform test
+// against cases generated from the corpus. Run with -update after changing
+// the corpus.
+func TestTerraformValidationCases(t *testing.T) {
+	want := terraformValidationCases(loadCorpus(t))
+	if *update {
+		if err := os.WriteFile(validationTests, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(validationTests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date with tests/fixtures/cron-corpus.json; run go test ./internal/schedule -update", validationTests)
+	}
+}
+
+func terraformValidationCases(c cronCorpus) []byte {
+	var b bytes.Buffer
+	b.WriteString("# Code generated by go test ./internal/schedule -update from\n")
+	b.WriteString("# tests/fixtures/cron-corpus.json. DO NOT EDIT.\n")
+	b.WriteString("\n# Cron expressions ParseCron accepts pass the schedules validation\n")
+	writeRun(&b, "cron_corpus_accepted", c.Accept, false)
+	for i, expr := range c.Reject {
+		fmt.Fprintf(&b, "\n# ParseCron rejects %s\n", strconv.Quote(expr))
+		writeRun(&b, fmt.Sprintf("cron_corpus_rejected_%d", i+1), []string{expr}, true)
+	}
+	// watchdog.cron holds one expression, so each gets its own run.
+	for i, expr := range c.Accept {
+		fmt.Fprintf(&b, "\n# watchdog.cron accepts %s\n", strconv.Quote(expr))
+		writeWatchdogRun(&b, fmt.Sprintf("watchdog_cron_corpus_accepted_%d", i+1), expr, false)
+	}
+	for i, expr := range c.Reject {
+		if expr == "" {
+			// An empty watchdog.cron leaves the Cron Trigger off.
+			continue
+		}
+		fmt.Fprintf(&b, "\n# watchdog.cron rejects %s\n", strconv.Quote(expr))
+		writeWatchdogRun(&b, fmt.Sprintf("watchdog_cron_corpus_rejected_%d", i+1), expr, true)
+	}
+	return b.Bytes()
+}
+
+func writeVariables(b *bytes.Buffer, name string) {
+	fmt.Fprintf(b, "run %q {\n  variables {\n", name)
+	b.WriteString("    cloudflare_account_id = \"test-account-id\"\n")
+	b.WriteString("    cloudflare_zone_id    = \"test-zone-id\"\n")
+	b.WriteString("    environment           = \"test\"\n")
+	b.WriteString("    kv_runtime_state      = true\n")
+}
+
+func writePlan(b *bytes.Buffer, failing string) {
+	b.WriteString("  }\n\n  module {\n    source = \"../\"\n  }\n\n  command = plan\n")
+	if failing != "" {
+		fmt.Fprintf(b, "\n  expect_failures = [\n    %s,\n  ]\n", failing)
+	}
+	b.WriteString("}\n")
+}
+
+func writeRun(b *bytes.Buffer, name string, crons []string, fails bool) {
+	writeVariables(b, name)
+	b.WriteString("    schedules = [\n")
+	for i, expr := range crons {
+		fmt.Fprintf(b, "      {\n        name     = \"schedule-%d\"\n        cron     = %s\n", i+1, strconv.Quote(expr))
+		b.WriteString("        duration = \"1h\"\n        timezone = \"UTC\"\n      },\n")
+	}
+	b.WriteString("    ]\n")
+	writePlan(b, failing(fails, "var.schedules"))
+}
+
+func writeWatchdogRun(b *bytes.Buffer, name, expr string, fails bool) {
+	writeVariables(b, name)
+	fmt.Fprintf(b, "    watchdog = {\n      cron = %s\n    }\n", strconv.Quote(expr))
+	writePlan(b, failing(fails, "var.watchdog"))
+}
+
+func failing(fails bool, variable string) string {
+	if fails {
+		return variable
+	}
+	return ""
+}
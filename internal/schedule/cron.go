@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed 5-field cron expression: minute, hour, day of month,
+// month and day of week. As in Vixie cron, when both day fields are
+// restricted a day matches either of them.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ...
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	// 7 is also Sunday.
+	dowField = field{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+)
+
+// ParseCron parses expr. Each field is a comma-separated list of *, N or
+// N-M, each optionally followed by /STEP. Numbers have one or two digits,
+// months and weekdays may also be names such as JAN or MON-FRI, and a step
+// is at most the field's maximum. This is the reference for the schedules
+// validation in variables.tf and parseCron in worker.js; all three are
+// checked against tests/fixtures/cron-corpus.json.
+func ParseCron(expr string) (Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Cron{}, fmt.Errorf("cron %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+	var c Cron
+	var err error
+	for i, f := range []struct {
+		spec  field
+		bits  *uint64
+		isAll *bool
+	}{
+		{minuteField, &c.minute, nil},
+		{hourField, &c.hour, nil},
+		{domField, &c.dom, &c.domStar},
+		{monthField, &c.month, nil},
+		{dowField, &c.dow, &c.dowStar},
+	} {
+		if *f.bits, err = f.spec.parse(fields[i]); err != nil {
+			return Cron{}, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		if f.isAll != nil {
+			// Like Vixie cron, */N still counts as unrestricted.
+			*f.isAll = strings.HasPrefix(fields[i], "*")
+		}
+	}
+	// Sunday is both 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		lo, hi, step := f.min, f.max, 1
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			n, err := number(stepStr)
+			if err != nil || n < 1 || n > f.max {
+				return 0, fmt.Errorf("%s: step in %q must be 1-%d", f.name, item, f.max)
+			}
+			step = n
+		}
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, fmt.Errorf("%s: %w in %q", f.name, err, item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(b); err != nil {
+					return 0, fmt.Errorf("%s: %w in %q", f.name, err, item)
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("%s: range %q runs backwards", f.name, item)
+				}
+			} else if hasStep {
+				// N/STEP runs from N to the end of the field.
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := number(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%d is outside %d-%d", n, f.min, f.max)
+	}
+	return n, nil
+}
+
+// number parses one or two digits; signs and longer numbers are rejected.
+func number(s string) (int, error) {
+	if len(s) < 1 || len(s) > 2 || strings.Trim(s, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not a number or name", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// Matches reports whether the wall-clock time t matches, ignoring seconds.
+func (c Cron) Matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,207 @@
+package schedule
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICSOptions describes an iCalendar feed of upcoming maintenance.
+type ICSOptions struct {
+	// Name is the calendar's display name.
+	Name string
+	// Host ends every UID; the status hostname the feed is served on.
+	Host string
+	// Window is the one-off maintenance_window, if set.
+	Window *Window
+	// The feed covers the UTC day before Now through Days days after its
+	// start. Now also stamps the events.
+	Now  time.Time
+	Days int
+}
+
+// ICSRange returns the period a feed made at now covers.
+func ICSRange(now time.Time, days int) (from, to time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -1), day.AddDate(0, 0, days)
+}
+
+var uidUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// WriteICS writes an RFC 5545 feed of the maintenance window and the
+// schedules' windows. A UID depends only on the schedule and the start, so a
+// subscribed calendar updates events in place. Windows a blackout holds are
+// published as cancelled. Schedule times carry their timezone, described by
+// a VTIMEZONE with the offsets in the feed's period; DURATION rather than
+// DTEND keeps windows across a clock change exact. Keep in sync with
+// maintenanceCalendar in worker.js; both are tested against
+// tests/fixtures/maintenance.ics.
+func (s *Set) WriteICS(w io.Writer, o ICSOptions) error {
+	if o.Host == "" {
+		return fmt.Errorf("an iCalendar feed needs a host for its UIDs")
+	}
+	from, to := ICSRange(o.Now, o.Days)
+	stamp := icsUTC(o.Now)
+
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		writeICSLine(&b, fmt.Sprintf(format, args...))
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//terraform-cloudflare-maintenance//maintenance.ics//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:%s", icsEscape(o.Name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	line("X-PUBLISHED-TTL:PT1H")
+
+	zones := map[string]string{}
+	var names []string
+	for _, c := range s.list {
+		zones[c.Name] = c.Timezone
+		if c.Timezone != "UTC" && !contains(names, c.Timezone) {
+			names = append(names, c.Timezone)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		loc, _ := time.LoadLocation(name)
+		writeVTimezone(line, name, loc, from, to)
+	}
+
+	if win := o.Window; win != nil && win.End.After(from) && win.Start.Before(to) {
+		line("BEGIN:VEVENT")
+		line("UID:window-%s@%s", icsUTC(win.Start), o.Host)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART:%s", icsUTC(win.Start))
+		line("DTEND:%s", icsUTC(win.End))
+		line("SUMMARY:Scheduled maintenance")
+		line("STATUS:CONFIRMED")
+		line("SEQUENCE:0")
+		line("END:VEVENT")
+	}
+	for _, win := range s.Expand(from, to) {
+		line("BEGIN:VEVENT")
+		line("UID:schedule-%s-%s@%s", uidUnsafe.ReplaceAllString(win.Schedule, "-"), icsUTC(win.Start), o.Host)
+		line("DTSTAMP:%s", stamp)
+		if tz := zones[win.Schedule]; tz == "UTC" {
+			line("DTSTART:%s", icsUTC(win.Start))
+		} else {
+			loc, _ := time.LoadLocation(tz)
+			line("DTSTART;TZID=%s:%s", tz, win.Start.In(loc).Format("20060102T150405"))
+		}
+		line("DURATION:%s", icsDuration(win.End.Sub(win.Start)))
+		line("SUMMARY:%s", icsEscape("Scheduled maintenance: "+win.Schedule))
+		if win.Blackout != "" {
+			line("DESCRIPTION:%s", icsEscape("Cancelled for the "+win.Blackout+" blackout"))
+			line("STATUS:CANCELLED")
+			line("SEQUENCE:1")
+		} else {
+			line("STATUS:CONFIRMED")
+			line("SEQUENCE:0")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeVTimezone describes loc from from to to: the offset at from, then
+// every change before to.
+func writeVTimezone(line func(string, ...any), name string, loc *time.Location, from, to time.Time) {
+	line("BEGIN:VTIMEZONE")
+	line("TZID:%s", name)
+	_, offset := from.In(loc).Zone()
+	writeObservance(line, loc, from, offset, offset)
+	for t := from; ; {
+		_, end := t.In(loc).ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		if _, next := end.In(loc).Zone(); next != offset {
+			writeObservance(line, loc, end, offset, next)
+			offset = next
+		}
+		t = end
+	}
+	line("END:VTIMEZONE")
+}
+
+// writeObservance writes the offset change at at. It is daylight time when
+// the new offset is ahead of the smaller of the offsets on January 1 and
+// July 1 of that year, both at 00:00 UTC.
+func writeObservance(line func(string, ...any), loc *time.Location, at time.Time, from, to int) {
+	year := at.In(loc).Year()
+	_, jan := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).In(loc).Zone()
+	_, jul := time.Date(year, time.July, 1, 0, 0, 0, 0, time.UTC).In(loc).Zone()
+	kind := "STANDARD"
+	if to > min(jan, jul) {
+		kind = "DAYLIGHT"
+	}
+	line("BEGIN:%s", kind)
+	line("DTSTART:%s", at.UTC().Add(time.Duration(from)*time.Second).Format("20060102T150405"))
+	line("TZOFFSETFROM:%s", icsOffset(from))
+	line("TZOFFSETTO:%s", icsOffset(to))
+	line("END:%s", kind)
+}
+
+func icsUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+func icsDuration(d time.Duration) string {
+	out := "PT"
+	if h := int(d / time.Hour); h > 0 {
+		out += fmt.Sprintf("%dH", h)
+	}
+	if m := int(d % time.Hour / time.Minute); m > 0 {
+		out += fmt.Sprintf("%dM", m)
+	}
+	return out
+}
+
+// icsEscape is the TEXT escaping icalText undoes.
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine ends a content line with CRLF, folding it so no line is
+// longer than 75 octets and no UTF-8 sequence is split.
+func writeICSLine(b *strings.Builder, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+const icsFeed = "../../tests/fixtures/maintenance.ics"
+
+type icsFixture struct {
+	Name   string    `json:"name"`
+	Host   string    `json:"host"`
+	Now    time.Time `json:"now"`
+	Days   int       `json:"days"`
+	Window struct {
+		StartTime time.Time `json:"start_time"`
+		EndTime   time.Time `json:"end_time"`
+	} `json:"maintenance_window"`
+	Schedules []Schedule     `json:"schedules"`
+	Blackouts []BlackoutSpec `json:"blackouts"`
+}
+
+// The same fixture drives maintenanceCalendar in tests/unit/worker.test.js.
+// Run with -update after changing the fixture or the feed.
+func TestWriteICS(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/ics-feed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f icsFixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	set, err := NewSet(f.Schedules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blackouts, err := ResolveBlackouts(f.Blackouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SetBlackouts(blackouts)
+
+	var got bytes.Buffer
+	err = set.WriteICS(&got, ICSOptions{
+		Name:   f.Name,
+		Host:   f.Host,
+		Window: &Window{Start: f.Window.StartTime, End: f.Window.EndTime},
+		Now:    f.Now,
+		Days:   f.Days,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(icsFeed, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(icsFeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("WriteICS differs from %s; run go test ./internal/schedule -update and review the diff", icsFeed)
+	}
+}
+
+func TestWriteICSFolding(t *testing.T) {
+	set, err := NewSet([]Schedule{{Name: strings.Repeat("é", 60), Cron: "0 3 * * *", Duration: "1h", Timezone: "UTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := set.WriteICS(&b, ICSOptions{Host: "status.example.com", Now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), Days: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 || !utf8.ValidString(line) || strings.Contains(line, "\n") {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+
+	if err := set.WriteICS(&b, ICSOptions{Now: time.Now(), Days: 1}); err == nil {
+		t.Error("WriteICS without a host succeeded")
+	}
+}
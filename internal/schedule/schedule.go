@@ -0,0 +1,200 @@
+// Package schedule evaluates the module's schedules variable: recurring
+// maintenance windows given as a cron expression in a timezone plus a
+// duration. The worker's Cron Trigger opens and closes the windows in the KV
+// runtime state; this package mirrors that logic so timelines can be
+// simulated before apply. Keep in sync with the schedule functions in
+// worker.js; both are tested against tests/fixtures/schedules.json.
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schedule is one entry of the schedules variable.
+type Schedule struct {
+	Name     string   `json:"name"`
+	Cron     string   `json:"cron"`
+	Duration string   `json:"duration"`
+	Timezone string   `json:"timezone"`
+	Notify   []string `json:"notify,omitempty"`
+}
+
+// Window is one occurrence of a schedule. Blackout names the blackout that
+// keeps it from opening.
+type Window struct {
+	Schedule string    `json:"schedule,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Blackout string    `json:"blackout,omitempty"`
+}
+
+var durationRE = regexp.MustCompile(`^(?:([0-9]+)h)?(?:([0-9]+)m)?$`)
+
+// ParseDuration parses a schedule duration such as 2h, 45m or 1h30m.
+func ParseDuration(s string) (time.Duration, error) {
+	m := durationRE.FindStringSubmatch(s)
+	if m == nil || s == "" {
+		return 0, fmt.Errorf("duration %q must be hours and/or minutes, like 2h, 45m or 1h30m", s)
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	d := time.Duration(h)*time.Hour + time.Duration(min)*time.Minute
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be longer than zero", s)
+	}
+	return d, nil
+}
+
+type compiled struct {
+	Schedule
+	cron Cron
+	loc  *time.Location
+	dur  time.Duration
+}
+
+// Set is a validated list of schedules and the blackouts that hold them.
+type Set struct {
+	list      []compiled
+	blackouts []Blackout
+}
+
+// SetBlackouts makes windows that overlap any of blackouts stay closed.
+func (s *Set) SetBlackouts(blackouts []Blackout) {
+	s.blackouts = blackouts
+}
+
+// NewSet validates schedules: names must be unique, and every cron
+// expression, duration and timezone must parse.
+func NewSet(schedules []Schedule) (*Set, error) {
+	set := &Set{}
+	seen := map[string]bool{}
+	for _, s := range schedules {
+		if s.Name == "" || seen[s.Name] {
+			return nil, fmt.Errorf("schedule names must be set and unique, got %q twice or empty", s.Name)
+		}
+		seen[s.Name] = true
+		c := compiled{Schedule: s}
+		var err error
+		if c.cron, err = ParseCron(s.Cron); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+		}
+		if c.dur, err = ParseDuration(s.Duration); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+		}
+		if c.loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, fmt.Errorf("schedule %s: timezone %q: %w", s.Name, s.Timezone, err)
+		}
+		set.list = append(set.list, c)
+	}
+	return set, nil
+}
+
+// Len returns the number of schedules.
+func (s *Set) Len() int {
+	return len(s.list)
+}
+
+// startsAt reports whether a window starts at the UTC minute t. A wall-clock
+// time that is skipped when clocks go forward starts at the first minute
+// after the gap; one that happens twice when clocks go back starts once.
+func (c compiled) startsAt(t time.Time) bool {
+	local := t.In(c.loc)
+	_, offset := local.Zone()
+	if c.cron.Matches(local) {
+		_, earlier := t.Add(-3 * time.Hour).In(c.loc).Zone()
+		if d := time.Duration(earlier-offset) * time.Second; d > 0 && wall(t.Add(-d).In(c.loc)).Equal(wall(local)) {
+			return false
+		}
+		return true
+	}
+	_, before := t.Add(-time.Minute).In(c.loc).Zone()
+	for k := 1; k <= (offset-before)/60; k++ {
+		if c.cron.Matches(wall(local).Add(-time.Duration(k) * time.Minute)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wall drops t's zone, so wall-clock times can be compared and shifted.
+func wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// active returns the window open at now: the earliest start in the last
+// duration whose window overlaps none of blackouts.
+func (c compiled) active(now time.Time, blackouts []Blackout) (Window, bool) {
+	now = now.UTC().Truncate(time.Minute)
+	for t := now.Add(-c.dur + time.Minute); !t.After(now); t = t.Add(time.Minute) {
+		if !c.startsAt(t) {
+			continue
+		}
+		if _, blocked := FindBlackout(blackouts, t, t.Add(c.dur)); !blocked {
+			return Window{Schedule: c.Name, Start: t, End: t.Add(c.dur)}, true
+		}
+	}
+	return Window{}, false
+}
+
+// Active returns the windows open at now, in schedule order.
+func (s *Set) Active(now time.Time) []Window {
+	var open []Window
+	for _, c := range s.list {
+		if w, ok := c.active(now, s.blackouts); ok {
+			open = append(open, w)
+		}
+	}
+	return open
+}
+
+// Expand returns the windows that start in [from, to), by start time,
+// including those a blackout keeps closed.
+func (s *Set) Expand(from, to time.Time) []Window {
+	var windows []Window
+	for _, c := range s.list {
+		for t := from.UTC().Truncate(time.Minute); t.Before(to); t = t.Add(time.Minute) {
+			if t.Before(from) || !c.startsAt(t) {
+				continue
+			}
+			w := Window{Schedule: c.Name, Start: t, End: t.Add(c.dur)}
+			if b, blocked := FindBlackout(s.blackouts, w.Start, w.End); blocked {
+				w.Blackout = b.Name
+			}
+			windows = append(windows, w)
+		}
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows
+}
+
+// TickMinutes is how often the worker has to check so that it sees every
+// start and end on time: 15, 5, 3 or 1, the largest that divides every start
+// minute and duration. Timezone offsets are multiples of 15 minutes, so the
+// tick lines up in every zone. Keep in sync with schedule_tick_minutes in
+// main.tf.
+func (s *Set) TickMinutes() int {
+	for _, g := range []int{15, 5, 3} {
+		if s.alignedTo(g) {
+			return g
+		}
+	}
+	return 1
+}
+
+func (s *Set) alignedTo(g int) bool {
+	for _, c := range s.list {
+		if int(c.dur/time.Minute)%g != 0 {
+			return false
+		}
+		for m := 0; m < 60; m++ {
+			if c.cron.minute&(1<<m) != 0 && m%g != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
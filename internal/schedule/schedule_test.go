@@ -0,0 +1,233 @@
+package schedule
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/notify"
+)
+
+type fixtureTransition struct {
+	At       time.Time `json:"at"`
+	Status   string    `json:"status"`
+	Schedule string    `json:"schedule"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+type replay struct {
+	Name        string              `json:"name"`
+	Schedules   []Schedule          `json:"schedules"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	TickMinutes int                 `json:"tick_minutes"`
+	Transitions []fixtureTransition `json:"transitions"`
+}
+
+func loadReplays(t *testing.T) []replay {
+	t.Helper()
+	data, err := os.ReadFile("../../tests/fixtures/schedules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Replays []replay `json:"replays"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	return fixture.Replays
+}
+
+func flatten(timeline []Transition) []fixtureTransition {
+	out := make([]fixtureTransition, len(timeline))
+	for i, tr := range timeline {
+		out[i] = fixtureTransition{tr.At, tr.Status, tr.Window.Schedule, tr.Window.Start, tr.Window.End}
+	}
+	return out
+}
+
+// The same replays drive the schedule functions in tests/unit/worker.test.js.
+func TestSimulateSharedFixture(t *testing.T) {
+	for _, r := range loadReplays(t) {
+		set, err := NewSet(r.Schedules)
+		if err != nil {
+			t.Fatalf("%s: %v", r.Name, err)
+		}
+		if got := set.TickMinutes(); got != r.TickMinutes {
+			t.Errorf("%s: TickMinutes = %d, want %d", r.Name, got, r.TickMinutes)
+		}
+		got := flatten(set.Simulate(r.From, r.To))
+		if len(got) != len(r.Transitions) {
+			t.Fatalf("%s: %d transitions, want %d:\n%+v", r.Name, len(got), len(r.Transitions), got)
+		}
+		for i := range got {
+			want := r.Transitions[i]
+			if !got[i].At.Equal(want.At) || got[i].Status != want.Status || got[i].Schedule != want.Schedule ||
+				!got[i].Start.Equal(want.Start) || !got[i].End.Equal(want.End) {
+				t.Errorf("%s: transition %d = %+v, want %+v", r.Name, i, got[i], want)
+			}
+		}
+	}
+}
+
+// Ticking every TickMinutes must open and close each window exactly on time.
+func TestSimulateMatchesExpand(t *testing.T) {
+	for _, r := range loadReplays(t) {
+		set, _ := NewSet(r.Schedules)
+		var onTime []fixtureTransition
+		for _, w := range set.Expand(r.From, r.To) {
+			onTime = append(onTime, fixtureTransition{w.Start, notify.StatusStarting, w.Schedule, w.Start, w.End})
+		}
+		simulated := flatten(set.Simulate(r.From, r.To))
+		opened := 0
+		for _, tr := range simulated {
+			if tr.Status != notify.StatusStarting {
+				if !tr.At.Equal(tr.End) {
+					t.Errorf("%s: %s closed at %s, want %s", r.Name, tr.Schedule, tr.At, tr.End)
+				}
+				continue
+			}
+			if opened >= len(onTime) || tr != onTime[opened] {
+				t.Errorf("%s: opened %+v, want the next expanded window", r.Name, tr)
+			}
+			opened++
+		}
+		if opened != len(onTime) {
+			t.Errorf("%s: %d windows opened, %d expanded", r.Name, opened, len(onTime))
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		expr  string
+		at    string
+		match bool
+	}{
+		{"0 2 * * SUN", "2025-03-02 02:00", true},
+		{"0 2 * * sun", "2025-03-02 02:00", true},
+		{"0 2 * * 7", "2025-03-02 02:00", true},
+		{"0 2 * * SUN", "2025-03-03 02:00", false},
+		{"*/15 * * * *", "2025-03-03 04:45", true},
+		{"*/15 * * * *", "2025-03-03 04:50", false},
+		{"30 4 * * 1-5", "2025-03-07 04:30", true},
+		{"30 4 * * 1-5", "2025-03-08 04:30", false},
+		{"0 0 * * 0,6", "2025-03-08 00:00", true},
+		{"5/20 * * * *", "2025-03-08 00:45", true},
+		// Both day fields restricted: either may match.
+		{"0 3 1 * MON", "2025-03-03 03:00", true},
+		{"0 3 1 * MON", "2025-03-01 03:00", true},
+		{"0 3 1 * MON", "2025-03-04 03:00", false},
+		// */N counts as unrestricted, so both must match.
+		{"0 3 */2 * MON", "2025-03-03 03:00", true},
+		{"0 3 */2 * MON", "2025-03-10 03:00", false},
+		{"0 3 1 6 *", "2025-03-01 03:00", false},
+	}
+	for _, tc := range cases {
+		c, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := c.Matches(at(tc.at)); got != tc.match {
+			t.Errorf("%q at %s = %v, want %v", tc.expr, tc.at, got, tc.match)
+		}
+	}
+
+	for _, expr := range []string{"", "0 2 * *", "0 2 * * * *", "60 * * * *", "99 99 * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "* * * * SUNDAY"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for in, want := range map[string]time.Duration{"2h": 2 * time.Hour, "45m": 45 * time.Minute, "1h30m": 90 * time.Minute} {
+		if got, err := ParseDuration(in); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "0h", "1.5h", "90s", "2 hours", "m"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) succeeded", in)
+		}
+	}
+}
+
+func TestTickMinutes(t *testing.T) {
+	cases := []struct {
+		cron, duration string
+		want           int
+	}{
+		{"0 2 * * SUN", "2h", 15},
+		{"45 2 * * *", "1h15m", 15},
+		{"10 2 * * *", "2h", 5},
+		{"0 2 * * *", "50m", 5},
+		{"*/5 * * * *", "1h", 5},
+		{"0 2 * * *", "1h21m", 3},
+		{"7 2 * * *", "1h", 1},
+		{"0-10 2 * * *", "1h", 1},
+	}
+	for _, tc := range cases {
+		set, err := NewSet([]Schedule{{Name: "s", Cron: tc.cron, Duration: tc.duration, Timezone: "UTC"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := set.TickMinutes(); got != tc.want {
+			t.Errorf("TickMinutes(%q, %s) = %d, want %d", tc.cron, tc.duration, got, tc.want)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+	set, err := NewSet([]Schedule{{Name: "hourly", Cron: "0 * * * *", Duration: "1h", Timezone: "UTC"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	rec, changes := set.Step(Record{}, at)
+	if len(changes) != 1 || changes[0].Status != notify.StatusStarting || len(rec) != 1 {
+		t.Fatalf("first step = %+v, %+v", rec, changes)
+	}
+	if _, changes := set.Step(rec, at.Add(30*time.Minute)); len(changes) != 0 {
+		t.Errorf("mid-window step changed %+v", changes)
+	}
+
+	// Back-to-back windows close and reopen at the same tick.
+	rec, changes = set.Step(rec, at.Add(time.Hour))
+	if len(changes) != 2 || changes[0].Status != notify.StatusCompleted || changes[1].Status != notify.StatusStarting || len(rec) != 1 {
+		t.Errorf("next window = %+v, %+v", rec, changes)
+	}
+
+	// Windows of removed schedules close.
+	empty, _ := NewSet(nil)
+	rec, changes = empty.Step(rec, at.Add(90*time.Minute))
+	if len(rec) != 0 || len(changes) != 1 || changes[0].Window.Schedule != "hourly" || changes[0].Status != notify.StatusCompleted {
+		t.Errorf("removed schedule = %+v, %+v", rec, changes)
+	}
+}
+
+func TestNewSetErrors(t *testing.T) {
+	ok := Schedule{Name: "a", Cron: "0 2 * * *", Duration: "2h", Timezone: "UTC"}
+	for name, list := range map[string][]Schedule{
+		"duplicate name":   {ok, ok},
+		"empty name":       {{Cron: ok.Cron, Duration: ok.Duration, Timezone: ok.Timezone}},
+		"bad cron":         {{Name: "a", Cron: "99 99 * * *", Duration: "2h", Timezone: "UTC"}},
+		"bad duration":     {{Name: "a", Cron: ok.Cron, Duration: "2 hours", Timezone: "UTC"}},
+		"unknown timezone": {{Name: "a", Cron: ok.Cron, Duration: "2h", Timezone: "Mars/Olympus_Mons"}},
+	} {
+		if _, err := NewSet(list); err == nil {
+			t.Errorf("%s: NewSet succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/notify"
+)
+
+// RecordKey is the KV key the worker keeps its Record under.
+const RecordKey = "schedule_windows"
+
+// Record is the windows the scheduler has opened, by schedule name.
+type Record map[string]Window
+
+// Transition is a window opening (notify.StatusStarting) or closing
+// (notify.StatusCompleted) at a tick.
+type Transition struct {
+	At     time.Time `json:"at"`
+	Status string    `json:"status"`
+	Window Window    `json:"window"`
+}
+
+// Step compares the open windows at now with rec and returns the new record
+// and what changed, closings first. A schedule whose window was replaced by
+// its next occurrence closes and opens at the same tick; windows of
+// schedules that were removed close. Maintenance is on while the returned
+// record is not empty.
+func (s *Set) Step(rec Record, now time.Time) (Record, []Transition) {
+	now = now.UTC().Truncate(time.Minute)
+	next := Record{}
+	var ends, starts []Transition
+	configured := map[string]bool{}
+	for _, c := range s.list {
+		configured[c.Name] = true
+		w, open := c.active(now, s.blackouts)
+		old, had := rec[c.Name]
+		changed := !had || !open || !old.Start.Equal(w.Start)
+		if had && changed {
+			ends = append(ends, Transition{At: now, Status: notify.StatusCompleted, Window: Window{Schedule: c.Name, Start: old.Start, End: old.End}})
+		}
+		if open {
+			next[c.Name] = Window{Start: w.Start, End: w.End}
+			if changed {
+				starts = append(starts, Transition{At: now, Status: notify.StatusStarting, Window: w})
+			}
+		}
+	}
+	var removed []string
+	for name := range rec {
+		if !configured[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		old := rec[name]
+		ends = append(ends, Transition{At: now, Status: notify.StatusCompleted, Window: Window{Schedule: name, Start: old.Start, End: old.End}})
+	}
+	return next, append(ends, starts...)
+}
+
+// Simulate replays the worker's ticks, every TickMinutes from from (rounded
+// down to a tick) until to, starting with no open windows, and returns the
+// transitions in order.
+func (s *Set) Simulate(from, to time.Time) []Transition {
+	tick := time.Duration(s.TickMinutes()) * time.Minute
+	rec := Record{}
+	var timeline []Transition
+	for t := from.UTC().Truncate(tick); t.Before(to); t = t.Add(tick) {
+		var changes []Transition
+		rec, changes = s.Step(rec, t)
+		timeline = append(timeline, changes...)
+	}
+	return timeline
+}
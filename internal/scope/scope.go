@@ -0,0 +1,80 @@
+// Package scope decides which maintenance_scopes entry applies to a request.
+// Keep in sync with matchScope in worker.js; both are tested against
+// tests/fixtures/maintenance-scopes.json.
+package scope
+
+import "strings"
+
+// Scope is one maintenance_scopes entry. An empty Host matches every host,
+// "*.example.com" matches subdomains of example.com, and Path matches itself
+// and everything below it on a segment boundary.
+type Scope struct {
+	Host    string `json:"host"`
+	Path    string `json:"path"`
+	Enabled bool   `json:"enabled"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Match returns the index of the most specific scope matching host and path,
+// or -1. An exact host beats a wildcard, which beats no host; longer hosts
+// and then longer paths break ties, and the first entry wins a full tie.
+func Match(scopes []Scope, host, path string) int {
+	host = strings.ToLower(host)
+	best := -1
+	var bestRank [3]int
+	for i, s := range scopes {
+		rank, ok := s.match(host, path)
+		if !ok {
+			continue
+		}
+		if best < 0 || outranks(rank, bestRank) {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
+// Enabled reports whether a request gets the maintenance page: the matching
+// scope decides, and requests matching no scope follow global.
+func Enabled(scopes []Scope, host, path string, global bool) bool {
+	if i := Match(scopes, host, path); i >= 0 {
+		return scopes[i].Enabled
+	}
+	return global
+}
+
+func (s Scope) match(host, path string) ([3]int, bool) {
+	var rank [3]int
+	pattern := strings.ToLower(s.Host)
+	switch {
+	case pattern == "":
+	case strings.HasPrefix(pattern, "*."):
+		suffix := pattern[1:]
+		if !strings.HasSuffix(host, suffix) || len(host) == len(suffix) {
+			return rank, false
+		}
+		rank[0], rank[1] = 1, len(suffix)
+	default:
+		if host != pattern {
+			return rank, false
+		}
+		rank[0], rank[1] = 2, len(pattern)
+	}
+
+	prefix := strings.TrimRight(s.Path, "/")
+	if prefix != "" && path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return rank, false
+	}
+	rank[2] = len(prefix)
+	return rank, true
+}
+
+func outranks(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package scope
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// The same decision table drives matchScope in tests/unit/worker.test.js.
+func TestMatchSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/maintenance-scopes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Scopes []Scope `json:"scopes"`
+		Cases  []struct {
+			Name          string `json:"name"`
+			Host          string `json:"host"`
+			Path          string `json:"path"`
+			GlobalEnabled bool   `json:"global_enabled"`
+			WantScope     int    `json:"want_scope"`
+			WantEnabled   bool   `json:"want_enabled"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range fixture.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := Match(fixture.Scopes, tc.Host, tc.Path); got != tc.WantScope {
+				t.Errorf("Match(%q, %q) = %d, want %d", tc.Host, tc.Path, got, tc.WantScope)
+			}
+			if got := Enabled(fixture.Scopes, tc.Host, tc.Path, tc.GlobalEnabled); got != tc.WantEnabled {
+				t.Errorf("Enabled(%q, %q, %v) = %v, want %v", tc.Host, tc.Path, tc.GlobalEnabled, got, tc.WantEnabled)
+			}
+		})
+	}
+}
+
+func TestMatchFirstEntryWinsTie(t *testing.T) {
+	scopes := []Scope{{Path: "/a", Enabled: true}, {Path: "/a/", Enabled: false}}
+	if got := Match(scopes, "example.com", "/a/b"); got != 0 {
+		t.Errorf("Match = %d, want 0", got)
+	}
+}
@@ -0,0 +1,105 @@
+// Package stale mirrors how the worker serves stale copies of stale_paths
+// during maintenance. While the site is up the worker stores good origin
+// responses for those paths in the Cache API; during maintenance it serves
+// the stored copy and only falls back to the maintenance page on a miss.
+// Keep in sync with the stale functions in worker.js; both are tested
+// against tests/fixtures/stale-paths.json.
+package stale
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Header marks a response served from the stale cache.
+const Header = "X-Maintenance-Stale"
+
+// Entry is a cached response.
+type Entry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Cache is the part of the Workers Cache API the worker uses. Put follows
+// the Cache API: entries expire after the Cache-Control max-age they are
+// stored with.
+type Cache interface {
+	Match(key string, now time.Time) (Entry, bool)
+	Put(key string, e Entry, now time.Time) error
+}
+
+// Policy is the module's stale_paths and stale_ttl_seconds.
+type Policy struct {
+	Paths []string
+	TTL   time.Duration
+}
+
+// Applies reports whether a request may be served from, and stored in, the
+// stale cache: GET requests under one of the paths, matched on segment
+// boundaries like maintenance_scopes.
+func (p Policy) Applies(method, path string) bool {
+	if method != http.MethodGet {
+		return false
+	}
+	for _, prefix := range p.Paths {
+		prefix = strings.TrimRight(prefix, "/")
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Storable reports whether an origin response is safe to replay to other
+// visitors: a 200 without cookies or Vary that the origin didn't mark private
+// or no-store, to a request without credentials unless the origin marked the
+// response public. The copy is keyed by URL alone, so a response that varies
+// or belongs to a logged-in visitor would leak to everyone else.
+func Storable(req http.Header, status int, h http.Header) bool {
+	if status != http.StatusOK || h.Get("Set-Cookie") != "" || h.Get("Vary") != "" {
+		return false
+	}
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	if strings.Contains(cc, "private") || strings.Contains(cc, "no-store") {
+		return false
+	}
+	if req.Get("Authorization") == "" && req.Get("Cookie") == "" {
+		return true
+	}
+	for _, d := range strings.Split(cc, ",") {
+		if strings.TrimSpace(d) == "public" {
+			return true
+		}
+	}
+	return false
+}
+
+// Remember stores a copy of a storable response to a request with header req
+// for TTL and reports whether it did.
+func (p Policy) Remember(c Cache, key string, req http.Header, e Entry, now time.Time) (bool, error) {
+	if !Storable(req, e.Status, e.Header) {
+		return false, nil
+	}
+	stored := Entry{Status: e.Status, Header: e.Header.Clone(), Body: e.Body}
+	stored.Header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(p.TTL.Seconds())))
+	if err := c.Put(key, stored, now); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Serve returns the stored copy, marked with Header and made uncacheable
+// downstream so browsers and proxies don't keep it as fresh.
+func (p Policy) Serve(c Cache, key string, now time.Time) (Entry, bool) {
+	e, ok := c.Match(key, now)
+	if !ok {
+		return Entry{}, false
+	}
+	e.Header = e.Header.Clone()
+	e.Header.Set("Cache-Control", "no-store")
+	e.Header.Set(Header, "true")
+	return e, true
+}
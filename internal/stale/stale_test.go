@@ -0,0 +1,219 @@
+package stale
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The same cases drive the stale functions in tests/unit/worker.test.js.
+func TestSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/stale-paths.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Paths   []string `json:"paths"`
+		Applies []struct {
+			Method string `json:"method"`
+			Path   string `json:"path"`
+			Want   bool   `json:"want"`
+		} `json:"applies"`
+		Storable []struct {
+			Name           string            `json:"name"`
+			RequestHeaders map[string]string `json:"request_headers"`
+			Status         int               `json:"status"`
+			Headers        map[string]string `json:"headers"`
+			Want           bool              `json:"want"`
+		} `json:"storable"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Policy{Paths: fixture.Paths}
+	for _, tc := range fixture.Applies {
+		if got := p.Applies(tc.Method, tc.Path); got != tc.Want {
+			t.Errorf("Applies(%s %s) = %v, want %v", tc.Method, tc.Path, got, tc.Want)
+		}
+	}
+	for _, tc := range fixture.Storable {
+		req, h := http.Header{}, http.Header{}
+		for k, v := range tc.RequestHeaders {
+			req.Set(k, v)
+		}
+		for k, v := range tc.Headers {
+			h.Set(k, v)
+		}
+		if got := Storable(req, tc.Status, h); got != tc.Want {
+			t.Errorf("Storable(%s) = %v, want %v", tc.Name, got, tc.Want)
+		}
+	}
+}
+
+// fakeCache follows the Workers Cache API: put refuses 206 and Vary: *,
+// skips responses that are private, no-store or set cookies, and entries
+// expire after their max-age.
+type fakeCache struct {
+	entries map[string]fakeEntry
+}
+
+type fakeEntry struct {
+	Entry
+	expires time.Time
+}
+
+func (c *fakeCache) Put(key string, e Entry, now time.Time) error {
+	if e.Status == http.StatusPartialContent || e.Header.Get("Vary") == "*" {
+		return errors.New("cache.put: response is not cacheable")
+	}
+	cc := strings.ToLower(e.Header.Get("Cache-Control"))
+	if strings.Contains(cc, "private") || strings.Contains(cc, "no-store") || e.Header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	var maxAge int
+	for _, directive := range strings.Split(cc, ",") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age="); ok {
+			maxAge, _ = strconv.Atoi(v)
+		}
+	}
+	if maxAge <= 0 {
+		return nil
+	}
+	if c.entries == nil {
+		c.entries = map[string]fakeEntry{}
+	}
+	c.entries[key] = fakeEntry{Entry: e, expires: now.Add(time.Duration(maxAge) * time.Second)}
+	return nil
+}
+
+func (c *fakeCache) Match(key string, now time.Time) (Entry, bool) {
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return Entry{}, false
+	}
+	return e.Entry, true
+}
+
+// site is the worker's flow around the cache: remember on the way through
+// while the site is up, serve the copy or the page during maintenance.
+type site struct {
+	t       *testing.T
+	policy  Policy
+	cache   *fakeCache
+	origin  *httptest.Server
+	down    bool
+	private map[string]bool
+}
+
+func newSite(t *testing.T, policy Policy) *site {
+	s := &site{t: t, policy: policy, cache: &fakeCache{}, private: map[string]bool{}}
+	s.origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.private[r.URL.Path] {
+			w.Header().Set("Cache-Control", "private")
+		}
+		fmt.Fprintf(w, "origin copy of %s", r.URL.RequestURI())
+	}))
+	t.Cleanup(s.origin.Close)
+	return s
+}
+
+func (s *site) get(path string, now time.Time) (status int, body string, stale bool) {
+	s.t.Helper()
+	key := s.origin.URL + path
+	applies := s.policy.Applies(http.MethodGet, strings.SplitN(path, "?", 2)[0])
+	if s.down {
+		if applies {
+			if e, ok := s.policy.Serve(s.cache, key, now); ok {
+				return e.Status, string(e.Body), e.Header.Get(Header) == "true"
+			}
+		}
+		return http.StatusServiceUnavailable, "maintenance page", false
+	}
+
+	resp, err := http.Get(key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if applies {
+		if _, err := s.policy.Remember(s.cache, key, resp.Request.Header, Entry{Status: resp.StatusCode, Header: resp.Header, Body: raw}, now); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, string(raw), false
+}
+
+func TestStaleCopiesDuringMaintenance(t *testing.T) {
+	s := newSite(t, Policy{Paths: []string{"/blog", "/pricing"}, TTL: time.Hour})
+	s.private["/pricing/account"] = true
+	now := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+
+	for _, path := range []string{"/blog/launch", "/pricing/account", "/checkout", "/blog/launch?utm=x"} {
+		if status, _, _ := s.get(path, now); status != http.StatusOK {
+			t.Fatalf("GET %s while up: %d", path, status)
+		}
+	}
+
+	s.down = true
+	now = now.Add(30 * time.Minute)
+	cases := []struct {
+		path      string
+		wantStale bool
+	}{
+		{"/blog/launch", true},       // cached while up
+		{"/blog/launch?utm=x", true}, // its own key
+		{"/blog/other", false},       // never fetched
+		{"/pricing/account", false},  // origin said private
+		{"/checkout", false},         // not in stale_paths
+	}
+	for _, tc := range cases {
+		status, body, stale := s.get(tc.path, now)
+		if stale != tc.wantStale {
+			t.Errorf("GET %s during maintenance: stale = %v (%d %q)", tc.path, stale, status, body)
+		}
+		if tc.wantStale && (status != http.StatusOK || body != "origin copy of "+tc.path) {
+			t.Errorf("GET %s: %d %q, want the cached copy", tc.path, status, body)
+		}
+		if !tc.wantStale && status != http.StatusServiceUnavailable {
+			t.Errorf("GET %s: %d, want the maintenance page", tc.path, status)
+		}
+	}
+
+	// Past the TTL the copy is gone and the page is served again.
+	if _, _, stale := s.get("/blog/launch", now.Add(31*time.Minute)); stale {
+		t.Error("served a copy older than stale_ttl_seconds")
+	}
+}
+
+func TestServeMarksCopyUncacheable(t *testing.T) {
+	c := &fakeCache{}
+	p := Policy{Paths: []string{"/"}, TTL: time.Minute}
+	now := time.Now()
+	h := http.Header{"Cache-Control": {"public, max-age=31536000"}, "Content-Type": {"text/html"}}
+	if ok, err := p.Remember(c, "k", http.Header{}, Entry{Status: http.StatusOK, Header: h, Body: []byte("x")}, now); !ok || err != nil {
+		t.Fatalf("Remember = %v, %v", ok, err)
+	}
+	if h.Get("Cache-Control") != "public, max-age=31536000" {
+		t.Error("Remember modified the origin response headers")
+	}
+	if _, ok := c.Match("k", now.Add(2*time.Minute)); ok {
+		t.Error("stored copy outlived the policy TTL")
+	}
+	e, ok := p.Serve(c, "k", now)
+	if !ok || e.Header.Get("Cache-Control") != "no-store" || e.Header.Get(Header) != "true" || e.Header.Get("Content-Type") != "text/html" {
+		t.Errorf("Serve = %+v, %v", e, ok)
+	}
+}
@@ -0,0 +1,131 @@
+// Package state reads and writes the live maintenance state the worker picks
+// up from Workers KV when the module is applied with kv_runtime_state. Keep
+// the document format in sync with getRuntimeState in worker.js and the
+// cloudflare_workers_kv.runtime_state seed in main.tf.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Key is the KV key holding the state document.
+const Key = "state"
+
+// State is the document stored under Key. Empty Title and Message fall back
+// to the worker's bindings; the window fields are used as-is, so an empty
+// pair clears a window set in Terraform.
+type State struct {
+	Enabled     bool   `json:"enabled"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	WindowStart string `json:"window_start"`
+	WindowEnd   string `json:"window_end"`
+	// RolloutPercentage is the share of clients that get the page while
+	// maintenance is on (see internal/rollout).
+	RolloutPercentage int `json:"rollout_percentage"`
+	// EnabledAt is when maintenance was last turned on (RFC3339), empty while
+	// it is off. Save maintains it; the watchdog measures durations from it.
+	EnabledAt string `json:"enabled_at,omitempty"`
+	// Environment is the module's environment, seeded by Terraform, so
+	// maintctl knows which environment a namespace belongs to whatever its
+	// flags say. Namespaces seeded before it existed leave it empty.
+	Environment string    `json:"environment,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EnvironmentFor is the environment a change to s applies to, given the
+// environment a caller was told to use. The environment Terraform seeded
+// wins, and a different one is an error rather than a mislabelled change.
+// States seeded without one fall back to environment, which may be empty.
+func (s State) EnvironmentFor(environment string) (string, error) {
+	switch {
+	case s.Environment == "":
+		return environment, nil
+	case environment != "" && !strings.EqualFold(environment, s.Environment):
+		return "", fmt.Errorf("the namespace holds the %s state, not %s", s.Environment, environment)
+	}
+	return s.Environment, nil
+}
+
+// Store is the subset of a KV namespace the state needs.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// Load reads the state document. A missing key is returned as an error
+// wrapping the store's not-found error, since it means the module was not
+// applied with kv_runtime_state. Documents written before rollouts existed
+// load with RolloutPercentage 100.
+func Load(ctx context.Context, kv Store) (State, error) {
+	raw, err := kv.Get(ctx, Key)
+	if err != nil {
+		return State{}, fmt.Errorf("reading %s (was the module applied with kv_runtime_state = true?): %w", Key, err)
+	}
+	s := State{RolloutPercentage: 100}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return State{}, fmt.Errorf("decoding %s: %w", Key, err)
+	}
+	return s, nil
+}
+
+// Validate checks the window the same way the module's maintenance_window
+// validation does, plus ordering, and the rollout percentage range.
+func (s State) Validate() error {
+	if s.RolloutPercentage < 0 || s.RolloutPercentage > 100 {
+		return fmt.Errorf("rollout percentage %d is not between 0 and 100", s.RolloutPercentage)
+	}
+	if (s.WindowStart == "") != (s.WindowEnd == "") {
+		return errors.New("window start and end must be set together")
+	}
+	if s.WindowStart == "" {
+		return nil
+	}
+	start, err := time.Parse(time.RFC3339, s.WindowStart)
+	if err != nil {
+		return fmt.Errorf("window start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, s.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("window end: %w", err)
+	}
+	if !end.After(start) {
+		return errors.New("window end must be after window start")
+	}
+	return nil
+}
+
+// Save validates s, stamps UpdatedAt (and EnabledAt when s turns
+// maintenance on) and writes it, returning the document as stored.
+func Save(ctx context.Context, kv Store, s State, now time.Time) (State, error) {
+	if err := s.Validate(); err != nil {
+		return State{}, err
+	}
+	s.UpdatedAt = now.UTC().Truncate(time.Second)
+	switch {
+	case !s.Enabled:
+		s.EnabledAt = ""
+	case s.EnabledAt == "":
+		s.EnabledAt = s.UpdatedAt.Format(time.RFC3339)
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return State{}, err
+	}
+	return s, kv.Put(ctx, Key, raw)
+}
+
+// Update loads the state, applies change and saves the result.
+func Update(ctx context.Context, kv Store, now time.Time, change func(*State)) (State, error) {
+	s, err := Load(ctx, kv)
+	if err != nil {
+		return State{}, err
+	}
+	change(&s)
+	return Save(ctx, kv, s, now)
+}
@@ -0,0 +1,166 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("get %s: %w", key, cloudflare.ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m memStore) Put(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+// seed is what main.tf writes for enabled = false and no window.
+const seed = `{"enabled":false,"message":"We are currently performing scheduled maintenance. We will be back shortly.","rollout_percentage":100,"title":"Maintenance Mode","window_end":"","window_start":""}`
+
+func TestUpdateTogglesEnabled(t *testing.T) {
+	ctx := context.Background()
+	kv := memStore{Key: []byte(seed)}
+	now := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+
+	s, err := Update(ctx, kv, now, func(s *State) { s.Enabled = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled || s.Title != "Maintenance Mode" || !s.UpdatedAt.Equal(now) {
+		t.Errorf("Update = %+v", s)
+	}
+	loaded, err := Load(ctx, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != s {
+		t.Errorf("Load = %+v, want %+v", loaded, s)
+	}
+}
+
+func TestLoadMissingState(t *testing.T) {
+	if _, err := Load(context.Background(), memStore{}); !errors.Is(err, cloudflare.ErrNotFound) {
+		t.Errorf("Load error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLoadDefaultsRolloutPercentage(t *testing.T) {
+	kv := memStore{Key: []byte(`{"enabled":true,"title":"","message":"","window_start":"","window_end":""}`)}
+	s, err := Load(context.Background(), kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RolloutPercentage != 100 {
+		t.Errorf("RolloutPercentage = %d, want 100 for a document without the field", s.RolloutPercentage)
+	}
+}
+
+func TestValidateRolloutPercentage(t *testing.T) {
+	for pct, ok := range map[int]bool{-1: false, 0: true, 25: true, 100: true, 101: false} {
+		err := State{RolloutPercentage: pct}.Validate()
+		if (err == nil) != ok {
+			t.Errorf("Validate(rollout %d) = %v, want ok=%v", pct, err, ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		start, end string
+		ok         bool
+	}{
+		{"", "", true},
+		{"2025-04-06T08:00:00Z", "2025-04-06T10:00:00Z", true},
+		{"2025-04-06T08:00:00Z", "", false},
+		{"2025-04-06T10:00:00Z", "2025-04-06T08:00:00Z", false},
+		{"tomorrow", "2025-04-06T10:00:00Z", false},
+	}
+	for _, tc := range cases {
+		err := State{WindowStart: tc.start, WindowEnd: tc.end}.Validate()
+		if (err == nil) != tc.ok {
+			t.Errorf("Validate(%q, %q) = %v, want ok=%v", tc.start, tc.end, err, tc.ok)
+		}
+	}
+}
+
+func TestEnvironmentFor(t *testing.T) {
+	cases := []struct {
+		seeded, flag, want string
+		ok                 bool
+	}{
+		{"production", "", "production", true},
+		{"production", "Production", "production", true},
+		{"production", "staging", "", false},
+		{"", "staging", "staging", true},
+		{"", "", "", true},
+	}
+	for _, tc := range cases {
+		got, err := State{Environment: tc.seeded}.EnvironmentFor(tc.flag)
+		if got != tc.want || (err == nil) != tc.ok {
+			t.Errorf("EnvironmentFor(%q) on %q = %q, %v", tc.flag, tc.seeded, got, err)
+		}
+	}
+}
+
+func TestUpdateAgainstMockKV(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	nsID, err := client.CreateKVNamespace(ctx, cftest.AccountID, "maintenance-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.KV(cftest.AccountID, nsID)
+	if err := kv.Put(ctx, Key, []byte(seed)); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	if _, err := Update(ctx, kv, now, func(s *State) {
+		s.Enabled = true
+		s.Message = "Database upgrade"
+		s.WindowStart, s.WindowEnd = "2025-04-06T08:00:00Z", "2025-04-06T10:00:00Z"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(ctx, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Enabled || s.Message != "Database upgrade" || s.WindowEnd != "2025-04-06T10:00:00Z" {
+		t.Errorf("state after Update = %+v", s)
+	}
+
+	if _, err := Update(ctx, kv, now, func(s *State) { s.WindowEnd = "" }); err == nil {
+		t.Error("Update accepted a half-cleared window")
+	}
+}
+
+func TestSaveTracksEnabledAt(t *testing.T) {
+	ctx := context.Background()
+	kv := memStore{Key: []byte(seed)}
+	on := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+
+	s, err := Update(ctx, kv, on, func(s *State) { s.Enabled = true })
+	if err != nil || s.EnabledAt != "2025-04-06T08:00:00Z" {
+		t.Fatalf("enable: EnabledAt = %q, %v", s.EnabledAt, err)
+	}
+	// Later edits while on keep the original time.
+	if s, _ = Update(ctx, kv, on.Add(time.Hour), func(s *State) { s.Message = "Nearly done" }); s.EnabledAt != "2025-04-06T08:00:00Z" {
+		t.Errorf("edit while on: EnabledAt = %q", s.EnabledAt)
+	}
+	if s, _ = Update(ctx, kv, on.Add(2*time.Hour), func(s *State) { s.Enabled = false }); s.EnabledAt != "" {
+		t.Errorf("disable: EnabledAt = %q", s.EnabledAt)
+	}
+}
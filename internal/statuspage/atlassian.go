@@ -0,0 +1,77 @@
+package statuspage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Atlassian is an Atlassian Statuspage page. Maintenances are incidents with
+// a scheduled window; they never start or complete on their own, Sync moves
+// them along.
+type Atlassian struct {
+	BaseURL string // default https://api.statuspage.io/v1
+	PageID  string
+	APIKey  string
+	HTTP    *http.Client
+}
+
+type atlassianIncident struct {
+	Name                    string `json:"name"`
+	Status                  string `json:"status"`
+	ImpactOverride          string `json:"impact_override"`
+	ScheduledFor            string `json:"scheduled_for"`
+	ScheduledUntil          string `json:"scheduled_until"`
+	ScheduledAutoInProgress bool   `json:"scheduled_auto_in_progress"`
+	ScheduledAutoCompleted  bool   `json:"scheduled_auto_completed"`
+	Body                    string `json:"body"`
+}
+
+func (a *Atlassian) incident(m Maintenance, status string, body string) map[string]atlassianIncident {
+	return map[string]atlassianIncident{"incident": {
+		Name:           m.Title,
+		Status:         status,
+		ImpactOverride: "maintenance",
+		ScheduledFor:   m.Start.UTC().Format(time.RFC3339),
+		ScheduledUntil: m.End.UTC().Format(time.RFC3339),
+		Body:           body,
+	}}
+}
+
+func (a *Atlassian) url(parts ...string) string {
+	base := a.BaseURL
+	if base == "" {
+		base = "https://api.statuspage.io/v1"
+	}
+	u := base + "/pages/" + url.PathEscape(a.PageID) + "/incidents"
+	for _, p := range parts {
+		u += "/" + url.PathEscape(p)
+	}
+	return u
+}
+
+func (a *Atlassian) do(ctx context.Context, method, url string, body, out any) error {
+	return doJSON(ctx, a.HTTP, method, url, "OAuth "+a.APIKey, body, out)
+}
+
+// Create opens a scheduled maintenance, already in progress if m is.
+func (a *Atlassian) Create(ctx context.Context, m Maintenance) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := a.do(ctx, http.MethodPost, a.url(), a.incident(m, string(m.Status), m.Message), &created)
+	return created.ID, err
+}
+
+// Update changes the maintenance's title, message, window and status.
+func (a *Atlassian) Update(ctx context.Context, id string, m Maintenance) error {
+	return a.do(ctx, http.MethodPatch, a.url(id), a.incident(m, string(m.Status), m.Message), nil)
+}
+
+// Complete marks the maintenance completed.
+func (a *Atlassian) Complete(ctx context.Context, id string, m Maintenance) error {
+	return a.do(ctx, http.MethodPatch, a.url(id), a.incident(m, "completed", completedMessage), nil)
+}
+
+const completedMessage = "The scheduled maintenance has been completed."
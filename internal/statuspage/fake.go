@@ -0,0 +1,66 @@
+package statuspage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Fake is an in-memory Provider for tests. Err, when set, fails every call.
+type Fake struct {
+	mu           sync.Mutex
+	Maintenances map[string]Maintenance
+	Completed    map[string]bool
+	Calls        []string // "create m1", "update m1", "complete m1"
+	Err          error
+}
+
+func (f *Fake) record(call, id string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.Maintenances == nil {
+		f.Maintenances, f.Completed = map[string]Maintenance{}, map[string]bool{}
+	}
+	if call != "create" {
+		if _, ok := f.Maintenances[id]; !ok || f.Completed[id] {
+			return fmt.Errorf("%s %s: no open maintenance", call, id)
+		}
+	}
+	f.Calls = append(f.Calls, call+" "+id)
+	return nil
+}
+
+// Create stores m under the next ID, m1, m2, ...
+func (f *Fake) Create(_ context.Context, m Maintenance) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := fmt.Sprintf("m%d", len(f.Maintenances)+1)
+	if err := f.record("create", id); err != nil {
+		return "", err
+	}
+	f.Maintenances[id] = m
+	return id, nil
+}
+
+// Update replaces an open maintenance.
+func (f *Fake) Update(_ context.Context, id string, m Maintenance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("update", id); err != nil {
+		return err
+	}
+	f.Maintenances[id] = m
+	return nil
+}
+
+// Complete closes an open maintenance.
+func (f *Fake) Complete(_ context.Context, id string, _ Maintenance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("complete", id); err != nil {
+		return err
+	}
+	f.Completed[id] = true
+	return nil
+}
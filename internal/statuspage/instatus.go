@@ -0,0 +1,73 @@
+package statuspage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Instatus is an Instatus page, using its maintenance endpoints.
+type Instatus struct {
+	BaseURL string // default https://api.instatus.com/v1
+	PageID  string
+	APIKey  string
+	HTTP    *http.Client
+}
+
+type instatusMaintenance struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Status  string `json:"status"`
+	Notify  bool   `json:"notify"`
+}
+
+var instatusStatus = map[Status]string{Scheduled: "NOTSTARTEDYET", InProgress: "INPROGRESS"}
+
+func (i *Instatus) maintenance(m Maintenance, status, message string) instatusMaintenance {
+	return instatusMaintenance{
+		Name:    m.Title,
+		Message: message,
+		Start:   m.Start.UTC().Format(time.RFC3339),
+		End:     m.End.UTC().Format(time.RFC3339),
+		Status:  status,
+		Notify:  true,
+	}
+}
+
+func (i *Instatus) url(parts ...string) string {
+	base := i.BaseURL
+	if base == "" {
+		base = "https://api.instatus.com/v1"
+	}
+	u := base + "/" + url.PathEscape(i.PageID) + "/maintenances"
+	for _, p := range parts {
+		u += "/" + url.PathEscape(p)
+	}
+	return u
+}
+
+func (i *Instatus) do(ctx context.Context, method, url string, body, out any) error {
+	return doJSON(ctx, i.HTTP, method, url, "Bearer "+i.APIKey, body, out)
+}
+
+// Create opens a maintenance, already in progress if m is.
+func (i *Instatus) Create(ctx context.Context, m Maintenance) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := i.do(ctx, http.MethodPost, i.url(), i.maintenance(m, instatusStatus[m.Status], m.Message), &created)
+	return created.ID, err
+}
+
+// Update changes the maintenance's title, message, window and status.
+func (i *Instatus) Update(ctx context.Context, id string, m Maintenance) error {
+	return i.do(ctx, http.MethodPut, i.url(id), i.maintenance(m, instatusStatus[m.Status], m.Message), nil)
+}
+
+// Complete marks the maintenance completed.
+func (i *Instatus) Complete(ctx context.Context, id string, m Maintenance) error {
+	return i.do(ctx, http.MethodPut, i.url(id), i.maintenance(m, "COMPLETED", completedMessage), nil)
+}
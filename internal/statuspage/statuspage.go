@@ -0,0 +1,194 @@
+// Package statuspage mirrors the maintenance state on a public status page
+// as a scheduled maintenance: created when maintenance is turned on or a
+// window is set ahead, updated when its title, message or window change, and
+// completed when it is over. Providers are Atlassian Statuspage and Instatus,
+// picked with a URL-like spec (see Open); Fake keeps maintenances in memory
+// for tests.
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+// Status is where a maintenance stands on the status page.
+type Status string
+
+const (
+	Scheduled  Status = "scheduled"
+	InProgress Status = "in_progress"
+)
+
+// Maintenance is what the status page shows.
+type Maintenance struct {
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Status  Status    `json:"status"`
+}
+
+func (m Maintenance) equal(o Maintenance) bool {
+	return m.Title == o.Title && m.Message == o.Message && m.Start.Equal(o.Start) && m.End.Equal(o.End) && m.Status == o.Status
+}
+
+// Provider creates, updates and completes scheduled maintenances on one
+// status page.
+type Provider interface {
+	Create(ctx context.Context, m Maintenance) (id string, err error)
+	Update(ctx context.Context, id string, m Maintenance) error
+	Complete(ctx context.Context, id string, m Maintenance) error
+}
+
+// Open returns the provider for spec:
+//
+//	statuspage://PAGE_ID    Atlassian Statuspage, key in STATUSPAGE_API_KEY
+//	instatus://PAGE_ID      Instatus, key in INSTATUS_API_KEY
+//
+// STATUSPAGE_API_BASE_URL and INSTATUS_API_BASE_URL point them at a mock.
+func Open(spec string) (Provider, error) {
+	scheme, page, ok := strings.Cut(spec, "://")
+	if !ok || page == "" || strings.Contains(page, "/") {
+		return nil, fmt.Errorf("status page %q must be statuspage://PAGE_ID or instatus://PAGE_ID", spec)
+	}
+	switch scheme {
+	case "statuspage":
+		key := os.Getenv("STATUSPAGE_API_KEY")
+		if key == "" {
+			return nil, errors.New("statuspage:// needs STATUSPAGE_API_KEY")
+		}
+		return &Atlassian{BaseURL: os.Getenv("STATUSPAGE_API_BASE_URL"), PageID: page, APIKey: key}, nil
+	case "instatus":
+		key := os.Getenv("INSTATUS_API_KEY")
+		if key == "" {
+			return nil, errors.New("instatus:// needs INSTATUS_API_KEY")
+		}
+		return &Instatus{BaseURL: os.Getenv("INSTATUS_API_BASE_URL"), PageID: page, APIKey: key}, nil
+	}
+	return nil, fmt.Errorf("status page %q: unknown provider (use statuspage:// or instatus://)", spec)
+}
+
+// LinkKey is the KV key, next to the state, that remembers the open
+// maintenance between runs.
+const LinkKey = "statuspage"
+
+// Link is the document stored under LinkKey. Spec records the page the
+// maintenance is on, so switching pages never touches a foreign ID.
+type Link struct {
+	Spec        string      `json:"spec"`
+	ID          string      `json:"id"`
+	Maintenance Maintenance `json:"maintenance"`
+}
+
+// Defaults fill in what the state leaves to the worker's bindings.
+type Defaults struct {
+	Title   string // maintenance_title
+	Message string // maintenance_message
+	// Duration is how long maintenance turned on without a window is
+	// announced for.
+	Duration time.Duration
+}
+
+// FromState returns the maintenance s stands for at now, if any: in progress
+// while maintenance is on or inside the window, scheduled while the window
+// is ahead. Maintenance turned on without a window runs from EnabledAt for
+// the default duration.
+func FromState(s state.State, d Defaults, now time.Time) (Maintenance, bool) {
+	m := Maintenance{Title: s.Title, Message: s.Message}
+	if m.Title == "" {
+		m.Title = d.Title
+	}
+	if m.Message == "" {
+		m.Message = d.Message
+	}
+	start, errStart := time.Parse(time.RFC3339, s.WindowStart)
+	end, errEnd := time.Parse(time.RFC3339, s.WindowEnd)
+	window := errStart == nil && errEnd == nil && end.After(start)
+	switch {
+	case window && (s.Enabled || !now.Before(start)) && now.Before(end):
+		m.Start, m.End, m.Status = start, end, InProgress
+	case window && now.Before(start):
+		m.Start, m.End, m.Status = start, end, Scheduled
+	case s.Enabled:
+		if m.Start, errStart = time.Parse(time.RFC3339, s.EnabledAt); errStart != nil {
+			m.Start = now
+		}
+		m.End, m.Status = m.Start.Add(d.Duration), InProgress
+	default:
+		return Maintenance{}, false
+	}
+	m.Start, m.End = m.Start.UTC().Truncate(time.Second), m.End.UTC().Truncate(time.Second)
+	return m, true
+}
+
+// Syncer keeps the maintenance on one status page in line with the state.
+type Syncer struct {
+	Provider Provider
+	Spec     string
+	KV       state.Store
+	Defaults Defaults
+}
+
+// Sync creates, updates or completes the page's maintenance so it matches s
+// at now, and says what it did: "created", "updated", "completed" or "".
+func (y Syncer) Sync(ctx context.Context, s state.State, now time.Time) (string, error) {
+	link, err := y.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	if link.ID != "" && link.Spec != y.Spec {
+		return "", fmt.Errorf("maintenance %s is open on %s, not %s; complete it there first", link.ID, link.Spec, y.Spec)
+	}
+	m, open := FromState(s, y.Defaults, now)
+	switch {
+	case open && link.ID == "":
+		id, err := y.Provider.Create(ctx, m)
+		if err != nil {
+			return "", fmt.Errorf("creating maintenance: %w", err)
+		}
+		return "created", y.save(ctx, Link{Spec: y.Spec, ID: id, Maintenance: m})
+	case open && !m.equal(link.Maintenance):
+		if err := y.Provider.Update(ctx, link.ID, m); err != nil {
+			return "", fmt.Errorf("updating maintenance %s: %w", link.ID, err)
+		}
+		link.Maintenance = m
+		return "updated", y.save(ctx, link)
+	case !open && link.ID != "":
+		if err := y.Provider.Complete(ctx, link.ID, link.Maintenance); err != nil {
+			return "", fmt.Errorf("completing maintenance %s: %w", link.ID, err)
+		}
+		return "completed", y.save(ctx, Link{})
+	}
+	return "", nil
+}
+
+func (y Syncer) load(ctx context.Context) (Link, error) {
+	raw, err := y.KV.Get(ctx, LinkKey)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return Link{}, nil
+	}
+	if err != nil {
+		return Link{}, err
+	}
+	var link Link
+	if err := json.Unmarshal(raw, &link); err != nil {
+		return Link{}, fmt.Errorf("decoding %s: %w", LinkKey, err)
+	}
+	return link, nil
+}
+
+func (y Syncer) save(ctx context.Context, link Link) error {
+	raw, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return y.KV.Put(ctx, LinkKey, raw)
+}
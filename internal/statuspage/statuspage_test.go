@@ -0,0 +1,250 @@
+package statuspage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("get %s: %w", key, cloudflare.ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m memStore) Put(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+var (
+	now      = time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	defaults = Defaults{Title: "Maintenance Mode", Message: "Back shortly.", Duration: time.Hour}
+)
+
+func TestFromState(t *testing.T) {
+	for name, tc := range map[string]struct {
+		state state.State
+		want  Maintenance
+		open  bool
+	}{
+		"off": {state: state.State{}},
+		"on without a window": {
+			state: state.State{Enabled: true, EnabledAt: "2025-04-06T07:30:00Z"},
+			want:  Maintenance{Title: "Maintenance Mode", Message: "Back shortly.", Start: now.Add(-30 * time.Minute), End: now.Add(30 * time.Minute), Status: InProgress},
+			open:  true,
+		},
+		"on with a window": {
+			state: state.State{Enabled: true, Title: "DB migration", WindowStart: "2025-04-06T09:00:00Z", WindowEnd: "2025-04-06T11:00:00Z"},
+			want:  Maintenance{Title: "DB migration", Message: "Back shortly.", Start: now.Add(time.Hour), End: now.Add(3 * time.Hour), Status: InProgress},
+			open:  true,
+		},
+		"inside the window": {
+			state: state.State{WindowStart: "2025-04-06T09:00:00+01:00", WindowEnd: "2025-04-06T11:00:00+01:00"},
+			want:  Maintenance{Title: "Maintenance Mode", Message: "Back shortly.", Start: now, End: now.Add(2 * time.Hour), Status: InProgress},
+			open:  true,
+		},
+		"window later": {
+			state: state.State{Message: "Upgrading", WindowStart: "2025-04-07T08:00:00Z", WindowEnd: "2025-04-07T09:00:00Z"},
+			want:  Maintenance{Title: "Maintenance Mode", Message: "Upgrading", Start: now.Add(24 * time.Hour), End: now.Add(25 * time.Hour), Status: Scheduled},
+			open:  true,
+		},
+		"window over": {state: state.State{WindowStart: "2025-04-06T06:00:00Z", WindowEnd: "2025-04-06T07:00:00Z"}},
+	} {
+		got, open := FromState(tc.state, defaults, now)
+		if open != tc.open || !got.equal(tc.want) {
+			t.Errorf("%s: FromState = %+v, %v, want %+v, %v", name, got, open, tc.want, tc.open)
+		}
+	}
+}
+
+func TestSyncLifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := &Fake{}
+	kv := memStore{}
+	y := Syncer{Provider: fake, Spec: "statuspage://page", KV: kv, Defaults: defaults}
+
+	on := state.State{Enabled: true, EnabledAt: "2025-04-06T08:00:00Z"}
+	steps := []struct {
+		state state.State
+		at    time.Time
+		want  string
+	}{
+		{on, now, "created"},
+		{on, now.Add(time.Minute), ""},
+		{state.State{Enabled: true, EnabledAt: on.EnabledAt, Message: "Halfway"}, now.Add(2 * time.Minute), "updated"},
+		{state.State{}, now.Add(time.Hour), "completed"},
+		{state.State{}, now.Add(2 * time.Hour), ""},
+		{state.State{WindowStart: "2025-04-07T08:00:00Z", WindowEnd: "2025-04-07T09:00:00Z"}, now.Add(3 * time.Hour), "created"},
+		{state.State{WindowStart: "2025-04-07T08:00:00Z", WindowEnd: "2025-04-07T09:00:00Z"}, now.Add(24 * time.Hour), "updated"},
+		{state.State{WindowStart: "2025-04-07T08:00:00Z", WindowEnd: "2025-04-07T09:00:00Z"}, now.Add(25 * time.Hour), "completed"},
+	}
+	for i, s := range steps {
+		got, err := y.Sync(ctx, s.state, s.at)
+		if err != nil || got != s.want {
+			t.Fatalf("step %d: Sync = %q, %v, want %q", i, got, err, s.want)
+		}
+	}
+	want := []string{"create m1", "update m1", "complete m1", "create m2", "update m2", "complete m2"}
+	if fmt.Sprint(fake.Calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", fake.Calls, want)
+	}
+	if fake.Maintenances["m1"].Message != "Halfway" || fake.Maintenances["m2"].Status != InProgress {
+		t.Errorf("maintenances = %+v", fake.Maintenances)
+	}
+}
+
+func TestSyncKeepsLinkOnError(t *testing.T) {
+	ctx := context.Background()
+	fake := &Fake{}
+	kv := memStore{}
+	y := Syncer{Provider: fake, Spec: "instatus://page", KV: kv, Defaults: defaults}
+	on := state.State{Enabled: true}
+	if _, err := y.Sync(ctx, on, now); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Err = errors.New("HTTP 503")
+	if _, err := y.Sync(ctx, state.State{}, now); err == nil {
+		t.Fatal("Sync succeeded while the provider fails")
+	}
+	fake.Err = nil
+	if got, err := y.Sync(ctx, state.State{}, now); err != nil || got != "completed" {
+		t.Errorf("retry = %q, %v, want completed", got, err)
+	}
+
+	if _, err := y.Sync(ctx, on, now); err != nil {
+		t.Fatal(err)
+	}
+	other := Syncer{Provider: &Fake{}, Spec: "statuspage://other", KV: kv, Defaults: defaults}
+	if _, err := other.Sync(ctx, state.State{}, now); err == nil {
+		t.Error("Sync completed a maintenance opened on another page")
+	}
+}
+
+type request struct {
+	Method, Path, Auth string
+	Body               map[string]any
+}
+
+// recorder answers every request with a maintenance ID and keeps the
+// requests for inspection.
+func recorder(t *testing.T) (*httptest.Server, *[]request) {
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		req := request{Method: r.Method, Path: r.URL.Path, Auth: r.Header.Get("Authorization")}
+		if err := json.Unmarshal(raw, &req.Body); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s: body %q is not JSON", r.Method, r.URL.Path, raw)
+		}
+		got = append(got, req)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		fmt.Fprint(w, `{"id":"abc123"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+var window = Maintenance{Title: "DB migration", Message: "Back at 10:00", Start: now, End: now.Add(2 * time.Hour), Status: InProgress}
+
+func exercise(t *testing.T, p Provider) {
+	t.Helper()
+	ctx := context.Background()
+	id, err := p.Create(ctx, window)
+	if err != nil || id != "abc123" {
+		t.Fatalf("Create = %q, %v", id, err)
+	}
+	if err := p.Update(ctx, id, window); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Complete(ctx, id, window); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAtlassian(t *testing.T) {
+	srv, got := recorder(t)
+	exercise(t, &Atlassian{BaseURL: srv.URL, PageID: "page1", APIKey: "key"})
+
+	reqs := *got
+	if len(reqs) != 3 {
+		t.Fatalf("%d requests, want 3", len(reqs))
+	}
+	for i, want := range []struct{ method, path, status string }{
+		{"POST", "/pages/page1/incidents", "in_progress"},
+		{"PATCH", "/pages/page1/incidents/abc123", "in_progress"},
+		{"PATCH", "/pages/page1/incidents/abc123", "completed"},
+	} {
+		r := reqs[i]
+		incident, _ := r.Body["incident"].(map[string]any)
+		if r.Method != want.method || r.Path != want.path || r.Auth != "OAuth key" || incident["status"] != want.status {
+			t.Errorf("request %d = %s %s %q status %v, want %s %s %s", i, r.Method, r.Path, r.Auth, incident["status"], want.method, want.path, want.status)
+		}
+	}
+	incident := reqs[0].Body["incident"].(map[string]any)
+	if incident["name"] != "DB migration" || incident["body"] != "Back at 10:00" || incident["scheduled_for"] != "2025-04-06T08:00:00Z" ||
+		incident["scheduled_until"] != "2025-04-06T10:00:00Z" || incident["scheduled_auto_completed"] != false {
+		t.Errorf("incident = %v", incident)
+	}
+}
+
+func TestInstatus(t *testing.T) {
+	srv, got := recorder(t)
+	exercise(t, &Instatus{BaseURL: srv.URL, PageID: "page1", APIKey: "key"})
+
+	reqs := *got
+	if len(reqs) != 3 {
+		t.Fatalf("%d requests, want 3", len(reqs))
+	}
+	for i, want := range []struct{ method, path, status string }{
+		{"POST", "/page1/maintenances", "INPROGRESS"},
+		{"PUT", "/page1/maintenances/abc123", "INPROGRESS"},
+		{"PUT", "/page1/maintenances/abc123", "COMPLETED"},
+	} {
+		r := reqs[i]
+		if r.Method != want.method || r.Path != want.path || r.Auth != "Bearer key" || r.Body["status"] != want.status {
+			t.Errorf("request %d = %s %s %q status %v, want %s %s %s", i, r.Method, r.Path, r.Auth, r.Body["status"], want.method, want.path, want.status)
+		}
+	}
+	if b := reqs[0].Body; b["name"] != "DB migration" || b["message"] != "Back at 10:00" || b["start"] != "2025-04-06T08:00:00Z" || b["end"] != "2025-04-06T10:00:00Z" {
+		t.Errorf("maintenance = %v", b)
+	}
+}
+
+func TestProviderHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	if _, err := (&Instatus{BaseURL: srv.URL, PageID: "p", APIKey: "bad"}).Create(context.Background(), window); err == nil {
+		t.Error("Create succeeded on HTTP 401")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv("STATUSPAGE_API_KEY", "sp-key")
+	t.Setenv("INSTATUS_API_KEY", "")
+	if p, err := Open("statuspage://page1"); err != nil || p.(*Atlassian).PageID != "page1" || p.(*Atlassian).APIKey != "sp-key" {
+		t.Errorf("Open(statuspage://page1) = %+v, %v", p, err)
+	}
+	for _, spec := range []string{"instatus://page1", "statuspage://", "statuspage://a/b", "pagerduty://x", "page1"} {
+		if _, err := Open(spec); err == nil {
+			t.Errorf("Open(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package updates manages the timeline of status updates shown on the
+// maintenance page. The whole timeline lives under a single KV key so the
+// worker needs one read per request; keep the format in sync with
+// getStatusUpdates in worker.js.
+package updates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+)
+
+// Key is the KV key holding the JSON array of updates.
+const Key = "status_updates"
+
+// MaxEntries caps the timeline; posting beyond it drops the oldest update.
+const MaxEntries = 20
+
+// MaxMessageLength keeps a single update readable on the page.
+const MaxMessageLength = 500
+
+// ErrUnknownID is returned when deleting an update that does not exist.
+var ErrUnknownID = errors.New("no update with that id")
+
+// Update is one timeline entry.
+type Update struct {
+	ID       string    `json:"id"`
+	Message  string    `json:"message"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// Store is the subset of a KV namespace the timeline needs.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+}
+
+// Timeline reads and writes updates in a Store.
+type Timeline struct {
+	KV  Store
+	Now func() time.Time // defaults to time.Now
+}
+
+// List returns the updates, newest first. A missing key is an empty timeline.
+func (t Timeline) List(ctx context.Context) ([]Update, error) {
+	raw, err := t.KV.Get(ctx, Key)
+	if errors.Is(err, cloudflare.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Update
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", Key, err)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].PostedAt.After(list[j].PostedAt) })
+	return list, nil
+}
+
+// Post adds an update to the top of the timeline.
+func (t Timeline) Post(ctx context.Context, message string) (Update, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return Update{}, errors.New("update message must not be empty")
+	}
+	if n := utf8.RuneCountInString(message); n > MaxMessageLength {
+		return Update{}, fmt.Errorf("update message is %d characters, the limit is %d", n, MaxMessageLength)
+	}
+	list, err := t.List(ctx)
+	if err != nil {
+		return Update{}, err
+	}
+	now := time.Now
+	if t.Now != nil {
+		now = t.Now
+	}
+	posted := now().UTC().Truncate(time.Millisecond)
+	u := Update{ID: uniqueID(list, strconv.FormatInt(posted.UnixMilli(), 36)), Message: message, PostedAt: posted}
+	list = append([]Update{u}, list...)
+	if len(list) > MaxEntries {
+		list = list[:MaxEntries]
+	}
+	return u, t.save(ctx, list)
+}
+
+// Delete removes the update with the given id.
+func (t Timeline) Delete(ctx context.Context, id string) error {
+	list, err := t.List(ctx)
+	if err != nil {
+		return err
+	}
+	for i, u := range list {
+		if u.ID == id {
+			return t.save(ctx, append(list[:i], list[i+1:]...))
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownID, id)
+}
+
+// uniqueID suffixes base when two updates are posted in the same millisecond.
+func uniqueID(list []Update, base string) string {
+	id := base
+	for n := 2; ; n++ {
+		taken := false
+		for _, u := range list {
+			taken = taken || u.ID == id
+		}
+		if !taken {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (t Timeline) save(ctx context.Context, list []Update) error {
+	if list == nil {
+		list = []Update{}
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return t.KV.Put(ctx, Key, raw)
+}
@@ -0,0 +1,134 @@
+package updates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+type memStore map[string][]byte
+
+func (m memStore) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("get %s: %w", key, cloudflare.ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m memStore) Put(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func clock(start time.Time) func() time.Time {
+	return func() time.Time {
+		start = start.Add(time.Minute)
+		return start
+	}
+}
+
+func TestTimelinePostListDelete(t *testing.T) {
+	ctx := context.Background()
+	tl := Timeline{KV: memStore{}, Now: clock(time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC))}
+
+	if list, err := tl.List(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("empty List = %v, %v", list, err)
+	}
+
+	first, err := tl.Post(ctx, "  Started DB migration ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Message != "Started DB migration" {
+		t.Errorf("message not trimmed: %q", first.Message)
+	}
+	second, _ := tl.Post(ctx, "DB migration 60% done")
+
+	list, err := tl.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+		t.Fatalf("List = %+v, want newest first", list)
+	}
+
+	if err := tl.Delete(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := tl.Delete(ctx, first.ID); !errors.Is(err, ErrUnknownID) {
+		t.Errorf("second Delete error = %v, want ErrUnknownID", err)
+	}
+	if list, _ := tl.List(ctx); len(list) != 1 || list[0].ID != second.ID {
+		t.Errorf("after Delete List = %+v", list)
+	}
+}
+
+func TestTimelineRejectsBadMessages(t *testing.T) {
+	tl := Timeline{KV: memStore{}}
+	for _, msg := range []string{"", "   ", strings.Repeat("x", MaxMessageLength+1)} {
+		if _, err := tl.Post(context.Background(), msg); err == nil {
+			t.Errorf("Post(%d chars) succeeded, want error", len(msg))
+		}
+	}
+}
+
+func TestTimelineCapsEntriesAndDedupesIDs(t *testing.T) {
+	ctx := context.Background()
+	fixed := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	tl := Timeline{KV: memStore{}, Now: func() time.Time { return fixed }}
+
+	for i := 0; i < MaxEntries+5; i++ {
+		if _, err := tl.Post(ctx, fmt.Sprintf("update %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, _ := tl.List(ctx)
+	if len(list) != MaxEntries {
+		t.Fatalf("len(List) = %d, want %d", len(list), MaxEntries)
+	}
+	seen := map[string]bool{}
+	for _, u := range list {
+		if seen[u.ID] {
+			t.Fatalf("duplicate id %q", u.ID)
+		}
+		seen[u.ID] = true
+	}
+	if list[0].Message != fmt.Sprintf("update %d", MaxEntries+4) {
+		t.Errorf("newest update = %q", list[0].Message)
+	}
+}
+
+func TestTimelineAgainstMockKV(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	nsID, err := client.CreateKVNamespace(ctx, cftest.AccountID, "maintenance-updates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := Timeline{KV: client.KV(cftest.AccountID, nsID)}
+
+	u, err := tl.Post(ctx, `Restoring "orders" table <50%>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := tl.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Message != u.Message || !list[0].PostedAt.Equal(u.PostedAt) {
+		t.Fatalf("List = %+v, want [%+v]", list, u)
+	}
+	if err := tl.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := tl.List(ctx); len(list) != 0 {
+		t.Errorf("List after Delete = %+v", list)
+	}
+}
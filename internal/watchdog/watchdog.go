@@ -0,0 +1,190 @@
+// Package watchdog turns maintenance off when it has been left on: once the
+// maintenance window has ended plus a grace period, or once it has been on
+// longer than a maximum duration. It runs as `maintctl watchdog` against the
+// KV runtime state; the worker's Cron Trigger runs the same rules. Keep Decide
+// in sync with watchdogVerdict in worker.js; both are tested against
+// tests/fixtures/watchdog.json.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/audit"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/notify"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+// Actor is who audit events and notifications name for the watchdog's changes.
+const Actor = "watchdog"
+
+// Config is the module's watchdog variable.
+type Config struct {
+	// MaxDuration is how long one enable may last; zero means no limit.
+	MaxDuration time.Duration
+	// Grace is how long maintenance may stay on after the window ends.
+	Grace time.Duration
+}
+
+// Actions a Verdict can take.
+const (
+	ActionNone    = "none"
+	ActionStamp   = "stamp"
+	ActionDisable = "disable"
+)
+
+// Verdict is what a tick does. Stamp records EnabledAt for state written
+// without it (by Terraform or an older maintctl) so the max duration counts
+// from the first tick that saw it on.
+type Verdict struct {
+	Action string
+	Reason string
+}
+
+// Decide applies the rules to s at now. A window that ended before the
+// current enable began does not count: enabling maintenance by hand after a
+// stale window stays on until the max duration.
+func Decide(s state.State, now time.Time, cfg Config) Verdict {
+	if !s.Enabled {
+		return Verdict{Action: ActionNone}
+	}
+	enabledAt, err := time.Parse(time.RFC3339, s.EnabledAt)
+	stamped := err == nil
+
+	if end, err := time.Parse(time.RFC3339, s.WindowEnd); err == nil {
+		deadline := end.Add(cfg.Grace)
+		if !now.Before(deadline) && (!stamped || enabledAt.Before(deadline)) {
+			return Verdict{ActionDisable, fmt.Sprintf("maintenance window ended at %s (grace %s)", s.WindowEnd, minutes(cfg.Grace))}
+		}
+	}
+	if cfg.MaxDuration > 0 && stamped && !now.Before(enabledAt.Add(cfg.MaxDuration)) {
+		return Verdict{ActionDisable, fmt.Sprintf("maintenance on since %s, over the %s limit", s.EnabledAt, minutes(cfg.MaxDuration))}
+	}
+	if !stamped {
+		return Verdict{Action: ActionStamp}
+	}
+	return Verdict{Action: ActionNone}
+}
+
+// minutes formats d the way the worker does, which only knows whole minutes.
+func minutes(d time.Duration) string {
+	if d%time.Minute != 0 {
+		return d.String()
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// Watchdog checks one environment's runtime state.
+type Watchdog struct {
+	KV          state.Store
+	Config      Config
+	Environment string
+	// Notify lists notification targets told when maintenance is turned off.
+	Notify []string
+	HTTP   *http.Client
+	// Audit, when set, records every change the watchdog makes.
+	Audit audit.Sink
+	// Logf, when set, reports what each tick did.
+	Logf func(format string, args ...any)
+}
+
+// Tick loads the state, applies the verdict and reports it. An error after
+// the state was saved (audit or notification) is returned with the verdict
+// so the caller knows maintenance was still turned off.
+func (w *Watchdog) Tick(ctx context.Context, now time.Time) (Verdict, error) {
+	prev, err := state.Load(ctx, w.KV)
+	if err != nil {
+		return Verdict{}, err
+	}
+	// Environment only labels the audit event and the notification, and the
+	// namespace's own environment wins over it.
+	environment, err := prev.EnvironmentFor(w.Environment)
+	if err != nil {
+		return Verdict{}, err
+	}
+	v := Decide(prev, now, w.Config)
+	switch v.Action {
+	case ActionStamp:
+		// Save stamps EnabledAt when it is empty.
+		if _, err := state.Save(ctx, w.KV, prev, now); err != nil {
+			return v, err
+		}
+		w.logf("maintenance is on; watching from %s", now.UTC().Format(time.RFC3339))
+		return v, nil
+	case ActionDisable:
+	default:
+		return v, nil
+	}
+
+	// The window goes too: one still open would keep the page up without
+	// Enabled. Keep in sync with watchdogDisabledState in worker.js.
+	next := prev
+	next.Enabled = false
+	next.WindowStart, next.WindowEnd = "", ""
+	next, err = state.Save(ctx, w.KV, next, now)
+	if err != nil {
+		return v, err
+	}
+	w.logf("maintenance turned off: %s", v.Reason)
+
+	var errs []error
+	if w.Audit != nil {
+		e := audit.NewEvent(now, environment, Actor, ActionDisable, v.Reason, prev, next)
+		if err := w.Audit.Append(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("not audited: %w", err))
+		}
+	}
+	m := notify.Message{
+		Status:      notify.StatusCompleted,
+		Schedule:    Actor,
+		Environment: environment,
+		WindowStart: prev.WindowStart,
+		WindowEnd:   prev.WindowEnd,
+		Detail:      v.Reason,
+	}
+	if err := notify.Send(ctx, w.HTTP, w.Notify, m); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return v, fmt.Errorf("maintenance turned off but %w", err)
+	}
+	return v, nil
+}
+
+// Clock lets tests drive Run without waiting.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is the wall clock.
+var RealClock Clock = realClock{}
+
+// Run ticks immediately and then every interval until ctx is done. Tick
+// errors are logged and the next tick tries again.
+func (w *Watchdog) Run(ctx context.Context, clock Clock, interval time.Duration) error {
+	for {
+		if _, err := w.Tick(ctx, clock.Now()); err != nil {
+			w.logf("error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(interval):
+		}
+	}
+}
+
+func (w *Watchdog) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+	}
+}
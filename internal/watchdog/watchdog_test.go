@@ -0,0 +1,188 @@
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/audit"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/state"
+)
+
+// The same cases drive watchdogVerdict in tests/unit/worker.test.js.
+func TestDecideSharedFixture(t *testing.T) {
+	data, err := os.ReadFile("../../tests/fixtures/watchdog.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture struct {
+		Config struct {
+			MaxDurationMinutes int `json:"max_duration_minutes"`
+			GraceMinutes       int `json:"grace_minutes"`
+		} `json:"config"`
+		Cases []struct {
+			Name       string      `json:"name"`
+			Now        time.Time   `json:"now"`
+			State      state.State `json:"state"`
+			Want       string      `json:"want"`
+			WantReason string      `json:"want_reason"`
+		} `json:"cases"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		MaxDuration: time.Duration(fixture.Config.MaxDurationMinutes) * time.Minute,
+		Grace:       time.Duration(fixture.Config.GraceMinutes) * time.Minute,
+	}
+	for _, tc := range fixture.Cases {
+		v := Decide(tc.State, tc.Now, cfg)
+		if v.Action != tc.Want || v.Reason != tc.WantReason {
+			t.Errorf("%s: Decide = %s %q, want %s %q", tc.Name, v.Action, v.Reason, tc.Want, tc.WantReason)
+		}
+	}
+}
+
+// fakeClock jumps forward on every After and cancels the run once it passes
+// stop, so Run replays hours of ticks instantly.
+type fakeClock struct {
+	t      time.Time
+	stop   time.Time
+	cancel context.CancelFunc
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.t = c.t.Add(d)
+	if c.t.After(c.stop) {
+		c.cancel()
+		return nil
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+func TestRunAgainstMockKV(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	nsID, err := client.CreateKVNamespace(ctx, cftest.AccountID, "maintenance-production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.KV(cftest.AccountID, nsID)
+	// What Terraform writes: no enabled_at.
+	seed := `{"enabled":true,"title":"Maintenance Mode","message":"Back soon","rollout_percentage":100,"window_start":"2025-04-06T08:00:00Z","window_end":"2025-04-06T10:00:00Z"}`
+	if err := kv.Put(ctx, state.Key, []byte(seed)); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var hooks []map[string]any
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+		mu.Lock()
+		hooks = append(hooks, body)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	sink := &audit.FileSink{Path: filepath.Join(t.TempDir(), "audit.jsonl")}
+	w := &Watchdog{
+		KV:          kv,
+		Config:      Config{MaxDuration: 4 * time.Hour, Grace: 15 * time.Minute},
+		Environment: "production",
+		Notify:      []string{"webhook://" + receiver.URL},
+		HTTP:        receiver.Client(),
+		Audit:       sink,
+	}
+	var log []string
+	w.Logf = func(format string, args ...any) {
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+
+	start := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start, stop: start.Add(3 * time.Hour), cancel: cancel}
+	if err := w.Run(ctx, clock, 5*time.Minute); err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+
+	want := []string{
+		"maintenance is on; watching from 2025-04-06T08:00:00Z",
+		"maintenance turned off: maintenance window ended at 2025-04-06T10:00:00Z (grace 15m)",
+	}
+	if len(log) != len(want) || log[0] != want[0] || log[1] != want[1] {
+		t.Errorf("log = %q, want %q", log, want)
+	}
+
+	s, err := state.Load(context.Background(), kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled || s.EnabledAt != "" || s.WindowStart != "" || s.WindowEnd != "" ||
+		!s.UpdatedAt.Equal(start.Add(135*time.Minute)) || s.Message != "Back soon" {
+		t.Errorf("state after run = %+v", s)
+	}
+
+	if len(hooks) != 1 || hooks[0]["status"] != "COMPLETED" || hooks[0]["environment"] != "production" ||
+		hooks[0]["detail"] != "maintenance window ended at 2025-04-06T10:00:00Z (grace 15m)" {
+		t.Errorf("notifications = %v", hooks)
+	}
+
+	events, err := sink.List(context.Background(), audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Actor != Actor || events[0].Action != ActionDisable ||
+		events[0].Previous.EnabledAt != "2025-04-06T08:00:00Z" || events[0].New.Enabled {
+		t.Errorf("audit events = %+v", events)
+	}
+}
+
+func TestTickMaxDuration(t *testing.T) {
+	ctx := context.Background()
+	client := &cloudflare.Client{BaseURL: cftest.StartMock(t), Token: "test-token"}
+	nsID, err := client.CreateKVNamespace(ctx, cftest.AccountID, "maintenance-staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.KV(cftest.AccountID, nsID)
+	enabled := time.Date(2025, 4, 6, 8, 0, 0, 0, time.UTC)
+	// The window is still open when the limit trips.
+	open := state.State{
+		Enabled:           true,
+		RolloutPercentage: 100,
+		WindowStart:       enabled.Format(time.RFC3339),
+		WindowEnd:         enabled.Add(4 * time.Hour).Format(time.RFC3339),
+	}
+	if _, err := state.Save(ctx, kv, open, enabled); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watchdog{KV: kv, Config: Config{MaxDuration: time.Hour, Grace: 15 * time.Minute}}
+	if v, err := w.Tick(ctx, enabled.Add(59*time.Minute)); err != nil || v.Action != ActionNone {
+		t.Errorf("Tick before the limit = %+v, %v", v, err)
+	}
+	v, err := w.Tick(ctx, enabled.Add(time.Hour))
+	if err != nil || v.Action != ActionDisable {
+		t.Fatalf("Tick at the limit = %+v, %v", v, err)
+	}
+	// A window left in place would keep serving the page until it ends.
+	if s, _ := state.Load(ctx, kv); s.Enabled || s.WindowStart != "" || s.WindowEnd != "" {
+		t.Errorf("state after the limit = %+v", s)
+	}
+}
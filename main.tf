@@ -15,12 +15,157 @@ locals {
     "ip.geoip.country in {%s}",
     join(" ", [for region in var.allowed_regions : format("\"%s\"", region)])
   ) : ""
+
+  # Page template uploaded with the worker; run `maintctl template lint` for the full CSP checks
+  page_template = file(coalesce(var.page_template_file, "${path.module}/templates/default.html"))
+
+  # Per-environment names keep staging and production in one account from overwriting each other
+  script_name = coalesce(var.worker_script_name, "maintenance-page-worker-${lower(var.environment)}")
+
+  # One namespace serves status updates, the runtime state and auto maintenance trip records
+  kv_enabled = var.enable_status_updates || var.kv_runtime_state || var.auto_maintenance.enabled
+
+  # With runtime state in KV, an enabled scope, auto maintenance or stale copies the worker
+  # decides per request (or stores copies while the site is up), so its route must exist
+  route_enabled = (var.enabled || var.kv_runtime_state || var.auto_maintenance.enabled ||
+    length(var.stale_paths) > 0 || anytrue([for s in var.maintenance_scopes : s.enabled]))
+
+  # The worker checks schedules on one tick that sees every start and end on time:
+  # 15, 5, 3 or 1 minutes, the largest dividing every start minute and duration.
+  # Timezone offsets are multiples of 15 minutes, so it lines up in every zone.
+  # Keep in sync with TickMinutes in internal/schedule.
+  schedule_minute_terms = flatten([for s in var.schedules : concat(
+    [for item in split(",", try(regexall("\\S+", s.cron)[0], "")) : {
+      base = startswith(item, "*") ? 0 : try(tonumber(split("-", split("/", item)[0])[0]), 1)
+      step = strcontains(item, "/") ? try(tonumber(split("/", item)[1]), 1) : (item == "*" || strcontains(item, "-") ? 1 : 0)
+    }],
+    [{
+      base = try(tonumber(regex("([0-9]+)h", s.duration)[0]), 0) * 60 + try(tonumber(regex("([0-9]+)m", s.duration)[0]), 0)
+      step = 0
+    }]
+  )])
+  schedule_tick_minutes = [for g in [15, 5, 3, 1] : g if alltrue([for t in local.schedule_minute_terms : t.base % g == 0 && t.step % g == 0])][0]
+  schedule_cron         = local.schedule_tick_minutes == 1 ? "* * * * *" : "*/${local.schedule_tick_minutes} * * * *"
+
+  # Cron Triggers the worker's scheduled handler runs on; schedules act on the KV runtime state.
+  # The validation allows any whitespace between fields, so the watchdog cron is normalised.
+  worker_crons = distinct(compact([
+    join(" ", regexall("\\S+", var.watchdog.cron)),
+    var.kv_runtime_state && length(var.schedules) > 0 ? local.schedule_cron : "",
+  ]))
+
+  # Without rules, the top-level rate_limit fields make one rule over every
+  # request. Keep in sync with Config.Ruleset in internal/ratelimit.
+  rate_limit_rules = length(var.rate_limit.rules) > 0 ? var.rate_limit.rules : [{
+    description         = "Rate limit all requests"
+    expression          = "(http.request.uri.path matches \".*\")"
+    characteristics     = ["cf.colo.id", "ip.src"]
+    action              = var.rate_limit.action
+    period              = var.rate_limit.period
+    requests_per_period = var.rate_limit.requests_per_period
+    mitigation_timeout  = var.rate_limit.mitigation_timeout
+    requests_to_origin  = var.rate_limit.requests_to_origin
+    counting_expression = var.rate_limit.counting_expression
+    enabled             = true
+  }]
+
+  # Routes are keyed by zone and pattern, so removing or reordering one leaves the
+  # others in place. A single worker_route keeps a fixed key, moved from index 0 below.
+  routes = length(var.worker_routes) > 0 ? [for r in var.worker_routes : merge(r, { key = "${r.zone_id}/${r.pattern}" })] : [{
+    key     = "worker_route"
+    zone_id = var.cloudflare_zone_id
+    pattern = var.worker_route
+  }]
+
+  # With rate_limit.applies_to = "maintenance_page" the ruleset exists only while
+  # maintenance is on and its rules only see requests the worker answers with the
+  # page: on a route in cloudflare_zone_id, not bypassed, writes in read_only mode,
+  # and in a scope that is on. Scopes rank as in internal/scope.
+  page_routes = [for r in local.routes : {
+    host = split("/", r.pattern)[0]
+    path = "/${join("/", slice(split("/", r.pattern), 1, length(split("/", r.pattern))))}"
+  } if r.zone_id == var.cloudflare_zone_id]
+  page_route_expressions = [for r in local.page_routes : join(" and ", compact([
+    r.host == "*" ? "" : startswith(r.host, "*.") ? format("ends_with(http.host, \"%s\")", substr(r.host, 1, -1)) : startswith(r.host, "*") ? format("(http.host eq \"%s\" or ends_with(http.host, \".%s\"))", substr(r.host, 1, -1), substr(r.host, 1, -1)) : format("http.host eq \"%s\"", r.host),
+    r.path == "/*" ? "" : endswith(r.path, "*") ? format("starts_with(http.request.uri.path, \"%s\")", trimsuffix(r.path, "*")) : format("http.request.uri.path eq \"%s\"", r.path),
+  ]))]
+  page_scopes = [for s in var.maintenance_scopes : {
+    enabled = s.enabled
+    rank    = [s.host == "" ? 0 : startswith(s.host, "*.") ? 1 : 2, startswith(s.host, "*.") ? length(s.host) - 1 : length(s.host), length(replace(s.path, "/\\/+$/", ""))]
+    expression = join(" and ", compact([
+      s.host == "" ? "" : startswith(s.host, "*.") ? format("ends_with(http.host, \"%s\")", substr(s.host, 1, -1)) : format("http.host eq \"%s\"", s.host),
+      replace(s.path, "/\\/+$/", "") == "" ? "" : format("(http.request.uri.path eq \"%[1]s\" or starts_with(http.request.uri.path, \"%[1]s/\"))", replace(s.path, "/\\/+$/", "")),
+    ]))
+  }]
+  # A request is in maintenance when the best matching scope is on, or no scope matches and enabled is set
+  page_scope_expression = length(local.page_scopes) == 0 ? "" : join(" or ", concat(
+    [for s in local.page_scopes : join(" and not ", concat(["(${s.expression})"], [
+      for t in local.page_scopes : "(${t.expression})"
+      if t.rank[0] > s.rank[0] || (t.rank[0] == s.rank[0] && (t.rank[1] > s.rank[1] || (t.rank[1] == s.rank[1] && t.rank[2] > s.rank[2])))
+    ])) if s.enabled],
+    var.enabled ? ["not (${join(" or ", [for s in local.page_scopes : "(${s.expression})"])})"] : [],
+  ))
+  page_expression = join(" and ", [for e in compact([
+    join(" or ", [for e in local.page_route_expressions : e == "" ? "true" : length(local.page_route_expressions) > 1 ? "(${e})" : e]),
+    length(var.allowed_ips) > 0 || length(var.allowed_regions) > 0 ? "not (${join(" or ", compact([local.ip_bypass_expression, local.region_bypass_expression]))})" : "",
+    var.mode == "read_only" ? "not (http.request.method in {\"GET\" \"HEAD\" \"OPTIONS\"})" : "",
+    local.page_scope_expression,
+  ]) : "(${e})"])
+  maintenance_on = var.enabled || anytrue([for s in var.maintenance_scopes : s.enabled])
+
+  rate_limit_page_only = var.rate_limit.applies_to == "maintenance_page"
+  rate_limit_active = var.rate_limit.enabled && (!local.rate_limit_page_only ||
+    (local.maintenance_on && length(local.page_routes) > 0))
+
+  # Rulesets to run from their phase's entrypoint with ruleset_mode = "execute", keyed by the execute rule's ref suffix
+  execute_rulesets = merge(
+    { for r in cloudflare_ruleset.maintenance_bypass : "maintenance-bypass" => { phase = r.phase, id = r.id, first = true } if var.ruleset_mode == "execute" },
+    { for r in cloudflare_ruleset.rate_limit : "rate-limit" => { phase = r.phase, id = r.id, first = false } if var.ruleset_mode == "execute" },
+  )
+}
+
+# KV namespace for status updates, runtime state and auto maintenance trips, shared by maintctl and the worker
+resource "cloudflare_workers_kv_namespace" "maintenance" {
+  count      = local.kv_enabled ? 1 : 0
+  account_id = var.cloudflare_account_id
+  title      = "maintenance-${var.environment}"
+}
+
+# Seed the runtime state from the Terraform variables when the namespace is
+# created. From then on maintctl, approvals, the watchdog and schedules own the
+# value, so later applies leave it alone; `terraform apply -replace` re-seeds it.
+# Keep the document format in sync with internal/state.
+resource "cloudflare_workers_kv" "runtime_state" {
+  count        = var.kv_runtime_state ? 1 : 0
+  account_id   = var.cloudflare_account_id
+  namespace_id = cloudflare_workers_kv_namespace.maintenance[0].id
+  key          = "state"
+  value = jsonencode({
+    enabled      = var.enabled
+    title        = var.maintenance_title
+    message      = var.maintenance_message
+    window_start = var.maintenance_window != null ? var.maintenance_window.start_time : ""
+    window_end   = var.maintenance_window != null ? var.maintenance_window.end_time : ""
+
+    rollout_percentage = var.rollout_percentage
+    environment        = var.environment
+  })
+
+  lifecycle {
+    ignore_changes = [value]
+  }
+}
+
+# Key the worker signs rollout bucket cookies with
+resource "random_password" "rollout_cookie_key" {
+  length  = 32
+  special = false
 }
 
 # Deploy the maintenance worker
 resource "cloudflare_workers_script" "maintenance" {
   account_id = var.cloudflare_account_id
-  name       = "maintenance-page-worker"
+  name       = local.script_name
   content    = file("${path.module}/worker.js")
 
   # Environment variables for the worker
@@ -64,6 +209,118 @@ resource "cloudflare_workers_script" "maintenance" {
     text = var.maintenance_window != null ? var.maintenance_window.end_time : ""
   }
 
+  plain_text_binding {
+    name = "MAINTENANCE_MODE"
+    text = var.mode
+  }
+
+  plain_text_binding {
+    name = "MAINTENANCE_ROLLOUT_PERCENTAGE"
+    text = tostring(var.rollout_percentage)
+  }
+
+  plain_text_binding {
+    name = "MAINTENANCE_SCOPES"
+    text = jsonencode(var.maintenance_scopes)
+  }
+
+  plain_text_binding {
+    name = "STALE_PATHS"
+    text = jsonencode(var.stale_paths)
+  }
+
+  plain_text_binding {
+    name = "STALE_TTL_SECONDS"
+    text = tostring(var.stale_ttl_seconds)
+  }
+
+  plain_text_binding {
+    name = "AUTO_MAINTENANCE"
+    text = jsonencode(var.auto_maintenance)
+  }
+
+  plain_text_binding {
+    name = "WATCHDOG"
+    text = jsonencode({
+      max_duration_minutes = var.watchdog.max_duration_minutes
+      grace_minutes        = var.watchdog.grace_minutes
+    })
+  }
+
+  plain_text_binding {
+    name = "SCHEDULES"
+    text = jsonencode([for s in var.schedules : { name = s.name, cron = s.cron, duration = s.duration, timezone = s.timezone }])
+  }
+
+  # Blackouts hold scheduled windows; the worker resolves dates in their timezone
+  plain_text_binding {
+    name = "BLACKOUTS"
+    text = jsonencode(var.blackouts)
+  }
+
+  plain_text_binding {
+    name = "BLACKOUT_CALENDAR"
+    text = var.blackout_calendar
+  }
+
+  plain_text_binding {
+    name = "CALENDAR_FEED"
+    text = var.calendar_feed.enabled ? jsonencode({
+      host = lower(local.status_hostname)
+      name = var.calendar_feed.name
+      days = var.calendar_feed.days
+    }) : ""
+  }
+
+  plain_text_binding {
+    name = "MAINTENANCE_ENVIRONMENT"
+    text = var.environment
+  }
+
+  plain_text_binding {
+    name = "KV_RUNTIME_STATE"
+    text = tostring(var.kv_runtime_state)
+  }
+
+  plain_text_binding {
+    name = "DISPLAY_TIMEZONE"
+    text = var.display_timezone
+  }
+
+  plain_text_binding {
+    name = "PAGE_TEMPLATE"
+    text = local.page_template
+  }
+
+  plain_text_binding {
+    name = "LOCALIZED_CONTENT"
+    text = jsonencode(var.localized_content)
+  }
+
+  plain_text_binding {
+    name = "DEFAULT_LOCALE"
+    text = var.default_locale
+  }
+
+  plain_text_binding {
+    name = "COUNTRY_LOCALES"
+    text = jsonencode(var.country_locales)
+  }
+
+  dynamic "kv_namespace_binding" {
+    for_each = cloudflare_workers_kv_namespace.maintenance
+    content {
+      name         = "MAINTENANCE_KV"
+      namespace_id = kv_namespace_binding.value.id
+    }
+  }
+
+  # Signs the rollout bucket cookie so clients can't choose their bucket
+  secret_text_binding {
+    name = "ROLLOUT_COOKIE_KEY"
+    text = random_password.rollout_cookie_key.result
+  }
+
   secret_text_binding {
     name = "ALLOWED_IPS"
     text = jsonencode(var.allowed_ips)
@@ -73,19 +330,103 @@ resource "cloudflare_workers_script" "maintenance" {
     name = "ALLOWED_REGIONS"
     text = jsonencode(var.allowed_regions)
   }
+
+  # Webhook URLs and routing keys are credentials
+  secret_text_binding {
+    name = "WATCHDOG_NOTIFY"
+    text = jsonencode(var.watchdog.notify)
+  }
+
+  secret_text_binding {
+    name = "SCHEDULE_NOTIFY"
+    text = jsonencode({ for s in var.schedules : s.name => s.notify })
+  }
+
+  lifecycle {
+    # Renaming replaces the script; create the new one first so routes never point at nothing
+    create_before_destroy = true
+
+    precondition {
+      condition     = can(regex("^[a-z0-9][a-z0-9_-]{0,62}$", local.script_name))
+      error_message = "The worker script name derived from environment must be 1-63 lowercase letters, digits, dashes or underscores; set worker_script_name"
+    }
+
+    precondition {
+      condition     = alltrue([for name in ["title", "message", "window", "contact"] : can(regex("\\{\\{\\s*${name}\\s*\\}\\}", local.page_template))])
+      error_message = "Page template must contain the {{title}}, {{message}}, {{window}} and {{contact}} placeholders"
+    }
+
+    precondition {
+      condition     = !can(regex("(?i)<script", local.page_template))
+      error_message = "Page template must not contain <script> tags; only the worker's nonced countdown script may run"
+    }
+
+    precondition {
+      condition     = length(var.schedules) == 0 || var.kv_runtime_state
+      error_message = "schedules needs kv_runtime_state = true; the worker opens and closes the windows in the KV runtime state"
+    }
+
+    # The page-only rate limit is created and deleted by Terraform when enabled or a scope
+    # changes, so it can't follow maintenance that the worker turns on by itself
+    precondition {
+      condition = !local.rate_limit_page_only || !(var.kv_runtime_state || length(var.schedules) > 0 ||
+      var.auto_maintenance.enabled || var.maintenance_window != null)
+      error_message = "rate_limit.applies_to = \"maintenance_page\" can't be combined with kv_runtime_state, schedules, auto_maintenance or maintenance_window; they turn maintenance on without Terraform, so the rate limit wouldn't follow them"
+    }
+
+    precondition {
+      condition     = length(var.localized_content) == 0 || contains(keys(var.localized_content), var.default_locale)
+      error_message = "default_locale must be one of the localized_content locales"
+    }
+
+    precondition {
+      condition     = alltrue([for locale in values(var.country_locales) : length(var.localized_content) == 0 || contains(keys(var.localized_content), locale)])
+      error_message = "Every country_locales value must be one of the localized_content locales"
+    }
+  }
 }
 
-# Create the worker route when enabled
+# Create the worker routes when enabled (always, when the worker decides per request)
 resource "cloudflare_workers_route" "maintenance" {
-  count       = var.enabled ? 1 : 0
+  for_each    = { for r in local.routes : r.key => r if local.route_enabled }
+  zone_id     = each.value.zone_id
+  pattern     = each.value.pattern
+  script_name = cloudflare_workers_script.maintenance.name
+}
+
+# Routes used to be indexed by position; the single worker_route keeps its route.
+# worker_routes keys can't be computed here: see "Upgrading routes keyed by position" in the README
+moved {
+  from = cloudflare_workers_route.maintenance[0]
+  to   = cloudflare_workers_route.maintenance["worker_route"]
+}
+
+# The status hostname serves the .ics feed whether or not maintenance is on
+resource "cloudflare_workers_route" "calendar_feed" {
+  count       = var.calendar_feed.enabled ? 1 : 0
   zone_id     = var.cloudflare_zone_id
-  pattern     = var.worker_route
+  pattern     = "${lower(local.status_hostname)}/maintenance.ics"
   script_name = cloudflare_workers_script.maintenance.name
 }
 
+# Open and close scheduled windows and run the watchdog from the worker; every trigger checks the KV runtime state
+resource "cloudflare_workers_cron_trigger" "maintenance" {
+  count       = length(local.worker_crons) > 0 ? 1 : 0
+  account_id  = var.cloudflare_account_id
+  script_name = cloudflare_workers_script.maintenance.name
+  schedules   = local.worker_crons
+
+  lifecycle {
+    precondition {
+      condition     = var.watchdog.cron == "" || var.kv_runtime_state
+      error_message = "watchdog.cron needs kv_runtime_state = true; the watchdog turns maintenance off in the KV runtime state"
+    }
+  }
+}
+
 # Create a DNS record for maintenance status page
 resource "cloudflare_record" "maintenance_status" {
-  count   = var.enabled ? 1 : 0
+  count   = var.enabled || var.calendar_feed.enabled ? 1 : 0
   zone_id = var.cloudflare_zone_id
   name    = "maintenance-status-${var.environment}"
   content = "100::"
@@ -100,7 +441,7 @@ resource "cloudflare_ruleset" "maintenance_bypass" {
   count   = var.enabled && (length(var.allowed_ips) > 0 || length(var.allowed_regions) > 0) ? 1 : 0
   zone_id = var.cloudflare_zone_id
   name    = "maintenance-bypass-${var.environment}"
-  kind    = "zone"
+  kind    = var.ruleset_mode == "execute" ? "custom" : "zone"
   phase   = "http_request_firewall_custom"
 
   rules {
@@ -116,25 +457,61 @@ resource "cloudflare_ruleset" "maintenance_bypass" {
 
 # Rate limiting using modern Cloudflare Ruleset API (replaces deprecated cloudflare_rate_limit)
 resource "cloudflare_ruleset" "rate_limit" {
-  count       = var.rate_limit.enabled ? 1 : 0
+  count       = local.rate_limit_active ? 1 : 0
   zone_id     = var.cloudflare_zone_id
   name        = "Rate Limiting Rules"
   description = "Rate limiting for maintenance page protection"
-  kind        = "zone"
+  kind        = var.ruleset_mode == "execute" ? "custom" : "zone"
   phase       = "http_ratelimit"
 
-  rules {
-    action = var.rate_limit.action
-    ratelimit {
-      characteristics     = ["cf.colo.id", "ip.src"]
-      period              = var.rate_limit.period
-      requests_per_period = var.rate_limit.requests_per_period
-      mitigation_timeout  = var.rate_limit.mitigation_timeout
-      requests_to_origin  = var.rate_limit.requests_to_origin
-      counting_expression = var.rate_limit.counting_expression
+  # Rules keep the order of rate_limit.rules; the first one over its threshold acts
+  dynamic "rules" {
+    for_each = local.rate_limit_rules
+    content {
+      action = rules.value.action
+      ratelimit {
+        characteristics     = rules.value.characteristics
+        period              = rules.value.period
+        requests_per_period = rules.value.requests_per_period
+        mitigation_timeout  = rules.value.mitigation_timeout
+        requests_to_origin  = rules.value.requests_to_origin
+        counting_expression = rules.value.counting_expression
+      }
+      expression  = local.rate_limit_page_only ? "${local.page_expression} and (${rules.value.expression})" : rules.value.expression
+      description = rules.value.description
+      enabled     = rules.value.enabled
     }
-    expression  = "(http.request.uri.path matches \".*\")"
-    description = "Rate limit all requests"
-    enabled     = true
+  }
+}
+
+# With ruleset_mode = "execute" the rulesets above are custom rulesets; each is run by one execute rule in its
+# phase's entrypoint, which maintctl adds and removes so rules other teams keep there are left alone
+resource "terraform_data" "ruleset_execute" {
+  for_each = local.execute_rulesets
+
+  # Destroy-time provisioners can only read self, so everything detach needs is kept in input
+  input = {
+    command = var.maintctl_command
+    zone_id = var.cloudflare_zone_id
+    phase   = each.value.phase
+    ref     = "maintenance-${var.environment}-${each.key}"
+  }
+  triggers_replace = [each.value.id, var.cloudflare_zone_id]
+
+  # The bypass's execute rule goes first, but its skip only ends the bypass ruleset:
+  # other teams' firewall rules still run for allowed clients
+  provisioner "local-exec" {
+    command = "${self.input.command} ruleset attach -zone-id ${self.input.zone_id} -phase ${self.input.phase} -ref ${self.input.ref} -ruleset ${each.value.id}${each.value.first ? " -first" : ""}"
+    environment = {
+      CLOUDFLARE_API_TOKEN = var.cloudflare_api_token
+    }
+  }
+
+  # Destroy-time provisioners can't read variables, so detach uses the runner's CLOUDFLARE_API_TOKEN.
+  # Without it maintctl fails and so does the destroy, keeping the ruleset until detach succeeds
+  provisioner "local-exec" {
+    when       = destroy
+    on_failure = fail
+    command    = "${self.input.command} ruleset detach -zone-id ${self.input.zone_id} -phase ${self.input.phase} -ref ${self.input.ref}"
   }
 }
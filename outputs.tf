@@ -14,13 +14,27 @@ output "worker_script_name" {
 }
 
 output "worker_route" {
-  description = "The route pattern for the maintenance worker"
-  value       = var.enabled ? var.worker_route : "Not enabled"
+  description = "The route patterns for the maintenance worker, comma-separated"
+  value       = local.route_enabled ? join(", ", local.routes[*].pattern) : "Not enabled"
 }
 
 output "worker_route_pattern" {
-  description = "The route pattern for the maintenance worker (alias for compatibility)"
-  value       = var.enabled ? var.worker_route : "Maintenance mode disabled"
+  description = "The route patterns for the maintenance worker (alias for compatibility)"
+  value       = local.route_enabled ? join(", ", local.routes[*].pattern) : "Maintenance mode disabled"
+}
+
+output "worker_routes" {
+  description = "Deployed worker routes with their zone and route IDs (empty when disabled)"
+  value = [for r in local.routes : {
+    id      = cloudflare_workers_route.maintenance[r.key].id
+    zone_id = r.zone_id
+    pattern = r.pattern
+  } if local.route_enabled]
+}
+
+output "kv_namespace_id" {
+  description = "ID of the KV namespace holding status updates and runtime state (pass to maintctl as -namespace-id)"
+  value       = local.kv_enabled ? cloudflare_workers_kv_namespace.maintenance[0].id : null
 }
 
 output "maintenance_enabled" {
@@ -43,12 +57,18 @@ locals {
   clean_domain = trim(replace(replace(var.worker_route, "*.", ""), "/*", ""), "/")
 
   # Construct maintenance status page URL
-  maintenance_status_url = format("https://maintenance-status-%s.%s", var.environment, local.clean_domain)
+  status_hostname        = format("maintenance-status-%s.%s", var.environment, local.clean_domain)
+  maintenance_status_url = "https://${local.status_hostname}"
 }
 
 output "dns_record_id" {
   description = "ID of the DNS record for the maintenance status page"
-  value       = var.enabled && length(cloudflare_record.maintenance_status) > 0 ? cloudflare_record.maintenance_status[0].id : "No DNS record created"
+  value       = length(cloudflare_record.maintenance_status) > 0 ? cloudflare_record.maintenance_status[0].id : "No DNS record created"
+}
+
+output "calendar_feed_url" {
+  description = "URL of the iCalendar feed of upcoming maintenance (null unless calendar_feed is enabled)"
+  value       = var.calendar_feed.enabled ? "https://${lower(local.status_hostname)}/maintenance.ics" : null
 }
 
 output "ruleset_id" {
@@ -73,10 +93,10 @@ output "allowed_regions" {
 
 output "rate_limit_ruleset_id" {
   description = "The ID of the rate limiting ruleset"
-  value       = var.rate_limit.enabled ? cloudflare_ruleset.rate_limit[0].id : null
+  value       = local.rate_limit_active ? cloudflare_ruleset.rate_limit[0].id : null
 }
 
 output "rate_limit_enabled" {
-  description = "Whether rate limiting is currently enabled"
-  value       = var.rate_limit.enabled
+  description = "Whether rate limiting is currently enabled; with applies_to = \"maintenance_page\", only while maintenance is on"
+  value       = local.rate_limit_active
 }
@@ -0,0 +1,11 @@
+// Package templates ships the built-in maintenance page template. The same
+// file is the default for the module's page_template_file variable and is
+// mirrored as DEFAULT_TEMPLATE in worker.js.
+package templates
+
+import _ "embed"
+
+// Default is templates/default.html.
+//
+//go:embed default.html
+var Default string
@@ -0,0 +1,2637 @@
+# Code generated by go test ./internal/schedule -update from
+# tests/fixtures/cron-corpus.json. DO NOT EDIT.
+
+# Cron expressions ParseCron accepts pass the schedules validation
+run "cron_corpus_accepted" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "0 2 * * SUN"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-2"
+        cron     = "0 2 * * sun"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-3"
+        cron     = "*/15 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-4"
+        cron     = "0 3 1 * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-5"
+        cron     = "0 0 * * 0,6"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-6"
+        cron     = "30 4 * * 1-5"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-7"
+        cron     = "30 4 * * MON-FRI"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-8"
+        cron     = "30 4 * * Mon-Fri"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-9"
+        cron     = "0 0 1 JAN *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-10"
+        cron     = "0 0 1 jan-mar,OCT *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-11"
+        cron     = "0 2 * DEC SAT"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-12"
+        cron     = "0 2 * * 7"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-13"
+        cron     = "0 2 * * 0-7"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-14"
+        cron     = "0 2 * * MON/2"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-15"
+        cron     = "5/20 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-16"
+        cron     = "0-30/10 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-17"
+        cron     = "00 09 01 01 *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-18"
+        cron     = "59 23 31 12 6"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-19"
+        cron     = "0 3 1,15 * MON"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-20"
+        cron     = "*/59 */23 */31 */12 */7"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-21"
+        cron     = "0 2 * * 5-SAT"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-22"
+        cron     = "  0 2 * * SUN  "
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-23"
+        cron     = "0  2 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+      {
+        name     = "schedule-24"
+        cron     = "0\t2 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# ParseCron rejects ""
+run "cron_corpus_rejected_1" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = ""
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "0 2 * *"
+run "cron_corpus_rejected_2" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "0 2 * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "0 2 * * * *"
+run "cron_corpus_rejected_3" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "0 2 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "60 * * * *"
+run "cron_corpus_rejected_4" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "60 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "99 99 * * *"
+run "cron_corpus_rejected_5" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "99 99 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* 24 * * *"
+run "cron_corpus_rejected_6" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* 24 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * 0 * *"
+run "cron_corpus_rejected_7" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * 0 * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * 32 * *"
+run "cron_corpus_rejected_8" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * 32 * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * 0 *"
+run "cron_corpus_rejected_9" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * 0 *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * 13 *"
+run "cron_corpus_rejected_10" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * 13 *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * 8"
+run "cron_corpus_rejected_11" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * 8"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "5-1 * * * *"
+run "cron_corpus_rejected_12" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "5-1 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* 20-4 * * *"
+run "cron_corpus_rejected_13" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* 20-4 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * DEC-JAN *"
+run "cron_corpus_rejected_14" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * DEC-JAN *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * FRI-MON"
+run "cron_corpus_rejected_15" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * FRI-MON"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * SAT-1"
+run "cron_corpus_rejected_16" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * SAT-1"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "*/0 * * * *"
+run "cron_corpus_rejected_17" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "*/0 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "*/60 * * * *"
+run "cron_corpus_rejected_18" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "*/60 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* */24 * * *"
+run "cron_corpus_rejected_19" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* */24 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * */13 *"
+run "cron_corpus_rejected_20" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * */13 *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * */8"
+run "cron_corpus_rejected_21" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * */8"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "+5 * * * *"
+run "cron_corpus_rejected_22" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "+5 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "005 * * * *"
+run "cron_corpus_rejected_23" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "005 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * 1/007"
+run "cron_corpus_rejected_24" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * 1/007"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "a * * * *"
+run "cron_corpus_rejected_25" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "a * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "? * * * *"
+run "cron_corpus_rejected_26" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "? * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * SUNDAY"
+run "cron_corpus_rejected_27" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * SUNDAY"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * JANUARY *"
+run "cron_corpus_rejected_28" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * JANUARY *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * JAN"
+run "cron_corpus_rejected_29" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * JAN"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * MON *"
+run "cron_corpus_rejected_30" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * MON *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * MON-"
+run "cron_corpus_rejected_31" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * MON-"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "* * * * -MON"
+run "cron_corpus_rejected_32" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "* * * * -MON"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "*-5 * * * *"
+run "cron_corpus_rejected_33" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "*-5 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "1-2-3 * * * *"
+run "cron_corpus_rejected_34" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "1-2-3 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "*/5/2 * * * *"
+run "cron_corpus_rejected_35" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "*/5/2 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "1,,2 * * * *"
+run "cron_corpus_rejected_36" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "1,,2 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects ",1 * * * *"
+run "cron_corpus_rejected_37" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = ",1 * * * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "0 2 L * *"
+run "cron_corpus_rejected_38" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "0 2 L * *"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "0 2 * * 5#2"
+run "cron_corpus_rejected_39" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "0 2 * * 5#2"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# ParseCron rejects "@daily"
+run "cron_corpus_rejected_40" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "schedule-1"
+        cron     = "@daily"
+        duration = "1h"
+        timezone = "UTC"
+      },
+    ]
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# watchdog.cron accepts "0 2 * * SUN"
+run "watchdog_cron_corpus_accepted_1" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * SUN"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * * sun"
+run "watchdog_cron_corpus_accepted_2" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * sun"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "*/15 * * * *"
+run "watchdog_cron_corpus_accepted_3" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/15 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 3 1 * *"
+run "watchdog_cron_corpus_accepted_4" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 3 1 * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 0 * * 0,6"
+run "watchdog_cron_corpus_accepted_5" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 0 * * 0,6"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "30 4 * * 1-5"
+run "watchdog_cron_corpus_accepted_6" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "30 4 * * 1-5"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "30 4 * * MON-FRI"
+run "watchdog_cron_corpus_accepted_7" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "30 4 * * MON-FRI"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "30 4 * * Mon-Fri"
+run "watchdog_cron_corpus_accepted_8" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "30 4 * * Mon-Fri"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 0 1 JAN *"
+run "watchdog_cron_corpus_accepted_9" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 0 1 JAN *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 0 1 jan-mar,OCT *"
+run "watchdog_cron_corpus_accepted_10" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 0 1 jan-mar,OCT *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * DEC SAT"
+run "watchdog_cron_corpus_accepted_11" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * DEC SAT"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * * 7"
+run "watchdog_cron_corpus_accepted_12" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * 7"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * * 0-7"
+run "watchdog_cron_corpus_accepted_13" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * 0-7"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * * MON/2"
+run "watchdog_cron_corpus_accepted_14" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * MON/2"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "5/20 * * * *"
+run "watchdog_cron_corpus_accepted_15" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "5/20 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0-30/10 * * * *"
+run "watchdog_cron_corpus_accepted_16" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0-30/10 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "00 09 01 01 *"
+run "watchdog_cron_corpus_accepted_17" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "00 09 01 01 *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "59 23 31 12 6"
+run "watchdog_cron_corpus_accepted_18" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "59 23 31 12 6"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 3 1,15 * MON"
+run "watchdog_cron_corpus_accepted_19" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 3 1,15 * MON"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "*/59 */23 */31 */12 */7"
+run "watchdog_cron_corpus_accepted_20" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/59 */23 */31 */12 */7"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0 2 * * 5-SAT"
+run "watchdog_cron_corpus_accepted_21" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * 5-SAT"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "  0 2 * * SUN  "
+run "watchdog_cron_corpus_accepted_22" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "  0 2 * * SUN  "
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0  2 * * *"
+run "watchdog_cron_corpus_accepted_23" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0  2 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron accepts "0\t2 * * *"
+run "watchdog_cron_corpus_accepted_24" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0\t2 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+}
+
+# watchdog.cron rejects "0 2 * *"
+run "watchdog_cron_corpus_rejected_2" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "0 2 * * * *"
+run "watchdog_cron_corpus_rejected_3" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "60 * * * *"
+run "watchdog_cron_corpus_rejected_4" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "60 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "99 99 * * *"
+run "watchdog_cron_corpus_rejected_5" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "99 99 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* 24 * * *"
+run "watchdog_cron_corpus_rejected_6" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* 24 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * 0 * *"
+run "watchdog_cron_corpus_rejected_7" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * 0 * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * 32 * *"
+run "watchdog_cron_corpus_rejected_8" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * 32 * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * 0 *"
+run "watchdog_cron_corpus_rejected_9" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * 0 *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * 13 *"
+run "watchdog_cron_corpus_rejected_10" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * 13 *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * 8"
+run "watchdog_cron_corpus_rejected_11" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * 8"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "5-1 * * * *"
+run "watchdog_cron_corpus_rejected_12" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "5-1 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* 20-4 * * *"
+run "watchdog_cron_corpus_rejected_13" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* 20-4 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * DEC-JAN *"
+run "watchdog_cron_corpus_rejected_14" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * DEC-JAN *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * FRI-MON"
+run "watchdog_cron_corpus_rejected_15" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * FRI-MON"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * SAT-1"
+run "watchdog_cron_corpus_rejected_16" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * SAT-1"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "*/0 * * * *"
+run "watchdog_cron_corpus_rejected_17" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/0 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "*/60 * * * *"
+run "watchdog_cron_corpus_rejected_18" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/60 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* */24 * * *"
+run "watchdog_cron_corpus_rejected_19" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* */24 * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * */13 *"
+run "watchdog_cron_corpus_rejected_20" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * */13 *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * */8"
+run "watchdog_cron_corpus_rejected_21" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * */8"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "+5 * * * *"
+run "watchdog_cron_corpus_rejected_22" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "+5 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "005 * * * *"
+run "watchdog_cron_corpus_rejected_23" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "005 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * 1/007"
+run "watchdog_cron_corpus_rejected_24" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * 1/007"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "a * * * *"
+run "watchdog_cron_corpus_rejected_25" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "a * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "? * * * *"
+run "watchdog_cron_corpus_rejected_26" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "? * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * SUNDAY"
+run "watchdog_cron_corpus_rejected_27" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * SUNDAY"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * JANUARY *"
+run "watchdog_cron_corpus_rejected_28" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * JANUARY *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * JAN"
+run "watchdog_cron_corpus_rejected_29" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * JAN"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * MON *"
+run "watchdog_cron_corpus_rejected_30" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * MON *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * MON-"
+run "watchdog_cron_corpus_rejected_31" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * MON-"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "* * * * -MON"
+run "watchdog_cron_corpus_rejected_32" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "* * * * -MON"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "*-5 * * * *"
+run "watchdog_cron_corpus_rejected_33" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*-5 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "1-2-3 * * * *"
+run "watchdog_cron_corpus_rejected_34" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "1-2-3 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "*/5/2 * * * *"
+run "watchdog_cron_corpus_rejected_35" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/5/2 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "1,,2 * * * *"
+run "watchdog_cron_corpus_rejected_36" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "1,,2 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects ",1 * * * *"
+run "watchdog_cron_corpus_rejected_37" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = ",1 * * * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "0 2 L * *"
+run "watchdog_cron_corpus_rejected_38" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 L * *"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "0 2 * * 5#2"
+run "watchdog_cron_corpus_rejected_39" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "0 2 * * 5#2"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# watchdog.cron rejects "@daily"
+run "watchdog_cron_corpus_rejected_40" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "@daily"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+// mockToken satisfies the provider's 40-character API token format check.
+const mockToken = "mock0000000000000000000000000000000token"
+
+// mockAPI is tests/mocks/cloudflare-mock-server.js behind a TLS proxy, since
+// the Cloudflare provider only talks HTTPS.
+type mockAPI struct {
+	// Client talks to the mock directly, for assertions.
+	Client *cloudflare.Client
+	// Env points the Cloudflare provider at the mock.
+	Env map[string]string
+}
+
+func startMockAPI(t *testing.T) mockAPI {
+	t.Helper()
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform not installed; skipping mock API e2e test")
+	}
+	base := cftest.StartMock(t)
+
+	target, err := url.Parse(base)
+	require.NoError(t, err)
+	target.Path = ""
+	proxy := httptest.NewTLSServer(httputil.NewSingleHostReverseProxy(target))
+	t.Cleanup(proxy.Close)
+
+	caFile := filepath.Join(t.TempDir(), "mock-ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: proxy.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	return mockAPI{
+		Client: &cloudflare.Client{BaseURL: base, Token: mockToken},
+		Env: map[string]string{
+			"SSL_CERT_FILE":            caFile,
+			"CLOUDFLARE_API_HOSTNAME":  proxy.Listener.Addr().String(),
+			"CLOUDFLARE_API_BASE_PATH": "/client/v4",
+		},
+	}
+}
+
+// options copies the module to a temp dir so parallel tests don't share
+// .terraform or state, and wires it to the mock.
+func (m mockAPI) options(t *testing.T, vars map[string]interface{}) *terraform.Options {
+	dir := copyModule(t)
+	all := map[string]interface{}{
+		"cloudflare_api_token":  mockToken,
+		"cloudflare_account_id": cftest.AccountID,
+		"cloudflare_zone_id":    cftest.ZoneID,
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+	return &terraform.Options{TerraformDir: dir, Vars: all, EnvVars: m.Env, NoColor: true}
+}
+
+// copyModule copies what the root module needs to plan: its .tf files,
+// worker.js and the templates it reads with file(). backend.tf is left out
+// so state stays local to the copy.
+func copyModule(t *testing.T) string {
+	t.Helper()
+	dst := t.TempDir()
+	root := filepath.Join("..", "..")
+	for _, name := range []string{"main.tf", "variables.tf", "outputs.tf", "versions.tf", "worker.js"} {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dst, name), b, 0o644))
+	}
+	require.NoError(t, os.CopyFS(filepath.Join(dst, "templates"), os.DirFS(filepath.Join(root, "templates"))))
+	return dst
+}
+
+func (m mockAPI) routePatterns(t *testing.T, zoneID string) []string {
+	routes, err := m.Client.ListWorkerRoutes(context.Background(), zoneID)
+	require.NoError(t, err)
+	patterns := make([]string, 0, len(routes))
+	for _, r := range routes {
+		patterns = append(patterns, r.Pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// TestMockMultiZoneRoutes applies worker_routes across two zones and checks
+// every route is created when enabled and removed when disabled.
+func TestMockMultiZoneRoutes(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+
+	opts := mock.options(t, map[string]interface{}{
+		"enabled":     true,
+		"environment": "mock",
+		"worker_routes": []map[string]string{
+			{"zone_id": cftest.ZoneID, "pattern": "app.example.com/*"},
+			{"zone_id": cftest.ZoneID, "pattern": "api.example.com/*"},
+			{"zone_id": cftest.SecondZoneID, "pattern": "example.org/*"},
+		},
+	})
+	defer terraform.Destroy(t, opts)
+	terraform.InitAndApply(t, opts)
+
+	assert.Equal(t, []string{"api.example.com/*", "app.example.com/*"}, mock.routePatterns(t, cftest.ZoneID))
+	assert.Equal(t, []string{"example.org/*"}, mock.routePatterns(t, cftest.SecondZoneID))
+
+	routes := terraform.OutputListOfObjects(t, opts, "worker_routes")
+	require.Len(t, routes, 3)
+
+	// Routes are keyed by zone and pattern: dropping the first leaves the
+	// others as they are.
+	opts.Vars["worker_routes"] = opts.Vars["worker_routes"].([]map[string]string)[1:]
+	terraform.Apply(t, opts)
+	assert.Equal(t, []string{"api.example.com/*"}, mock.routePatterns(t, cftest.ZoneID))
+	assert.Equal(t, routes[1:], terraform.OutputListOfObjects(t, opts, "worker_routes"))
+
+	opts.Vars["enabled"] = false
+	terraform.Apply(t, opts)
+
+	assert.Empty(t, mock.routePatterns(t, cftest.ZoneID))
+	assert.Empty(t, mock.routePatterns(t, cftest.SecondZoneID))
+	assert.Empty(t, terraform.OutputListOfObjects(t, opts, "worker_routes"))
+}
+
+// TestMockEnvironmentsConcurrent applies three environments into one account
+// at the same time and checks each gets its own script and bindings.
+func TestMockEnvironmentsConcurrent(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+
+	environments := []string{"development", "staging", "production"}
+	opts := map[string]*terraform.Options{}
+	for _, env := range environments {
+		opts[env] = mock.options(t, map[string]interface{}{
+			"enabled":           true,
+			"environment":       env,
+			"maintenance_title": env + " Maintenance",
+			"worker_route":      env + ".example.com/*",
+		})
+		defer terraform.Destroy(t, opts[env])
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		for _, env := range environments {
+			env := env
+			t.Run(env, func(t *testing.T) {
+				t.Parallel()
+				terraform.InitAndApply(t, opts[env])
+			})
+		}
+	})
+
+	ctx := context.Background()
+	scripts, err := mock.Client.ListWorkerScripts(ctx, cftest.AccountID)
+	require.NoError(t, err)
+	names := make([]string, 0, len(scripts))
+	for _, s := range scripts {
+		names = append(names, s.ID)
+	}
+	assert.ElementsMatch(t, []string{
+		"maintenance-page-worker-development",
+		"maintenance-page-worker-staging",
+		"maintenance-page-worker-production",
+	}, names)
+
+	for _, env := range environments {
+		name := "maintenance-page-worker-" + env
+		assert.Equal(t, name, terraform.Output(t, opts[env], "worker_script_name"))
+
+		bindings, err := mock.Client.ListWorkerBindings(ctx, cftest.AccountID, name)
+		require.NoError(t, err)
+		title := ""
+		for _, b := range bindings {
+			if b.Name == "MAINTENANCE_TITLE" {
+				title = b.Text
+			}
+		}
+		assert.Equal(t, env+" Maintenance", title, "bindings of %s", name)
+	}
+}
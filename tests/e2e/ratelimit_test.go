@@ -0,0 +1,100 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/ratelimit"
+)
+
+type rateLimitCase struct {
+	Name      string                 `json:"name"`
+	RateLimit map[string]interface{} `json:"rate_limit"`
+}
+
+func loadRateLimitCases(t *testing.T) (accept, reject []rateLimitCase) {
+	t.Helper()
+	data, err := os.ReadFile("../fixtures/rate-limit-rules.json")
+	require.NoError(t, err)
+	var f struct {
+		Accept []rateLimitCase `json:"accept"`
+		Reject []rateLimitCase `json:"reject"`
+	}
+	require.NoError(t, json.Unmarshal(data, &f))
+	return f.Accept, f.Reject
+}
+
+// TestMockRateLimitPlan plans every case of the rules fixture and checks the
+// planned ruleset holds the rules in order, as ratelimit.Config.Ruleset
+// resolves them, and that cases Validate rejects fail to plan.
+func TestMockRateLimitPlan(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+	accept, reject := loadRateLimitCases(t)
+
+	for _, c := range accept {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+			opts := mock.options(t, map[string]interface{}{"environment": "mock", "rate_limit": c.RateLimit})
+			opts.PlanFilePath = filepath.Join(opts.TerraformDir, "plan.out")
+
+			plan, err := ratelimit.ReadPlan(strings.NewReader(terraform.InitAndPlanAndShow(t, opts)))
+			require.NoError(t, err)
+			require.NoError(t, ratelimit.Validate(plan.Config))
+			assert.Equal(t, plan.Config.Ruleset(), plan.Rules)
+		})
+	}
+	for _, c := range reject {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Parallel()
+			opts := mock.options(t, map[string]interface{}{"environment": "mock", "rate_limit": c.RateLimit})
+			_, err := terraform.InitAndPlanE(t, opts)
+			require.Error(t, err)
+		})
+	}
+}
+
+// TestMockRateLimitFollowsMaintenance applies applies_to = "maintenance_page"
+// and checks the ruleset exists only while maintenance is on.
+func TestMockRateLimitFollowsMaintenance(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+
+	opts := mock.options(t, map[string]interface{}{
+		"enabled":      false,
+		"environment":  "mock",
+		"worker_route": "app.example.com/*",
+		"rate_limit":   map[string]interface{}{"enabled": true, "applies_to": "maintenance_page"},
+	})
+	defer terraform.Destroy(t, opts)
+	terraform.InitAndApply(t, opts)
+	assert.Equal(t, "false", terraform.Output(t, opts, "rate_limit_enabled"))
+	assert.Empty(t, terraform.Output(t, opts, "rate_limit_ruleset_id"))
+
+	opts.Vars["enabled"] = true
+	terraform.Apply(t, opts)
+	id := terraform.Output(t, opts, "rate_limit_ruleset_id")
+	require.NotEmpty(t, id)
+	rs, err := mock.Client.GetRuleset(context.Background(), cftest.ZoneID, id)
+	require.NoError(t, err)
+	require.Len(t, rs.Rules, 1)
+	assert.Equal(t, `(http.host eq "app.example.com") and (`+ratelimit.LegacyExpression+`)`, rs.Rules[0].Expression)
+
+	opts.Vars["enabled"] = false
+	terraform.Apply(t, opts)
+	_, err = mock.Client.GetRuleset(context.Background(), cftest.ZoneID, id)
+	assert.ErrorIs(t, err, cloudflare.ErrNotFound)
+	assert.Empty(t, terraform.Output(t, opts, "rate_limit_ruleset_id"))
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare"
+	"github.com/thomasvincent/terraform-cloudflare-maintenance/internal/cloudflare/cftest"
+)
+
+// foreignRules are what other teams keep in the zone's entrypoints.
+var foreignRules = map[string][]cloudflare.Rule{
+	"http_request_firewall_custom": {{
+		Ref:         "waf-block-bad-asn",
+		Action:      "block",
+		Expression:  "ip.geoip.asnum eq 64496",
+		Description: "Managed by the security team",
+		Enabled:     true,
+	}},
+	"http_ratelimit": {{
+		Ref:         "api-rate-limit",
+		Action:      "block",
+		Expression:  `starts_with(http.request.uri.path, "/api/")`,
+		Description: "Managed by the API team",
+		Enabled:     true,
+		RateLimit: &cloudflare.RateLimit{
+			Characteristics:   []string{"cf.colo.id", "ip.src"},
+			Period:            60,
+			RequestsPerPeriod: 1000,
+			MitigationTimeout: 600,
+		},
+	}},
+}
+
+func seedForeignRules(t *testing.T, mock mockAPI) {
+	t.Helper()
+	for phase, rules := range foreignRules {
+		_, err := mock.Client.UpdateEntrypoint(context.Background(), cftest.ZoneID, phase, cloudflare.Ruleset{Rules: rules})
+		require.NoError(t, err)
+	}
+}
+
+func entrypointRules(t *testing.T, mock mockAPI, phase string) []cloudflare.Rule {
+	t.Helper()
+	ep, err := mock.Client.GetEntrypoint(context.Background(), cftest.ZoneID, phase)
+	require.NoError(t, err)
+	for i := range ep.Rules {
+		ep.Rules[i].ID = ""
+	}
+	return ep.Rules
+}
+
+// buildMaintctl builds the CLI the module runs to attach execute rules.
+func buildMaintctl(t *testing.T) string {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "maintctl")
+	cmd := exec.Command("go", "build", "-o", bin, "./cmd/maintctl")
+	cmd.Dir = filepath.Join("..", "..")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return bin
+}
+
+// TestMockRulesetExecuteKeepsForeignRules applies ruleset_mode = "execute"
+// into a zone whose entrypoints already hold other teams' rules, and checks
+// those rules survive apply and destroy next to the module's execute rules.
+func TestMockRulesetExecuteKeepsForeignRules(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+	seedForeignRules(t, mock)
+
+	opts := mock.options(t, map[string]interface{}{
+		"enabled":          true,
+		"environment":      "mock",
+		"allowed_ips":      []string{"192.0.2.1"},
+		"rate_limit":       map[string]interface{}{"enabled": true},
+		"ruleset_mode":     "execute",
+		"maintctl_command": buildMaintctl(t),
+	})
+	// maintctl talks to the mock directly, with the environment Terraform
+	// passes to local-exec. detach runs at destroy time and can't be given
+	// cloudflare_api_token, so the token is in the environment too.
+	env := map[string]string{"CLOUDFLARE_API_BASE_URL": mock.Client.BaseURL, "CLOUDFLARE_API_TOKEN": mockToken}
+	for k, v := range opts.EnvVars {
+		env[k] = v
+	}
+	opts.EnvVars = env
+	defer terraform.Destroy(t, opts)
+	terraform.InitAndApply(t, opts)
+
+	bypassID := terraform.Output(t, opts, "ruleset_id")
+	rateLimitID := terraform.Output(t, opts, "rate_limit_ruleset_id")
+	for _, id := range []string{bypassID, rateLimitID} {
+		rs, err := mock.Client.GetRuleset(context.Background(), cftest.ZoneID, id)
+		require.NoError(t, err)
+		assert.Equal(t, "custom", rs.Kind)
+	}
+
+	// The bypass runs before the security team's rules; rate limiting after
+	// the API team's.
+	firewall := entrypointRules(t, mock, "http_request_firewall_custom")
+	require.Len(t, firewall, 2)
+	assert.Equal(t, "maintenance-mock-maintenance-bypass", firewall[0].Ref)
+	assert.Equal(t, "execute", firewall[0].Action)
+	assert.Equal(t, bypassID, firewall[0].ActionParameters["id"])
+	assert.Equal(t, foreignRules["http_request_firewall_custom"], firewall[1:])
+
+	ratelimit := entrypointRules(t, mock, "http_ratelimit")
+	require.Len(t, ratelimit, 2)
+	assert.Equal(t, foreignRules["http_ratelimit"], ratelimit[:1])
+	assert.Equal(t, "maintenance-mock-rate-limit", ratelimit[1].Ref)
+	assert.Equal(t, rateLimitID, ratelimit[1].ActionParameters["id"])
+
+	// A second apply leaves everything as it is.
+	terraform.Apply(t, opts)
+	assert.Len(t, entrypointRules(t, mock, "http_request_firewall_custom"), 2)
+	assert.Len(t, entrypointRules(t, mock, "http_ratelimit"), 2)
+
+	terraform.Destroy(t, opts)
+	for phase, rules := range foreignRules {
+		assert.Equal(t, rules, entrypointRules(t, mock, phase), phase)
+	}
+	for _, id := range []string{bypassID, rateLimitID} {
+		_, err := mock.Client.GetRuleset(context.Background(), cftest.ZoneID, id)
+		assert.ErrorIs(t, err, cloudflare.ErrNotFound)
+	}
+}
+
+// TestMockRulesetDetachNeedsToken destroys ruleset_mode = "execute" without
+// CLOUDFLARE_API_TOKEN in the environment. The destroy-time detach can't be
+// given cloudflare_api_token, so the destroy must fail and keep the ruleset
+// and its execute rule, then finish once the token is exported.
+func TestMockRulesetDetachNeedsToken(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+	seedForeignRules(t, mock)
+
+	opts := mock.options(t, map[string]interface{}{
+		"enabled":          true,
+		"environment":      "mock",
+		"rate_limit":       map[string]interface{}{"enabled": true},
+		"ruleset_mode":     "execute",
+		"maintctl_command": buildMaintctl(t),
+	})
+	env := map[string]string{"CLOUDFLARE_API_BASE_URL": mock.Client.BaseURL, "CLOUDFLARE_API_TOKEN": ""}
+	for k, v := range opts.EnvVars {
+		env[k] = v
+	}
+	opts.EnvVars = env
+	withToken := *opts
+	withToken.EnvVars = map[string]string{"CLOUDFLARE_API_TOKEN": mockToken}
+	for k, v := range env {
+		if k != "CLOUDFLARE_API_TOKEN" {
+			withToken.EnvVars[k] = v
+		}
+	}
+	defer terraform.Destroy(t, &withToken)
+
+	// attach gets cloudflare_api_token from Terraform, so apply works without
+	// the token in the environment.
+	terraform.InitAndApply(t, opts)
+	rateLimitID := terraform.Output(t, opts, "rate_limit_ruleset_id")
+	require.Len(t, entrypointRules(t, mock, "http_ratelimit"), 2)
+
+	_, err := terraform.DestroyE(t, opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CLOUDFLARE_API_TOKEN is not set")
+	ratelimit := entrypointRules(t, mock, "http_ratelimit")
+	require.Len(t, ratelimit, 2)
+	assert.Equal(t, "maintenance-mock-rate-limit", ratelimit[1].Ref)
+	_, err = mock.Client.GetRuleset(context.Background(), cftest.ZoneID, rateLimitID)
+	assert.NoError(t, err, "the ruleset was deleted while its execute rule was still attached")
+
+	terraform.Destroy(t, &withToken)
+	for phase, rules := range foreignRules {
+		assert.Equal(t, rules, entrypointRules(t, mock, phase), phase)
+	}
+	_, err = mock.Client.GetRuleset(context.Background(), cftest.ZoneID, rateLimitID)
+	assert.ErrorIs(t, err, cloudflare.ErrNotFound)
+}
+
+// TestMockRulesetEntrypointCollides shows what ruleset_mode = "execute" is
+// for: by default the module makes the entrypoints itself, which fails when
+// another team already has, and leaves their rules alone.
+func TestMockRulesetEntrypointCollides(t *testing.T) {
+	t.Parallel()
+	mock := startMockAPI(t)
+	seedForeignRules(t, mock)
+
+	opts := mock.options(t, map[string]interface{}{
+		"environment": "mock",
+		"rate_limit":  map[string]interface{}{"enabled": true},
+	})
+	defer terraform.Destroy(t, opts)
+	_, err := terraform.InitAndApplyE(t, opts)
+	require.Error(t, err)
+	assert.Equal(t, foreignRules["http_ratelimit"], entrypointRules(t, mock, "http_ratelimit"))
+}
@@ -379,8 +379,9 @@ func TestMaintenanceModuleEnvironments(t *testing.T) {
 			uniqueID := random.UniqueId()
 			workerRoute := fmt.Sprintf("test-%s-%s.example.com/*", env, uniqueID)
 
+			// Each environment gets its own copy so parallel runs don't share state
 			terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-				TerraformDir: "../../",
+				TerraformDir: copyModule(t),
 				Vars: map[string]interface{}{
 					"cloudflare_api_token":   os.Getenv("CLOUDFLARE_API_TOKEN"),
 					"cloudflare_account_id":  os.Getenv("CLOUDFLARE_ACCOUNT_ID"),
@@ -399,6 +400,9 @@ func TestMaintenanceModuleEnvironments(t *testing.T) {
 
 			outputEnv := terraform.Output(t, terraformOptions, "environment")
 			assert.Equal(t, env, outputEnv, "Environment should match input")
+
+			scriptName := terraform.Output(t, terraformOptions, "worker_script_name")
+			assert.Equal(t, "maintenance-page-worker-"+env, scriptName, "Script name should be per environment")
 		})
 	}
 }
@@ -277,6 +277,442 @@ describe('Worker Integration Tests', () => {
     });
   });
 
+  describe('Display Timezone', () => {
+    const windowBindings = (displayTimezone) => ({
+      MAINTENANCE_ENABLED: 'true',
+      MAINTENANCE_TITLE: 'Scheduled Maintenance',
+      MAINTENANCE_MESSAGE: 'In progress',
+      CONTACT_EMAIL: '',
+      CUSTOM_CSS: '',
+      LOGO_URL: '',
+      MAINTENANCE_WINDOW_START: '2025-04-06T08:00:00Z',
+      MAINTENANCE_WINDOW_END: '2025-04-06T10:00:00Z',
+      DISPLAY_TIMEZONE: displayTimezone,
+      ALLOWED_IPS: '[]',
+      ALLOWED_REGIONS: '[]',
+    });
+
+    it('should render expected completion in the display timezone', async () => {
+      await mf.setOptions({ bindings: windowBindings('America/New_York') });
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      expect(body).toContain('Sun, Apr 6, 2025, 06:00 EDT');
+      expect(body).toContain('datetime="2025-04-06T10:00:00.000Z"');
+    });
+
+    it('should allow only the nonced countdown script', async () => {
+      await mf.setOptions({ bindings: windowBindings('UTC') });
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      const csp = response.headers.get('Content-Security-Policy');
+      const nonce = csp.match(/'nonce-([a-f0-9]+)'/)[1];
+      expect(body).toContain(`<script nonce="${nonce}">`);
+      expect(body).toContain('maintenance-countdown');
+    });
+  });
+
+  describe('Localized Content', () => {
+    beforeEach(async () => {
+      await mf.setOptions({
+        bindings: {
+          MAINTENANCE_ENABLED: 'true',
+          MAINTENANCE_TITLE: 'Maintenance',
+          MAINTENANCE_MESSAGE: 'Under maintenance',
+          CONTACT_EMAIL: '',
+          CUSTOM_CSS: '',
+          LOGO_URL: '',
+          MAINTENANCE_WINDOW_START: '',
+          MAINTENANCE_WINDOW_END: '',
+          LOCALIZED_CONTENT: JSON.stringify({
+            en: { title: 'Maintenance', message: 'We will be back shortly.' },
+            de: { title: 'Wartungsarbeiten', message: 'Wir sind bald zurück.' },
+            ja: { title: 'メンテナンス中', message: 'まもなく再開します。' },
+          }),
+          DEFAULT_LOCALE: 'en',
+          COUNTRY_LOCALES: '{"JP": "ja"}',
+          ALLOWED_IPS: '[]',
+          ALLOWED_REGIONS: '[]',
+        },
+      });
+    });
+
+    it('should serve the language negotiated from Accept-Language', async () => {
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'Accept-Language': 'de-AT,de;q=0.9,en;q=0.5' },
+      });
+      const body = await response.text();
+      expect(body).toContain('<html lang="de">');
+      expect(body).toContain('Wartungsarbeiten');
+      expect(response.headers.get('Content-Language')).toBe('de');
+      expect(response.headers.get('Vary')).toContain('Accept-Language');
+    });
+
+    it('should fall back to the country mapping', async () => {
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'Accept-Language': 'ko' },
+        cf: { country: 'JP' },
+      });
+      const body = await response.text();
+      expect(body).toContain('メンテナンス中');
+    });
+
+    it('should use the default locale when nothing matches', async () => {
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'Accept-Language': 'ko' },
+      });
+      const body = await response.text();
+      expect(body).toContain('<html lang="en">');
+      expect(body).toContain('We will be back shortly.');
+    });
+  });
+
+  describe('Status Updates', () => {
+    it('should render the timeline stored in MAINTENANCE_KV', async () => {
+      await mf.setOptions({
+        bindings: {
+          MAINTENANCE_ENABLED: 'true',
+          MAINTENANCE_TITLE: 'System Maintenance',
+          MAINTENANCE_MESSAGE: 'Back soon',
+          CONTACT_EMAIL: '',
+          CUSTOM_CSS: '',
+          LOGO_URL: '',
+          MAINTENANCE_WINDOW_START: '',
+          MAINTENANCE_WINDOW_END: '',
+          ALLOWED_IPS: '[]',
+          ALLOWED_REGIONS: '[]',
+        },
+        kvNamespaces: ['MAINTENANCE_KV'],
+      });
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      await kv.put('status_updates', JSON.stringify([
+        { id: 'a', message: 'Started DB migration', posted_at: '2025-04-06T08:05:00Z' },
+        { id: 'b', message: 'DB migration 60% <done>', posted_at: '2025-04-06T09:00:00Z' },
+      ]));
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      expect(response.status).toBe(503);
+      expect(body).toContain('<ul class="updates">');
+      expect(body.indexOf('DB migration 60% &lt;done&gt;')).toBeLessThan(body.indexOf('Started DB migration'));
+    });
+
+    it('should render the page without a timeline when the key is missing', async () => {
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      await kv.delete('status_updates');
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      expect(response.status).toBe(503);
+      expect(body).not.toContain('<ul class="updates">');
+    });
+  });
+
+  describe('Runtime State in KV', () => {
+    const bindings = {
+      MAINTENANCE_ENABLED: 'false',
+      MAINTENANCE_TITLE: 'System Maintenance',
+      MAINTENANCE_MESSAGE: 'Back soon',
+      CONTACT_EMAIL: '',
+      CUSTOM_CSS: '',
+      LOGO_URL: '',
+      MAINTENANCE_WINDOW_START: '',
+      MAINTENANCE_WINDOW_END: '',
+      KV_RUNTIME_STATE: 'true',
+      ALLOWED_IPS: '[]',
+      ALLOWED_REGIONS: '[]',
+    };
+
+    it('should enable maintenance from the KV state without changing bindings', async () => {
+      await mf.setOptions({ bindings, kvNamespaces: ['MAINTENANCE_KV'] });
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      await kv.put('state', JSON.stringify({
+        enabled: true,
+        title: '',
+        message: 'Toggled from KV',
+        window_start: '',
+        window_end: '',
+      }));
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      expect(response.status).toBe(503);
+      expect(body).toContain('System Maintenance');
+      expect(body).toContain('Toggled from KV');
+    });
+
+    it('should ignore the KV state unless KV_RUNTIME_STATE is set', async () => {
+      await mf.setOptions({ bindings: { ...bindings, KV_RUNTIME_STATE: 'false' }, kvNamespaces: ['MAINTENANCE_KV'] });
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      await kv.put('state', JSON.stringify({ enabled: true }));
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      expect(response.status).not.toBe(503);
+    });
+  });
+
+  describe('Maintenance Scopes', () => {
+    beforeEach(async () => {
+      await mf.setOptions({
+        bindings: {
+          MAINTENANCE_ENABLED: 'false',
+          MAINTENANCE_TITLE: 'System Maintenance',
+          MAINTENANCE_MESSAGE: 'Back soon',
+          CONTACT_EMAIL: '',
+          CUSTOM_CSS: '',
+          LOGO_URL: '',
+          MAINTENANCE_WINDOW_START: '',
+          MAINTENANCE_WINDOW_END: '',
+          ALLOWED_IPS: '[]',
+          ALLOWED_REGIONS: '[]',
+          MAINTENANCE_SCOPES: JSON.stringify([
+            { host: 'shop.example.com', path: '/checkout', enabled: true, title: '', message: 'Checkout is down for an upgrade' },
+            { host: '', path: '/admin', enabled: true, title: 'Admin Maintenance', message: '' },
+            { host: '', path: '/admin/health', enabled: false, title: '', message: '' },
+          ]),
+        },
+      });
+    });
+
+    it('should show the scope message for a scoped path', async () => {
+      const response = await mf.dispatchFetch('https://shop.example.com/checkout/pay');
+      const body = await response.text();
+      expect(response.status).toBe(503);
+      expect(body).toContain('Checkout is down for an upgrade');
+      expect(body).toContain('System Maintenance');
+    });
+
+    it('should let requests outside every scope through', async () => {
+      const response = await mf.dispatchFetch('https://shop.example.com/products');
+      expect(response.status).not.toBe(503);
+    });
+
+    it('should let a more specific disabled scope carve out a path', async () => {
+      const admin = await mf.dispatchFetch('https://example.com/admin/users');
+      expect(admin.status).toBe(503);
+      expect(await admin.text()).toContain('Admin Maintenance');
+
+      const health = await mf.dispatchFetch('https://example.com/admin/health');
+      expect(health.status).not.toBe(503);
+    });
+  });
+
+  describe('Gradual Rollout', () => {
+    const bindings = {
+      MAINTENANCE_ENABLED: 'true',
+      MAINTENANCE_TITLE: 'System Maintenance',
+      MAINTENANCE_MESSAGE: 'Back soon',
+      CONTACT_EMAIL: '',
+      CUSTOM_CSS: '',
+      LOGO_URL: '',
+      MAINTENANCE_WINDOW_START: '',
+      MAINTENANCE_WINDOW_END: '',
+      MAINTENANCE_ROLLOUT_PERCENTAGE: '25',
+      ALLOWED_IPS: '[]',
+      ALLOWED_REGIONS: '[]',
+    };
+
+    // Buckets from tests/fixtures/rollout-buckets.json: 203.0.113.250 is 2, 192.0.2.1 is 76
+    it('should serve the page to clients in the rollout and pin their bucket', async () => {
+      await mf.setOptions({ bindings });
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'CF-Connecting-IP': '203.0.113.250' },
+      });
+      expect(response.status).toBe(503);
+      expect(response.headers.get('Set-Cookie')).toContain('maintenance_bucket=2;');
+    });
+
+    it('should let clients outside the rollout through', async () => {
+      await mf.setOptions({ bindings });
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'CF-Connecting-IP': '192.0.2.1' },
+      });
+      expect(response.status).not.toBe(503);
+    });
+
+    it('should keep a pinned client in the rollout when its IP changes', async () => {
+      await mf.setOptions({ bindings });
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'CF-Connecting-IP': '192.0.2.1', Cookie: 'maintenance_bucket=2' },
+      });
+      expect(response.status).toBe(503);
+      expect(response.headers.get('Set-Cookie')).toBeNull();
+    });
+
+    it('should take the percentage from the KV state', async () => {
+      await mf.setOptions({
+        bindings: { ...bindings, MAINTENANCE_ROLLOUT_PERCENTAGE: '100', KV_RUNTIME_STATE: 'true' },
+        kvNamespaces: ['MAINTENANCE_KV'],
+      });
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      await kv.put('state', JSON.stringify({ enabled: true, rollout_percentage: 0 }));
+
+      const response = await mf.dispatchFetch('https://example.com/', {
+        headers: { 'CF-Connecting-IP': '203.0.113.250' },
+      });
+      expect(response.status).not.toBe(503);
+    });
+  });
+
+  describe('Read-only Mode', () => {
+    beforeEach(async () => {
+      await mf.setOptions({
+        bindings: {
+          MAINTENANCE_ENABLED: 'true',
+          MAINTENANCE_TITLE: 'Database Upgrade',
+          MAINTENANCE_MESSAGE: 'Writes are paused while we upgrade the database',
+          MAINTENANCE_MODE: 'read_only',
+          CONTACT_EMAIL: '',
+          CUSTOM_CSS: '',
+          LOGO_URL: '',
+          MAINTENANCE_WINDOW_START: '',
+          MAINTENANCE_WINDOW_END: '',
+          ALLOWED_IPS: '[]',
+          ALLOWED_REGIONS: '[]',
+        },
+      });
+    });
+
+    it('should pass reads through to the origin', async () => {
+      for (const method of ['GET', 'HEAD', 'OPTIONS']) {
+        const response = await mf.dispatchFetch('https://example.com/api/orders', { method });
+        expect(response.status).not.toBe(503);
+      }
+    });
+
+    it('should answer writes with a 503 JSON error', async () => {
+      for (const method of ['POST', 'PUT', 'PATCH', 'DELETE']) {
+        const response = await mf.dispatchFetch('https://example.com/api/orders', { method, body: method === 'DELETE' ? undefined : '{}' });
+        expect(response.status).toBe(503);
+        expect(response.headers.get('Content-Type')).toContain('application/json');
+        expect(response.headers.get('Retry-After')).toBe('3600');
+        expect(await response.json()).toEqual({
+          error: 'read_only',
+          title: 'Database Upgrade',
+          message: 'Writes are paused while we upgrade the database',
+        });
+      }
+    });
+  });
+
+  describe('Auto Maintenance', () => {
+    const bindings = {
+      MAINTENANCE_ENABLED: 'false',
+      MAINTENANCE_TITLE: 'System Maintenance',
+      MAINTENANCE_MESSAGE: 'Back soon',
+      CONTACT_EMAIL: '',
+      CUSTOM_CSS: '',
+      LOGO_URL: '',
+      MAINTENANCE_WINDOW_START: '',
+      MAINTENANCE_WINDOW_END: '',
+      ALLOWED_IPS: '[]',
+      ALLOWED_REGIONS: '[]',
+      AUTO_MAINTENANCE: JSON.stringify({
+        enabled: true,
+        failure_threshold: 2,
+        window_seconds: 60,
+        timeout_ms: 1000,
+        cooldown_seconds: 120,
+      }),
+    };
+
+    it('should pass origin errors through below the threshold', async () => {
+      await mf.setOptions({ bindings, outboundService: () => new Response('bad gateway', { status: 502 }) });
+      const response = await mf.dispatchFetch('https://example.com/');
+      expect(response.status).toBe(502);
+    });
+
+    it('should serve the page and record why once the origin keeps failing', async () => {
+      let originHits = 0;
+      await mf.setOptions({
+        bindings,
+        kvNamespaces: ['MAINTENANCE_KV'],
+        outboundService: () => {
+          originHits++;
+          return new Response('unavailable', { status: 503 });
+        },
+      });
+
+      await mf.dispatchFetch('https://example.com/');
+      const tripped = await mf.dispatchFetch('https://example.com/');
+      expect(tripped.status).toBe(503);
+      expect(tripped.headers.get('X-Maintenance-Trigger')).toBe('origin-health');
+      expect(await tripped.text()).toContain('System Maintenance');
+
+      // Open breaker: the origin is left alone during the cool-down
+      const held = await mf.dispatchFetch('https://example.com/');
+      expect(held.headers.get('X-Maintenance-Trigger')).toBe('origin-health');
+      expect(originHits).toBe(2);
+
+      const kv = await mf.getKVNamespace('MAINTENANCE_KV');
+      const trip = await kv.get('auto_trip', 'json');
+      expect(trip.reason).toBe('2 origin failures within 60s, last: HTTP 503');
+    });
+
+    it('should leave healthy traffic alone', async () => {
+      await mf.setOptions({ bindings, outboundService: () => new Response('ok') });
+      const response = await mf.dispatchFetch('https://example.com/');
+      expect(response.status).toBe(200);
+      expect(await response.text()).toBe('ok');
+    });
+  });
+
+  describe('Stale Copies', () => {
+    const bindings = {
+      MAINTENANCE_ENABLED: 'true',
+      MAINTENANCE_TITLE: 'System Maintenance',
+      MAINTENANCE_MESSAGE: 'Back soon',
+      CONTACT_EMAIL: '',
+      CUSTOM_CSS: '',
+      LOGO_URL: '',
+      MAINTENANCE_WINDOW_START: '',
+      MAINTENANCE_WINDOW_END: '',
+      ALLOWED_IPS: '[]',
+      ALLOWED_REGIONS: '[]',
+      STALE_PATHS: JSON.stringify(['/blog']),
+      STALE_TTL_SECONDS: '3600',
+    };
+
+    it('should store good responses on stale paths while the site is up', async () => {
+      await mf.setOptions({
+        bindings: { ...bindings, MAINTENANCE_ENABLED: 'false' },
+        outboundService: () => new Response('<h1>Launch week</h1>', { headers: { 'Content-Type': 'text/html' } }),
+      });
+      const response = await mf.dispatchFetch('https://example.com/blog/launch-week');
+      expect(await response.text()).toBe('<h1>Launch week</h1>');
+
+      const caches = await mf.getCaches();
+      const cached = await caches.default.match('https://example.com/blog/launch-week');
+      expect(cached).toBeDefined();
+      expect(cached.headers.get('Cache-Control')).toBe('public, max-age=3600');
+    });
+
+    it('should serve the stored copy during maintenance', async () => {
+      await mf.setOptions({ bindings });
+      const caches = await mf.getCaches();
+      await caches.default.put(
+        'https://example.com/blog/launch-week',
+        new Response('<h1>Launch week</h1>', { headers: { 'Cache-Control': 'public, max-age=3600' } })
+      );
+
+      const response = await mf.dispatchFetch('https://example.com/blog/launch-week');
+      expect(response.status).toBe(200);
+      expect(response.headers.get('X-Maintenance-Stale')).toBe('true');
+      expect(response.headers.get('Cache-Control')).toBe('no-store');
+      expect(await response.text()).toBe('<h1>Launch week</h1>');
+    });
+
+    it('should fall back to the page on a miss or outside stale paths', async () => {
+      await mf.setOptions({ bindings });
+      const miss = await mf.dispatchFetch('https://example.com/blog/never-fetched');
+      expect(miss.status).toBe(503);
+
+      const checkout = await mf.dispatchFetch('https://example.com/checkout');
+      expect(checkout.status).toBe(503);
+    });
+  });
+
   describe('Custom Styling', () => {
     it('should include custom CSS when provided', async () => {
       await mf.setOptions({
@@ -342,6 +778,33 @@ describe('Worker Integration Tests', () => {
     });
   });
 
+  describe('Custom Page Template', () => {
+    it('should render the PAGE_TEMPLATE binding instead of the built-in page', async () => {
+      await mf.setOptions({
+        bindings: {
+          MAINTENANCE_ENABLED: 'true',
+          MAINTENANCE_TITLE: 'Checkout <paused>',
+          MAINTENANCE_MESSAGE: 'Back soon',
+          CONTACT_EMAIL: 'ops@example.com',
+          CUSTOM_CSS: '',
+          LOGO_URL: '',
+          MAINTENANCE_WINDOW_START: '',
+          MAINTENANCE_WINDOW_END: '',
+          PAGE_TEMPLATE: '<html lang="{{lang}}"><main><h2>{{title}}</h2>{{message}}{{window}}{{contact}}</main></html>',
+          ALLOWED_IPS: '[]',
+          ALLOWED_REGIONS: '[]',
+        },
+      });
+
+      const response = await mf.dispatchFetch('https://example.com/');
+      const body = await response.text();
+      expect(response.status).toBe(503);
+      expect(body).toContain('<main><h2>Checkout &lt;paused&gt;</h2>Back soon');
+      expect(body).toContain('mailto:ops@example.com');
+      expect(body).not.toContain('class="container"');
+    });
+  });
+
   describe('Security Tests', () => {
     it('should prevent XSS via logo URL', async () => {
       await mf.setOptions({
@@ -3,6 +3,7 @@
  * This server simulates Cloudflare API endpoints for testing without real API calls
  */
 
+import { createHash } from 'crypto';
 import { createServer } from 'http';
 import { parse } from 'url';
 
@@ -14,6 +15,9 @@ const mockData = {
   workers: new Map(),
   rulesets: new Map(),
   routes: new Map(),
+  dnsRecords: new Map(),
+  kvNamespaces: new Map(),
+  kvValues: new Map(), // namespaceId -> Map(key -> value)
 };
 
 // Initialize default mock zone
@@ -24,6 +28,14 @@ mockData.zones.set('test-zone-id', {
   account: { id: 'test-account-id', name: 'Test Account' },
 });
 
+// Second zone for multi-zone route tests
+mockData.zones.set('test-zone-id-2', {
+  id: 'test-zone-id-2',
+  name: 'example.org',
+  status: 'active',
+  account: { id: 'test-account-id', name: 'Test Account' },
+});
+
 /**
  * Generate a mock ID
  */
@@ -49,6 +61,38 @@ async function parseBody(req) {
   });
 }
 
+/**
+ * Read the raw request body (KV values are not JSON-wrapped)
+ */
+async function readBody(req) {
+  return new Promise((resolve, reject) => {
+    let body = '';
+    req.on('data', chunk => (body += chunk));
+    req.on('end', () => resolve(body));
+    req.on('error', reject);
+  });
+}
+
+/**
+ * Split a multipart/form-data body into { name: content } (worker uploads
+ * send a "metadata" JSON part plus the script part)
+ */
+function parseMultipart(body, contentType) {
+  const match = /boundary=(?:"([^"]+)"|([^;]+))/.exec(contentType || '');
+  if (!match) return {};
+  const boundary = `--${match[1] || match[2]}`;
+  const parts = {};
+  for (const chunk of body.split(boundary)) {
+    const headerEnd = chunk.indexOf('\r\n\r\n');
+    if (headerEnd < 0) continue;
+    const name = /name="([^"]+)"/.exec(chunk.slice(0, headerEnd));
+    if (name) {
+      parts[name[1]] = chunk.slice(headerEnd + 4).replace(/\r\n$/, '');
+    }
+  }
+  return parts;
+}
+
 /**
  * Send JSON response
  */
@@ -69,6 +113,22 @@ function cfResponse(result, success = true, errors = [], messages = []) {
   };
 }
 
+/**
+ * Give every rule an ID, as the API does when a ruleset is written
+ */
+function withRuleIds(rules) {
+  return (rules || []).map(rule => ({ ...rule, id: rule.id || generateId() }));
+}
+
+/**
+ * The zone's entrypoint ruleset for a phase; a zone has at most one
+ */
+function findEntrypoint(zoneId, phase) {
+  return Array.from(mockData.rulesets.values()).find(
+    r => r.zone_id === zoneId && r.kind === 'zone' && r.phase === phase
+  );
+}
+
 /**
  * Route handlers for different Cloudflare API endpoints
  */
@@ -89,16 +149,45 @@ const handlers = {
 
   // Workers API
   'PUT /accounts/:accountId/workers/scripts/:scriptName': async (req, res, params) => {
-    const scriptId = generateId();
+    const parts = parseMultipart(await readBody(req), req.headers['content-type']);
+    let metadata = {};
+    try {
+      metadata = JSON.parse(parts.metadata || '{}');
+    } catch (error) {
+      // Plain script upload without metadata
+    }
+    const existing = mockData.workers.get(params.scriptName);
     const worker = {
-      id: scriptId,
+      id: params.scriptName,
       script_name: params.scriptName,
       account_id: params.accountId,
-      created_on: new Date().toISOString(),
+      created_on: existing ? existing.created_on : new Date().toISOString(),
       modified_on: new Date().toISOString(),
+      script: parts[metadata.body_part || 'script'] || '',
+      bindings: metadata.bindings || [],
+      // Uploading a new version keeps the worker's Cron Triggers
+      schedules: existing ? existing.schedules : [],
     };
     mockData.workers.set(params.scriptName, worker);
-    sendJson(res, cfResponse(worker));
+    const { script, bindings, schedules, ...result } = worker;
+    sendJson(res, cfResponse(result));
+  },
+
+  'GET /accounts/:accountId/workers/scripts': (req, res, params) => {
+    const scripts = Array.from(mockData.workers.values())
+      .filter(w => w.account_id === params.accountId)
+      .map(({ script, bindings, schedules, ...result }) => result);
+    sendJson(res, cfResponse(scripts));
+  },
+
+  'GET /accounts/:accountId/workers/scripts/:scriptName/bindings': (req, res, params) => {
+    const worker = mockData.workers.get(params.scriptName);
+    if (!worker) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10007, message: 'Worker not found' }]), 404);
+    }
+    // Like the real API, secret values are never returned
+    const bindings = worker.bindings.map(({ text, ...binding }) => (binding.type === 'secret_text' ? binding : { ...binding, text }));
+    sendJson(res, cfResponse(bindings));
   },
 
   'GET /accounts/:accountId/workers/scripts/:scriptName': (req, res, params) => {
@@ -106,7 +195,9 @@ const handlers = {
     if (!worker) {
       return sendJson(res, cfResponse(null, false, [{ code: 10007, message: 'Worker not found' }]), 404);
     }
-    sendJson(res, cfResponse(worker));
+    // Downloading a script returns its source, not a JSON envelope
+    res.writeHead(200, { 'Content-Type': 'application/javascript' });
+    res.end(worker.script);
   },
 
   'DELETE /accounts/:accountId/workers/scripts/:scriptName': (req, res, params) => {
@@ -114,6 +205,26 @@ const handlers = {
     sendJson(res, cfResponse(null));
   },
 
+  // Cron Triggers API
+  'PUT /accounts/:accountId/workers/scripts/:scriptName/schedules': async (req, res, params) => {
+    const worker = mockData.workers.get(params.scriptName);
+    if (!worker) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10007, message: 'Worker not found' }]), 404);
+    }
+    const body = await parseBody(req);
+    const now = new Date().toISOString();
+    worker.schedules = (Array.isArray(body) ? body : []).map(s => ({ cron: s.cron, created_on: now, modified_on: now }));
+    sendJson(res, cfResponse({ schedules: worker.schedules }));
+  },
+
+  'GET /accounts/:accountId/workers/scripts/:scriptName/schedules': (req, res, params) => {
+    const worker = mockData.workers.get(params.scriptName);
+    if (!worker) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10007, message: 'Worker not found' }]), 404);
+    }
+    sendJson(res, cfResponse({ schedules: worker.schedules }));
+  },
+
   // Worker Routes API
   'POST /zones/:zoneId/workers/routes': async (req, res, params) => {
     const body = await parseBody(req);
@@ -133,21 +244,93 @@ const handlers = {
     sendJson(res, cfResponse(routes));
   },
 
+  'GET /zones/:zoneId/workers/routes/:routeId': (req, res, params) => {
+    const route = mockData.routes.get(params.routeId);
+    if (!route || route.zone_id !== params.zoneId) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10020, message: 'Route not found' }]), 404);
+    }
+    sendJson(res, cfResponse(route));
+  },
+
+  'PUT /zones/:zoneId/workers/routes/:routeId': async (req, res, params) => {
+    const route = mockData.routes.get(params.routeId);
+    if (!route || route.zone_id !== params.zoneId) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10020, message: 'Route not found' }]), 404);
+    }
+    const body = await parseBody(req);
+    const updated = { ...route, pattern: body.pattern, script: body.script };
+    mockData.routes.set(params.routeId, updated);
+    sendJson(res, cfResponse(updated));
+  },
+
   'DELETE /zones/:zoneId/workers/routes/:routeId': (req, res, params) => {
     mockData.routes.delete(params.routeId);
     sendJson(res, cfResponse(null));
   },
 
+  // DNS Records API
+  'POST /zones/:zoneId/dns_records': async (req, res, params) => {
+    const body = await parseBody(req);
+    const zone = mockData.zones.get(params.zoneId);
+    const record = {
+      ...body,
+      id: generateId(),
+      zone_id: params.zoneId,
+      zone_name: zone ? zone.name : '',
+      name: zone && !body.name.endsWith(zone.name) ? `${body.name}.${zone.name}` : body.name,
+      created_on: new Date().toISOString(),
+      modified_on: new Date().toISOString(),
+    };
+    mockData.dnsRecords.set(record.id, record);
+    sendJson(res, cfResponse(record));
+  },
+
+  'GET /zones/:zoneId/dns_records': (req, res, params) => {
+    const records = Array.from(mockData.dnsRecords.values()).filter(r => r.zone_id === params.zoneId);
+    sendJson(res, cfResponse(records));
+  },
+
+  'GET /zones/:zoneId/dns_records/:recordId': (req, res, params) => {
+    const record = mockData.dnsRecords.get(params.recordId);
+    if (!record || record.zone_id !== params.zoneId) {
+      return sendJson(res, cfResponse(null, false, [{ code: 81044, message: 'Record does not exist.' }]), 404);
+    }
+    sendJson(res, cfResponse(record));
+  },
+
+  'PATCH /zones/:zoneId/dns_records/:recordId': async (req, res, params) => {
+    const record = mockData.dnsRecords.get(params.recordId);
+    if (!record || record.zone_id !== params.zoneId) {
+      return sendJson(res, cfResponse(null, false, [{ code: 81044, message: 'Record does not exist.' }]), 404);
+    }
+    const body = await parseBody(req);
+    const updated = { ...record, ...body, id: record.id, modified_on: new Date().toISOString() };
+    mockData.dnsRecords.set(record.id, updated);
+    sendJson(res, cfResponse(updated));
+  },
+
+  'DELETE /zones/:zoneId/dns_records/:recordId': (req, res, params) => {
+    mockData.dnsRecords.delete(params.recordId);
+    sendJson(res, cfResponse({ id: params.recordId }));
+  },
+
   // Rulesets API (for rate limiting)
   'POST /zones/:zoneId/rulesets': async (req, res, params) => {
     const body = await parseBody(req);
+    if ((body.kind || 'zone') === 'zone' && findEntrypoint(params.zoneId, body.phase || 'http_ratelimit')) {
+      return sendJson(res, cfResponse(null, false, [{
+        code: 20217,
+        message: 'A similar configuration with rules already exists and overwriting will have unintended consequences',
+      }]), 400);
+    }
     const rulesetId = generateId();
     const ruleset = {
       id: rulesetId,
       name: body.name,
+      description: body.description || '',
       kind: body.kind || 'zone',
       phase: body.phase || 'http_ratelimit',
-      rules: body.rules || [],
+      rules: withRuleIds(body.rules),
       zone_id: params.zoneId,
     };
     mockData.rulesets.set(rulesetId, ruleset);
@@ -173,16 +356,153 @@ const handlers = {
     if (!existing) {
       return sendJson(res, cfResponse(null, false, [{ code: 10000, message: 'Ruleset not found' }]), 404);
     }
-    const ruleset = { ...existing, ...body, id: params.rulesetId };
+    const ruleset = { ...existing, ...body, rules: withRuleIds(body.rules || existing.rules), id: params.rulesetId };
     mockData.rulesets.set(params.rulesetId, ruleset);
     sendJson(res, cfResponse(ruleset));
   },
 
   'DELETE /zones/:zoneId/rulesets/:rulesetId': (req, res, params) => {
+    const user = Array.from(mockData.rulesets.values()).find(r =>
+      r.rules.some(rule => rule.action === 'execute' && rule.action_parameters?.id === params.rulesetId)
+    );
+    if (user) {
+      return sendJson(res, cfResponse(null, false, [{
+        code: 20134,
+        message: `ruleset is executed by ruleset ${user.id} and cannot be deleted`,
+      }]), 400);
+    }
     mockData.rulesets.delete(params.rulesetId);
     sendJson(res, cfResponse(null));
   },
 
+  // Phase entrypoints and single rules, for adding rules next to ones other
+  // tools manage
+  'GET /zones/:zoneId/rulesets/phases/:phase/entrypoint': (req, res, params) => {
+    const ruleset = findEntrypoint(params.zoneId, params.phase);
+    if (!ruleset) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10003, message: 'Entrypoint not found' }]), 404);
+    }
+    sendJson(res, cfResponse(ruleset));
+  },
+
+  'PUT /zones/:zoneId/rulesets/phases/:phase/entrypoint': async (req, res, params) => {
+    const body = await parseBody(req);
+    const existing = findEntrypoint(params.zoneId, params.phase) || {
+      id: generateId(),
+      name: 'default',
+      kind: 'zone',
+      phase: params.phase,
+      zone_id: params.zoneId,
+    };
+    const ruleset = { ...existing, description: body.description ?? existing.description ?? '', rules: withRuleIds(body.rules) };
+    mockData.rulesets.set(ruleset.id, ruleset);
+    sendJson(res, cfResponse(ruleset));
+  },
+
+  'POST /zones/:zoneId/rulesets/:rulesetId/rules': async (req, res, params) => {
+    const { position, ...body } = await parseBody(req);
+    const ruleset = mockData.rulesets.get(params.rulesetId);
+    if (!ruleset) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10000, message: 'Ruleset not found' }]), 404);
+    }
+    const [rule] = withRuleIds([body]);
+    const rules = [...ruleset.rules];
+    const anchor = position?.before ?? position?.after;
+    const at = anchor ? rules.findIndex(r => r.id === anchor) : -1;
+    if (position?.before === '') {
+      rules.unshift(rule);
+    } else if (at >= 0) {
+      rules.splice(position.before ? at : at + 1, 0, rule);
+    } else {
+      rules.push(rule);
+    }
+    ruleset.rules = rules;
+    sendJson(res, cfResponse(ruleset));
+  },
+
+  'PATCH /zones/:zoneId/rulesets/:rulesetId/rules/:ruleId': async (req, res, params) => {
+    const body = await parseBody(req);
+    const ruleset = mockData.rulesets.get(params.rulesetId);
+    const index = ruleset ? ruleset.rules.findIndex(r => r.id === params.ruleId) : -1;
+    if (index < 0) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10000, message: 'Rule not found' }]), 404);
+    }
+    ruleset.rules[index] = { ...body, id: params.ruleId };
+    sendJson(res, cfResponse(ruleset));
+  },
+
+  'DELETE /zones/:zoneId/rulesets/:rulesetId/rules/:ruleId': (req, res, params) => {
+    const ruleset = mockData.rulesets.get(params.rulesetId);
+    const index = ruleset ? ruleset.rules.findIndex(r => r.id === params.ruleId) : -1;
+    if (index < 0) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10000, message: 'Rule not found' }]), 404);
+    }
+    ruleset.rules.splice(index, 1);
+    sendJson(res, cfResponse(ruleset));
+  },
+
+  // Workers KV API
+  'POST /accounts/:accountId/storage/kv/namespaces': async (req, res, params) => {
+    const body = await parseBody(req);
+    const namespace = {
+      id: generateId(),
+      title: body.title,
+      account_id: params.accountId,
+      supports_url_encoding: true,
+    };
+    mockData.kvNamespaces.set(namespace.id, namespace);
+    mockData.kvValues.set(namespace.id, new Map());
+    sendJson(res, cfResponse(namespace));
+  },
+
+  'GET /accounts/:accountId/storage/kv/namespaces': (req, res, params) => {
+    const namespaces = Array.from(mockData.kvNamespaces.values()).filter(n => n.account_id === params.accountId);
+    sendJson(res, cfResponse(namespaces));
+  },
+
+  'DELETE /accounts/:accountId/storage/kv/namespaces/:namespaceId': (req, res, params) => {
+    mockData.kvNamespaces.delete(params.namespaceId);
+    mockData.kvValues.delete(params.namespaceId);
+    sendJson(res, cfResponse(null));
+  },
+
+  'GET /accounts/:accountId/storage/kv/namespaces/:namespaceId/keys': (req, res, params, query) => {
+    const values = mockData.kvValues.get(params.namespaceId);
+    if (!values) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10013, message: 'namespace not found' }]), 404);
+    }
+    const prefix = query.prefix || '';
+    const keys = Array.from(values.keys()).filter(k => k.startsWith(prefix)).sort().map(name => ({ name }));
+    sendJson(res, { ...cfResponse(keys), result_info: { count: keys.length, cursor: '' } });
+  },
+
+  'GET /accounts/:accountId/storage/kv/namespaces/:namespaceId/values/:key': (req, res, params) => {
+    const values = mockData.kvValues.get(params.namespaceId);
+    const key = decodeURIComponent(params.key);
+    if (!values || !values.has(key)) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10009, message: 'get: \'key not found\'' }]), 404);
+    }
+    res.writeHead(200, { 'Content-Type': 'application/octet-stream' });
+    res.end(values.get(key));
+  },
+
+  'PUT /accounts/:accountId/storage/kv/namespaces/:namespaceId/values/:key': async (req, res, params) => {
+    const values = mockData.kvValues.get(params.namespaceId);
+    if (!values) {
+      return sendJson(res, cfResponse(null, false, [{ code: 10013, message: 'namespace not found' }]), 404);
+    }
+    values.set(decodeURIComponent(params.key), await readBody(req));
+    sendJson(res, cfResponse(null));
+  },
+
+  'DELETE /accounts/:accountId/storage/kv/namespaces/:namespaceId/values/:key': (req, res, params) => {
+    const values = mockData.kvValues.get(params.namespaceId);
+    if (values) {
+      values.delete(decodeURIComponent(params.key));
+    }
+    sendJson(res, cfResponse(null));
+  },
+
   // Account verification
   'GET /accounts/:accountId': (req, res, params) => {
     sendJson(res, cfResponse({
@@ -193,10 +513,11 @@ const handlers = {
     }));
   },
 
-  // User token verification
+  // User token verification; each bearer token gets its own stable ID
   'GET /user/tokens/verify': (req, res) => {
+    const token = (req.headers.authorization || '').replace(/^Bearer /, '');
     sendJson(res, cfResponse({
-      id: 'mock-token-id',
+      id: token ? `mock-token-${createHash('sha256').update(token).digest('hex').slice(0, 12)}` : 'mock-token-id',
       status: 'active',
     }));
   },
@@ -235,7 +556,7 @@ function matchRoute(method, pathname) {
  * Request handler
  */
 async function handleRequest(req, res) {
-  const { pathname } = parse(req.url, true);
+  const { pathname, query } = parse(req.url, true);
   const method = req.method;
 
   // Remove /client/v4 prefix if present
@@ -247,7 +568,7 @@ async function handleRequest(req, res) {
 
   if (matched) {
     try {
-      await matched.handler(req, res, matched.params);
+      await matched.handler(req, res, matched.params, query);
     } catch (error) {
       console.error(`[Mock Server] Error: ${error.message}`);
       sendJson(res, cfResponse(null, false, [{ code: 500, message: error.message }]), 500);
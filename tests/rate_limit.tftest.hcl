@@ -345,3 +345,329 @@ run "verify_rate_limit_maximum_period" {
     error_message = "Rate limiting should work with maximum period"
   }
 }
+
+# Test case 12: Rate limiting for the maintenance page only is not created while maintenance is off
+run "verify_rate_limit_page_only_off" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_ruleset.rate_limit) == 0
+    error_message = "The maintenance page rate limit should not exist while maintenance is off"
+  }
+
+  assert {
+    condition     = output.rate_limit_enabled == false && output.rate_limit_ruleset_id == null
+    error_message = "Rate limiting should be reported off while maintenance is off"
+  }
+}
+
+# Test case 13: Rate limiting for the maintenance page only is created while maintenance is on
+run "verify_rate_limit_page_only_on" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+      rules = [
+        {
+          description = "Login attempts"
+          expression  = "(http.request.uri.path eq \"/login\")"
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_ruleset.rate_limit) == 1 && output.rate_limit_enabled == true
+    error_message = "The maintenance page rate limit should exist while maintenance is on"
+  }
+
+  assert {
+    condition     = cloudflare_ruleset.rate_limit[0].rules[0].expression == "(http.host eq \"example.com\") and ((http.request.uri.path eq \"/login\"))"
+    error_message = "The rule should only see requests on the maintenance route"
+  }
+}
+
+# Test case 14: Bypassed clients, reads in read_only mode and scopes that are off are not rate limited
+run "verify_rate_limit_page_only_expression" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    mode                  = "read_only"
+    allowed_ips           = ["192.0.2.1"]
+    allowed_regions       = ["CA"]
+    worker_routes = [
+      { zone_id = "test-zone-id", pattern = "app.example.com/*" },
+      { zone_id = "other-zone-id", pattern = "example.org/*" },
+    ]
+    maintenance_scopes = [
+      { host = "", path = "/status", enabled = false, title = "", message = "" },
+    ]
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_ruleset.rate_limit[0].rules[0].expression == "(http.host eq \"app.example.com\") and (not (ip.src in {\"192.0.2.1\"} or ip.geoip.country in {\"CA\"})) and (not (http.request.method in {\"GET\" \"HEAD\" \"OPTIONS\"})) and (not (((http.request.uri.path eq \"/status\" or starts_with(http.request.uri.path, \"/status/\"))))) and ((http.request.uri.path matches \".*\"))"
+    error_message = "The rule should only see requests that get the maintenance page"
+  }
+}
+
+# Test case 15: A scope that is on turns on the maintenance page rate limit without enabled
+run "verify_rate_limit_page_only_scope" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    maintenance_scopes = [
+      { host = "", path = "/checkout", enabled = true, title = "", message = "" },
+    ]
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_ruleset.rate_limit[0].rules[0].expression == "(http.host eq \"example.com\") and (((http.request.uri.path eq \"/checkout\" or starts_with(http.request.uri.path, \"/checkout/\")))) and ((http.request.uri.path matches \".*\"))"
+    error_message = "The rule should only see requests in the scope that is on"
+  }
+}
+
+# Test case 16: Zone-wide rate limiting stays on while maintenance is off
+run "verify_rate_limit_zone_while_off" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    rate_limit = {
+      enabled = true
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_ruleset.rate_limit) == 1 && cloudflare_ruleset.rate_limit[0].rules[0].expression == "(http.request.uri.path matches \".*\")"
+    error_message = "Zone-wide rate limiting should not depend on maintenance"
+  }
+}
+
+# Test case 17: By default the rulesets are the zone's phase entrypoints
+run "verify_ruleset_mode_entrypoint" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    allowed_ips           = ["192.0.2.1"]
+    rate_limit = {
+      enabled = true
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_ruleset.maintenance_bypass[0].kind == "zone" && cloudflare_ruleset.rate_limit[0].kind == "zone"
+    error_message = "Rulesets should be zone entrypoints by default"
+  }
+
+  assert {
+    condition     = length(terraform_data.ruleset_execute) == 0
+    error_message = "No execute rules should be attached by default"
+  }
+}
+
+# Test case 18: ruleset_mode = "execute" makes custom rulesets and attaches an execute rule for each
+run "verify_ruleset_mode_execute" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    allowed_ips           = ["192.0.2.1"]
+    ruleset_mode          = "execute"
+    maintctl_command      = "/usr/local/bin/maintctl"
+    rate_limit = {
+      enabled = true
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_ruleset.maintenance_bypass[0].kind == "custom" && cloudflare_ruleset.rate_limit[0].kind == "custom"
+    error_message = "Rulesets should be custom rulesets in execute mode"
+  }
+
+  assert {
+    condition     = keys(terraform_data.ruleset_execute) == ["maintenance-bypass", "rate-limit"]
+    error_message = "Each ruleset should get an execute rule"
+  }
+
+  assert {
+    condition = terraform_data.ruleset_execute["rate-limit"].input == {
+      command = "/usr/local/bin/maintctl"
+      zone_id = "test-zone-id"
+      phase   = "http_ratelimit"
+      ref     = "maintenance-test-rate-limit"
+    }
+    error_message = "The rate limit execute rule should be attached to the http_ratelimit entrypoint"
+  }
+}
+
+# Test case 19: Execute rules follow their rulesets, so none is attached while nothing is created
+run "verify_ruleset_mode_execute_nothing_to_attach" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    ruleset_mode          = "execute"
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(terraform_data.ruleset_execute) == 0
+    error_message = "No execute rule should be attached without a ruleset"
+  }
+}
+
+# Test case 20: Unknown ruleset modes are rejected
+run "verify_ruleset_mode_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    ruleset_mode          = "merge"
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.ruleset_mode,
+  ]
+}
+
+# Test case 21: The maintenance page rate limit can't follow maintenance the worker turns on by itself
+run "verify_rate_limit_page_only_rejects_runtime_state" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    kv_runtime_state      = true
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_script.maintenance,
+  ]
+}
+
+# Test case 22: Nor a maintenance window, which the worker opens on its own
+run "verify_rate_limit_page_only_rejects_window" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    maintenance_window = {
+      start_time = "2025-04-06T08:00:00Z"
+      end_time   = "2025-04-06T10:00:00Z"
+    }
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance_page"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_script.maintenance,
+  ]
+}
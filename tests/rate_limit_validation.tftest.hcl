@@ -0,0 +1,418 @@
+# Code generated by go test ./internal/ratelimit -update from
+# tests/fixtures/rate-limit-rules.json. DO NOT EDIT.
+
+# Validate accepts top_level_fields
+run "rate_limit_accepted_top_level_fields" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled             = true
+      requests_per_period = 100
+      period              = 60
+      action              = "block"
+      mitigation_timeout  = 600
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = [for r in cloudflare_ruleset.rate_limit[0].rules : r.expression] == ["(http.request.uri.path matches \".*\")"]
+    error_message = "Rate limit rules should be planned in rate_limit.rules order"
+  }
+}
+
+# Validate accepts per_path_rules
+run "rate_limit_accepted_per_path_rules" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          description         = "Login attempts per IP"
+          expression          = "(http.request.uri.path eq \"/login\" and http.request.method eq \"POST\")"
+          characteristics     = ["cf.colo.id", "ip.src"]
+          action              = "managed_challenge"
+          period              = 60
+          requests_per_period = 5
+          mitigation_timeout  = 600
+        },
+        {
+          description         = "API calls per key"
+          expression          = "(starts_with(http.request.uri.path, \"/api/\"))"
+          characteristics     = ["cf.colo.id", "http.request.headers[\"x-api-key\"]"]
+          action              = "block"
+          period              = 10
+          requests_per_period = 50
+          mitigation_timeout  = 60
+          counting_expression = "(http.response.code eq 429)"
+        },
+        {
+          description         = "Sessions"
+          expression          = "(http.request.uri.path matches \"^/account\")"
+          characteristics     = ["cf.colo.id", "http.request.cookies[\"session\"]"]
+          action              = "log"
+          period              = 3600
+          requests_per_period = 1000
+          mitigation_timeout  = 3600
+          requests_to_origin  = true
+        },
+        {
+          description     = "Everything else per network"
+          expression      = "true"
+          characteristics = ["cf.colo.id", "ip.geoip.asnum"]
+          action          = "js_challenge"
+          enabled         = false
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = [for r in cloudflare_ruleset.rate_limit[0].rules : r.expression] == ["(http.request.uri.path eq \"/login\" and http.request.method eq \"POST\")", "(starts_with(http.request.uri.path, \"/api/\"))", "(http.request.uri.path matches \"^/account\")", "true"]
+    error_message = "Rate limit rules should be planned in rate_limit.rules order"
+  }
+}
+
+# Validate accepts rule_defaults
+run "rate_limit_accepted_rule_defaults" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression = "(http.host eq \"example.com\")"
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = [for r in cloudflare_ruleset.rate_limit[0].rules : r.expression] == ["(http.host eq \"example.com\")"]
+    error_message = "Rate limit rules should be planned in rate_limit.rules order"
+  }
+}
+
+# Validate rejects empty_expression: rules[0]: expression is empty
+run "rate_limit_rejected_empty_expression" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression = " "
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects missing_colo: characteristics must include cf.colo.id
+run "rate_limit_rejected_missing_colo" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression      = "true"
+          characteristics = ["ip.src"]
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects unknown_characteristic: characteristic "ip.src.subnet" is not supported
+run "rate_limit_rejected_unknown_characteristic" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression      = "true"
+          characteristics = ["cf.colo.id", "ip.src.subnet"]
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects uppercase_header: is not supported or repeated
+run "rate_limit_rejected_uppercase_header" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression      = "true"
+          characteristics = ["cf.colo.id", "http.request.headers[\"X-Api-Key\"]"]
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects repeated_characteristic: characteristic "ip.src" is not supported or repeated
+run "rate_limit_rejected_repeated_characteristic" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression      = "true"
+          characteristics = ["cf.colo.id", "ip.src", "ip.src"]
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects second_rule_period: rules[1]: period 5 must be between 10 and 86400 seconds
+run "rate_limit_rejected_second_rule_period" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression = "true"
+        },
+        {
+          expression = "true"
+          period     = 5
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects zero_requests: requests_per_period 0 must be at least 1
+run "rate_limit_rejected_zero_requests" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression          = "true"
+          requests_per_period = 0
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects rule_action: action "deny" must be one of
+run "rate_limit_rejected_rule_action" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression = "true"
+          action     = "deny"
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects rule_mitigation_timeout: mitigation_timeout 90000 must be between 60 and 86400 seconds
+run "rate_limit_rejected_rule_mitigation_timeout" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      rules = [
+        {
+          expression         = "true"
+          mitigation_timeout = 90000
+        },
+      ]
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects applies_to: applies_to "maintenance" must be zone or maintenance_page
+run "rate_limit_rejected_applies_to" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled    = true
+      applies_to = "maintenance"
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
+
+# Validate rejects top_level_period: period 5 must be between 10 and 86400 seconds
+run "rate_limit_rejected_top_level_period" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rate_limit = {
+      enabled = true
+      period  = 5
+    }
+  }
+
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rate_limit,
+  ]
+}
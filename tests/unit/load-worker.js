@@ -0,0 +1,29 @@
+/**
+ * Loads worker.js for the unit tests, so they exercise the deployed code
+ * instead of copies of it. The worker is a service-worker script with no
+ * exports: it runs in this context wrapped in a function that stubs
+ * addEventListener and returns every top-level function. Bindings such as
+ * MAINTENANCE_KV are read from globalThis, as on Cloudflare.
+ */
+
+import { readFileSync } from 'fs';
+import { dirname, join } from 'path';
+import { fileURLToPath } from 'url';
+import vm from 'vm';
+
+const workerPath = join(dirname(fileURLToPath(import.meta.url)), '../../worker.js');
+
+export function loadWorker() {
+  const source = readFileSync(workerPath, 'utf8');
+  const names = [...source.matchAll(/^(?:async )?function (\w+)/gm)].map(m => m[1]);
+  // The wrapper's first line is offset so stack traces point at worker.js lines
+  const factory = vm.runInThisContext(
+    `(function (addEventListener) {\n${source}\nreturn { ${names.join(', ')} };\n})`,
+    { filename: workerPath, lineOffset: -1 }
+  );
+  const listeners = {};
+  const functions = factory((type, listener) => {
+    listeners[type] = listener;
+  });
+  return { ...functions, listeners };
+}
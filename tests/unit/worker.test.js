@@ -4,6 +4,9 @@
  */
 
 import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { readFileSync } from 'fs';
+import { join } from 'path';
+import { loadWorker } from './load-worker.js';
 
 // Mock global variables that would be injected by Cloudflare
 const mockGlobals = {
@@ -49,6 +52,7 @@ global.fetch = vi.fn(() => Promise.resolve(new Response('OK', { status: 200 })))
 
 // Import worker functions (we'll test them individually)
 // Since the worker uses addEventListener, we need to extract the functions
+const worker = loadWorker();
 
 // ===== Function Tests =====
 
@@ -188,19 +192,125 @@ describe('checkMaintenanceWindow', () => {
   });
 });
 
+describe('resolveRuntimeState', () => {
+  const { resolveRuntimeState } = worker;
+
+  const bindings = {
+    enabled: false,
+    title: 'Binding title',
+    message: 'Binding message',
+    windowStart: '2025-04-06T08:00:00Z',
+    windowEnd: '2025-04-06T10:00:00Z',
+    rolloutPercentage: 100,
+  };
+
+  it('should use the bindings when there is no KV state', () => {
+    expect(resolveRuntimeState(null, bindings)).toEqual(bindings);
+    expect(resolveRuntimeState('garbage', bindings)).toEqual(bindings);
+  });
+
+  it('should let the KV document toggle maintenance', () => {
+    expect(resolveRuntimeState({ enabled: true }, bindings).enabled).toBe(true);
+    expect(resolveRuntimeState({ enabled: 'true' }, bindings).enabled).toBe(false);
+  });
+
+  it('should fall back to the bindings for empty title and message', () => {
+    const state = resolveRuntimeState({ enabled: true, title: '', message: 'From KV' }, bindings);
+    expect(state.title).toBe('Binding title');
+    expect(state.message).toBe('From KV');
+  });
+
+  it('should let empty window fields clear the Terraform window', () => {
+    const state = resolveRuntimeState({ window_start: '', window_end: '' }, bindings);
+    expect(state.windowStart).toBe('');
+    expect(state.windowEnd).toBe('');
+    expect(resolveRuntimeState({}, bindings).windowEnd).toBe(bindings.windowEnd);
+  });
+
+  it('should take the rollout percentage from KV when present', () => {
+    expect(resolveRuntimeState({ rollout_percentage: 25 }, bindings).rolloutPercentage).toBe(25);
+    expect(resolveRuntimeState({ rollout_percentage: 0 }, bindings).rolloutPercentage).toBe(0);
+    expect(resolveRuntimeState({ enabled: true }, bindings).rolloutPercentage).toBe(100);
+  });
+});
+
+describe('decideRollout', () => {
+  const { rolloutBucket, getRolloutCookie, decideRollout, rolloutCookie } = worker;
+
+  // Shared with internal/rollout/rollout_test.go so the worker and the Go
+  // statistical tests bucket clients the same way
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/rollout-buckets.json'), 'utf8')
+  );
+
+  it('should hash client keys to the same buckets as Go', () => {
+    for (const [key, bucket] of Object.entries(fixture.buckets)) {
+      expect(rolloutBucket(key)).toBe(bucket);
+    }
+  });
+
+  for (const tc of fixture.cases) {
+    it(tc.name, async () => {
+      expect(await decideRollout(getRolloutCookie(tc.cookie), tc.client_ip, tc.percentage, fixture.cookie_key)).toEqual({
+        bucket: tc.want_bucket || 0,
+        included: tc.want_included,
+        setCookie: tc.want_set_cookie,
+      });
+    });
+  }
+
+  it('should honour the cookie it sets', async () => {
+    const header = await rolloutCookie(5, fixture.cookie_key);
+    const decision = await decideRollout(getRolloutCookie(header.split(';')[0]), '192.0.2.1', 25, fixture.cookie_key);
+    expect(decision).toEqual({ bucket: 5, included: true, setCookie: false });
+  });
+
+  it('should ignore forged cookies', async () => {
+    // 192.0.2.1 hashes to bucket 76, outside a 25% rollout
+    const signed = (await rolloutCookie(5, fixture.cookie_key)).split(';')[0].split('=')[1];
+    const otherKey = (await rolloutCookie(5, 'another-key')).split(';')[0].split('=')[1];
+    for (const cookie of ['5', `5.${'0'.repeat(64)}`, otherKey, signed.slice(0, 20), signed.replace(/^5\./, '6.')]) {
+      expect(await decideRollout(cookie, '192.0.2.1', 25, fixture.cookie_key)).toEqual({ bucket: 76, included: false, setCookie: true });
+    }
+  });
+
+  it('should neither trust nor set cookies without a key', async () => {
+    for (const cookie of ['', '5']) {
+      expect(await decideRollout(cookie, '192.0.2.1', 25, '')).toEqual({ bucket: 76, included: false, setCookie: false });
+    }
+  });
+});
+
 describe('getMaintenanceWindowMessage', () => {
   // Inline implementation for testing
-  function getMaintenanceWindowMessage(startTime, endTime) {
+  function formatInTimeZone(date, timeZone) {
+    try {
+      return new Intl.DateTimeFormat('en-US', {
+        weekday: 'short',
+        year: 'numeric',
+        month: 'short',
+        day: 'numeric',
+        hour: '2-digit',
+        minute: '2-digit',
+        hourCycle: 'h23',
+        timeZone,
+        timeZoneName: 'short',
+      }).format(date);
+    } catch (e) {
+      return date.toUTCString();
+    }
+  }
+
+  function getMaintenanceWindowMessage(startTime, endTime, displayTimezone = 'UTC') {
     if (!startTime || !endTime) {
       return '';
     }
 
-    try {
-      const end = new Date(endTime);
-      return `<p style="font-size: 0.9rem; color: #888;">Expected completion: ${end.toUTCString()}</p>`;
-    } catch (e) {
+    const end = new Date(endTime);
+    if (isNaN(end.getTime())) {
       return '';
     }
+    return `<p class="window">Expected completion: <time datetime="${end.toISOString()}" data-maintenance-end>${formatInTimeZone(end, displayTimezone)}</time></p>`;
   }
 
   it('should return formatted message when window is set', () => {
@@ -209,9 +319,49 @@ describe('getMaintenanceWindowMessage', () => {
       '2025-04-06T10:00:00Z'
     );
     expect(result).toContain('Expected completion:');
+    expect(result).toContain('Sun, Apr 6, 2025, 10:00 UTC');
+  });
+
+  it('should render the completion time in the display timezone', () => {
+    const result = getMaintenanceWindowMessage(
+      '2025-04-06T08:00:00Z',
+      '2025-04-06T10:00:00Z',
+      'America/New_York'
+    );
+    expect(result).toContain('Sun, Apr 6, 2025, 06:00 EDT');
+  });
+
+  it('should keep a machine-readable end time for client-side rendering', () => {
+    const result = getMaintenanceWindowMessage(
+      '2025-04-06T08:00:00Z',
+      '2025-04-06T10:00:00Z',
+      'Asia/Tokyo'
+    );
+    expect(result).toContain('datetime="2025-04-06T10:00:00.000Z"');
+    expect(result).toContain('data-maintenance-end');
+  });
+
+  it('should fall back to UTC for unknown timezones', () => {
+    const result = getMaintenanceWindowMessage(
+      '2025-04-06T08:00:00Z',
+      '2025-04-06T10:00:00Z',
+      'Mars/Olympus_Mons'
+    );
     expect(result).toContain('Sun, 06 Apr 2025 10:00:00 GMT');
   });
 
+  // Shared with internal/page/page_test.go so previews format times the same
+  // way, unknown zones included
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/timezone-format.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(`formats like Go: ${tc.name}`, () => {
+      expect(formatInTimeZone(new Date(tc.time), tc.timezone)).toBe(tc.want);
+    });
+  }
+
   it('should return empty string when no window is set', () => {
     expect(getMaintenanceWindowMessage('', '')).toBe('');
     expect(getMaintenanceWindowMessage(null, null)).toBe('');
@@ -222,6 +372,392 @@ describe('getMaintenanceWindowMessage', () => {
   });
 });
 
+describe('renderStatusUpdates', () => {
+  const { renderStatusUpdates } = worker;
+
+  // Same input and expected markup as TestUpdatesHTML in internal/page
+  it('should render updates newest first in the display timezone', () => {
+    const updates = [
+      { id: 'a', message: 'Started <migration>', posted_at: '2025-04-06T08:05:00Z' },
+      { id: 'b', message: 'Migration 60% done', posted_at: '2025-04-06T09:00:00Z' },
+      { id: 'c', message: '', posted_at: '2025-04-06T09:30:00Z' },
+    ];
+    expect(renderStatusUpdates(updates, 'America/New_York')).toBe(
+      '<ul class="updates">' +
+        '<li><time datetime="2025-04-06T09:00:00.000Z">Sun, Apr 6, 2025, 05:00 EDT</time> Migration 60% done</li>' +
+        '<li><time datetime="2025-04-06T08:05:00.000Z">Sun, Apr 6, 2025, 04:05 EDT</time> Started &lt;migration&gt;</li>' +
+        '</ul>'
+    );
+  });
+
+  it('should skip malformed entries', () => {
+    expect(renderStatusUpdates([null, { message: 'x', posted_at: 'soon' }, { posted_at: '2025-04-06T09:00:00Z' }], 'UTC')).toBe('');
+  });
+
+  it('should render nothing without updates', () => {
+    expect(renderStatusUpdates([], 'UTC')).toBe('');
+  });
+});
+
+describe('negotiateLocale', () => {
+  const { negotiateLocale } = worker;
+
+  // Shared with internal/locale/locale_test.go so the worker and maintctl agree
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/locale-negotiation.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(tc.name, () => {
+      expect(
+        negotiateLocale(
+          tc.accept_language,
+          tc.country,
+          fixture.available,
+          fixture.default_locale,
+          fixture.country_locales
+        )
+      ).toBe(tc.want);
+    });
+  }
+});
+
+describe('matchScope', () => {
+  const { matchScope } = worker;
+
+  // Shared with internal/scope/scope_test.go so the worker and maintctl agree
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/maintenance-scopes.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(tc.name, () => {
+      const scope = matchScope(fixture.scopes, tc.host, tc.path);
+      expect(scope ? fixture.scopes.indexOf(scope) : -1).toBe(tc.want_scope);
+      expect(scope ? scope.enabled : tc.global_enabled).toBe(tc.want_enabled);
+    });
+  }
+});
+
+describe('maintenanceAction', () => {
+  const { maintenanceAction } = worker;
+
+  // Shared with internal/mode/mode_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/read-only-mode.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(`should ${tc.want} ${tc.method} in mode "${tc.mode}"`, () => {
+      expect(maintenanceAction(tc.mode, tc.method)).toBe(tc.want);
+    });
+  }
+});
+
+describe('auto maintenance breaker', () => {
+  const { breakerOpen, breakerRecord } = worker;
+
+  // Same outcome strings guardedFetch records
+  function failureFor(origin, config) {
+    if (origin === 'ok') return '';
+    if (origin === 'timeout') return `timeout after ${config.timeout_ms}ms`;
+    return parseInt(origin.slice('HTTP '.length), 10) >= 500 ? origin : '';
+  }
+
+  // Shared with internal/health/health_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/auto-maintenance.json'), 'utf8')
+  );
+
+  it('should trip, hold through the cool-down and recover like the Go breaker', () => {
+    const breaker = { failures: [], openedAt: null, reason: '' };
+    for (const step of fixture.steps) {
+      let got = 'page';
+      if (!breakerOpen(breaker, step.at_ms, fixture.config)) {
+        if (!breakerRecord(breaker, step.at_ms, failureFor(step.origin, fixture.config), fixture.config)) {
+          got = 'pass';
+        }
+      }
+      expect({ at: step.at_ms, got, reason: breaker.reason }).toEqual({
+        at: step.at_ms,
+        got: step.want,
+        reason: step.want_reason || '',
+      });
+    }
+  });
+
+  it('should record when the cool-down ends', () => {
+    const breaker = { failures: [], openedAt: null, reason: '' };
+    const config = { failure_threshold: 1, window_seconds: 60, cooldown_seconds: 120 };
+    expect(breakerRecord(breaker, Date.UTC(2025, 3, 6, 8), 'HTTP 502', config)).toEqual({
+      tripped_at: '2025-04-06T08:00:00.000Z',
+      reason: '1 origin failures within 60s, last: HTTP 502',
+      cooldown_until: '2025-04-06T08:02:00.000Z',
+    });
+  });
+});
+
+describe('stale copies', () => {
+  const { staleApplies, isStorableForStale } = worker;
+
+  // Shared with internal/stale/stale_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/stale-paths.json'), 'utf8')
+  );
+
+  for (const tc of fixture.applies) {
+    it(`should ${tc.want ? '' : 'not '}apply to ${tc.method} ${tc.path}`, () => {
+      expect(staleApplies(fixture.paths, tc.method, tc.path)).toBe(tc.want);
+    });
+  }
+
+  for (const tc of fixture.storable) {
+    it(`should ${tc.want ? '' : 'not '}store ${tc.name}`, () => {
+      expect(isStorableForStale(new Headers(tc.request_headers), tc.status, new Headers(tc.headers))).toBe(tc.want);
+    });
+  }
+});
+
+describe('watchdog', () => {
+  const { watchdogVerdict, watchdogDisabledState } = worker;
+
+  // Shared with internal/watchdog/watchdog_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/watchdog.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(`should ${tc.want} when ${tc.name}`, () => {
+      expect(watchdogVerdict(tc.state, Date.parse(tc.now), fixture.config)).toEqual({
+        action: tc.want,
+        reason: tc.want_reason || '',
+      });
+    });
+  }
+
+  it('should clear a window that is still open when it trips', () => {
+    const stored = {
+      enabled: true,
+      title: 'Upgrading',
+      rollout_percentage: 100,
+      enabled_at: '2025-04-06T08:00:00Z',
+      window_start: '2025-04-06T08:00:00Z',
+      window_end: '2025-04-06T12:00:00Z',
+    };
+    expect(watchdogDisabledState(stored, '2025-04-06T09:00:00Z')).toEqual({
+      enabled: false,
+      title: 'Upgrading',
+      rollout_percentage: 100,
+      window_start: '',
+      window_end: '',
+      updated_at: '2025-04-06T09:00:00Z',
+    });
+    expect(stored.enabled).toBe(true);
+  });
+});
+
+describe('notificationRequest', () => {
+  const { notificationRequest } = worker;
+
+  // Shared with internal/notify/notify_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/notifications.json'), 'utf8')
+  );
+
+  for (const tc of fixture.cases) {
+    it(`should build the request for ${tc.target.split('://')[0]}`, () => {
+      const request = notificationRequest(tc.target, fixture.message);
+      if (tc.error) {
+        expect(request).toBeNull();
+      } else {
+        expect(request).toEqual({ url: tc.url, body: tc.body });
+      }
+    });
+  }
+});
+
+describe('schedules', () => {
+  const { parseCron, parseScheduleDuration, rfc3339, scheduleStep, findBlackout, resolveBlackouts, parseIcal, maintenanceCalendar } = worker;
+
+  const compile = s => ({ name: s.name, timezone: s.timezone, cron: parseCron(s.cron), minutes: parseScheduleDuration(s.duration) });
+
+  it('should parse the cron forms the module documents', () => {
+    for (const expr of ['0 2 * * SUN', '*/15 * * * *', '0 3 1 * *', '0 0 * * 0,6', '30 4 * * 1-5']) {
+      expect(parseCron(expr)).not.toBeNull();
+    }
+    for (const expr of ['99 99 * * *', '0 2 * *', '* * * 13 *', '5-1 * * * *', '*/0 * * * *']) {
+      expect(parseCron(expr)).toBeNull();
+    }
+  });
+
+  it('should parse durations', () => {
+    expect(parseScheduleDuration('2h')).toBe(120);
+    expect(parseScheduleDuration('1h30m')).toBe(90);
+    expect(parseScheduleDuration('2 hours')).toBeNaN();
+  });
+
+  // Shared with internal/schedule/schedule_test.go
+  const fixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/schedules.json'), 'utf8')
+  );
+
+  // Shared with internal/schedule/corpus_test.go
+  const corpus = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/cron-corpus.json'), 'utf8')
+  );
+
+  it('should accept and reject the same cron expressions as ParseCron', () => {
+    for (const expr of corpus.accept) {
+      expect(parseCron(expr)).not.toBeNull();
+    }
+    for (const expr of corpus.reject) {
+      expect(parseCron(expr)).toBeNull();
+    }
+  });
+
+  for (const replay of fixture.replays) {
+    it(`should open and close windows on time: ${replay.name}`, () => {
+      const schedules = replay.schedules.map(compile);
+      const tick = replay.tick_minutes * 60000;
+      let record = {};
+      const timeline = [];
+      for (let t = Math.floor(Date.parse(replay.from) / tick) * tick; t < Date.parse(replay.to); t += tick) {
+        const step = scheduleStep(schedules, record, t);
+        record = step.record;
+        timeline.push(...step.transitions);
+      }
+      expect(timeline).toEqual(replay.transitions);
+    });
+  }
+  // Shared with internal/schedule/blackout_test.go
+  const blackoutFixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/blackouts.json'), 'utf8')
+  );
+  const allBlackouts = () => resolveBlackouts(blackoutFixture.blackouts).concat(parseIcal(blackoutFixture.calendar));
+  const shown = list => list.map(b => ({ name: b.name, start: rfc3339(b.start), end: rfc3339(b.end) }));
+
+  it('should resolve blackout dates as whole days in their timezone', () => {
+    expect(shown(resolveBlackouts(blackoutFixture.blackouts))).toEqual(blackoutFixture.resolved);
+    expect(() => resolveBlackouts([{ name: 'x', start: '2025-11-28T05:00:00Z' }])).toThrow();
+    expect(() => resolveBlackouts([{ name: 'x', start: '2025-12-01', end: '2025-11-28' }])).toThrow();
+  });
+
+  it('should read blackouts from an iCalendar file', () => {
+    expect(shown(parseIcal(blackoutFixture.calendar))).toEqual(blackoutFixture.calendar_resolved);
+    const event = lines => `BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:x\n${lines}\nEND:VEVENT\nEND:VCALENDAR\n`;
+    expect(() => parseIcal(event('DTSTART:20251128T050000Z\nDTEND:20251128T060000Z\nRRULE:FREQ=YEARLY'))).toThrow();
+    expect(() => parseIcal(event('DTSTART;TZID=Eastern Standard Time:20251128T000000\nDTEND;TZID=Eastern Standard Time:20251129T000000'))).toThrow();
+  });
+
+  it('should find blackouts that overlap a window', () => {
+    const all = allBlackouts();
+    for (const o of blackoutFixture.overlaps) {
+      const b = findBlackout(all, Date.parse(o.start), Date.parse(o.end));
+      expect(b ? b.name : '').toBe(o.blackout);
+    }
+  });
+
+  it('should keep windows that overlap a blackout closed', () => {
+    const replay = blackoutFixture.replay;
+    const schedules = replay.schedules.map(compile);
+    const blackouts = allBlackouts();
+    const tick = replay.tick_minutes * 60000;
+    let record = {};
+    const timeline = [];
+    for (let t = Math.floor(Date.parse(replay.from) / tick) * tick; t < Date.parse(replay.to); t += tick) {
+      const step = scheduleStep(schedules, record, t, blackouts);
+      record = step.record;
+      timeline.push(...step.transitions);
+    }
+    expect(timeline).toEqual(replay.transitions);
+  });
+  // Shared with internal/schedule/ics_test.go
+  const icsFixture = JSON.parse(
+    readFileSync(join(__dirname, '../fixtures/ics-feed.json'), 'utf8')
+  );
+
+  it('should write the same .ics feed as WriteICS', () => {
+    const feed = maintenanceCalendar(icsFixture.schedules.map(compile), resolveBlackouts(icsFixture.blackouts), {
+      name: icsFixture.name,
+      host: icsFixture.host,
+      days: icsFixture.days,
+      now: Date.parse(icsFixture.now),
+      window: {
+        start: Date.parse(icsFixture.maintenance_window.start_time),
+        end: Date.parse(icsFixture.maintenance_window.end_time)
+      }
+    });
+    expect(feed).toBe(readFileSync(join(__dirname, '../fixtures/maintenance.ics'), 'utf8'));
+  });
+
+  it('should fold feed lines at 75 octets', () => {
+    const schedule = compile({ name: 'é'.repeat(60), cron: '0 3 * * *', duration: '1h', timezone: 'UTC' });
+    const feed = maintenanceCalendar([schedule], [], { name: 'x', host: 'status.example.com', days: 1, now: Date.UTC(2025, 5, 1) });
+    for (const line of feed.split('\r\n')) {
+      expect(Buffer.byteLength(line) <= 75).toBe(true);
+    }
+  });
+
+});
+
+describe('scheduledState', () => {
+  const { scheduledState } = worker;
+
+  const weekly = { weekly: { start: '2025-04-06T09:00:00Z', end: '2025-04-06T11:00:00Z' } };
+  const stamp = '2025-04-06T11:00:00Z';
+
+  it('should open a window over a state that is off', () => {
+    const next = scheduledState({ enabled: false, title: 'Down' }, {}, weekly, '2025-04-06T09:00:00Z');
+    expect(next).toEqual({
+      enabled: true, title: 'Down', window_start: '2025-04-06T09:00:00Z', window_end: '2025-04-06T11:00:00Z',
+      enabled_at: '2025-04-06T09:00:00Z', updated_at: '2025-04-06T09:00:00Z',
+    });
+  });
+
+  it('should close the window it opened', () => {
+    const stored = { enabled: true, window_start: '2025-04-06T09:00:00Z', window_end: '2025-04-06T11:00:00Z', enabled_at: '2025-04-06T09:00:00Z' };
+    expect(scheduledState(stored, weekly, {}, stamp)).toEqual({
+      enabled: false, window_start: '', window_end: '', updated_at: stamp,
+    });
+  });
+
+  it('should leave an operator window on when the schedule closes', () => {
+    const stored = { enabled: true, window_start: '2025-04-06T10:00:00Z', window_end: '2025-04-06T14:00:00Z' };
+    expect(scheduledState(stored, weekly, {}, stamp)).toBeNull();
+  });
+
+  it('should leave maintenance that was already on when a window opens', () => {
+    const stored = { enabled: true, window_start: '', window_end: '' };
+    expect(scheduledState(stored, {}, weekly, stamp)).toBeNull();
+  });
+
+  it('should not write a state that is already off', () => {
+    expect(scheduledState({ enabled: false }, {}, {}, stamp)).toBeNull();
+  });
+});
+
+describe('renderTemplate', () => {
+  const { renderTemplate } = worker;
+
+  it('should substitute placeholders with optional whitespace', () => {
+    expect(renderTemplate('<h1>{{title}}</h1><p>{{ message }}</p>', { title: 'T', message: 'M' }))
+      .toBe('<h1>T</h1><p>M</p>');
+  });
+
+  it('should render unknown placeholders as empty strings', () => {
+    expect(renderTemplate('a{{footer}}b', {})).toBe('ab');
+  });
+
+  it('should not re-process placeholders inside substituted values', () => {
+    expect(renderTemplate('{{title}}', { title: '{{message}}', message: 'boom' })).toBe('{{message}}');
+  });
+
+  it('should repeat values for repeated placeholders', () => {
+    expect(renderTemplate('<title>{{title}}</title><h1>{{title}}</h1>', { title: 'Down' }))
+      .toBe('<title>Down</title><h1>Down</h1>');
+  });
+});
+
 describe('IP Allowlist Logic', () => {
   function isAllowedIP(clientIP, allowedIPsJson) {
     try {
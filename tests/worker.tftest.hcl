@@ -252,4 +252,908 @@ run "verify_disabled_worker_configuration" {
     condition     = output.api_endpoint == "Maintenance mode disabled"
     error_message = "API endpoint should be disabled when maintenance is disabled"
   }
-}
\ No newline at end of file
+}
+# Test case 7: Reject display timezones that are not IANA names
+run "verify_invalid_display_timezone_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    display_timezone      = "Eastern Standard Time"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.display_timezone,
+  ]
+}
+
+# Test case 8: Localized content negotiated by the worker
+run "verify_localized_content" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    default_locale        = "en"
+    localized_content = {
+      en = { title = "Maintenance", message = "We will be back shortly." }
+      de = { title = "Wartungsarbeiten", message = "Wir sind bald zurück." }
+    }
+    country_locales = {
+      AT = "de"
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = output.maintenance_status == "ENABLED"
+    error_message = "Localized content should not affect maintenance status"
+  }
+}
+
+# Test case 9: Default locale must have localized content
+run "verify_missing_default_locale_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    default_locale        = "fr"
+    localized_content = {
+      en = { title = "Maintenance", message = "We will be back shortly." }
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_script.maintenance,
+  ]
+}
+
+# Test case 10: Custom page template
+run "verify_custom_page_template" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    page_template_file    = "examples/advanced-config/maintenance.html"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = output.maintenance_status == "ENABLED"
+    error_message = "Custom page template should be accepted"
+  }
+}
+
+# Test case 11: Templates without required placeholders are rejected
+run "verify_template_missing_placeholder_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    page_template_file    = "tests/fixtures/template-missing-contact.html"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_script.maintenance,
+  ]
+}
+
+# Test case 12: Status updates create a KV namespace bound to the worker
+run "verify_status_updates_namespace" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    enable_status_updates = true
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_workers_kv_namespace.maintenance[0].title == "maintenance-test"
+    error_message = "Status updates should create a per-environment KV namespace"
+  }
+
+  assert {
+    condition     = one(cloudflare_workers_script.maintenance.kv_namespace_binding).name == "MAINTENANCE_KV"
+    error_message = "The KV namespace should be bound to the worker as MAINTENANCE_KV"
+  }
+}
+
+# Test case 13: Runtime state in KV keeps the route while disabled and seeds the state
+run "verify_kv_runtime_state" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    kv_runtime_state      = true
+    maintenance_title     = "Planned upgrade"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_route.maintenance) == 1
+    error_message = "The route must exist while disabled so a KV toggle takes effect"
+  }
+
+  assert {
+    condition     = cloudflare_workers_route.maintenance["worker_route"].pattern == "example.com/*"
+    error_message = "A single worker_route should keep the key it is moved to from index 0"
+  }
+
+  assert {
+    condition     = jsondecode(cloudflare_workers_kv.runtime_state[0].value).enabled == false && jsondecode(cloudflare_workers_kv.runtime_state[0].value).title == "Planned upgrade"
+    error_message = "The runtime state should be seeded from the Terraform variables"
+  }
+
+  assert {
+    condition     = jsondecode(cloudflare_workers_kv.runtime_state[0].value).environment == "test"
+    error_message = "The runtime state should name its environment so maintctl can't be pointed at it under another"
+  }
+
+  assert {
+    condition     = output.maintenance_status == "DISABLED"
+    error_message = "Maintenance status should reflect the Terraform value"
+  }
+}
+
+# Test case 14: Multiple routes across zones
+run "verify_multiple_worker_routes" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_routes = [
+      { zone_id = "test-zone-id", pattern = "app.example.com/*" },
+      { zone_id = "test-zone-id", pattern = "api.example.com/*" },
+      { zone_id = "test-zone-id-2", pattern = "example.org/*" },
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_route.maintenance) == 3
+    error_message = "Every worker_routes entry should get a route"
+  }
+
+  assert {
+    condition     = cloudflare_workers_route.maintenance["test-zone-id-2/example.org/*"].zone_id == "test-zone-id-2" && cloudflare_workers_route.maintenance["test-zone-id-2/example.org/*"].pattern == "example.org/*"
+    error_message = "Routes should be created in their own zone, keyed by zone and pattern"
+  }
+
+  assert {
+    condition     = output.worker_route_pattern == "app.example.com/*, api.example.com/*, example.org/*"
+    error_message = "worker_route_pattern should list every pattern"
+  }
+}
+
+# Test case 15: Duplicate routes are rejected
+run "verify_duplicate_worker_routes_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_routes = [
+      { zone_id = "test-zone-id", pattern = "app.example.com/*" },
+      { zone_id = "test-zone-id", pattern = "app.example.com/*" },
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.worker_routes,
+  ]
+}
+
+# Test case 16: Script names are derived from the environment
+run "verify_per_environment_script_name" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "staging"
+    worker_route          = "example.com/*"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = output.worker_script_name == "maintenance-page-worker-staging"
+    error_message = "Script name should include the environment"
+  }
+}
+
+# Test case 17: The legacy script name can be pinned
+run "verify_script_name_override" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "production"
+    worker_route          = "example.com/*"
+    worker_script_name    = "maintenance-page-worker"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = output.worker_script_name == "maintenance-page-worker"
+    error_message = "worker_script_name should override the derived name"
+  }
+}
+
+# Test case 18: An enabled scope deploys the route while maintenance is off globally
+run "verify_scoped_maintenance" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    maintenance_scopes = [
+      { path = "/checkout", enabled = true, message = "Checkout is down for an upgrade" },
+      { path = "/checkout/health", enabled = false },
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_route.maintenance) == 1
+    error_message = "An enabled scope should deploy the worker route"
+  }
+
+  assert {
+    condition     = length(cloudflare_record.maintenance_status) == 0
+    error_message = "Scoped maintenance should not create the global status record"
+  }
+}
+
+# Test case 19: The same host and path cannot be scoped twice
+run "verify_duplicate_scopes_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    maintenance_scopes = [
+      { host = "shop.example.com", path = "/admin", enabled = true },
+      { host = "shop.example.com", path = "/admin/", enabled = false },
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.maintenance_scopes,
+  ]
+}
+
+# Test case 20: Rollout percentage is seeded into the KV state
+run "verify_rollout_percentage" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = true
+    environment           = "test"
+    worker_route          = "example.com/*"
+    kv_runtime_state      = true
+    rollout_percentage    = 25
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = jsondecode(cloudflare_workers_kv.runtime_state[0].value).rollout_percentage == 25
+    error_message = "The KV state should carry rollout_percentage"
+  }
+}
+
+# Test case 21: Rollout percentage must be a whole percentage
+run "verify_rollout_percentage_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    rollout_percentage    = 12.5
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.rollout_percentage,
+  ]
+}
+
+# Test case 22: Only full and read_only modes are accepted
+run "verify_mode_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    mode                  = "readonly"
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.mode,
+  ]
+}
+
+# Test case 23: Auto maintenance deploys the route and a namespace for trip records
+run "verify_auto_maintenance" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    auto_maintenance = {
+      enabled           = true
+      failure_threshold = 3
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_route.maintenance) == 1
+    error_message = "Auto maintenance needs the worker route while maintenance is off"
+  }
+
+  assert {
+    condition     = length(cloudflare_workers_kv_namespace.maintenance) == 1
+    error_message = "Auto maintenance should create a KV namespace for trip records"
+  }
+}
+
+# Test case 24: Auto maintenance needs a positive threshold
+run "verify_auto_maintenance_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    auto_maintenance = {
+      enabled           = true
+      failure_threshold = 0
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.auto_maintenance,
+  ]
+}
+
+# Test case 25: Stale paths keep the route so copies are stored while the site is up
+run "verify_stale_paths" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    enabled               = false
+    environment           = "test"
+    worker_route          = "example.com/*"
+    stale_paths           = ["/blog", "/pricing"]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_route.maintenance) == 1
+    error_message = "stale_paths needs the worker route while maintenance is off"
+  }
+}
+
+# Test case 26: Stale paths must be absolute
+run "verify_stale_paths_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    stale_paths           = ["blog"]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.stale_paths,
+  ]
+}
+
+# Test case 27: The watchdog cron creates a Cron Trigger for the worker
+run "verify_watchdog_cron_trigger" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      max_duration_minutes = 240
+      cron                 = "*/5 * * * *"
+      notify               = ["slack://T000/B000/XXXX"]
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_cron_trigger.maintenance) == 1
+    error_message = "watchdog.cron should create a Cron Trigger"
+  }
+
+  assert {
+    condition     = tolist(cloudflare_workers_cron_trigger.maintenance[0].schedules) == tolist(["*/5 * * * *"])
+    error_message = "The Cron Trigger should run on watchdog.cron"
+  }
+}
+
+# Test case 28: No watchdog cron, no Cron Trigger
+run "verify_no_watchdog_cron_trigger" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = length(cloudflare_workers_cron_trigger.maintenance) == 0
+    error_message = "No Cron Trigger should be created without watchdog.cron"
+  }
+}
+
+# Test case 29: The watchdog writes the KV runtime state, so its cron needs it
+run "verify_watchdog_needs_runtime_state" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    watchdog = {
+      cron = "*/5 * * * *"
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_cron_trigger.maintenance,
+  ]
+}
+
+# Test case 30: Watchdog notify targets must use a known scheme
+run "verify_watchdog_notify_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    watchdog = {
+      notify = ["https://hooks.example.com/maintenance"]
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.watchdog,
+  ]
+}
+
+# Test case 31: Schedules get a Cron Trigger that sees every start and end on time
+run "verify_schedule_cron_trigger" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    schedules = [
+      {
+        name     = "weekly-maintenance"
+        cron     = "0 2 * * SUN"
+        duration = "2h"
+        timezone = "America/Los_Angeles"
+      },
+      {
+        name     = "india-batch"
+        cron     = "30 22 * * 1"
+        duration = "45m"
+        timezone = "Asia/Kolkata"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = tolist(cloudflare_workers_cron_trigger.maintenance[0].schedules) == tolist(["*/15 * * * *"])
+    error_message = "Schedules starting and ending on quarter hours should tick every 15 minutes"
+  }
+}
+
+# Test case 32: An off-quarter schedule ticks more often and shares the watchdog's trigger
+run "verify_schedule_cron_trigger_shared" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    kv_runtime_state      = true
+    watchdog = {
+      cron = "*/5 * * * *"
+    }
+    schedules = [
+      {
+        name     = "nightly"
+        cron     = "10 1 * * *"
+        duration = "1h"
+        timezone = "UTC"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = tolist(cloudflare_workers_cron_trigger.maintenance[0].schedules) == tolist(["*/5 * * * *"])
+    error_message = "The schedule and the watchdog should share one */5 trigger"
+  }
+}
+
+# Test case 33: Without runtime state nothing would open the windows, so schedules are rejected
+run "verify_schedules_need_runtime_state" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    schedules = [
+      {
+        name     = "weekly-maintenance"
+        cron     = "0 2 * * SUN"
+        duration = "2h"
+        timezone = "America/Los_Angeles"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    cloudflare_workers_script.maintenance,
+  ]
+}
+
+# Test case 34: Schedule durations are hours and minutes
+run "verify_schedule_duration_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    schedules = [
+      {
+        name     = "weekly-maintenance"
+        cron     = "0 2 * * SUN"
+        duration = "2 hours"
+        timezone = "America/Los_Angeles"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.schedules,
+  ]
+}
+
+# Test case 35: Blackouts reach the worker with their timezone
+run "verify_blackouts_binding" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    blackouts = [
+      {
+        name     = "black-friday"
+        start    = "2025-11-28"
+        end      = "2025-12-01"
+        timezone = "America/New_York"
+      },
+      {
+        name  = "q1-close"
+        start = "2025-03-31T18:00:00Z"
+        end   = "2025-04-01T06:00:00Z"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = [for b in jsondecode(one([for b in cloudflare_workers_script.maintenance.plain_text_binding : b.text if b.name == "BLACKOUTS"])) : b.timezone] == ["America/New_York", "UTC"]
+    error_message = "Blackouts should reach the worker, with UTC as the default timezone"
+  }
+}
+
+# Test case 36: Blackout dates are YYYY-MM-DD or RFC3339
+run "verify_blackout_date_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    blackouts = [
+      {
+        name  = "black-friday"
+        start = "28/11/2025"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.blackouts,
+  ]
+}
+
+# Test case 37: Blackouts end after they start
+run "verify_blackout_order_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    blackouts = [
+      {
+        name  = "quarter-close"
+        start = "2025-12-31"
+        end   = "2025-12-01"
+      }
+    ]
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.blackouts,
+  ]
+}
+
+# Test case 38: The calendar feed is routed on the status hostname
+run "verify_calendar_feed" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "Production"
+    worker_route          = "*.example.com/*"
+    calendar_feed = {
+      enabled = true
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  assert {
+    condition     = cloudflare_workers_route.calendar_feed[0].pattern == "maintenance-status-production.example.com/maintenance.ics"
+    error_message = "The feed route should cover /maintenance.ics on the lowercase status hostname"
+  }
+
+  assert {
+    condition     = length(cloudflare_record.maintenance_status) == 1
+    error_message = "The status hostname needs its DNS record while the feed is enabled"
+  }
+
+  assert {
+    condition     = output.calendar_feed_url == "https://maintenance-status-production.example.com/maintenance.ics"
+    error_message = "calendar_feed_url should point at the feed"
+  }
+}
+
+# Test case 39: The feed lists at most 90 days
+run "verify_calendar_feed_days_rejected" {
+  variables {
+    cloudflare_account_id = "test-account-id"
+    cloudflare_zone_id    = "test-zone-id"
+    environment           = "test"
+    calendar_feed = {
+      enabled = true
+      days    = 365
+    }
+  }
+
+  # Specify module to test
+  module {
+    source = "../"
+  }
+
+  command = plan
+
+  expect_failures = [
+    var.calendar_feed,
+  ]
+}
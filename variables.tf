@@ -23,11 +23,84 @@ variable "enabled" {
 }
 
 variable "worker_route" {
-  description = "URL pattern to trigger the maintenance worker"
+  description = "URL pattern to trigger the maintenance worker (ignored when worker_routes is set)"
   type        = string
   default     = "*.example.com/*"
 }
 
+variable "worker_routes" {
+  description = "Routes for the maintenance worker across one or more zones. Defaults to worker_route on cloudflare_zone_id"
+  type = list(object({
+    zone_id = string
+    pattern = string
+  }))
+  default = []
+
+  validation {
+    condition     = alltrue([for r in var.worker_routes : r.zone_id != "" && r.pattern != ""])
+    error_message = "Every worker_routes entry needs a zone_id and a pattern"
+  }
+
+  validation {
+    condition     = length(distinct([for r in var.worker_routes : "${r.zone_id}/${r.pattern}"])) == length(var.worker_routes)
+    error_message = "worker_routes must not contain the same pattern twice in one zone"
+  }
+}
+
+variable "maintenance_scopes" {
+  description = "Host and path scopes with their own maintenance switch and page text. The most specific matching scope decides; requests matching none follow enabled and maintenance_window"
+  type = list(object({
+    host    = optional(string, "")
+    path    = optional(string, "")
+    enabled = bool
+    title   = optional(string, "")
+    message = optional(string, "")
+  }))
+  default = []
+
+  validation {
+    condition     = alltrue([for s in var.maintenance_scopes : s.host != "" || s.path != ""])
+    error_message = "Every maintenance_scopes entry needs a host, a path or both"
+  }
+
+  validation {
+    condition     = alltrue([for s in var.maintenance_scopes : s.host == "" || can(regex("^(\\*\\.)?[a-z0-9]([a-z0-9.-]*[a-z0-9])?$", s.host))])
+    error_message = "maintenance_scopes hosts must be lowercase hostnames, optionally starting with \"*.\" (no scheme, port or path)"
+  }
+
+  validation {
+    condition     = alltrue([for s in var.maintenance_scopes : s.path == "" || startswith(s.path, "/")])
+    error_message = "maintenance_scopes paths must start with /"
+  }
+
+  validation {
+    condition     = length(distinct([for s in var.maintenance_scopes : "${s.host}${trimsuffix(s.path, "/")}"])) == length(var.maintenance_scopes)
+    error_message = "maintenance_scopes must not contain the same host and path twice"
+  }
+}
+
+variable "mode" {
+  description = "What maintenance blocks: full serves the page for every request, read_only lets GET, HEAD and OPTIONS through and answers other methods with a 503 JSON error"
+  type        = string
+  default     = "full"
+
+  validation {
+    condition     = contains(["full", "read_only"], var.mode)
+    error_message = "mode must be full or read_only"
+  }
+}
+
+variable "rollout_percentage" {
+  description = "Share of clients (0-100) that get the maintenance page while it is on. Clients are bucketed by IP and pinned with a cookie, so each one stays on the same side"
+  type        = number
+  default     = 100
+
+  validation {
+    condition     = var.rollout_percentage >= 0 && var.rollout_percentage <= 100 && floor(var.rollout_percentage) == var.rollout_percentage
+    error_message = "rollout_percentage must be a whole number from 0 to 100"
+  }
+}
+
 variable "maintenance_title" {
   description = "Title for the maintenance page"
   type        = string
@@ -40,6 +113,57 @@ variable "maintenance_message" {
   default     = "We are currently performing scheduled maintenance. We will be back shortly."
 }
 
+variable "localized_content" {
+  description = "Map of locale (e.g., en, de, fr-CA) to page title and message, negotiated from Accept-Language. Falls back to maintenance_title/maintenance_message when empty"
+  type = map(object({
+    title   = string
+    message = string
+  }))
+  default = {}
+
+  validation {
+    condition = alltrue([
+      for locale, content in var.localized_content :
+      can(regex("^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$", locale)) && trimspace(content.title) != "" && trimspace(content.message) != ""
+    ])
+    error_message = "Each localized_content key must be a language tag (e.g., en, de-AT) with a non-empty title and message"
+  }
+}
+
+variable "default_locale" {
+  description = "Locale served when Accept-Language and country matching find nothing; must be a key of localized_content when that is set"
+  type        = string
+  default     = "en"
+
+  validation {
+    condition     = can(regex("^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$", var.default_locale))
+    error_message = "Default locale must be a language tag (e.g., en, pt-BR)"
+  }
+}
+
+variable "country_locales" {
+  description = "Optional map of ISO 3166-1 alpha-2 country code to locale, used when Accept-Language has no supported language"
+  type        = map(string)
+  default     = {}
+
+  validation {
+    condition     = alltrue([for country in keys(var.country_locales) : can(regex("^[A-Z]{2}$", country))])
+    error_message = "Country locale keys must be ISO 3166-1 alpha-2 country codes (e.g., DE, JP)"
+  }
+}
+
+variable "enable_status_updates" {
+  description = "Create a Workers KV namespace for status updates posted with `maintctl update post` and show them on the page"
+  type        = bool
+  default     = false
+}
+
+variable "kv_runtime_state" {
+  description = "Keep the live state (enabled, title, message, window) in a Workers KV namespace so `maintctl state` can change it without re-uploading the worker. The route is then always deployed"
+  type        = bool
+  default     = false
+}
+
 variable "contact_email" {
   description = "Contact email to display on the maintenance page"
   type        = string
@@ -58,6 +182,17 @@ variable "environment" {
   default     = "production"
 }
 
+variable "worker_script_name" {
+  description = "Override the worker script name, which defaults to maintenance-page-worker-<environment>. Set it to \"maintenance-page-worker\" to keep the name used before per-environment names"
+  type        = string
+  default     = null
+
+  validation {
+    condition     = var.worker_script_name == null ? true : can(regex("^[a-z0-9][a-z0-9_-]{0,62}$", var.worker_script_name))
+    error_message = "worker_script_name must be 1-63 lowercase letters, digits, dashes or underscores"
+  }
+}
+
 variable "maintenance_window" {
   description = "Scheduled maintenance window with start and end times in RFC3339 format"
   type = object({
@@ -75,8 +210,19 @@ variable "maintenance_window" {
   }
 }
 
+variable "display_timezone" {
+  description = "IANA timezone for the expected completion time on the maintenance page (visitors with JavaScript see their local time)"
+  type        = string
+  default     = "UTC"
+
+  validation {
+    condition     = can(regex("^(UTC|[A-Za-z]+(/[A-Za-z0-9_+-]+)+)$", var.display_timezone))
+    error_message = "Display timezone must be UTC or an IANA timezone name (e.g., America/New_York, Europe/Berlin)"
+  }
+}
+
 variable "schedules" {
-  description = "List of cron-based scheduled maintenance windows"
+  description = "Recurring maintenance windows: from each cron start in timezone, maintenance is on for duration (e.g. 2h, 45m, 1h30m). With kv_runtime_state the worker opens and closes them on a Cron Trigger and tells the notify targets"
   type = list(object({
     name     = string
     cron     = string
@@ -86,13 +232,125 @@ variable "schedules" {
   }))
   default = []
 
+  # Each field is a list of *, N or N-M with an optional /STEP, as in ParseCron
+  # in internal/schedule; its tests generate tests/cron_validation.tftest.hcl
+  # from the same corpus so both accept the same expressions.
   validation {
     condition = alltrue([
-      for schedule in var.schedules :
-      can(regex("^([0-9*,/-]+|\\*) ([0-9*,/-]+|\\*) ([0-9*,/-]+|\\*) ([0-9*,/-]+|\\*) ([0-9*,/-]+|[A-Z]{3}|\\*)$", schedule.cron)) &&
-      length(split(" ", schedule.cron)) == 5
+      for schedule in var.schedules : can(regex(
+        format("^\\s*%s\\s*$", join("\\s+", [
+          for item in [
+            for f in [
+              { value = "[0-5]?[0-9]", step = "0?[1-9]|[1-5][0-9]" },
+              { value = "[01]?[0-9]|2[0-3]", step = "0?[1-9]|1[0-9]|2[0-3]" },
+              { value = "0?[1-9]|[12][0-9]|3[01]", step = "0?[1-9]|[12][0-9]|3[01]" },
+              { value = "0?[1-9]|1[0-2]|(?i:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)", step = "0?[1-9]|1[0-2]" },
+              { value = "0?[0-7]|(?i:SUN|MON|TUE|WED|THU|FRI|SAT)", step = "0?[1-7]" },
+            ] : "(?:\\*|(?:${f.value})(?:-(?:${f.value}))?)(?:/(?:${f.step}))?"
+          ] : "${item}(?:,${item})*"
+        ])),
+        schedule.cron
+      ))
     ])
-    error_message = "Cron expressions must be in valid 5-field format: 'minute hour day month weekday' (e.g., '0 2 * * SUN', '*/15 * * * *')"
+    error_message = "Cron expressions must have 5 fields, 'minute hour day month weekday', each a list of *, N or N-M with an optional /STEP; months and weekdays may be names (e.g., '0 2 * * SUN', '*/15 * * * *', '30 4 * JAN-MAR MON-FRI'). Check them with maintctl schedule validate"
+  }
+
+  validation {
+    condition = alltrue(flatten([
+      for schedule in var.schedules : [
+        for f in regexall("\\S+", schedule.cron) : [
+          for item in split(",", f) : [
+            for r in [[for v in split("-", split("/", item)[0]) : try(
+              tonumber(v),
+              index(["JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"], upper(v)) + 1,
+              index(["SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"], upper(v)),
+              0
+            )]] : try(r[0] <= r[1], true)
+          ][0]
+        ]
+      ]
+    ]))
+    error_message = "Cron ranges must run forwards (e.g., MON-FRI, not FRI-MON)"
+  }
+
+  validation {
+    condition     = alltrue([for schedule in var.schedules : can(regex("^([0-9]+h)?([0-9]+m)?$", schedule.duration)) && can(regex("[1-9]", schedule.duration))])
+    error_message = "Schedule durations must be hours and/or minutes (e.g., 2h, 45m, 1h30m)"
+  }
+
+  validation {
+    condition     = alltrue([for schedule in var.schedules : can(regex("^(UTC|[A-Za-z]+(/[A-Za-z0-9_+-]+)+)$", schedule.timezone))])
+    error_message = "Schedule timezones must be UTC or an IANA timezone name (e.g., America/Los_Angeles)"
+  }
+
+  validation {
+    condition     = length(distinct([for schedule in var.schedules : schedule.name])) == length(var.schedules)
+    error_message = "Schedule names must be unique"
+  }
+
+  validation {
+    condition     = alltrue(flatten([for schedule in var.schedules : [for t in schedule.notify : can(regex("^(slack|pagerduty|webhook)://.+", t))]]))
+    error_message = "Schedule notify targets must start with slack://, pagerduty:// or webhook://"
+  }
+}
+
+variable "blackouts" {
+  description = "Freeze periods in which no scheduled window may open, such as Black Friday or quarter close. start and end are dates, covering whole days in timezone with end included, or RFC3339 times; an empty end means the start date only. maintctl state enable refuses to turn maintenance on during one without -force"
+  type = list(object({
+    name     = string
+    start    = string
+    end      = optional(string, "")
+    timezone = optional(string, "UTC")
+  }))
+  default = []
+
+  validation {
+    condition = alltrue([
+      for b in var.blackouts :
+      can(regex("^[0-9]{4}-[0-9]{2}-[0-9]{2}$", b.start)) ? (b.end == "" || can(regex("^[0-9]{4}-[0-9]{2}-[0-9]{2}$", b.end)) || can(timeadd(b.end, "0s"))) : (can(timeadd(b.start, "0s")) && can(timeadd(b.end, "0s")))
+    ])
+    error_message = "Blackout start and end must be dates (YYYY-MM-DD) or RFC3339 times, and an RFC3339 start needs an end"
+  }
+
+  validation {
+    condition = alltrue([
+      for b in var.blackouts : b.end == "" || try(
+        length(b.start) == 10 && length(b.end) == 10 ? tonumber(replace(b.end, "-", "")) >= tonumber(replace(b.start, "-", "")) : timecmp(b.end, b.start) > 0,
+        true
+      )
+    ])
+    error_message = "Blackouts must end after they start"
+  }
+
+  validation {
+    condition     = alltrue([for b in var.blackouts : can(regex("^(UTC|[A-Za-z]+(/[A-Za-z0-9_+-]+)+)$", b.timezone))])
+    error_message = "Blackout timezones must be UTC or an IANA timezone name (e.g., America/New_York)"
+  }
+}
+
+variable "blackout_calendar" {
+  description = "iCalendar text whose events are blackouts too, e.g. file(\"freeze.ics\"). Floating times and dates use the calendar's X-WR-TIMEZONE, else UTC; cancelled events are ignored and recurring events are not supported"
+  type        = string
+  default     = ""
+
+  validation {
+    condition     = var.blackout_calendar == "" || strcontains(var.blackout_calendar, "BEGIN:VCALENDAR")
+    error_message = "blackout_calendar must be iCalendar text (BEGIN:VCALENDAR ...), e.g. file(\"freeze.ics\")"
+  }
+}
+
+variable "calendar_feed" {
+  description = "Serve an iCalendar feed of the maintenance_window and the schedules' windows for the next days days at https://maintenance-status-<environment>.<domain>/maintenance.ics. Windows a blackout holds are listed as cancelled"
+  type = object({
+    enabled = optional(bool, false)
+    name    = optional(string, "Scheduled maintenance")
+    days    = optional(number, 30)
+  })
+  default = {}
+
+  validation {
+    condition     = var.calendar_feed.days >= 1 && var.calendar_feed.days <= 90 && floor(var.calendar_feed.days) == var.calendar_feed.days
+    error_message = "calendar_feed.days must be a whole number from 1 to 90"
   }
 }
 
@@ -102,6 +360,17 @@ variable "custom_css" {
   default     = ""
 }
 
+variable "page_template_file" {
+  description = "Path to a custom HTML page template using {{title}}, {{message}}, {{window}}, {{contact}} (required) and {{lang}}, {{updates}}, {{logo}}, {{custom_css}} placeholders. Defaults to templates/default.html"
+  type        = string
+  default     = null
+
+  validation {
+    condition     = var.page_template_file == null ? true : fileexists(var.page_template_file)
+    error_message = "Page template file does not exist (use a path like \"$${path.module}/maintenance.html\")"
+  }
+}
+
 variable "logo_url" {
   description = "URL to the logo to display on the maintenance page"
   type        = string
@@ -122,8 +391,115 @@ variable "allowed_regions" {
   }
 }
 
+variable "stale_paths" {
+  description = "Path prefixes (e.g., /blog) where visitors get a stale copy from the Cache API during maintenance instead of the page; copies are stored while the site is up"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for p in var.stale_paths : startswith(p, "/")])
+    error_message = "stale_paths entries must start with /"
+  }
+}
+
+variable "stale_ttl_seconds" {
+  description = "How long a copy stored for stale_paths may be served"
+  type        = number
+  default     = 86400
+
+  validation {
+    condition     = var.stale_ttl_seconds >= 60 && floor(var.stale_ttl_seconds) == var.stale_ttl_seconds
+    error_message = "stale_ttl_seconds must be a whole number of at least 60"
+  }
+}
+
+variable "auto_maintenance" {
+  description = "Serve the maintenance page automatically while the origin fails: failure_threshold 5xx responses or timeouts within window_seconds trip it, and it stays on for cooldown_seconds before the origin is tried again"
+  type = object({
+    enabled           = optional(bool, false)
+    failure_threshold = optional(number, 5)
+    window_seconds    = optional(number, 60)
+    timeout_ms        = optional(number, 10000)
+    cooldown_seconds  = optional(number, 120)
+  })
+  default = {}
+
+  validation {
+    condition     = var.auto_maintenance.failure_threshold >= 1 && floor(var.auto_maintenance.failure_threshold) == var.auto_maintenance.failure_threshold
+    error_message = "auto_maintenance.failure_threshold must be a whole number of at least 1"
+  }
+
+  validation {
+    condition     = var.auto_maintenance.window_seconds >= 1 && var.auto_maintenance.cooldown_seconds >= 1
+    error_message = "auto_maintenance.window_seconds and cooldown_seconds must be at least 1"
+  }
+
+  validation {
+    condition     = var.auto_maintenance.timeout_ms >= 100 && var.auto_maintenance.timeout_ms <= 100000
+    error_message = "auto_maintenance.timeout_ms must be between 100 and 100000"
+  }
+}
+
+variable "watchdog" {
+  description = "Turn maintenance off when it is left on: grace_minutes after maintenance_window ends, or max_duration_minutes after it was enabled (0 for no limit). With cron set (e.g. \"*/5 * * * *\") the worker checks on a Cron Trigger and tells the notify targets (slack://, pagerduty://, webhook://); maintctl watchdog runs the same checks. Needs kv_runtime_state"
+  type = object({
+    max_duration_minutes = optional(number, 0)
+    grace_minutes        = optional(number, 15)
+    cron                 = optional(string, "")
+    notify               = optional(list(string), [])
+  })
+  default = {}
+
+  validation {
+    condition = (var.watchdog.max_duration_minutes >= 0 && floor(var.watchdog.max_duration_minutes) == var.watchdog.max_duration_minutes &&
+    var.watchdog.grace_minutes >= 0 && floor(var.watchdog.grace_minutes) == var.watchdog.grace_minutes)
+    error_message = "watchdog.max_duration_minutes and grace_minutes must be whole numbers of at least 0"
+  }
+
+  # The same checks as the schedules cron validations; the generated
+  # tests/cron_validation.tftest.hcl runs the corpus against both.
+  validation {
+    condition = var.watchdog.cron == "" || can(regex(
+      format("^\\s*%s\\s*$", join("\\s+", [
+        for item in [
+          for f in [
+            { value = "[0-5]?[0-9]", step = "0?[1-9]|[1-5][0-9]" },
+            { value = "[01]?[0-9]|2[0-3]", step = "0?[1-9]|1[0-9]|2[0-3]" },
+            { value = "0?[1-9]|[12][0-9]|3[01]", step = "0?[1-9]|[12][0-9]|3[01]" },
+            { value = "0?[1-9]|1[0-2]|(?i:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)", step = "0?[1-9]|1[0-2]" },
+            { value = "0?[0-7]|(?i:SUN|MON|TUE|WED|THU|FRI|SAT)", step = "0?[1-7]" },
+          ] : "(?:\\*|(?:${f.value})(?:-(?:${f.value}))?)(?:/(?:${f.step}))?"
+        ] : "${item}(?:,${item})*"
+      ])),
+      var.watchdog.cron
+    ))
+    error_message = "watchdog.cron must have 5 fields, 'minute hour day month weekday', each a list of *, N or N-M with an optional /STEP (e.g., '*/5 * * * *'). Check it with maintctl schedule validate"
+  }
+
+  validation {
+    condition = alltrue(flatten([
+      for f in regexall("\\S+", var.watchdog.cron) : [
+        for item in split(",", f) : [
+          for r in [[for v in split("-", split("/", item)[0]) : try(
+            tonumber(v),
+            index(["JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"], upper(v)) + 1,
+            index(["SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"], upper(v)),
+            0
+          )]] : try(r[0] <= r[1], true)
+        ][0]
+      ]
+    ]))
+    error_message = "watchdog.cron ranges must run forwards (e.g., MON-FRI, not FRI-MON)"
+  }
+
+  validation {
+    condition     = alltrue([for t in var.watchdog.notify : can(regex("^(slack|pagerduty|webhook)://.+", t))])
+    error_message = "watchdog.notify targets must start with slack://, pagerduty:// or webhook://"
+  }
+}
+
 variable "rate_limit" {
-  description = "Rate limiting configuration using Cloudflare Ruleset API"
+  description = "Rate limiting configuration using Cloudflare Ruleset API. rules are evaluated in order, each with its own expression, characteristics, action and thresholds; without rules, the top-level fields make one rule over every request. applies_to = \"maintenance_page\" limits only requests that get the maintenance page, only while enabled or a maintenance scope is on; it can't be combined with kv_runtime_state, schedules, auto_maintenance or maintenance_window."
   type = object({
     enabled             = optional(bool, false)
     requests_per_period = optional(number, 100)
@@ -132,6 +508,19 @@ variable "rate_limit" {
     mitigation_timeout  = optional(number, 600)
     counting_expression = optional(string, null)
     requests_to_origin  = optional(bool, false)
+    applies_to          = optional(string, "zone")
+    rules = optional(list(object({
+      description         = optional(string, "")
+      expression          = string
+      characteristics     = optional(list(string), ["cf.colo.id", "ip.src"])
+      action              = optional(string, "block")
+      period              = optional(number, 60)
+      requests_per_period = optional(number, 100)
+      mitigation_timeout  = optional(number, 600)
+      requests_to_origin  = optional(bool, false)
+      counting_expression = optional(string, null)
+      enabled             = optional(bool, true)
+    })), [])
   })
   default = {
     enabled             = false
@@ -141,6 +530,8 @@ variable "rate_limit" {
     mitigation_timeout  = 600
     counting_expression = null
     requests_to_origin  = false
+    applies_to          = "zone"
+    rules               = []
   }
 
   validation {
@@ -157,4 +548,60 @@ variable "rate_limit" {
     condition     = var.rate_limit.mitigation_timeout >= 60 && var.rate_limit.mitigation_timeout <= 86400
     error_message = "Mitigation timeout must be between 60 and 86400 seconds."
   }
+
+  validation {
+    condition     = contains(["zone", "maintenance_page"], var.rate_limit.applies_to)
+    error_message = "rate_limit.applies_to must be zone (every request, always) or maintenance_page (only requests that get the maintenance page, only while maintenance is on)."
+  }
+
+  # The rules validations follow Validate in internal/ratelimit; its tests
+  # generate tests/rate_limit_validation.tftest.hcl from the same fixture.
+  validation {
+    condition     = alltrue([for r in var.rate_limit.rules : trimspace(r.expression) != ""])
+    error_message = "Every rate_limit rule needs an expression."
+  }
+
+  validation {
+    condition     = alltrue([for r in var.rate_limit.rules : contains(r.characteristics, "cf.colo.id")])
+    error_message = "Every rate_limit rule must count by cf.colo.id."
+  }
+
+  validation {
+    condition = alltrue([for r in var.rate_limit.rules : length(distinct(r.characteristics)) == length(r.characteristics) && alltrue([
+      for c in r.characteristics : can(regex("^(cf\\.colo\\.id|ip\\.src|cf\\.unique_visitor_id|http\\.host|http\\.request\\.uri\\.path|ip\\.geoip\\.asnum|ip\\.geoip\\.country|cf\\.bot_management\\.ja3_hash|cf\\.bot_management\\.ja4|http\\.request\\.headers\\[\"[a-z0-9_-]+\"\\]|http\\.request\\.(cookies|uri\\.args|body\\.form)\\[\"[^\"]+\"\\])$", c))
+    ])])
+    error_message = "Rate limit characteristics must not repeat and must be cf.colo.id, ip.src, cf.unique_visitor_id, http.host, http.request.uri.path, ip.geoip.asnum, ip.geoip.country, cf.bot_management.ja3_hash, cf.bot_management.ja4, or http.request.headers[\"name\"] (lowercase), http.request.cookies[\"name\"], http.request.uri.args[\"name\"] or http.request.body.form[\"name\"]."
+  }
+
+  validation {
+    condition     = alltrue([for r in var.rate_limit.rules : r.period >= 10 && r.period <= 86400 && r.requests_per_period >= 1])
+    error_message = "Each rate_limit rule's period must be between 10 and 86400 seconds and its requests_per_period at least 1."
+  }
+
+  validation {
+    condition     = alltrue([for r in var.rate_limit.rules : contains(["block", "challenge", "js_challenge", "managed_challenge", "log"], r.action)])
+    error_message = "Each rate_limit rule's action must be one of: block, challenge, js_challenge, managed_challenge, log."
+  }
+
+  validation {
+    condition     = alltrue([for r in var.rate_limit.rules : r.mitigation_timeout >= 60 && r.mitigation_timeout <= 86400])
+    error_message = "Each rate_limit rule's mitigation timeout must be between 60 and 86400 seconds."
+  }
+}
+
+variable "ruleset_mode" {
+  description = "How the IP/region bypass and rate limiting rules reach the zone. entrypoint makes them the zone's http_request_firewall_custom and http_ratelimit entrypoint rulesets, which fails if another ruleset already is. execute keeps them in custom rulesets and adds one execute rule for each to the existing entrypoints with maintctl, leaving other rules there alone"
+  type        = string
+  default     = "entrypoint"
+
+  validation {
+    condition     = contains(["entrypoint", "execute"], var.ruleset_mode)
+    error_message = "ruleset_mode must be entrypoint or execute"
+  }
+}
+
+variable "maintctl_command" {
+  description = "Command that runs maintctl, which adds and removes the execute rules when ruleset_mode is execute. It runs on the machine running Terraform, so maintctl must be installed there (on PATH for the default). attach is given cloudflare_api_token; detach runs at destroy time and reads CLOUDFLARE_API_TOKEN from the environment Terraform runs in"
+  type        = string
+  default     = "maintctl"
 }
@@ -1,18 +1,40 @@
 // CloudFlare Worker for maintenance mode - because sometimes things break and we need to fix them
 // without everyone watching us frantically debug at 3am
 addEventListener('fetch', event => {
-  event.respondWith(handleRequest(event.request))
+  event.respondWith(handleRequest(event.request, event))
 })
 
-async function handleRequest(request) {
-  // Check if we're in a scheduled maintenance window
+// Cron Triggers open and close scheduled windows, then run the watchdog
+addEventListener('scheduled', event => {
+  const now = new Date(event.scheduledTime)
+  event.waitUntil(runSchedules(now).then(() => runWatchdog(now)))
+})
+
+async function handleRequest(request, event) {
+  // The status hostname serves the .ics feed of upcoming maintenance, whatever the state
+  const url = new URL(request.url)
+  const feed = getCalendarFeed()
+  if (feed && url.hostname === feed.host && url.pathname === '/maintenance.ics') {
+    return serveCalendarFeed(request, feed, Date.now())
+  }
+
+  // Live state comes from KV when kv_runtime_state is on, otherwise from the bindings
   const now = new Date()
-  const inMaintenanceWindow = checkMaintenanceWindow(now)
-  
+  const state = await getRuntimeState(now.getTime())
+
+  // Check if we're in a scheduled maintenance window
+  const inMaintenanceWindow = checkMaintenanceWindow(now, state)
+
+  // The most specific maintenance scope for this host and path decides; requests
+  // outside every scope follow the global switch and window
+  const scope = matchScope(getMaintenanceScopes(), url.hostname, url.pathname)
+  const inMaintenance = scope ? scope.enabled : (state.enabled || inMaintenanceWindow)
+
   // First, check if we're actually in maintenance mode
-  // If not and not in a maintenance window, let traffic through
-  if ((!MAINTENANCE_ENABLED || MAINTENANCE_ENABLED === 'false') && !inMaintenanceWindow) {
-    return fetch(request)
+  // If not, let traffic through (watching the origin's health in auto mode)
+  const auto = getAutoMaintenanceConfig()
+  if (!inMaintenance && !auto) {
+    return rememberStale(request, await fetch(request), event)
   }
 
   // Check if this IP is on the VIP list (developers, ops team, that one stakeholder
@@ -42,6 +64,48 @@ async function handleRequest(request) {
     return fetch(request)
   }
 
+  let rollout = { bucket: 0, included: true, setCookie: false }
+  let action = 'page'
+  let autoReason = ''
+  if (inMaintenance) {
+    // Below 100% only clients whose sticky bucket is in the rollout get the page;
+    // the bucket is pinned in a signed cookie so a client doesn't flip when its IP changes
+    rollout = await decideRollout(
+      getRolloutCookie(request.headers.get('Cookie')),
+      clientIP || '',
+      state.rolloutPercentage,
+      getRolloutCookieKey()
+    )
+    if (!rollout.included) {
+      return passThrough(request, rollout)
+    }
+
+    // In read_only mode reads still reach the origin and only writes are refused
+    action = maintenanceAction(getMaintenanceMode(), request.method)
+    if (action === 'origin') {
+      return passThrough(request, rollout)
+    }
+  } else {
+    // Auto mode: the origin answers unless it has been failing, in which case
+    // everyone gets the full page until the cool-down ends
+    const guarded = await guardedFetch(request, auto, now.getTime(), event)
+    if (guarded.response) {
+      return rememberStale(request, guarded.response, event)
+    }
+    autoReason = guarded.reason
+  }
+
+  // On stale_paths a copy stored while the site was up beats a 503
+  if (action === 'page') {
+    const stale = await serveStale(request)
+    if (stale) {
+      if (rollout.setCookie) {
+        stale.headers.append('Set-Cookie', await rolloutCookie(rollout.bucket, getRolloutCookieKey()))
+      }
+      return stale
+    }
+  }
+
   // Validate and sanitize logo URL to prevent XSS
   let logoHtml = ''
   if (LOGO_URL && isValidHttpsUrl(LOGO_URL)) {
@@ -59,13 +123,1334 @@ async function handleRequest(request) {
   
   // Sanitize contact email to prevent XSS
   const sanitizedEmail = sanitizeEmail(CONTACT_EMAIL || '')
+
+  // Pick the page language from Accept-Language (and optionally the visitor's country)
+  const content = getLocalizedContent(request, state)
+  if (scope) {
+    // Scope text is written for that part of the site, so it wins over translations
+    content.title = scope.title || content.title
+    content.message = scope.message || content.message
+  }
+
+  if (action === 'reject') {
+    // API clients get JSON they can parse instead of an HTML page
+    const response = new Response(JSON.stringify({
+      error: 'read_only',
+      title: content.title || 'Maintenance Mode',
+      message: content.message || 'Writes are paused during maintenance. Please try again later.'
+    }), {
+      status: 503,
+      headers: {
+        'Content-Type': 'application/json;charset=UTF-8',
+        'Content-Language': content.locale,
+        'Vary': 'Accept-Language',
+        'Cache-Control': 'no-store',
+        'Retry-After': '3600',
+        'X-Content-Type-Options': 'nosniff'
+      }
+    })
+    if (rollout.setCookie) {
+      response.headers.append('Set-Cookie', await rolloutCookie(rollout.bucket, getRolloutCookieKey()))
+    }
+    return response
+  }
+
+  // Progress updates posted during the maintenance with `maintctl update post`
+  const statusUpdates = await getStatusUpdates()
+
+  // Fresh nonce per response so the countdown script is the only one CSP lets run
+  const nonce = crypto.randomUUID().replace(/-/g, '')
   
-  const html = `<!DOCTYPE html>
-<html lang="en">
+  const html = renderTemplate(getPageTemplate(), {
+    lang: escapeHtml(content.locale),
+    title: escapeHtml(content.title) || 'Maintenance Mode',
+    message: escapeHtml(content.message) || 'We are currently performing scheduled maintenance. We will be back shortly.',
+    updates: renderStatusUpdates(statusUpdates, getDisplayTimezone()),
+    window: getMaintenanceWindowMessage(nonce, state),
+    contact: sanitizedEmail ? `<p class="contact">Contact: <a href="mailto:${sanitizedEmail}">${sanitizedEmail}</a></p>` : '',
+    logo: logoHtml,
+    custom_css: customStyles
+  })
+
+  // Return a 503 because we're being honest about the service being unavailable
+  // The Retry-After header is optimistic, but hey, we can hope
+  const response = new Response(html, {
+    status: 503,
+    headers: {
+      'Content-Type': 'text/html;charset=UTF-8',
+      'Content-Language': content.locale,
+      'Vary': 'Accept-Language',
+      'Cache-Control': 'no-store, no-cache, must-revalidate', // Don't cache this disaster
+      'Retry-After': '3600', // Try again in an hour (fingers crossed we're done by then)
+      'Content-Security-Policy': `default-src 'none'; script-src 'nonce-${nonce}'; style-src 'unsafe-inline'; img-src https:;`,
+      'X-Content-Type-Options': 'nosniff',
+      'X-Frame-Options': 'DENY',
+      'Referrer-Policy': 'no-referrer'
+    }
+  })
+  if (rollout.setCookie) {
+    response.headers.append('Set-Cookie', await rolloutCookie(rollout.bucket, getRolloutCookieKey()))
+  }
+  if (autoReason) {
+    // The reason itself is only in the logs and KV; visitors just see that it was automatic
+    response.headers.set('X-Maintenance-Trigger', 'origin-health')
+  }
+  return response
+}
+
+// With kv_runtime_state the "state" key in MAINTENANCE_KV overrides the bindings,
+// so toggling maintenance is a KV write instead of a script upload. Isolates serve
+// many requests, so the document is kept in memory for a few seconds rather than
+// read from KV on every request. Keep in sync with internal/state.
+const RUNTIME_STATE_TTL_MS = 5000
+let runtimeStateCache = { expires: 0, value: null }
+
+async function getRuntimeState(nowMs) {
+  const bindings = {
+    enabled: !!MAINTENANCE_ENABLED && MAINTENANCE_ENABLED !== 'false',
+    title: MAINTENANCE_TITLE,
+    message: MAINTENANCE_MESSAGE,
+    windowStart: MAINTENANCE_WINDOW_START,
+    windowEnd: MAINTENANCE_WINDOW_END,
+    rolloutPercentage: parseRolloutPercentage(typeof MAINTENANCE_ROLLOUT_PERCENTAGE !== 'undefined' ? MAINTENANCE_ROLLOUT_PERCENTAGE : '')
+  }
+  if (typeof MAINTENANCE_KV === 'undefined' || typeof KV_RUNTIME_STATE === 'undefined' || KV_RUNTIME_STATE !== 'true') {
+    return bindings
+  }
+  if (nowMs >= runtimeStateCache.expires) {
+    let stored = null
+    try {
+      stored = await MAINTENANCE_KV.get('state', { type: 'json' })
+    } catch (e) {
+      // KV unavailable or invalid JSON, keep serving from the last known state
+      stored = runtimeStateCache.value
+    }
+    runtimeStateCache = { expires: nowMs + RUNTIME_STATE_TTL_MS, value: stored }
+  }
+  return resolveRuntimeState(runtimeStateCache.value, bindings)
+}
+
+// Fields present in the KV document win; empty title/message fall back to the
+// bindings, while empty window fields clear a window set in Terraform
+function resolveRuntimeState(stored, bindings) {
+  if (!stored || typeof stored !== 'object') {
+    return bindings
+  }
+  return {
+    enabled: typeof stored.enabled === 'boolean' ? stored.enabled : bindings.enabled,
+    title: stored.title || bindings.title,
+    message: stored.message || bindings.message,
+    windowStart: typeof stored.window_start === 'string' ? stored.window_start : bindings.windowStart,
+    windowEnd: typeof stored.window_end === 'string' ? stored.window_end : bindings.windowEnd,
+    rolloutPercentage: typeof stored.rollout_percentage === 'number' ? stored.rollout_percentage : bindings.rolloutPercentage
+  }
+}
+
+// watchdog: on each Cron Trigger, maintenance left on in the KV runtime state is
+// turned off once the window has ended plus grace_minutes, or once it has been
+// on for max_duration_minutes, and the notify targets are told. maintctl
+// watchdog runs the same rules. Keep in sync with internal/watchdog.
+function getWatchdogConfig() {
+  const defaults = { max_duration_minutes: 0, grace_minutes: 15 }
+  try {
+    const config = JSON.parse((typeof WATCHDOG !== 'undefined' && WATCHDOG) || '{}')
+    return Object.assign(defaults, config)
+  } catch (e) {
+    // Invalid JSON, window rule with the default grace only
+    return defaults
+  }
+}
+
+function getWatchdogTargets() {
+  try {
+    const targets = JSON.parse((typeof WATCHDOG_NOTIFY !== 'undefined' && WATCHDOG_NOTIFY) || '[]')
+    return Array.isArray(targets) ? targets : []
+  } catch (e) {
+    return []
+  }
+}
+
+async function runWatchdog(now) {
+  if (typeof MAINTENANCE_KV === 'undefined') {
+    return
+  }
+  const stored = await MAINTENANCE_KV.get('state', { type: 'json' })
+  if (!stored || typeof stored !== 'object') {
+    return
+  }
+  const config = getWatchdogConfig()
+  const verdict = watchdogVerdict(stored, now.getTime(), config)
+  if (verdict.action === 'none') {
+    return
+  }
+
+  // Other fields are kept as-is
+  const stamp = rfc3339(now.getTime())
+  if (verdict.action === 'stamp') {
+    await MAINTENANCE_KV.put('state', JSON.stringify(Object.assign({}, stored, { enabled_at: stamp, updated_at: stamp })))
+    return
+  }
+  await MAINTENANCE_KV.put('state', JSON.stringify(watchdogDisabledState(stored, stamp)))
+  console.warn(`watchdog turned maintenance off: ${verdict.reason}`)
+
+  await sendNotifications(getWatchdogTargets(), {
+    status: 'COMPLETED',
+    schedule: 'watchdog',
+    environment: getEnvironment(),
+    window_start: stored.window_start || '',
+    window_end: stored.window_end || '',
+    detail: verdict.reason
+  })
+}
+
+// The state a watchdog trip writes: maintenance off and the window cleared,
+// since a window still open would keep the page up without enabled. Keep in
+// sync with Watchdog.Tick in internal/watchdog.
+function watchdogDisabledState(stored, stamp) {
+  const next = Object.assign({}, stored, { enabled: false, window_start: '', window_end: '', updated_at: stamp })
+  delete next.enabled_at
+  return next
+}
+
+// Returns { action: 'none' | 'stamp' | 'disable', reason }. A window that ended
+// before the current enable began does not count.
+function watchdogVerdict(stored, nowMs, config) {
+  if (stored.enabled !== true) {
+    return { action: 'none', reason: '' }
+  }
+  const enabledAt = parseRFC3339(stored.enabled_at)
+  const stamped = !isNaN(enabledAt)
+
+  const end = parseRFC3339(stored.window_end)
+  if (!isNaN(end)) {
+    const deadline = end + config.grace_minutes * 60000
+    if (nowMs >= deadline && (!stamped || enabledAt < deadline)) {
+      return { action: 'disable', reason: `maintenance window ended at ${stored.window_end} (grace ${config.grace_minutes}m)` }
+    }
+  }
+  if (config.max_duration_minutes > 0 && stamped && nowMs >= enabledAt + config.max_duration_minutes * 60000) {
+    return { action: 'disable', reason: `maintenance on since ${stored.enabled_at}, over the ${config.max_duration_minutes}m limit` }
+  }
+  return { action: stamped ? 'none' : 'stamp', reason: '' }
+}
+
+// schedules: each Cron Trigger tick checks which schedules have a window open
+// (a cron start in the schedule's timezone, lasting its duration), records them
+// in MAINTENANCE_KV under "schedule_windows", and turns maintenance on with the
+// window while any is open. Openings and closings are sent to the schedule's
+// notify targets. Keep in sync with internal/schedule.
+const SCHEDULE_RECORD_KEY = 'schedule_windows'
+
+function getSchedules() {
+  let list = []
+  try {
+    list = JSON.parse((typeof SCHEDULES !== 'undefined' && SCHEDULES) || '[]')
+  } catch (e) {
+    // Invalid JSON, no schedules
+    return []
+  }
+  if (!Array.isArray(list)) {
+    return []
+  }
+  return list.map(compileSchedule).filter(Boolean)
+}
+
+function getScheduleTargets() {
+  try {
+    const targets = JSON.parse((typeof SCHEDULE_NOTIFY !== 'undefined' && SCHEDULE_NOTIFY) || '{}')
+    return targets && typeof targets === 'object' ? targets : {}
+  } catch (e) {
+    return {}
+  }
+}
+
+function getEnvironment() {
+  return (typeof MAINTENANCE_ENVIRONMENT !== 'undefined' && MAINTENANCE_ENVIRONMENT) || ''
+}
+
+// Terraform validates schedules; anything that still doesn't parse is skipped
+function compileSchedule(schedule) {
+  const cron = parseCron(schedule.cron)
+  const minutes = parseScheduleDuration(schedule.duration)
+  if (!cron || !(minutes > 0) || !schedule.name) {
+    return null
+  }
+  try {
+    zoneOffsetMinutes(schedule.timezone, 0)
+  } catch (e) {
+    return null
+  }
+  return { name: schedule.name, timezone: schedule.timezone, cron, minutes }
+}
+
+async function runSchedules(now) {
+  if (typeof MAINTENANCE_KV === 'undefined') {
+    return
+  }
+  const schedules = getSchedules()
+  const record = (await MAINTENANCE_KV.get(SCHEDULE_RECORD_KEY, { type: 'json' })) || {}
+  if (schedules.length === 0 && Object.keys(record).length === 0) {
+    return
+  }
+  const step = scheduleStep(schedules, record, now.getTime(), getBlackouts())
+  if (step.transitions.length === 0) {
+    return
+  }
+  await MAINTENANCE_KV.put(SCHEDULE_RECORD_KEY, JSON.stringify(step.record))
+
+  const stored = (await MAINTENANCE_KV.get('state', { type: 'json' })) || {}
+  const next = scheduledState(stored, record, step.record, rfc3339(now.getTime()))
+  if (next) {
+    await MAINTENANCE_KV.put('state', JSON.stringify(next))
+  }
+
+  const targets = getScheduleTargets()
+  for (const t of step.transitions) {
+    console.log(`schedule ${t.schedule}: ${t.status} ${t.start} - ${t.end}`)
+    await sendNotifications(targets[t.schedule] || [], {
+      status: t.status,
+      schedule: t.schedule,
+      environment: getEnvironment(),
+      window_start: t.start,
+      window_end: t.end
+    })
+  }
+}
+
+// Returns the runtime state after the scheduler's open windows went from record
+// to nextRecord, or null to leave it alone. The scheduler only owns the state
+// while it is off or shows the span of the windows the scheduler opened; an
+// operator or incident enable with its own window stays on when they close.
+function scheduledState(stored, record, nextRecord, stamp) {
+  const opened = windowSpan(Object.values(record))
+  const span = windowSpan(Object.values(nextRecord))
+  const ours = !stored.enabled ||
+    (opened !== null && stored.window_start === opened.start && stored.window_end === opened.end)
+  if (!ours || (!span && !stored.enabled)) {
+    return null
+  }
+  // Other fields are kept as-is
+  const next = Object.assign({}, stored, { updated_at: stamp })
+  if (span) {
+    if (!stored.enabled || !stored.enabled_at) {
+      next.enabled_at = stamp
+    }
+    next.enabled = true
+    next.window_start = span.start
+    next.window_end = span.end
+  } else {
+    next.enabled = false
+    next.window_start = ''
+    next.window_end = ''
+    delete next.enabled_at
+  }
+  return next
+}
+
+// The earliest start and latest end of windows, or null when there are none
+function windowSpan(windows) {
+  if (windows.length === 0) {
+    return null
+  }
+  return { start: windows.map(w => w.start).sort()[0], end: windows.map(w => w.end).sort().pop() }
+}
+
+// Returns the new record and the transitions at nowMs, closings first. A
+// schedule whose window was replaced by its next occurrence closes and opens
+// at the same tick; windows of removed schedules close, and windows that
+// overlap a blackout don't open.
+function scheduleStep(schedules, record, nowMs, blackouts = []) {
+  const now = Math.floor(nowMs / 60000) * 60000
+  const stamp = rfc3339(now)
+  const next = {}
+  const ends = []
+  const starts = []
+  for (const s of schedules) {
+    const w = activeScheduleWindow(s, now, blackouts)
+    const old = record[s.name]
+    const changed = !old || !w || old.start !== w.start
+    if (old && changed) {
+      ends.push({ at: stamp, status: 'COMPLETED', schedule: s.name, start: old.start, end: old.end })
+    }
+    if (w) {
+      next[s.name] = w
+      if (changed) {
+        starts.push({ at: stamp, status: 'STARTING', schedule: s.name, start: w.start, end: w.end })
+      }
+    }
+  }
+  const configured = new Set(schedules.map(s => s.name))
+  for (const name of Object.keys(record).filter(name => !configured.has(name)).sort()) {
+    const old = record[name]
+    ends.push({ at: stamp, status: 'COMPLETED', schedule: name, start: old.start, end: old.end })
+  }
+  return { record: next, transitions: ends.concat(starts) }
+}
+
+// The window open at now is the earliest start within the last duration whose
+// window overlaps no blackout
+function activeScheduleWindow(schedule, now, blackouts = []) {
+  const from = now - (schedule.minutes - 1) * 60000
+  const offsetAt = zoneOffsets(schedule.timezone, from - 3 * 3600000, now)
+  for (let t = from; t <= now; t += 60000) {
+    if (scheduleStartsAt(schedule, t, offsetAt) && !findBlackout(blackouts, t, t + schedule.minutes * 60000)) {
+      return { start: rfc3339(t), end: rfc3339(t + schedule.minutes * 60000) }
+    }
+  }
+  return null
+}
+
+// A wall-clock time skipped when clocks go forward starts at the first minute
+// after the gap; one that happens twice when clocks go back starts once
+function scheduleStartsAt(schedule, t, offsetAt) {
+  const offset = offsetAt(t)
+  const local = t + offset * 60000
+  if (cronMatches(schedule.cron, local)) {
+    const d = offsetAt(t - 3 * 3600000) - offset
+    return !(d > 0 && t - d * 60000 + offsetAt(t - d * 60000) * 60000 === local)
+  }
+  const gap = offset - offsetAt(t - 60000)
+  for (let k = 1; k <= gap; k++) {
+    if (cronMatches(schedule.cron, local - k * 60000)) {
+      return true
+    }
+  }
+  return false
+}
+
+// Blackouts hold scheduled windows: the blackouts variable (dates cover whole
+// days in their timezone, end included) plus the VEVENTs of an iCalendar
+// file. Keep in sync with internal/schedule/blackout.go.
+function getBlackouts() {
+  try {
+    const specs = JSON.parse((typeof BLACKOUTS !== 'undefined' && BLACKOUTS) || '[]')
+    const calendar = (typeof BLACKOUT_CALENDAR !== 'undefined' && BLACKOUT_CALENDAR) || ''
+    return resolveBlackouts(specs).concat(calendar ? parseIcal(calendar) : [])
+  } catch (e) {
+    // A freeze that can't be read holds every window rather than none
+    console.error(`blackouts: ${e.message}`)
+    return [{ name: 'unreadable blackouts', start: -Infinity, end: Infinity }]
+  }
+}
+
+function findBlackout(blackouts, start, end) {
+  for (const b of blackouts) {
+    if (end > start ? start < b.end && b.start < end : start >= b.start && start < b.end) {
+      return b
+    }
+  }
+  return null
+}
+
+function resolveBlackouts(specs) {
+  return specs.map(spec => {
+    const timeZone = spec.timezone || 'UTC'
+    const start = blackoutTime(spec.start, timeZone)
+    const endText = spec.end || (start.date ? spec.start : '')
+    if (!endText) {
+      throw new Error(`blackout ${spec.name}: an RFC3339 start needs an end`)
+    }
+    const end = blackoutTime(endText, timeZone)
+    const b = { name: spec.name, start: start.ms, end: end.date ? zonedWallToUtc(timeZone, end.wall + 86400000) : end.ms }
+    if (!(b.end > b.start)) {
+      throw new Error(`blackout ${spec.name}: end ${endText} is not after start ${spec.start}`)
+    }
+    return b
+  })
+}
+
+// A date is midnight in timeZone; anything else must be RFC3339
+function blackoutTime(value, timeZone) {
+  const m = /^(\d{4})-(\d{2})-(\d{2})$/.exec(value || '')
+  if (m) {
+    const wall = Date.UTC(+m[1], +m[2] - 1, +m[3])
+    return { date: true, wall, ms: zonedWallToUtc(timeZone, wall) }
+  }
+  const ms = parseRFC3339(value)
+  if (isNaN(ms)) {
+    throw new Error(`"${value}" is not YYYY-MM-DD or RFC3339`)
+  }
+  return { date: false, ms }
+}
+
+// Floating times and dates use the calendar's X-WR-TIMEZONE, else UTC;
+// cancelled events are skipped and recurring ones rejected
+function parseIcal(text) {
+  const lines = []
+  for (const line of text.split(/\r?\n/)) {
+    if (/^[ \t]/.test(line) && lines.length > 0) {
+      lines[lines.length - 1] += line.slice(1)
+    } else if (line) {
+      lines.push(line)
+    }
+  }
+  const props = lines.map(icalProperty)
+  let floating = 'UTC'
+  for (const p of props) {
+    if (p.name === 'X-WR-TIMEZONE') {
+      zoneOffsetMinutes(p.value, 0)
+      floating = p.value
+    }
+  }
+  const out = []
+  let ev = null
+  for (const p of props) {
+    if (p.name === 'BEGIN' && p.value === 'VEVENT') {
+      ev = {}
+    } else if (p.name === 'END' && p.value === 'VEVENT') {
+      if (!ev) {
+        throw new Error('END:VEVENT without BEGIN')
+      }
+      const b = icalEvent(ev, floating)
+      if (b) {
+        out.push(b)
+      }
+      ev = null
+    } else if (ev) {
+      ev[p.name] = p
+    }
+  }
+  return out
+}
+
+function icalProperty(line) {
+  const colon = line.indexOf(':')
+  const head = colon < 0 ? line : line.slice(0, colon)
+  const parts = head.split(';')
+  const params = {}
+  for (const part of parts.slice(1)) {
+    const eq = part.indexOf('=')
+    params[(eq < 0 ? part : part.slice(0, eq)).toUpperCase()] = eq < 0 ? '' : part.slice(eq + 1)
+  }
+  return { name: parts[0].toUpperCase(), params, value: colon < 0 ? '' : line.slice(colon + 1) }
+}
+
+function icalEvent(ev, floating) {
+  const value = key => (ev[key] ? ev[key].value : '')
+  const name = value('SUMMARY').replace(/\\([nN,;\\])/g, (m, c) => (c === 'n' || c === 'N' ? ' ' : c)) || value('UID')
+  if (value('STATUS').toUpperCase() === 'CANCELLED') {
+    return null
+  }
+  if (value('RRULE') || value('RDATE')) {
+    throw new Error(`event "${name}": recurring events are not supported`)
+  }
+  if (!value('DTSTART')) {
+    throw new Error(`event "${name}": no DTSTART`)
+  }
+  const start = icalTime(ev.DTSTART, floating, name)
+  let end
+  if (value('DTEND')) {
+    end = icalTime(ev.DTEND, floating, name).ms
+  } else if (value('DURATION')) {
+    throw new Error(`event "${name}": DURATION is not supported, use DTEND`)
+  } else if (start.date) {
+    // A date without an end is that one day
+    end = zonedWallToUtc(start.zone, start.wall + 86400000)
+  } else {
+    throw new Error(`event "${name}": no DTEND`)
+  }
+  if (!(end > start.ms)) {
+    throw new Error(`event "${name}": DTEND is not after DTSTART`)
+  }
+  return { name, start: start.ms, end }
+}
+
+// DTEND dates are exclusive in iCalendar, so both ends are used as they are
+function icalTime(prop, floating, name) {
+  let zone = floating
+  const tzid = (prop.params.TZID || '').replace(/^"|"$/g, '')
+  if (tzid) {
+    try {
+      zoneOffsetMinutes(tzid, 0)
+    } catch (e) {
+      throw new Error(`event "${name}": TZID "${tzid}" is not an IANA timezone`)
+    }
+    zone = tzid
+  }
+  const m = /^(\d{4})(\d{2})(\d{2})(?:T(\d{2})(\d{2})(\d{2})(Z?))?$/.exec(prop.value)
+  const wall = m ? Date.UTC(+m[1], +m[2] - 1, +m[3], +(m[4] || 0), +(m[5] || 0), +(m[6] || 0)) : NaN
+  if (!m || (prop.params.VALUE === 'DATE' && m[4]) || new Date(wall).getUTCDate() !== +m[3] || new Date(wall).getUTCMonth() !== +m[2] - 1) {
+    throw new Error(`event "${name}": bad time ${prop.value}`)
+  }
+  if (m[7]) {
+    return { date: false, ms: wall }
+  }
+  return { date: !m[4], wall, zone, ms: zonedWallToUtc(zone, wall) }
+}
+
+// The instant at which the clock in timeZone shows wallMs
+function zonedWallToUtc(timeZone, wallMs) {
+  const guess = wallMs - zoneOffsetMinutes(timeZone, wallMs) * 60000
+  return wallMs - zoneOffsetMinutes(timeZone, guess) * 60000
+}
+
+const zoneFormatters = {}
+
+// Minutes east of UTC in timeZone at ms; throws for unknown zones
+function zoneOffsetMinutes(timeZone, ms) {
+  if (!zoneFormatters[timeZone]) {
+    zoneFormatters[timeZone] = new Intl.DateTimeFormat('en-US', {
+      timeZone, hourCycle: 'h23', year: 'numeric', month: 'numeric', day: 'numeric', hour: 'numeric', minute: 'numeric'
+    })
+  }
+  const parts = {}
+  for (const part of zoneFormatters[timeZone].formatToParts(new Date(ms))) {
+    parts[part.type] = part.value
+  }
+  const wall = Date.UTC(+parts.year, +parts.month - 1, +parts.day, +parts.hour, +parts.minute)
+  return Math.round((wall - Math.floor(ms / 60000) * 60000) / 60000)
+}
+
+// Offsets between fromMs and toMs from a few Intl lookups instead of one per
+// minute; at most one clock change fits in a window
+function zoneOffsets(timeZone, fromMs, toMs) {
+  const first = zoneOffsetMinutes(timeZone, fromMs)
+  const last = zoneOffsetMinutes(timeZone, toMs)
+  if (first === last) {
+    return () => first
+  }
+  let lo = fromMs
+  let hi = toMs
+  while (hi - lo > 60000) {
+    const mid = lo + Math.floor((hi - lo) / 120000) * 60000
+    if (zoneOffsetMinutes(timeZone, mid) === first) {
+      lo = mid
+    } else {
+      hi = mid
+    }
+  }
+  return t => (t < hi ? first : last)
+}
+
+function parseScheduleDuration(text) {
+  const m = /^(?:(\d+)h)?(?:(\d+)m)?$/.exec(typeof text === 'string' ? text : '')
+  return m && text ? (+(m[1] || 0)) * 60 + (+(m[2] || 0)) : NaN
+}
+
+// 5-field cron: lists of *, N or N-M with optional /STEP; months and weekdays
+// also take names. When both day fields are restricted either may match.
+// Keep in sync with ParseCron in internal/schedule.
+const CRON_FIELDS = [
+  { min: 0, max: 59 },
+  { min: 0, max: 23 },
+  { min: 1, max: 31 },
+  { min: 1, max: 12, names: ['JAN', 'FEB', 'MAR', 'APR', 'MAY', 'JUN', 'JUL', 'AUG', 'SEP', 'OCT', 'NOV', 'DEC'] },
+  { min: 0, max: 7, names: ['SUN', 'MON', 'TUE', 'WED', 'THU', 'FRI', 'SAT'] }
+]
+
+function parseCron(expr) {
+  const fields = String(expr || '').trim().split(/\s+/)
+  if (fields.length !== 5) {
+    return null
+  }
+  const sets = []
+  for (let i = 0; i < 5; i++) {
+    const set = parseCronField(fields[i], CRON_FIELDS[i])
+    if (!set) {
+      return null
+    }
+    sets.push(set)
+  }
+  // Sunday is both 0 and 7
+  if (sets[4][7]) {
+    sets[4][0] = true
+  }
+  return {
+    minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+    // Like Vixie cron, */N still counts as unrestricted
+    domStar: fields[2].startsWith('*'),
+    dowStar: fields[4].startsWith('*')
+  }
+}
+
+function parseCronField(text, field) {
+  const set = []
+  for (const item of text.split(',')) {
+    const [range, stepText, extra] = item.split('/')
+    if (extra !== undefined) {
+      return null
+    }
+    let step = 1
+    if (stepText !== undefined) {
+      if (!/^\d{1,2}$/.test(stepText) || +stepText < 1 || +stepText > field.max) {
+        return null
+      }
+      step = +stepText
+    }
+    let lo = field.min
+    let hi = field.max
+    if (range !== '*') {
+      const [a, b, more] = range.split('-')
+      lo = cronValue(a, field)
+      if (lo === null || more !== undefined) {
+        return null
+      }
+      hi = lo
+      if (b !== undefined) {
+        hi = cronValue(b, field)
+        if (hi === null || hi < lo) {
+          return null
+        }
+      } else if (stepText !== undefined) {
+        // N/STEP runs from N to the end of the field
+        hi = field.max
+      }
+    }
+    for (let v = lo; v <= hi; v += step) {
+      set[v] = true
+    }
+  }
+  return set
+}
+
+function cronValue(text, field) {
+  const named = (field.names || []).indexOf(text.toUpperCase())
+  if (named >= 0) {
+    return field.min + named
+  }
+  if (!/^\d{1,2}$/.test(text)) {
+    return null
+  }
+  const n = +text
+  return n >= field.min && n <= field.max ? n : null
+}
+
+// wallMs is a wall-clock time expressed as if it were UTC
+function cronMatches(cron, wallMs) {
+  const d = new Date(wallMs)
+  if (!cron.minute[d.getUTCMinutes()] || !cron.hour[d.getUTCHours()] || !cron.month[d.getUTCMonth() + 1]) {
+    return false
+  }
+  const dom = !!cron.dom[d.getUTCDate()]
+  const dow = !!cron.dow[d.getUTCDay()]
+  return cron.domStar || cron.dowStar ? dom && dow : dom || dow
+}
+
+// The .ics feed of upcoming maintenance, served on the status hostname at
+// /maintenance.ics: the maintenance window and the schedules' windows from the
+// UTC day before now through the configured days. UIDs depend only on the
+// schedule and start, and windows a blackout holds are published as
+// cancelled. Keep in sync with WriteICS in internal/schedule/ics.go.
+function getCalendarFeed() {
+  try {
+    const feed = JSON.parse((typeof CALENDAR_FEED !== 'undefined' && CALENDAR_FEED) || 'null')
+    return feed && feed.host ? feed : null
+  } catch (e) {
+    return null
+  }
+}
+
+// Expanding a month of schedules takes tens of milliseconds, so an isolate
+// keeps the feed as long as clients may cache it
+const CALENDAR_FEED_TTL_MS = 300000
+let calendarFeedCache = { expires: 0, body: '' }
+
+function serveCalendarFeed(request, feed, nowMs) {
+  if (request.method !== 'GET' && request.method !== 'HEAD') {
+    return new Response('Method Not Allowed', { status: 405, headers: { 'Allow': 'GET, HEAD' } })
+  }
+  if (nowMs >= calendarFeedCache.expires) {
+    const start = parseRFC3339(MAINTENANCE_WINDOW_START || '')
+    const end = parseRFC3339(MAINTENANCE_WINDOW_END || '')
+    const body = maintenanceCalendar(getSchedules(), getBlackouts(), {
+      name: feed.name,
+      host: feed.host,
+      days: feed.days,
+      now: nowMs,
+      window: start < end ? { start, end } : null
+    })
+    calendarFeedCache = { expires: nowMs + CALENDAR_FEED_TTL_MS, body }
+  }
+  return new Response(request.method === 'HEAD' ? null : calendarFeedCache.body, {
+    headers: {
+      'Content-Type': 'text/calendar;charset=UTF-8',
+      'Content-Disposition': 'inline; filename="maintenance.ics"',
+      'Cache-Control': 'public, max-age=300'
+    }
+  })
+}
+
+function maintenanceCalendar(schedules, blackouts, options) {
+  const day = Math.floor(options.now / 86400000) * 86400000
+  const from = day - 86400000
+  const to = day + options.days * 86400000
+  const stamp = icsUTC(options.now)
+  const lines = [
+    'BEGIN:VCALENDAR',
+    'VERSION:2.0',
+    'PRODID:-//terraform-cloudflare-maintenance//maintenance.ics//EN',
+    'CALSCALE:GREGORIAN',
+    'METHOD:PUBLISH',
+    `X-WR-CALNAME:${icsEscape(options.name || '')}`,
+    'REFRESH-INTERVAL;VALUE=DURATION:PT1H',
+    'X-PUBLISHED-TTL:PT1H'
+  ]
+  const zones = [...new Set(schedules.map(s => s.timezone).filter(tz => tz !== 'UTC'))].sort()
+  for (const timeZone of zones) {
+    lines.push(...vtimezone(timeZone, from, to))
+  }
+
+  const w = options.window
+  if (w && w.end > from && w.start < to) {
+    lines.push('BEGIN:VEVENT', `UID:window-${icsUTC(w.start)}@${options.host}`, `DTSTAMP:${stamp}`,
+      `DTSTART:${icsUTC(w.start)}`, `DTEND:${icsUTC(w.end)}`, 'SUMMARY:Scheduled maintenance',
+      'STATUS:CONFIRMED', 'SEQUENCE:0', 'END:VEVENT')
+  }
+  for (const win of expandSchedules(schedules, from, to, blackouts)) {
+    lines.push('BEGIN:VEVENT', `UID:schedule-${win.schedule.name.replace(/[^A-Za-z0-9._-]/g, '-')}-${icsUTC(win.start)}@${options.host}`,
+      `DTSTAMP:${stamp}`)
+    if (win.schedule.timezone === 'UTC') {
+      lines.push(`DTSTART:${icsUTC(win.start)}`)
+    } else {
+      lines.push(`DTSTART;TZID=${win.schedule.timezone}:${icsLocal(win.start + win.offset * 60000)}`)
+    }
+    const minutes = win.schedule.minutes
+    lines.push(`DURATION:PT${minutes >= 60 ? Math.floor(minutes / 60) + 'H' : ''}${minutes % 60 ? minutes % 60 + 'M' : ''}`,
+      `SUMMARY:${icsEscape('Scheduled maintenance: ' + win.schedule.name)}`)
+    if (win.blackout) {
+      lines.push(`DESCRIPTION:${icsEscape('Cancelled for the ' + win.blackout.name + ' blackout')}`, 'STATUS:CANCELLED', 'SEQUENCE:1')
+    } else {
+      lines.push('STATUS:CONFIRMED', 'SEQUENCE:0')
+    }
+    lines.push('END:VEVENT')
+  }
+  lines.push('END:VCALENDAR')
+  return lines.map(foldIcsLine).join('')
+}
+
+// Windows starting in [fromMs, toMs) by start time, a day of offsets at a time
+function expandSchedules(schedules, fromMs, toMs, blackouts) {
+  const windows = []
+  for (const schedule of schedules) {
+    for (let day = fromMs; day < toMs; day += 86400000) {
+      const end = Math.min(day + 86400000, toMs)
+      const offsetAt = zoneOffsets(schedule.timezone, day - 3 * 3600000, end)
+      for (let t = day; t < end; t += 60000) {
+        // Away from a clock change a start needs the cron's minute and hour
+        const offset = offsetAt(t)
+        const local = Math.floor((t + offset * 60000) / 60000)
+        if (offset === offsetAt(t - 60000) && (!schedule.cron.minute[local % 60] || !schedule.cron.hour[Math.floor(local / 60) % 24])) {
+          continue
+        }
+        if (scheduleStartsAt(schedule, t, offsetAt)) {
+          const blackout = findBlackout(blackouts, t, t + schedule.minutes * 60000)
+          windows.push({ schedule, start: t, offset: offsetAt(t), blackout })
+        }
+      }
+    }
+  }
+  return windows.sort((a, b) => a.start - b.start)
+}
+
+// The offset at fromMs, then every change before toMs; it is daylight time
+// when ahead of the smaller of the offsets on January 1 and July 1
+function vtimezone(timeZone, fromMs, toMs) {
+  const lines = ['BEGIN:VTIMEZONE', `TZID:${timeZone}`]
+  const observance = (at, from, to) => {
+    const year = new Date(at + to * 60000).getUTCFullYear()
+    const standard = Math.min(zoneOffsetMinutes(timeZone, Date.UTC(year, 0, 1)), zoneOffsetMinutes(timeZone, Date.UTC(year, 6, 1)))
+    const kind = to > standard ? 'DAYLIGHT' : 'STANDARD'
+    lines.push(`BEGIN:${kind}`, `DTSTART:${icsLocal(at + from * 60000)}`, `TZOFFSETFROM:${icsOffset(from)}`,
+      `TZOFFSETTO:${icsOffset(to)}`, `END:${kind}`)
+  }
+  let offset = zoneOffsetMinutes(timeZone, fromMs)
+  observance(fromMs, offset, offset)
+  for (let day = fromMs; day < toMs; day += 86400000) {
+    const end = Math.min(day + 86400000, toMs)
+    const next = zoneOffsetMinutes(timeZone, end)
+    if (next === offset) {
+      continue
+    }
+    let lo = day
+    let hi = end
+    while (hi - lo > 60000) {
+      const mid = lo + Math.floor((hi - lo) / 120000) * 60000
+      if (zoneOffsetMinutes(timeZone, mid) === offset) {
+        lo = mid
+      } else {
+        hi = mid
+      }
+    }
+    if (hi < toMs) {
+      observance(hi, offset, next)
+      offset = next
+    }
+  }
+  lines.push('END:VTIMEZONE')
+  return lines
+}
+
+function icsUTC(ms) {
+  return icsLocal(ms) + 'Z'
+}
+
+// A wall-clock time given as UTC milliseconds, without a zone
+function icsLocal(ms) {
+  return new Date(ms).toISOString().slice(0, 19).replace(/[-:]/g, '')
+}
+
+function icsOffset(minutes) {
+  const abs = Math.abs(minutes)
+  return (minutes < 0 ? '-' : '+') + String(Math.floor(abs / 60)).padStart(2, '0') + String(abs % 60).padStart(2, '0')
+}
+
+function icsEscape(text) {
+  return text.replace(/\\/g, '\\\\').replace(/;/g, '\\;').replace(/,/g, '\\,').replace(/\n/g, '\\n')
+}
+
+// Lines end in CRLF and fold at 75 octets without splitting a UTF-8 sequence
+function foldIcsLine(line) {
+  let out = ''
+  let chunk = ''
+  let size = 0
+  let limit = 75
+  for (const ch of line) {
+    const cp = ch.codePointAt(0)
+    const n = cp < 0x80 ? 1 : cp < 0x800 ? 2 : cp < 0x10000 ? 3 : 4
+    if (size + n > limit) {
+      out += chunk + '\r\n '
+      chunk = ''
+      size = 0
+      limit = 74
+    }
+    chunk += ch
+    size += n
+  }
+  return out + chunk + '\r\n'
+}
+
+// Whole-second RFC3339 in UTC, the way internal/state writes times
+function rfc3339(ms) {
+  return new Date(Math.floor(ms / 1000) * 1000).toISOString().replace('.000Z', 'Z')
+}
+
+// Date.parse accepts more than RFC3339; Go's time.Parse doesn't
+function parseRFC3339(value) {
+  if (typeof value !== 'string' || !/^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$/.test(value)) {
+    return NaN
+  }
+  return Date.parse(value)
+}
+
+// Notification targets use the notifications submodule's formats and payloads.
+// Keep in sync with internal/notify.
+const PAGERDUTY_EVENTS_URL = 'https://events.pagerduty.com/v2/enqueue'
+
+function notificationRequest(target, message) {
+  const summary = `Maintenance ${message.status}: ${message.schedule}`
+  if (target.startsWith('slack://')) {
+    const mrkdwn = (label, value) => ({ type: 'mrkdwn', text: `*${label}:*\n${value}` })
+    const fields = [
+      mrkdwn('Status', message.status),
+      mrkdwn('Window', `${message.window_start} - ${message.window_end}`),
+      mrkdwn('Environment', message.environment),
+      mrkdwn('Schedule', message.schedule)
+    ]
+    if (message.detail) {
+      fields.push(mrkdwn('Detail', message.detail))
+    }
+    return {
+      url: 'https://hooks.slack.com/services/' + target.slice('slack://'.length),
+      body: {
+        text: `Maintenance ${message.status}`,
+        blocks: [
+          { type: 'header', text: { type: 'plain_text', text: summary } },
+          { type: 'section', fields }
+        ]
+      }
+    }
+  }
+  if (target.startsWith('pagerduty://')) {
+    const details = {
+      status: message.status,
+      environment: message.environment,
+      schedule: message.schedule,
+      start_time: message.window_start,
+      end_time: message.window_end
+    }
+    if (message.detail) {
+      details.detail = message.detail
+    }
+    return {
+      url: PAGERDUTY_EVENTS_URL,
+      body: {
+        routing_key: target.slice('pagerduty://'.length),
+        event_action: 'trigger',
+        payload: { summary, severity: 'warning', source: 'terraform-cloudflare-maintenance', custom_details: details }
+      }
+    }
+  }
+  if (target.startsWith('webhook://')) {
+    const body = {
+      status: message.status,
+      schedule_name: message.schedule,
+      environment: message.environment,
+      maintenance_window: { start_time: message.window_start, end_time: message.window_end }
+    }
+    if (message.detail) {
+      body.detail = message.detail
+    }
+    return { url: target.slice('webhook://'.length), body }
+  }
+  return null
+}
+
+async function sendNotifications(targets, message) {
+  await Promise.all(targets.map(async target => {
+    const request = notificationRequest(target, message)
+    const scheme = target.split('://')[0]
+    if (!request) {
+      console.warn(`unknown notification target type ${scheme}`)
+      return
+    }
+    try {
+      const response = await fetch(request.url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(request.body)
+      })
+      if (!response.ok) {
+        console.warn(`notifying ${scheme}: HTTP ${response.status}`)
+      }
+    } catch (e) {
+      // The URL is a credential, so only the scheme is logged
+      console.warn(`notifying ${scheme}: ${e.message}`)
+    }
+  }))
+}
+
+// auto_maintenance: a circuit breaker over origin 5xx responses and timeouts.
+// Failures are counted in a sliding window, and healthy responses in between
+// don't reset the count, so a flapping origin trips too. At the threshold the breaker opens
+// and the page is served without contacting the origin until the cool-down
+// ends, then the next request probes the origin and closes or re-trips it.
+// The breaker lives in the isolate, so each isolate trips on what it sees;
+// trips are logged and written to MAINTENANCE_KV under "auto_trip".
+// Keep in sync with internal/health.
+let autoBreaker = { failures: [], openedAt: null, reason: '' }
+
+function getAutoMaintenanceConfig() {
+  try {
+    const config = JSON.parse((typeof AUTO_MAINTENANCE !== 'undefined' && AUTO_MAINTENANCE) || '{}')
+    return config && config.enabled ? config : null
+  } catch (e) {
+    // Invalid JSON, auto mode stays off
+    return null
+  }
+}
+
+async function guardedFetch(request, config, nowMs, event) {
+  if (breakerOpen(autoBreaker, nowMs, config)) {
+    return { reason: autoBreaker.reason }
+  }
+
+  const controller = new AbortController()
+  const timer = setTimeout(() => controller.abort(), config.timeout_ms)
+  let response = null
+  let failure = ''
+  try {
+    response = await fetch(request, { signal: controller.signal })
+    if (response.status >= 500) {
+      failure = `HTTP ${response.status}`
+    }
+  } catch (e) {
+    failure = controller.signal.aborted ? `timeout after ${config.timeout_ms}ms` : `fetch failed: ${e.message}`
+  } finally {
+    clearTimeout(timer)
+  }
+
+  const trip = breakerRecord(autoBreaker, nowMs, failure, config)
+  if (trip) {
+    console.warn(`auto maintenance tripped: ${trip.reason}`)
+    if (typeof MAINTENANCE_KV !== 'undefined') {
+      const write = MAINTENANCE_KV.put('auto_trip', JSON.stringify(trip)).catch(() => {})
+      if (event) event.waitUntil(write)
+    }
+    return { reason: trip.reason }
+  }
+  return { response: response || new Response('Origin unavailable', { status: controller.signal.aborted ? 504 : 502 }) }
+}
+
+function breakerOpen(breaker, nowMs, config) {
+  return breaker.openedAt !== null && nowMs < breaker.openedAt + config.cooldown_seconds * 1000
+}
+
+// Returns the trip record when the breaker opens, null otherwise
+function breakerRecord(breaker, nowMs, failure, config) {
+  const probing = breaker.openedAt !== null
+  if (!failure) {
+    if (probing) {
+      breaker.openedAt = null
+      breaker.reason = ''
+    }
+    return null
+  }
+  if (probing) {
+    return breakerTrip(breaker, nowMs, `origin still failing after cool-down, last: ${failure}`, config)
+  }
+
+  breaker.failures = breaker.failures.filter(at => at > nowMs - config.window_seconds * 1000)
+  breaker.failures.push(nowMs)
+  if (breaker.failures.length < config.failure_threshold) {
+    return null
+  }
+  return breakerTrip(breaker, nowMs, `${breaker.failures.length} origin failures within ${config.window_seconds}s, last: ${failure}`, config)
+}
+
+function breakerTrip(breaker, nowMs, reason, config) {
+  breaker.failures = []
+  breaker.openedAt = nowMs
+  breaker.reason = reason
+  return {
+    tripped_at: new Date(nowMs).toISOString(),
+    reason,
+    cooldown_until: new Date(nowMs + config.cooldown_seconds * 1000).toISOString()
+  }
+}
+
+// stale_paths: while the site is up, good GET responses under these paths are
+// stored in the Cache API, and during maintenance the stored copy is served
+// instead of the page. The Cache API doesn't see the CDN cache, so only copies
+// the worker stored itself can be served. Keep in sync with internal/stale.
+function getStaleConfig() {
+  let paths = []
+  try {
+    paths = JSON.parse((typeof STALE_PATHS !== 'undefined' && STALE_PATHS) || '[]')
+  } catch (e) {
+    // Invalid JSON, serve the page as usual
+  }
+  if (!Array.isArray(paths) || paths.length === 0) {
+    return null
+  }
+  const ttl = parseInt((typeof STALE_TTL_SECONDS !== 'undefined' && STALE_TTL_SECONDS) || '', 10)
+  return { paths, ttl: ttl > 0 ? ttl : 86400 }
+}
+
+// GET requests under one of the paths, on segment boundaries like maintenance_scopes
+function staleApplies(paths, method, path) {
+  if (method !== 'GET') {
+    return false
+  }
+  return paths.some(p => {
+    const prefix = p.replace(/\/+$/, '')
+    return !prefix || path === prefix || path.startsWith(prefix + '/')
+  })
+}
+
+// Only replay what is safe to show other visitors: a 200 without cookies or Vary
+// that the origin didn't mark private or no-store, to a request without
+// credentials unless the origin marked it public. Copies are keyed by URL alone.
+function isStorableForStale(requestHeaders, status, headers) {
+  if (status !== 200 || headers.get('Set-Cookie') || headers.get('Vary')) {
+    return false
+  }
+  const cacheControl = (headers.get('Cache-Control') || '').toLowerCase()
+  if (cacheControl.includes('private') || cacheControl.includes('no-store')) {
+    return false
+  }
+  if (!requestHeaders.get('Authorization') && !requestHeaders.get('Cookie')) {
+    return true
+  }
+  return cacheControl.split(',').some(d => d.trim() === 'public')
+}
+
+function rememberStale(request, response, event) {
+  const config = getStaleConfig()
+  if (!config || !staleApplies(config.paths, request.method, new URL(request.url).pathname) ||
+      !isStorableForStale(request.headers, response.status, response.headers)) {
+    return response
+  }
+  const copy = new Response(response.clone().body, response)
+  copy.headers.set('Cache-Control', `public, max-age=${config.ttl}`)
+  const put = caches.default.put(request.url, copy).catch(() => {})
+  if (event) event.waitUntil(put)
+  return response
+}
+
+async function serveStale(request) {
+  const config = getStaleConfig()
+  if (!config || !staleApplies(config.paths, request.method, new URL(request.url).pathname)) {
+    return null
+  }
+  const cached = await caches.default.match(request.url)
+  if (!cached) {
+    return null
+  }
+  // Don't let browsers or proxies keep the stale copy as fresh
+  const stale = new Response(cached.body, cached)
+  stale.headers.set('Cache-Control', 'no-store')
+  stale.headers.set('X-Maintenance-Stale', 'true')
+  return stale
+}
+
+async function passThrough(request, rollout) {
+  const response = await fetch(request)
+  if (!rollout.setCookie) {
+    return response
+  }
+  const pinned = new Response(response.body, response)
+  pinned.headers.append('Set-Cookie', await rolloutCookie(rollout.bucket, getRolloutCookieKey()))
+  return pinned
+}
+
+function getMaintenanceMode() {
+  return (typeof MAINTENANCE_MODE !== 'undefined' && MAINTENANCE_MODE) || 'full'
+}
+
+// read_only lets GET, HEAD and OPTIONS through and refuses every other method,
+// including unknown ones; anything but read_only behaves as full. Keep in sync
+// with internal/mode.
+function maintenanceAction(mode, method) {
+  if (mode !== 'read_only') {
+    return 'page'
+  }
+  return ['GET', 'HEAD', 'OPTIONS'].includes((method || '').toUpperCase()) ? 'origin' : 'reject'
+}
+
+// Rollout buckets: FNV-1a of the client IP (or the pinned cookie value) modulo
+// 100, and a client is in maintenance when its bucket is below the percentage,
+// so raising the percentage only adds clients. The cookie carries an HMAC of the
+// bucket under ROLLOUT_COOKIE_KEY so clients can't pick their own bucket; without
+// a key cookies are neither trusted nor set. Keep in sync with internal/rollout.
+const ROLLOUT_BUCKETS = 100
+const ROLLOUT_COOKIE = 'maintenance_bucket'
+
+function getRolloutCookieKey() {
+  return (typeof ROLLOUT_COOKIE_KEY !== 'undefined' && ROLLOUT_COOKIE_KEY) || ''
+}
+
+function parseRolloutPercentage(value) {
+  const pct = parseInt(value, 10)
+  return isNaN(pct) ? 100 : Math.min(Math.max(pct, 0), 100)
+}
+
+function rolloutBucket(key) {
+  let hash = 0x811c9dc5
+  for (const byte of new TextEncoder().encode(key)) {
+    hash ^= byte
+    hash = Math.imul(hash, 0x01000193) >>> 0
+  }
+  return hash % ROLLOUT_BUCKETS
+}
+
+function getRolloutCookie(header) {
+  for (const part of (header || '').split(';')) {
+    const trimmed = part.trim()
+    const eq = trimmed.indexOf('=')
+    if (eq > 0 && trimmed.slice(0, eq) === ROLLOUT_COOKIE) {
+      return trimmed.slice(eq + 1)
+    }
+  }
+  return ''
+}
+
+async function decideRollout(cookie, clientIP, percentage, key) {
+  if (percentage <= 0 || percentage >= ROLLOUT_BUCKETS) {
+    return { bucket: 0, included: percentage >= ROLLOUT_BUCKETS, setCookie: false }
+  }
+  const pinned = await verifyRolloutCookie(cookie, key)
+  if (pinned !== null) {
+    return { bucket: pinned, included: pinned < percentage, setCookie: false }
+  }
+  const bucket = rolloutBucket(clientIP)
+  return { bucket, included: bucket < percentage, setCookie: key !== '' }
+}
+
+function rolloutHmacKey(key, usage) {
+  return crypto.subtle.importKey('raw', new TextEncoder().encode(key), { name: 'HMAC', hash: 'SHA-256' }, false, [usage])
+}
+
+// The bucket a cookie value of the form BUCKET.HEX-HMAC pins, or null when it
+// isn't signed with key
+async function verifyRolloutCookie(cookie, key) {
+  const match = /^([0-9]+)\.((?:[0-9a-fA-F]{2})+)$/.exec(cookie)
+  if (!key || !match || Number(match[1]) >= ROLLOUT_BUCKETS) {
+    return null
+  }
+  const signature = new Uint8Array(match[2].match(/../g).map(h => parseInt(h, 16)))
+  const valid = await crypto.subtle.verify('HMAC', await rolloutHmacKey(key, 'verify'), signature, new TextEncoder().encode(match[1]))
+  return valid ? Number(match[1]) : null
+}
+
+async function rolloutCookie(bucket, key) {
+  const mac = await crypto.subtle.sign('HMAC', await rolloutHmacKey(key, 'sign'), new TextEncoder().encode(String(bucket)))
+  const hex = Array.from(new Uint8Array(mac), b => b.toString(16).padStart(2, '0')).join('')
+  return `${ROLLOUT_COOKIE}=${bucket}.${hex}; Path=/; Max-Age=86400; Secure; HttpOnly; SameSite=Lax`
+}
+
+function getMaintenanceScopes() {
+  try {
+    const scopes = JSON.parse((typeof MAINTENANCE_SCOPES !== 'undefined' && MAINTENANCE_SCOPES) || '[]')
+    return Array.isArray(scopes) ? scopes : []
+  } catch (e) {
+    // Invalid JSON, behave as if no scopes were configured
+    return []
+  }
+}
+
+// Picks the most specific scope matching the request: an exact host beats a
+// wildcard ("*.example.com"), which beats no host; longer hosts and then longer
+// paths break ties. Paths match on segment boundaries, so "/admin" covers
+// "/admin/users" but not "/administrator". Keep in sync with internal/scope.
+function matchScope(scopes, host, path) {
+  host = (host || '').toLowerCase()
+  let best = null
+  let bestRank = null
+  for (const scope of scopes) {
+    const rank = scopeRank(scope, host, path)
+    if (!rank) continue
+    if (!bestRank || outranks(rank, bestRank)) {
+      best = scope
+      bestRank = rank
+    }
+  }
+  return best
+}
+
+function scopeRank(scope, host, path) {
+  const rank = [0, 0, 0]
+  const pattern = (scope.host || '').toLowerCase()
+  if (pattern.startsWith('*.')) {
+    const suffix = pattern.slice(1)
+    if (!host.endsWith(suffix) || host.length === suffix.length) return null
+    rank[0] = 1
+    rank[1] = suffix.length
+  } else if (pattern) {
+    if (host !== pattern) return null
+    rank[0] = 2
+    rank[1] = pattern.length
+  }
+
+  const prefix = (scope.path || '').replace(/\/+$/, '')
+  if (prefix && path !== prefix && !path.startsWith(prefix + '/')) return null
+  rank[2] = prefix.length
+  return rank
+}
+
+function outranks(a, b) {
+  for (let i = 0; i < a.length; i++) {
+    if (a[i] !== b[i]) return a[i] > b[i]
+  }
+  return false
+}
+
+// Custom templates arrive through the PAGE_TEMPLATE binding; values are already
+// escaped, and substitution is single-pass so user text can't inject placeholders
+function getPageTemplate() {
+  return (typeof PAGE_TEMPLATE !== 'undefined' && PAGE_TEMPLATE) || DEFAULT_TEMPLATE
+}
+
+function renderTemplate(template, values) {
+  return template.replace(/\{\{\s*([a-z_]+)\s*\}\}/g, (_, name) => values[name] ?? '')
+}
+
+// Keep in sync with templates/default.html (internal/page tests compare the two)
+const DEFAULT_TEMPLATE = `<!DOCTYPE html>
+<html lang="{{lang}}">
 <head>
   <meta charset="utf-8">
   <meta name="viewport" content="width=device-width, initial-scale=1">
-  <title>${escapeHtml(MAINTENANCE_TITLE) || 'Maintenance Mode'}</title>
+  <title>{{title}}</title>
   <style>
     /* Making it look professional even when things aren't working */
     body {
@@ -88,40 +1473,93 @@ async function handleRequest(request) {
     h1 { color: #333; margin-bottom: 1rem; }
     p { color: #666; line-height: 1.6; }
     .contact { margin-top: 2rem; font-size: 0.9rem; color: #999; }
-    ${customStyles}
+    .updates { list-style: none; padding: 0; margin: 1.5rem 0; text-align: left; font-size: 0.9rem; color: #666; }
+    .updates li { border-left: 3px solid #ddd; padding-left: 0.75rem; margin-bottom: 0.75rem; }
+    .updates time { display: block; font-size: 0.8rem; color: #999; }
+    {{custom_css}}
   </style>
 </head>
 <body>
   <div class="container">
-    ${logoHtml}
-    <h1>${escapeHtml(MAINTENANCE_TITLE) || 'Maintenance Mode'}</h1>
-    <p>${escapeHtml(MAINTENANCE_MESSAGE) || 'We are currently performing scheduled maintenance. We will be back shortly.'}</p>
-    ${getMaintenanceWindowMessage()}
-    ${sanitizedEmail ? `<p class="contact">Contact: <a href="mailto:${sanitizedEmail}">${sanitizedEmail}</a></p>` : ''}
+    {{logo}}
+    <h1>{{title}}</h1>
+    <p>{{message}}</p>
+    {{updates}}
+    {{window}}
+    {{contact}}
   </div>
 </body>
-</html>`
+</html>
+`
 
-  // Return a 503 because we're being honest about the service being unavailable
-  // The Retry-After header is optimistic, but hey, we can hope
-  return new Response(html, {
-    status: 503,
-    headers: {
-      'Content-Type': 'text/html;charset=UTF-8',
-      'Cache-Control': 'no-store, no-cache, must-revalidate', // Don't cache this disaster
-      'Retry-After': '3600', // Try again in an hour (fingers crossed we're done by then)
-      'Content-Security-Policy': "default-src 'none'; style-src 'unsafe-inline'; img-src https:;",
-      'X-Content-Type-Options': 'nosniff',
-      'X-Frame-Options': 'DENY',
-      'Referrer-Policy': 'no-referrer'
-    }
-  })
+function getLocalizedContent(request, state) {
+  const defaultLocale = (typeof DEFAULT_LOCALE !== 'undefined' && DEFAULT_LOCALE) || 'en'
+  let localized = {}
+  let countryLocales = {}
+  try {
+    localized = JSON.parse((typeof LOCALIZED_CONTENT !== 'undefined' && LOCALIZED_CONTENT) || '{}')
+    countryLocales = JSON.parse((typeof COUNTRY_LOCALES !== 'undefined' && COUNTRY_LOCALES) || '{}')
+  } catch (e) {
+    // Invalid JSON, fall back to the single-language bindings
+  }
+
+  const locale = negotiateLocale(
+    request.headers.get('Accept-Language'),
+    request.cf?.country,
+    Object.keys(localized),
+    defaultLocale,
+    countryLocales
+  )
+  const entry = localized[locale] || {}
+  return {
+    locale,
+    title: entry.title || state.title,
+    message: entry.message || state.message
+  }
 }
 
-function checkMaintenanceWindow(now) {
+// Picks the best available locale: Accept-Language ranges in q order (exact tag,
+// then primary language, then any sibling region), then the visitor's country,
+// then the default. Keep in sync with internal/locale/negotiate.go.
+function negotiateLocale(acceptLanguage, country, available, defaultLocale, countryLocales) {
+  const byLower = {}
+  for (const tag of available) {
+    byLower[tag.toLowerCase()] = tag
+  }
+
+  const ranges = (acceptLanguage || '')
+    .split(',')
+    .map((part, index) => {
+      const [tag, ...params] = part.trim().split(';')
+      let q = 1
+      for (const param of params) {
+        const [key, value] = param.trim().split('=')
+        if (key === 'q') q = parseFloat(value)
+      }
+      return { tag: tag.trim().toLowerCase(), q: isNaN(q) ? 0 : q, index }
+    })
+    .filter(range => range.tag && range.tag !== '*' && range.q > 0)
+    .sort((a, b) => b.q - a.q || a.index - b.index)
+
+  for (const { tag } of ranges) {
+    if (byLower[tag]) return byLower[tag]
+    const primary = tag.split('-')[0]
+    if (byLower[primary]) return byLower[primary]
+    const sibling = Object.keys(byLower).sort().find(candidate => candidate.split('-')[0] === primary)
+    if (sibling) return byLower[sibling]
+  }
+
+  const countryLocale = country && countryLocales && countryLocales[country]
+  if (countryLocale && byLower[countryLocale.toLowerCase()]) {
+    return byLower[countryLocale.toLowerCase()]
+  }
+  return defaultLocale
+}
+
+function checkMaintenanceWindow(now, state) {
   // Check if we're within a scheduled maintenance window
-  const startTime = MAINTENANCE_WINDOW_START
-  const endTime = MAINTENANCE_WINDOW_END
+  const startTime = state.windowStart
+  const endTime = state.windowEnd
   
   if (!startTime || !endTime) {
     return false
@@ -137,22 +1575,104 @@ function checkMaintenanceWindow(now) {
   }
 }
 
-function getMaintenanceWindowMessage() {
-  const startTime = MAINTENANCE_WINDOW_START
-  const endTime = MAINTENANCE_WINDOW_END
+function getMaintenanceWindowMessage(nonce, state) {
+  const startTime = state.windowStart
+  const endTime = state.windowEnd
   
   if (!startTime || !endTime) {
     return ''
   }
   
+  const end = new Date(endTime)
+  if (isNaN(end.getTime())) {
+    return ''
+  }
+
+  // Server-side text uses the configured display timezone so the page still reads
+  // sensibly with JavaScript off; the script below swaps in the visitor's local time
+  const displayTimezone = getDisplayTimezone()
+  return `<p class="window" style="font-size: 0.9rem; color: #888;">Expected completion: <time datetime="${end.toISOString()}" data-maintenance-end>${escapeHtml(formatInTimeZone(end, displayTimezone))}</time></p>
+    <p class="countdown" id="maintenance-countdown" style="font-size: 0.9rem; color: #888;"></p>
+    <script nonce="${nonce}">${COUNTDOWN_SCRIPT}</script>`
+}
+
+function getDisplayTimezone() {
+  return (typeof DISPLAY_TIMEZONE !== 'undefined' && DISPLAY_TIMEZONE) || 'UTC'
+}
+
+// The timeline is one JSON array under a single key so each request costs one
+// cached KV read; the binding only exists when enable_status_updates is set.
+// Keep the format in sync with internal/updates.
+async function getStatusUpdates() {
+  if (typeof MAINTENANCE_KV === 'undefined') {
+    return []
+  }
   try {
-    const end = new Date(endTime)
-    return `<p style="font-size: 0.9rem; color: #888;">Expected completion: ${end.toUTCString()}</p>`
+    const updates = await MAINTENANCE_KV.get('status_updates', { type: 'json', cacheTtl: 30 })
+    return Array.isArray(updates) ? updates : []
   } catch (e) {
-    return ''
+    // Unreadable timeline, show the page without it
+    return []
   }
 }
 
+// Newest first; entries without a message or a valid timestamp are skipped.
+// Keep in sync with page.UpdatesHTML.
+function renderStatusUpdates(updates, timeZone) {
+  const items = updates
+    .map(update => ({ message: update && update.message, postedAt: new Date(update && update.posted_at) }))
+    .filter(update => typeof update.message === 'string' && update.message && !isNaN(update.postedAt.getTime()))
+    .sort((a, b) => b.postedAt - a.postedAt)
+    .map(update => `<li><time datetime="${update.postedAt.toISOString()}">${escapeHtml(formatInTimeZone(update.postedAt, timeZone))}</time> ${escapeHtml(update.message)}</li>`)
+  return items.length ? `<ul class="updates">${items.join('')}</ul>` : ''
+}
+
+// Formats a date for humans in the given IANA timezone, e.g.
+// "Sun, Apr 6, 2025, 10:00 UTC". Unknown zones fall back to plain UTC
+// rather than taking the whole page down with a RangeError.
+function formatInTimeZone(date, timeZone) {
+  try {
+    return new Intl.DateTimeFormat('en-US', {
+      weekday: 'short',
+      year: 'numeric',
+      month: 'short',
+      day: 'numeric',
+      hour: '2-digit',
+      minute: '2-digit',
+      hourCycle: 'h23',
+      timeZone,
+      timeZoneName: 'short'
+    }).format(date)
+  } catch (e) {
+    return date.toUTCString()
+  }
+}
+
+// Runs in the visitor's browser: rewrites the completion time in their own
+// timezone/locale and ticks a countdown to the end of the window
+const COUNTDOWN_SCRIPT = `(function () {
+  var el = document.querySelector('time[data-maintenance-end]');
+  var out = document.getElementById('maintenance-countdown');
+  if (!el) return;
+  var end = new Date(el.getAttribute('datetime'));
+  try {
+    el.textContent = end.toLocaleString(undefined, {
+      year: 'numeric', month: '2-digit', day: '2-digit',
+      hour: '2-digit', minute: '2-digit', timeZoneName: 'short'
+    });
+  } catch (e) {}
+  function pad(n) { return n < 10 ? '0' + n : '' + n; }
+  function tick() {
+    var ms = end.getTime() - Date.now();
+    if (ms <= 0) { out.textContent = 'We should be back any moment now.'; return; }
+    var s = Math.floor(ms / 1000);
+    var h = Math.floor(s / 3600);
+    out.textContent = 'Time remaining: ' + h + ':' + pad(Math.floor(s % 3600 / 60)) + ':' + pad(s % 60);
+    setTimeout(tick, 1000);
+  }
+  if (out) tick();
+})();`
+
 function isValidHttpsUrl(url) {
   try {
     const parsedUrl = new URL(url)